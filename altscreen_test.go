@@ -0,0 +1,75 @@
+package vt10x
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAltScreenVariants(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    int
+		reentry string // alternate screen content after leaving and entering again with the same mode
+		leave   string // alternate screen content after leaving
+	}{
+		{"47 keeps the alternate screen", 47, "alt", "alt"},
+		{"1047 clears on leaving", 1047, "", ""},
+		{"1049 clears on entering", 1049, "", "alt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := New(WithSize(10, 3))
+			set, reset := fmt.Sprintf("\033[?%dh", tt.mode), fmt.Sprintf("\033[?%dl", tt.mode)
+			writeSeq(t, term, "main"+set+"\033[Halt")
+			if s := term.(MetaDumper).DumpMeta(); !s.AltScreen || s.AltScreenMode != tt.mode {
+				t.Fatalf("expected the alternate screen entered with %d, got %v %d", tt.mode, s.AltScreen, s.AltScreenMode)
+			}
+
+			writeSeq(t, term, reset)
+			s := term.DumpState()
+			if s.AltScreen || s.AltScreenMode != 0 {
+				t.Fatalf("expected the primary screen, got %v %d", s.AltScreen, s.AltScreenMode)
+			}
+			if got := strings.TrimSpace(cellText(s.PrimaryBuffer[0])); got != "main" {
+				t.Fatalf("expected the primary screen kept, got %q", got)
+			}
+			if got := strings.TrimSpace(cellText(s.AlternateBuffer[0])); got != tt.leave {
+				t.Fatalf("expected %q left on the alternate screen, got %q", tt.leave, got)
+			}
+
+			writeSeq(t, term, set)
+			if got := strings.TrimSpace(cellText(term.DumpState().PrimaryBuffer[0])); got != tt.reentry {
+				t.Fatalf("expected %q on entering again, got %q", tt.reentry, got)
+			}
+		})
+	}
+}
+
+func TestAltScreen1049SavesCursor(t *testing.T) {
+	term := New(WithSize(10, 5))
+
+	writeSeq(t, term, "\033[3;4H\033[?1049h\033[5;5H\033[?1049l")
+	if c := term.Cursor(); c.X != 3 || c.Y != 2 {
+		t.Fatalf("expected 1049 to restore the cursor, got (%d,%d)", c.X, c.Y)
+	}
+
+	writeSeq(t, term, "\033[?1047h\033[5;5H\033[?1047l")
+	if c := term.Cursor(); c.X != 4 || c.Y != 4 {
+		t.Fatalf("expected 1047 to leave the cursor alone, got (%d,%d)", c.X, c.Y)
+	}
+}
+
+func TestAltScreenModeMixed(t *testing.T) {
+	term := New(WithSize(10, 3))
+
+	// Entering again with another mode keeps the mode that switched, and leaving follows the mode that leaves.
+	writeSeq(t, term, "\033[?1049h\033[?47halt")
+	if s := term.(MetaDumper).DumpMeta(); s.AltScreenMode != 1049 {
+		t.Fatalf("expected 1049 to stay the active mode, got %d", s.AltScreenMode)
+	}
+	writeSeq(t, term, "\033[?1047l")
+	if got := strings.TrimSpace(cellText(term.DumpState().AlternateBuffer[0])); got != "" {
+		t.Fatalf("expected 1047 to clear the alternate screen on leaving, got %q", got)
+	}
+}
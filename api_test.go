@@ -0,0 +1,72 @@
+package vt10x
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// Compile-time checks that the concrete types keep satisfying the public interfaces.
+var (
+	_ Terminal = (*terminal)(nil)
+	_ View     = (*State)(nil)
+	_ Terminal = New()
+
+	_ MetaDumper = (*State)(nil)
+	_ Hasher     = (*State)(nil)
+
+	_ interface {
+		io.ReaderFrom
+		MetaDumper
+		Hasher
+		ScrollbackLineTaker
+		CursorRecorder
+		PromptReporter
+		LinkFinder
+		Watcher
+		InputSender
+		EpochAdvancer
+		Searcher
+		LineJoiner
+		CommandTracker
+		Subscriber
+		StatsReporter
+		MemoryManager
+	} = (*terminal)(nil)
+)
+
+// TestDeprecatedShims ensures the deprecated surface keeps behaving as documented until it is removed.
+func TestDeprecatedShims(t *testing.T) {
+	if !RGBPattern.MatchString("ff/80/00") || HashPattern.FindString("#a") != "a" {
+		t.Fatal("deprecated color patterns no longer match color specs")
+	}
+
+	// Replacing the deprecated patterns must not affect color parsing.
+	saved := RGBPattern
+	defer func() { RGBPattern = saved }()
+	RGBPattern = nil
+	if _, _, _, err := parseColor("rgb:ff/80/00"); err != nil {
+		t.Fatalf("parseColor depends on the deprecated RGBPattern: %v", err)
+	}
+
+	state := New().DumpState()
+	if state.AutoWrap != state.Wrap {
+		t.Fatalf("expected AutoWrap to mirror Wrap, got AutoWrap=%v Wrap=%v", state.AutoWrap, state.Wrap)
+	}
+}
+
+func TestOptionNames(t *testing.T) {
+	var replies strings.Builder
+	opts := []Option{WithSize(4, 1), WithResponder(&replies), WithScrollback(1), WithPalette(DefaultPalette())}
+	term := New(opts...)
+	writeSeq(t, term, "a\r\nb\033[6n")
+	if cols, rows := term.Size(); cols != 4 || rows != 1 {
+		t.Fatalf("expected 4x1, got %dx%d", cols, rows)
+	}
+	if replies.String() != "\033[1;2R" {
+		t.Fatalf("expected the cursor report sent to the responder, got %q", replies.String())
+	}
+	if lines, _ := term.TakeScrollback(); len(lines) != 1 || string(lines[0]) != "a   " {
+		t.Fatalf("expected the scrolled line captured, got %q", lines)
+	}
+}
@@ -0,0 +1,228 @@
+package automation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/hinshun/vt10x"
+	"github.com/hinshun/vt10x/expect"
+)
+
+// DefaultTimeout is how long Expect waits for a match unless configured otherwise.
+const DefaultTimeout = 10 * time.Second
+
+// ErrTimeout is returned by Expect when the screen does not match in time. It is expect.ErrTimeout.
+var ErrTimeout = expect.ErrTimeout
+
+// ErrExited is returned by Expect when the application's output ends without the screen matching. It is
+// expect.ErrExited.
+var ErrExited = expect.ErrExited
+
+// Starter starts cmd and returns the connection to it: reads return its output and writes go to its input. Closing
+// the connection must release it. Without one, Run starts commands on a pseudo-terminal; StartPipes is one for
+// programs that should not have a terminal, or platforms without pseudo-terminals.
+type Starter func(cmd *exec.Cmd) (io.ReadWriteCloser, error)
+
+// Automation drives a single terminal application through an emulated terminal. Its methods are safe for
+// concurrent use.
+type Automation struct {
+	termOpts []vt10x.TerminalOption
+	start    Starter
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	app  application    // nil until Run or Attach
+	term vt10x.Terminal // the terminal as vt10x.New returns it, for type assertions
+	cmd  *exec.Cmd      // set if Run started the application with a Starter
+	wait func() error   // waits for the application started by Run
+}
+
+// application is an attached application: a vt10x.PtyTerminal, or a connTerminal.
+type application interface {
+	expect.Screen
+	Input() io.Writer
+	Close() error
+}
+
+// Option configures an Automation.
+type Option func(*Automation)
+
+// WithTerminalOptions sets the options the emulated terminal is created with, such as its size.
+func WithTerminalOptions(opts ...vt10x.TerminalOption) Option {
+	return func(a *Automation) {
+		a.termOpts = opts
+	}
+}
+
+// WithStarter sets how Run starts applications, in place of a pseudo-terminal.
+func WithStarter(s Starter) Option {
+	return func(a *Automation) {
+		a.start = s
+	}
+}
+
+// WithTimeout sets how long Expect waits for a match.
+func WithTimeout(d time.Duration) Option {
+	return func(a *Automation) {
+		a.timeout = d
+	}
+}
+
+// New returns an Automation with no application attached; use Run or Attach to start driving one.
+func New(opts ...Option) *Automation {
+	a := &Automation{timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Run starts cmd and attaches to it. Unless a Starter is set, cmd runs on a pseudo-terminal the size of the emulated
+// terminal, as it would in a terminal emulator, and resizing the terminal resizes the pseudo-terminal too.
+func (a *Automation) Run(cmd *exec.Cmd) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.app != nil {
+		return errAttached
+	}
+	if a.start == nil {
+		term, err := vt10x.NewWithCommand(cmd, a.termOpts...)
+		if err != nil {
+			return fmt.Errorf("automation: %w", err)
+		}
+		a.app, a.term, a.wait = term, term.Terminal, term.Wait
+		return nil
+	}
+
+	conn, err := a.start(cmd)
+	if err != nil {
+		return fmt.Errorf("automation: starting %s: %w", cmd.Path, err)
+	}
+	t := newConnTerminal(conn, a.termOpts)
+	wait := sync.OnceValue(cmd.Wait)
+	a.app, a.term, a.cmd = t, t.Terminal, cmd
+	a.wait = func() error {
+		<-t.Done()
+		return wait()
+	}
+	return nil
+}
+
+// Attach drives an application that is already running, reading its output from conn and sending input to it.
+func (a *Automation) Attach(conn io.ReadWriteCloser) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.app != nil {
+		return errAttached
+	}
+	t := newConnTerminal(conn, a.termOpts)
+	a.app, a.term = t, t.Terminal
+	return nil
+}
+
+var errAttached = errors.New("automation: an application is already attached")
+
+// attached returns the attached application, or an error if there is none.
+func (a *Automation) attached() (application, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.app == nil {
+		return nil, errors.New("automation: no application attached")
+	}
+	return a.app, nil
+}
+
+// Terminal returns the emulated terminal the application draws on, or nil before Run or Attach. Resizing the
+// terminal of an application Run started on a pseudo-terminal resizes the pseudo-terminal too.
+func (a *Automation) Terminal() vt10x.Terminal {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.app == nil {
+		return nil
+	}
+	return a.app
+}
+
+// Type sends text to the application as if it were typed. It is sent as it is, so use "\r" for Enter, or Press for
+// keys that send escape sequences.
+func (a *Automation) Type(text string) error {
+	app, err := a.attached()
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(app.Input(), text)
+	return err
+}
+
+// Press sends the sequence key produces, with mods held, in the terminal's current modes.
+func (a *Automation) Press(key vt10x.Key, mods vt10x.KeyMod) error {
+	app, err := a.attached()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	term := a.term
+	a.mu.Unlock()
+
+	_, err = app.Input().Write(vt10x.EncodeKey(key, mods, term.(vt10x.MetaDumper).DumpMeta()))
+	return err
+}
+
+// Expect waits until the screen text, one line per row, matches re, and returns the match and its submatches. It
+// waits as expect.WaitForRegexp does, returning ErrTimeout if the screen does not match within the timeout, and
+// ErrExited, or the error that ended the application's output, if the output ends first.
+func (a *Automation) Expect(re *regexp.Regexp) ([]string, error) {
+	app, err := a.attached()
+	if err != nil {
+		return nil, err
+	}
+	return expect.WaitForRegexp(app, re, a.timeout)
+}
+
+// Screenshot returns the current terminal state, including the screen contents, cursor, and modes. It returns the
+// zero TerminalState before Run or Attach.
+func (a *Automation) Screenshot() vt10x.TerminalState {
+	app, err := a.attached()
+	if err != nil {
+		return vt10x.TerminalState{}
+	}
+	return app.DumpState()
+}
+
+// Wait waits for the application started by Run to exit and for its output to be read.
+func (a *Automation) Wait() error {
+	a.mu.Lock()
+	wait := a.wait
+	a.mu.Unlock()
+	if wait == nil {
+		return errors.New("automation: no application started by Run")
+	}
+	return wait()
+}
+
+// Close closes the connection to the application. If the application was started by Run, Close kills it if it is
+// still running and waits for it to exit.
+func (a *Automation) Close() error {
+	a.mu.Lock()
+	app, cmd, wait := a.app, a.cmd, a.wait
+	a.mu.Unlock()
+	if app == nil {
+		return nil
+	}
+
+	err := app.Close()
+	if cmd != nil {
+		cmd.Process.Kill()
+		wait()
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+package automation
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+// attachPipe attaches a to an in-memory connection and returns the application's end of it.
+func attachPipe(t *testing.T, a *Automation) net.Conn {
+	t.Helper()
+
+	app, conn := net.Pipe()
+	if err := a.Attach(conn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		app.Close()
+		a.Close()
+	})
+	return app
+}
+
+func TestExpect(t *testing.T) {
+	a := New(WithTerminalOptions(vt10x.WithSize(20, 4)), WithTimeout(time.Second))
+	app := attachPipe(t, a)
+
+	go func() {
+		io.WriteString(app, "loading...")
+		time.Sleep(10 * time.Millisecond)
+		// The check mark arrives split across writes.
+		io.WriteString(app, "\r\033[Kready: 4\xe2\x9c")
+		app.Write([]byte{0x93})
+	}()
+
+	m, err := a.Expect(regexp.MustCompile(`ready: (\d)✓`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m[1] != "4" {
+		t.Fatalf("expected the submatch 4, got %q", m[1])
+	}
+	if s := a.Screenshot(); s.CursorX != 9 || s.CursorY != 0 {
+		t.Fatalf("expected the cursor after the output, got (%d,%d)", s.CursorX, s.CursorY)
+	}
+}
+
+func TestExpectTimeout(t *testing.T) {
+	a := New(WithTimeout(20 * time.Millisecond))
+	attachPipe(t, a)
+
+	if _, err := a.Expect(regexp.MustCompile("never")); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestExpectExited(t *testing.T) {
+	a := New(WithTimeout(time.Second))
+	app := attachPipe(t, a)
+
+	go func() {
+		io.WriteString(app, "bye")
+		app.Close()
+	}()
+	if _, err := a.Expect(regexp.MustCompile("never")); !errors.Is(err, ErrExited) {
+		t.Fatalf("expected ErrExited, got %v", err)
+	}
+}
+
+func TestTypeAndPress(t *testing.T) {
+	a := New()
+	app := attachPipe(t, a)
+
+	read := func(want string) {
+		t.Helper()
+
+		buf := make([]byte, len(want))
+		if _, err := io.ReadFull(app, buf); err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != want {
+			t.Fatalf("expected the application to receive %q, got %q", want, buf)
+		}
+	}
+
+	go a.Type("ls\r")
+	read("ls\r")
+	go a.Press(vt10x.KeyUp, 0)
+	read("\033[A")
+
+	// Once the application enables application cursor keys, arrows send SS3 sequences.
+	go io.WriteString(app, "\033[?1h")
+	if err := waitFor(func() bool { return a.Screenshot().Mode&vt10x.ModeAppCursor != 0 }); err != nil {
+		t.Fatal(err)
+	}
+	go a.Press(vt10x.KeyUp, 0)
+	read("\033OA")
+}
+
+func TestReplies(t *testing.T) {
+	a := New()
+	app := attachPipe(t, a)
+
+	// The terminal answers a cursor position request through the connection, as a real one would.
+	go io.WriteString(app, "\033[3;5H\033[6n")
+	buf := make([]byte, len("\033[3;5R"))
+	if _, err := io.ReadFull(app, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "\033[3;5R" {
+		t.Fatalf("expected a cursor position report, got %q", buf)
+	}
+}
+
+func TestRun(t *testing.T) {
+	a := New(WithTimeout(5 * time.Second))
+	defer a.Close()
+	if err := a.Run(exec.Command("sh", "-c", `test -t 0 && echo tty; read name; printf 'hello, %s\n' "$name"`)); err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	// The application runs on a terminal, which echoes what is typed.
+	if _, err := a.Expect(regexp.MustCompile(`(?m)^tty *$`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Type("vt10x\r"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Expect(regexp.MustCompile("(?m)^vt10x *\nhello, vt10x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Wait(); err != nil {
+		t.Fatalf("expected the command to succeed, got %v", err)
+	}
+}
+
+func TestRunWithPipes(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	a := New(WithStarter(StartPipes), WithTimeout(5*time.Second))
+	defer a.Close()
+	if err := a.Run(exec.Command(sh, "-c", `test -t 0 || echo pipe; read name; printf 'hello, %s\n' "$name"`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Type("vt10x\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Expect(regexp.MustCompile(`(?m)^pipe\s+hello, vt10x`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Wait(); err != nil {
+		t.Fatalf("expected the command to succeed, got %v", err)
+	}
+}
+
+func waitFor(cond func() bool) error {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return errors.New("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
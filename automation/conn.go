@@ -0,0 +1,118 @@
+package automation
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/hinshun/vt10x"
+)
+
+// connTerminal is a terminal fed an application's output read from a connection, the counterpart of
+// vt10x.PtyTerminal for applications not on a pseudo-terminal. Replies the terminal generates, such as cursor
+// position reports, go back to the application like typed input.
+type connTerminal struct {
+	vt10x.Terminal
+	conn io.ReadWriteCloser
+
+	done    chan struct{} // closed once the application's output ends
+	readErr error         // set before done is closed
+
+	input sync.Mutex // serializes writes to the application
+
+	replyMu sync.Mutex
+	replies [][]byte
+	sending bool
+}
+
+func newConnTerminal(conn io.ReadWriteCloser, opts []vt10x.TerminalOption) *connTerminal {
+	t := &connTerminal{conn: conn, done: make(chan struct{})}
+	t.Terminal = vt10x.New(append(opts, vt10x.WithWriter(connReplies{t}))...)
+	go t.read()
+	return t
+}
+
+// read feeds the application's output to the terminal until it ends.
+func (t *connTerminal) read() {
+	_, err := t.Terminal.(io.ReaderFrom).ReadFrom(t.conn)
+	if err != nil && !errors.Is(err, io.EOF) {
+		t.readErr = err
+	}
+	close(t.done)
+}
+
+func (t *connTerminal) Subscribe() (updates <-chan vt10x.Update, cancel func()) {
+	return t.Terminal.(vt10x.Subscriber).Subscribe()
+}
+
+func (t *connTerminal) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *connTerminal) Err() error {
+	select {
+	case <-t.done:
+		return t.readErr
+	default:
+		return nil
+	}
+}
+
+// Input returns the writer that sends input to the application.
+func (t *connTerminal) Input() io.Writer {
+	return connInput{t}
+}
+
+// Close closes the connection and waits for the application's output to end.
+func (t *connTerminal) Close() error {
+	err := t.conn.Close()
+	<-t.done
+	return err
+}
+
+type connInput struct {
+	t *connTerminal
+}
+
+func (w connInput) Write(p []byte) (int, error) {
+	w.t.input.Lock()
+	defer w.t.input.Unlock()
+
+	return w.t.conn.Write(p)
+}
+
+// connReplies queues the terminal's replies for the application. The terminal writes them while locked, so they are
+// sent from another goroutine to keep an application that is not reading its input from stalling the terminal.
+type connReplies struct {
+	t *connTerminal
+}
+
+func (w connReplies) Write(p []byte) (int, error) {
+	t := w.t
+	t.replyMu.Lock()
+	defer t.replyMu.Unlock()
+
+	t.replies = append(t.replies, append([]byte(nil), p...))
+	if !t.sending {
+		t.sending = true
+		go t.sendReplies()
+	}
+	return len(p), nil
+}
+
+// sendReplies sends queued replies in order until the queue is empty.
+func (t *connTerminal) sendReplies() {
+	for {
+		t.replyMu.Lock()
+		if len(t.replies) == 0 {
+			t.sending = false
+			t.replyMu.Unlock()
+			return
+		}
+		p := t.replies[0]
+		t.replies = t.replies[1:]
+		t.replyMu.Unlock()
+
+		t.Input().Write(p)
+	}
+}
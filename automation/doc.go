@@ -0,0 +1,18 @@
+// Package automation drives terminal applications headlessly for testing, in the manner of a browser driver: it
+// runs a program, types into it, waits for its screen to show expected text, and takes screenshots of the emulated
+// terminal.
+//
+//	a := automation.New(automation.WithTerminalOptions(vt10x.WithSize(80, 24)))
+//	defer a.Close()
+//	if err := a.Run(exec.Command("bash", "--norc")); err != nil {
+//		return err
+//	}
+//	a.Type("echo hello\r")
+//	if _, err := a.Expect(regexp.MustCompile(`(?m)^hello$`)); err != nil {
+//		return err
+//	}
+//
+// Programs run on a pseudo-terminal, so full-screen and line-editing applications behave as they would in a terminal
+// emulator. WithStarter(StartPipes) runs them with pipes for their standard streams instead. Expect waits the way the
+// expect package does.
+package automation
@@ -0,0 +1,52 @@
+package automation
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// StartPipes starts cmd with pipes for its standard input, and for its standard output and error combined. Programs
+// see that they are not on a terminal, so some disable colors or line editing; it suits those that should not have
+// a terminal, and platforms without pseudo-terminals.
+func StartPipes(cmd *exec.Cmd) (io.ReadWriteCloser, error) {
+	inR, inW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		inR.Close()
+		inW.Close()
+		return nil, err
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = inR, outW, outW
+	err = cmd.Start()
+	// The child has its own copies; closing ours lets reads see EOF once it exits.
+	inR.Close()
+	outW.Close()
+	if err != nil {
+		inW.Close()
+		outR.Close()
+		return nil, err
+	}
+	return &pipeConn{r: outR, w: inW}, nil
+}
+
+type pipeConn struct {
+	r, w *os.File
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	return c.w.Write(p)
+}
+
+func (c *pipeConn) Close() error {
+	return errors.Join(c.w.Close(), c.r.Close())
+}
@@ -0,0 +1,71 @@
+package vt10x
+
+import "time"
+
+// defaultBellInterval is how often the bell handler is called at most, unless WithBellHandler is given another
+// interval. Programs ring the bell in bursts, such as a shell completing in a loop, which would otherwise flood it.
+const defaultBellInterval = 200 * time.Millisecond
+
+// Bell is a ring of the bell (BEL), as passed to the bell handler.
+type Bell struct {
+	// Time is when the bell rang.
+	Time time.Time
+
+	// Rung is the number of times the bell rang since the handler was last called, including this one, so it is
+	// more than 1 when rings were throttled.
+	Rung int
+
+	// Visual is set if the terminal was configured with WithVisualBell, to flash rather than sound.
+	Visual bool
+}
+
+// WithBellHandler sets a function called when the bell rings, at most once per interval; rings in between are counted
+// in the next call's Bell.Rung. A non-positive interval selects the default of 200ms. It is called while the terminal
+// is locked, so it must not call back into the terminal.
+func WithBellHandler(fn func(Bell), interval time.Duration) TerminalOption {
+	return func(info *TerminalInfo) {
+		if interval <= 0 {
+			interval = defaultBellInterval
+		}
+		info.onBell, info.bellInterval = fn, interval
+	}
+}
+
+// WithVisualBell marks the bell as visual, which TerminalState and Bell report so that renderers flash the screen
+// instead of sounding the bell.
+func WithVisualBell() TerminalOption {
+	return func(info *TerminalInfo) {
+		info.visualBell = true
+	}
+}
+
+// bellState counts the rings of the bell and throttles the handler told of them.
+type bellState struct {
+	count int       // rings since the terminal was created
+	last  time.Time // when it last rang
+	sent  time.Time // when the handler was last called
+	rung  int       // rings not yet passed to the handler
+}
+
+// now returns the current time, from the line clock if there is one.
+func (t *State) now() time.Time {
+	if t.lineClock != nil {
+		return t.lineClock()
+	}
+	return time.Now()
+}
+
+// ringBell handles BEL.
+func (t *State) ringBell() {
+	now := t.now()
+	t.bell.count++
+	t.bell.last = now
+	t.bell.rung++
+	t.changed |= ChangedBell
+	if t.onBell == nil || !t.bell.sent.IsZero() && now.Sub(t.bell.sent) < t.bellInterval {
+		return
+	}
+	b := Bell{Time: now, Rung: t.bell.rung, Visual: t.visualBell}
+	t.bell.sent, t.bell.rung = now, 0
+	t.onBell(b)
+}
@@ -0,0 +1,71 @@
+package vt10x
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBellCount(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	term := New(WithLineTimestamps(func() time.Time { return now }))
+
+	// BEL ending an OSC string is not a ring.
+	writeSeq(t, term, "\033]0;title\007")
+	if s := term.(MetaDumper).DumpMeta(); s.BellCount != 0 || !s.LastBell.IsZero() {
+		t.Fatalf("expected no bell, got %d at %v", s.BellCount, s.LastBell)
+	}
+
+	writeSeq(t, term, "\a\033[1\a;2H\a")
+	if s := term.(MetaDumper).DumpMeta(); s.BellCount != 3 || !s.LastBell.Equal(now) || s.VisualBell {
+		t.Fatalf("expected 3 audible bells at %v, got %d at %v (visual %v)", now, s.BellCount, s.LastBell, s.VisualBell)
+	}
+	if c := term.Cursor(); c.X != 1 || c.Y != 0 {
+		t.Fatalf("expected BEL inside CSI to leave the sequence intact, got the cursor at (%d,%d)", c.X, c.Y)
+	}
+
+	restored := New(WithState(term.DumpState()))
+	if s := restored.(MetaDumper).DumpMeta(); s.BellCount != 3 || !s.LastBell.Equal(now) {
+		t.Fatalf("expected the bell count restored, got %d at %v", s.BellCount, s.LastBell)
+	}
+}
+
+func TestBellHandlerThrottle(t *testing.T) {
+	var bells []Bell
+	handler := func(b Bell) { bells = append(bells, b) }
+
+	term := New(WithBellHandler(handler, time.Hour), WithVisualBell())
+	writeSeq(t, term, "\a\a\a")
+	if len(bells) != 1 || bells[0].Rung != 1 || !bells[0].Visual {
+		t.Fatalf("expected one visual bell for a burst, got %+v", bells)
+	}
+	if s := term.(MetaDumper).DumpMeta(); s.BellCount != 3 || !s.VisualBell {
+		t.Fatalf("expected every ring counted, got %d", s.BellCount)
+	}
+
+	bells = nil
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	term = New(WithBellHandler(handler, time.Second), WithLineTimestamps(func() time.Time { return now }))
+	writeSeq(t, term, "\a\a")
+	now = now.Add(time.Second)
+	writeSeq(t, term, "\a")
+	if len(bells) != 2 || bells[1].Rung != 2 || !bells[1].Time.Equal(now) {
+		t.Fatalf("expected the throttled ring counted in the next call, got %+v", bells)
+	}
+}
+
+func TestBellUpdate(t *testing.T) {
+	term := New()
+	updates, cancel := term.(Subscriber).Subscribe()
+	defer cancel()
+
+	writeSeq(t, term, "\a")
+	if u := <-updates; !u.Bell || len(u.Rows) != 0 {
+		t.Fatalf("expected an update for the bell alone, got %+v", u)
+	}
+	term.Lock()
+	rang := term.(*terminal).Changed(ChangedBell)
+	term.Unlock()
+	if !rang {
+		t.Fatal("expected ChangedBell set")
+	}
+}
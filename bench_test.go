@@ -0,0 +1,132 @@
+package vt10x
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchFixtures are recorded output streams under testdata/bench, each representative of a workload that stresses a
+// different part of the parser: plain text, dense SGR, region scrolling, and full-screen alt-screen redraws.
+//
+// The rest are real programs captured at 80x24 with script(1), its header and trailer lines removed: vim paging and
+// searching through a Go file with syntax highlighting, top refreshing, cat of an application log with colored
+// fields, and the output of find.
+var benchFixtures = []string{"plain", "sgr", "scroll", "tui", "vim", "top", "log", "find"}
+
+func loadBenchFixture(tb testing.TB, name string) []byte {
+	tb.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "bench", name+".in"))
+	if err != nil {
+		tb.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+
+	return data
+}
+
+// TestBenchFixturesParse guards the benchmark fixtures themselves: every recording must be fully consumed by Write so
+// the benchmarks measure what they claim to.
+func TestBenchFixturesParse(t *testing.T) {
+	for _, name := range benchFixtures {
+		t.Run(name, func(t *testing.T) {
+			data := loadBenchFixture(t, name)
+			term := New(WithSize(80, 24))
+
+			n, err := term.Write(data)
+			if err != nil {
+				t.Fatalf("Write returned error: %v", err)
+			}
+			if n != len(data) {
+				t.Fatalf("Write consumed %d of %d bytes", n, len(data))
+			}
+		})
+	}
+}
+
+func benchmarkWrite(b *testing.B, data []byte, chunk int) {
+	term := New(WithSize(80, 24))
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for off := 0; off < len(data); off += chunk {
+			end := min(off+chunk, len(data))
+			if _, err := term.Write(data[off:end]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkWrite replays each fixture in 4KiB chunks, roughly the size of a PTY read.
+func BenchmarkWrite(b *testing.B) {
+	for _, name := range benchFixtures {
+		data := loadBenchFixture(b, name)
+		b.Run(name, func(b *testing.B) {
+			benchmarkWrite(b, data, 4096)
+		})
+	}
+}
+
+// BenchmarkWriteGiant feeds a multi-megabyte stream in a single Write call.
+func BenchmarkWriteGiant(b *testing.B) {
+	var buf bytes.Buffer
+	for _, name := range benchFixtures {
+		buf.Write(loadBenchFixture(b, name))
+	}
+	data := bytes.Repeat(buf.Bytes(), 8)
+
+	benchmarkWrite(b, data, len(data))
+}
+
+// BenchmarkReadFrom copies each fixture into the terminal with io.Copy, as a host reading from a pty would.
+func BenchmarkReadFrom(b *testing.B) {
+	for _, name := range benchFixtures {
+		data := loadBenchFixture(b, name)
+		b.Run(name, func(b *testing.B) {
+			term := New(WithSize(80, 24))
+			r := bytes.NewReader(data)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r.Reset(data)
+				// Hide bytes.Reader's WriteTo so io.Copy goes through ReadFrom.
+				if _, err := io.Copy(term, struct{ io.Reader }{r}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDumpState(b *testing.B) {
+	term := New(WithSize(80, 24))
+	if _, err := term.Write(loadBenchFixture(b, "sgr")); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = term.DumpState()
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	term := New(WithSize(80, 24))
+	if _, err := term.Write(loadBenchFixture(b, "plain")); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = term.String()
+	}
+}
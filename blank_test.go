@@ -0,0 +1,112 @@
+package vt10x
+
+import (
+	"testing"
+)
+
+// materializedRows counts the rows of lines that own storage rather than sharing the blank row.
+func materializedRows(s *State, lines []line) int {
+	n := 0
+	for _, l := range lines {
+		if !isSameLine(l, s.blank) {
+			n++
+		}
+	}
+	return n
+}
+
+func assertBlankIntact(t *testing.T, s *State) {
+	t.Helper()
+
+	for x, c := range s.blank {
+		if c != blankCell {
+			t.Fatalf("shared blank row modified at column %d: %+v", x, c)
+		}
+	}
+}
+
+func TestBlankRowsShared(t *testing.T) {
+	term := New(WithSize(80, 24))
+	s := term.(*terminal).State
+
+	if n := materializedRows(s, s.lines); n != 0 {
+		t.Fatalf("expected a fresh screen to have no materialized rows, got %d", n)
+	}
+	if n := materializedRows(s, s.altLines); n != 0 {
+		t.Fatalf("expected a fresh alternate screen to have no materialized rows, got %d", n)
+	}
+
+	writeSeq(t, term, "\033[5;10Hx")
+	if n := materializedRows(s, s.lines); n != 1 {
+		t.Fatalf("expected one materialized row after a write, got %d", n)
+	}
+	if g := term.Cell(9, 4); g.Char != 'x' {
+		t.Fatalf("expected x at (9,4), got %q", g.Char)
+	}
+	if g := term.Cell(9, 5); g != blankGlyph {
+		t.Fatalf("expected the row below to stay blank, got %+v", g)
+	}
+	assertBlankIntact(t, s)
+
+	writeSeq(t, term, "\033[2J")
+	if n := materializedRows(s, s.lines); n != 0 {
+		t.Fatalf("expected ED 2 to release every row, got %d materialized", n)
+	}
+}
+
+func TestBlankRowsNotCorruptedByEdits(t *testing.T) {
+	term := New(WithSize(20, 6))
+	s := term.(*terminal).State
+
+	sequences := []string{
+		"\033[1;31mred\033[m",                      // attributed write
+		"\033[3;1H\033[5@\033[5P",                  // ICH/DCH on a blank row
+		"\033[2;1H\033[2L\033[2M",                  // IL/DL
+		"\033[4;1H" + "abcdefghijklmnopqrstuvwxyz", // autowrap marks the wrapped row
+		"\033[6;1H\033[5X\033[K",                   // ECH/EL on a blank row
+		"\033[2S\033[2T",                           // SU/SD
+		"\033[?1049h\033[?1049l",                   // alternate screen round trip
+	}
+	for _, seq := range sequences {
+		writeSeq(t, term, seq)
+		assertBlankIntact(t, s)
+	}
+
+	if g := term.Cell(0, 5); g.Char != ' ' {
+		t.Fatalf("expected ECH/EL to blank the row, got %q", g.Char)
+	}
+}
+
+func TestBlankRowsClearWithAttributes(t *testing.T) {
+	term := New(WithSize(10, 3))
+	s := term.(*terminal).State
+
+	// Erasing with a non-default background colors the cells, so the rows must be materialized.
+	writeSeq(t, term, "\033[41m\033[2J")
+	if n := materializedRows(s, s.lines); n != 3 {
+		t.Fatalf("expected all rows materialized, got %d", n)
+	}
+	if g := term.Cell(4, 1); g.BG != Red {
+		t.Fatalf("expected red background, got %d", g.BG)
+	}
+	assertBlankIntact(t, s)
+}
+
+func TestBlankRowsResize(t *testing.T) {
+	term := New(WithSize(10, 5))
+	s := term.(*terminal).State
+
+	writeSeq(t, term, "\033[2;1Hhello")
+	term.Resize(20, 8)
+
+	if got := extractStr(term, 0, 4, 1); got != "hello" {
+		t.Fatalf("expected content to survive resize, got %q", got)
+	}
+	if n := materializedRows(s, s.lines); n != 1 {
+		t.Fatalf("expected only the written row to be materialized after resize, got %d", n)
+	}
+	if len(s.blank) != 20 {
+		t.Fatalf("expected blank row to track the new width, got %d", len(s.blank))
+	}
+	assertBlankIntact(t, s)
+}
@@ -0,0 +1,62 @@
+package vt10x
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestC1Controls(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+	}{
+		{"raw", "\x9b2;3Hx\x9d2;title\x9c\x85y\x9b1m"},
+		{"utf8", "\u009b2;3Hx\u009d2;title\u009c\u0085y\u009b1m"},
+	} {
+		term := New(WithC1Controls())
+		writeSeq(t, term, tc.in)
+		if g := term.Cell(2, 1); g.Char != 'x' {
+			t.Errorf("%s: expected CSI to move the cursor, got %q at 2,1", tc.name, g.Char)
+		}
+		if got := term.Title(); got != "title" {
+			t.Errorf("%s: expected OSC to set the title, got %q", tc.name, got)
+		}
+		if g := term.Cell(0, 2); g.Char != 'y' {
+			t.Errorf("%s: expected NEL to start the next line, got %q at 0,2", tc.name, g.Char)
+		}
+		if term.Cursor().Attr.Mode&attrBold == 0 {
+			t.Errorf("%s: expected CSI to set bold", tc.name)
+		}
+	}
+}
+
+func TestC1ControlAbortsSequence(t *testing.T) {
+	term := New(WithC1Controls())
+	// IND in the middle of a CSI abandons it; the parameters that follow are printed.
+	writeSeq(t, term, "\x9b5\x84Ax")
+	if a, x := term.Cell(0, 1), term.Cell(1, 1); a.Char != 'A' || x.Char != 'x' {
+		t.Errorf("expected the CSI abandoned, got %q%q on the second line", a.Char, x.Char)
+	}
+}
+
+func TestC1ControlsDisabled(t *testing.T) {
+	term := New()
+	writeSeq(t, term, "\u0085a\x9bb")
+	if g := term.Cell(0, 0); g.Char != 0x85 {
+		t.Errorf("expected the C1 rune printed, got %q", g.Char)
+	}
+	if g := term.Cell(1, 0); g.Char != 'a' {
+		t.Errorf("expected the raw C1 byte dropped, got %q", g.Char)
+	}
+}
+
+func TestC1ControlsParse(t *testing.T) {
+	term := New(WithC1Controls())
+	br := bufio.NewReader(strings.NewReader("\x9b3Cx"))
+	if err := term.Parse(br); err != nil {
+		t.Fatal(err)
+	}
+	if g := term.Cell(3, 0); g.Char != 'x' {
+		t.Errorf("expected the raw CSI parsed, got %q at 3,0", g.Char)
+	}
+}
@@ -0,0 +1,182 @@
+package vt10x
+
+// cell is the form in which the screen buffers store a Glyph: 12 bytes rather than 20, which matters for large
+// terminals, where the cells dominate a session's memory. The attribute bits of Glyph.Mode share a word with the
+// underline style, and colors are 16-bit indexes into the terminal's colorTable.
+type cell struct {
+	char       rune
+	attrs      uint16
+	fg, bg, ul uint16
+}
+
+const (
+	// modeMask covers the attribute bits of Glyph.Mode that a cell keeps; the word's top bits hold the underline
+	// style. Glyph.Mode has no other bits defined, and underline styles fit in the rest.
+	modeMask       = 1<<13 - 1
+	underlineShift = 13
+)
+
+// Color indexes: the 256 indexed colors map to themselves and the default colors follow them. Other colors, which are
+// RGB values, are interned in the colorTable from rgbIndex on.
+const (
+	defaultIndex = 256
+	rgbIndex     = defaultIndex + int(DefaultUnderline-DefaultFG) + 1
+	maxRGBColors = 1<<16 - rgbIndex
+)
+
+// blankCell is blankGlyph as stored.
+var blankCell = cell{
+	char: ' ',
+	fg:   colorIndexOf(DefaultFG),
+	bg:   colorIndexOf(DefaultBG),
+	ul:   colorIndexOf(DefaultUnderline),
+}
+
+// colorIndexOf returns the fixed index of c, which must be an indexed or default color.
+func colorIndexOf(c Color) uint16 {
+	if c < 256 {
+		return uint16(c)
+	}
+	return uint16(defaultIndex + c - DefaultFG)
+}
+
+// colorTable interns the RGB colors of a terminal's cells.
+type colorTable struct {
+	rgb   []Color
+	index map[Color]uint16
+
+	// misses counts the colors approximated since the table last filled; see (*State).colorIndex.
+	misses int
+}
+
+// colorIndex returns the index of c, interning it if it is an RGB color not yet in the table.
+//
+// When the table fills, it is rebuilt from the colors still on the screens. If that leaves it mostly full, which takes
+// tens of thousands of distinct colors on screen at once, then once it fills again new colors are approximated by the
+// nearest palette color, until a rebuild attempted after a quarter of the table's worth of them makes room.
+func (t *State) colorIndex(c Color) uint16 {
+	if c < 256 || c >= DefaultFG && c <= DefaultUnderline {
+		return colorIndexOf(c)
+	}
+	if i, ok := t.colors.index[c]; ok {
+		return i
+	}
+	if len(t.colors.rgb) == maxRGBColors {
+		if t.colors.misses == 0 {
+			t.compactColors()
+		}
+		if len(t.colors.rgb) > maxRGBColors*3/4 {
+			t.colors.misses = (t.colors.misses + 1) % (maxRGBColors / 4)
+			return uint16(nearestIndexed(c))
+		}
+	}
+	if t.colors.index == nil {
+		t.colors.index = make(map[Color]uint16)
+	}
+	i := uint16(rgbIndex + len(t.colors.rgb))
+	t.colors.rgb = append(t.colors.rgb, c)
+	t.colors.index[c] = i
+	return i
+}
+
+// color returns the color of index i.
+func (t *State) color(i uint16) Color {
+	if i < defaultIndex {
+		return Color(i)
+	}
+	if int(i) < rgbIndex {
+		return DefaultFG + Color(i-defaultIndex)
+	}
+	return t.rgbColor(i)
+}
+
+// rgbColor is color for the RGB colors, kept apart so that color inlines.
+func (t *State) rgbColor(i uint16) Color {
+	if int(i)-rgbIndex < len(t.colors.rgb) {
+		return t.colors.rgb[int(i)-rgbIndex]
+	}
+	return DefaultFG
+}
+
+// compactColors rebuilds the color table from the colors of the cells of both screens, dropping those no longer used.
+func (t *State) compactColors() {
+	old := t.colors.rgb
+	t.colors = colorTable{misses: t.colors.misses}
+	t.packed.valid = false
+	remap := func(i uint16) uint16 {
+		if int(i) < rgbIndex || int(i)-rgbIndex >= len(old) {
+			return i
+		}
+		return t.colorIndex(old[int(i)-rgbIndex])
+	}
+	for _, lines := range [][]line{t.lines, t.altLines} {
+		for _, l := range lines {
+			if isSameLine(l, t.blank) {
+				continue
+			}
+			for x := range l {
+				c := &l[x]
+				c.fg, c.bg, c.ul = remap(c.fg), remap(c.bg), remap(c.ul)
+			}
+		}
+	}
+}
+
+// nearestIndexed returns the color of the 6x6x6 cube of the 256-color palette nearest to the RGB color c.
+func nearestIndexed(c Color) Color {
+	level := func(v Color) Color {
+		switch v &= 0xff; {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	return 16 + 36*level(c>>16) + 6*level(c>>8) + level(c)
+}
+
+// pack returns g as stored.
+func (t *State) pack(g Glyph) cell {
+	return cell{
+		char:  g.Char,
+		attrs: uint16(g.Mode)&modeMask | uint16(g.Underline)<<underlineShift,
+		fg:    t.colorIndex(g.FG),
+		bg:    t.colorIndex(g.BG),
+		ul:    t.colorIndex(g.UnderlineColor),
+	}
+}
+
+// unpack returns the Glyph that c stores.
+func (t *State) unpack(c cell) Glyph {
+	return Glyph{
+		Char:           c.char,
+		Mode:           int16(c.attrs & modeMask),
+		Underline:      UnderlineStyle(c.attrs >> underlineShift),
+		FG:             t.color(c.fg),
+		BG:             t.color(c.bg),
+		UnderlineColor: t.color(c.ul),
+	}
+}
+
+// unpackLine stores the glyphs of l in dst, which must be as long. Runs of cells share their attributes, so each run is
+// unpacked once.
+func (t *State) unpackLine(dst []Glyph, l line) {
+	var (
+		last uint64
+		g    Glyph
+	)
+	for x := range l {
+		c := &l[x]
+		if key := c.key(); x == 0 || key != last {
+			g, last = t.unpack(*c), key
+		}
+		g.Char = c.char
+		dst[x] = g
+	}
+}
+
+// key returns the attributes of c as one word.
+func (c *cell) key() uint64 {
+	return uint64(c.attrs) | uint64(c.fg)<<16 | uint64(c.bg)<<32 | uint64(c.ul)<<48
+}
@@ -0,0 +1,80 @@
+package vt10x
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestCellSize(t *testing.T) {
+	if n := unsafe.Sizeof(cell{}); n != 12 {
+		t.Fatalf("expected cells to take 12 bytes, got %d", n)
+	}
+}
+
+func TestCellRoundTrip(t *testing.T) {
+	s := New(WithSize(4, 1)).(*terminal).State
+	for _, g := range []Glyph{
+		blankGlyph,
+		{Char: 'x', Mode: attrBold | attrWrap | attrOverline, Underline: UnderlineDashed, FG: 196, BG: DefaultBG,
+			UnderlineColor: DefaultUnderline},
+		{Char: '✓', FG: 0x123456, BG: 0xfedcba, UnderlineColor: 0x010203},
+		{Char: 'y', FG: DefaultCursor, BG: 0, UnderlineColor: 255},
+	} {
+		if got := s.unpack(s.pack(g)); got != g {
+			t.Errorf("packing %+v gave back %+v", g, got)
+		}
+	}
+}
+
+// TestColorTableCompacts writes more distinct RGB colors than the table holds, which only works if it drops colors no
+// longer on the screen.
+func TestColorTableCompacts(t *testing.T) {
+	term := New(WithSize(8, 2))
+	s := term.(*terminal).State
+	rgb := func(i int) Color { return Color(1+i>>16)<<16 | Color(i&0xffff) }
+	n := maxRGBColors + 100
+	var b strings.Builder
+	for i := range n {
+		c := rgb(i)
+		fmt.Fprintf(&b, "\033[1;%dH\033[48;2;%d;%d;%dmx", i%8+1, c>>16, c>>8&0xff, c&0xff)
+	}
+	writeSeq(t, term, b.String())
+
+	if len(s.colors.rgb) > 200 {
+		t.Fatalf("expected the table to have been compacted, holding %d colors", len(s.colors.rgb))
+	}
+	for i := n - 8; i < n; i++ {
+		if g := term.Cell(i%8, 0); g.BG != rgb(i) {
+			t.Errorf("cell %d: expected background %#x, got %#x", i%8, rgb(i), g.BG)
+		}
+	}
+}
+
+// TestColorTableSaturated keeps more distinct colors on the screen than a compacted table leaves room for, after which
+// new colors fall back to the nearest palette color.
+func TestColorTableSaturated(t *testing.T) {
+	term := New(WithSize(256, 128))
+	var b strings.Builder
+	for i := range 256 * 128 {
+		fmt.Fprintf(&b, "\033[38;2;1;%d;%dm", i>>8, i&0xff)
+		if i < 17000 {
+			fmt.Fprintf(&b, "\033[48;2;2;%d;%dm", i>>8, i&0xff)
+		} else {
+			b.WriteString("\033[49m")
+		}
+		b.WriteString("x")
+	}
+	for i := range 40000 {
+		fmt.Fprintf(&b, "\033[H\033[38;2;3;%d;%dmy", i>>8, i&0xff)
+	}
+	writeSeq(t, term, "\033[H"+b.String()+"\033[H\033[38;2;250;10;130my")
+
+	if g := term.Cell(255, 127); g.FG != 0x017fff {
+		t.Fatalf("expected the colors already on the screen to stay exact, got %#x", g.FG)
+	}
+	if g := term.Cell(0, 0); g.FG != 16+36*5+6*0+2 {
+		t.Fatalf("expected a color past the table to be approximated, got %d", g.FG)
+	}
+}
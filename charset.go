@@ -0,0 +1,59 @@
+package vt10x
+
+// charset is a character set that can be designated G0 to G3.
+type charset uint8
+
+const (
+	charsetASCII charset = iota
+	charsetLineDrawing
+)
+
+// charsetState is the character sets designated G0 to G3 and which of them is invoked into GL, the set printable
+// characters are taken from.
+type charsetState struct {
+	g  [4]charset
+	gl uint8
+}
+
+// savedCursor is what DECSC saves, separately for each screen: the cursor with its attributes, pending wrap and origin
+// mode, and the character sets.
+type savedCursor struct {
+	Cursor
+	charsets charsetState
+}
+
+// designateCharset designates the character set named by final as G<g> (ESC ( final for G0, ESC ) final for G1, and
+// so on). Sets other than DEC line drawing are taken as ASCII.
+func (t *State) designateCharset(g int, final rune) {
+	switch final {
+	case '0': // DEC special graphics (line drawing)
+		t.charsets.g[g] = charsetLineDrawing
+	case 'B': // USASCII
+		t.charsets.g[g] = charsetASCII
+	case 'A', // UK (ignored)
+		'<', // multinational (ignored)
+		'5', // Finnish (ignored)
+		'C', // Finnish (ignored)
+		'K': // German (ignored)
+		t.charsets.g[g] = charsetASCII
+	default:
+		t.warnf("unknown alt. charset '%c'", final)
+		return
+	}
+	t.applyCharset()
+}
+
+// invokeCharset invokes G<g> into GL (SI, SO, LS2 and LS3).
+func (t *State) invokeCharset(g uint8) {
+	t.charsets.gl = g
+	t.applyCharset()
+}
+
+// applyCharset sets the cursor's line drawing attribute to match the set invoked into GL.
+func (t *State) applyCharset() {
+	if t.charsets.g[t.charsets.gl] == charsetLineDrawing {
+		t.cur.Attr.Mode |= attrGfx
+	} else {
+		t.cur.Attr.Mode &^= attrGfx
+	}
+}
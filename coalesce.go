@@ -0,0 +1,146 @@
+package vt10x
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCoalescerClosed is returned by writes to a Coalescer after Close.
+var ErrCoalescerClosed = errors.New("vt10x: write to closed Coalescer")
+
+// Defaults for NewCoalescer.
+const (
+	defaultBatchBytes = 32 << 10
+	defaultBatchDelay = 5 * time.Millisecond
+)
+
+// Coalescer batches writes to a terminal. A program writing a byte at a time would otherwise lock the terminal, and
+// wake whoever renders it, once per byte; the Coalescer buffers writes and applies them together, under one lock
+// acquisition, once enough bytes are pending, once the oldest has waited long enough, or on Flush.
+//
+// The terminal reflects a write only once its batch is applied, so readers of the terminal see the output of a
+// program at most the batch delay late. Write, Flush and Close may be called concurrently.
+type Coalescer struct {
+	term     Terminal
+	maxBytes int
+	maxDelay time.Duration
+	onBatch  func(dirty []int)
+
+	mu      sync.Mutex
+	pending []byte
+	timer   *time.Timer
+	closed  bool
+}
+
+// CoalesceOption configures NewCoalescer.
+type CoalesceOption func(*Coalescer)
+
+// WithBatchSize applies a batch as soon as n bytes are pending. The default is 32KiB.
+func WithBatchSize(n int) CoalesceOption {
+	return func(c *Coalescer) {
+		if n > 0 {
+			c.maxBytes = n
+		}
+	}
+}
+
+// WithBatchDelay applies a batch once its first write has waited d. The default is 5ms; 0 or less applies batches
+// only on reaching the batch size and on Flush.
+func WithBatchDelay(d time.Duration) CoalesceOption {
+	return func(c *Coalescer) {
+		c.maxDelay = d
+	}
+}
+
+// WithBatchHandler calls fn after each batch is applied with the rows it changed, in increasing order, as
+// WriteWithChanges returns them. fn runs with the terminal unlocked, from the goroutine that triggered the batch,
+// which is a timer's for batches applied on the delay, and must not write to or flush the Coalescer.
+func WithBatchHandler(fn func(dirty []int)) CoalesceOption {
+	return func(c *Coalescer) {
+		c.onBatch = fn
+	}
+}
+
+// NewCoalescer returns a Coalescer that writes to term. Close it to apply the last batch and stop its timer.
+func NewCoalescer(term Terminal, opts ...CoalesceOption) *Coalescer {
+	c := &Coalescer{term: term, maxBytes: defaultBatchBytes, maxDelay: defaultBatchDelay}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Write adds p to the pending batch, applying it if that makes the batch full. It always consumes all of p.
+func (c *Coalescer) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, ErrCoalescerClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(c.pending) == 0 && c.maxDelay > 0 {
+		if c.timer == nil {
+			c.timer = time.AfterFunc(c.maxDelay, c.expire)
+		} else {
+			c.timer.Reset(c.maxDelay)
+		}
+	}
+	c.pending = append(c.pending, p...)
+	if len(c.pending) >= c.maxBytes {
+		if err := c.apply(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush applies the pending batch, if any, before returning.
+func (c *Coalescer) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.apply()
+}
+
+// Close applies the pending batch and stops the Coalescer. Later writes fail with ErrCoalescerClosed.
+func (c *Coalescer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	return c.apply()
+}
+
+// expire applies the batch whose delay has run out, from the timer's goroutine.
+func (c *Coalescer) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.apply() // with no caller to report to; terminal writes do not fail
+}
+
+// apply writes the pending batch to the terminal. c.mu must be held, which also keeps batches and their handler calls
+// in order.
+func (c *Coalescer) apply() error {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	if len(c.pending) == 0 {
+		return nil
+	}
+	defer func() { c.pending = c.pending[:0] }()
+	if c.onBatch == nil {
+		_, err := c.term.Write(c.pending)
+		return err
+	}
+	dirty, err := c.term.WriteWithChanges(c.pending)
+	if err != nil {
+		return err
+	}
+	c.onBatch(dirty)
+	return nil
+}
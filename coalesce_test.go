@@ -0,0 +1,92 @@
+package vt10x
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoalescerBatches(t *testing.T) {
+	term := New(WithSize(10, 3))
+	var batches [][]int
+	c := NewCoalescer(term, WithBatchDelay(0), WithBatchHandler(func(dirty []int) {
+		batches = append(batches, dirty)
+	}))
+
+	for _, b := range []byte("ab\r\ncd") {
+		if n, err := c.Write([]byte{b}); n != 1 || err != nil {
+			t.Fatalf("Write returned %d, %v", n, err)
+		}
+	}
+	if got := extractStr(term, 0, 1, 0); got != "  " {
+		t.Fatalf("expected nothing applied before the batch, got %q", got)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := extractStr(term, 0, 1, 1); got != "cd" {
+		t.Fatalf("expected the batch applied on Flush, got %q", got)
+	}
+	if !reflect.DeepEqual(batches, [][]int{{0, 1}}) {
+		t.Fatalf("expected one batch changing rows 0 and 1, got %v", batches)
+	}
+
+	// An empty flush is not a batch.
+	c.Flush()
+	if len(batches) != 1 {
+		t.Fatalf("expected no batch for an empty flush, got %v", batches)
+	}
+}
+
+func TestCoalescerSize(t *testing.T) {
+	term := New(WithSize(10, 3))
+	c := NewCoalescer(term, WithBatchSize(4), WithBatchDelay(0))
+	c.Write([]byte("abc"))
+	if got := extractStr(term, 0, 0, 0); got != " " {
+		t.Fatalf("expected nothing applied below the batch size, got %q", got)
+	}
+	c.Write([]byte("d"))
+	if got := extractStr(term, 0, 3, 0); got != "abcd" {
+		t.Fatalf("expected the batch applied on reaching its size, got %q", got)
+	}
+}
+
+func TestCoalescerDelay(t *testing.T) {
+	term := New(WithSize(10, 3))
+	applied := make(chan []int, 1)
+	c := NewCoalescer(term, WithBatchDelay(time.Millisecond), WithBatchHandler(func(dirty []int) {
+		applied <- dirty
+	}))
+	defer c.Close()
+
+	c.Write([]byte("\r\nx"))
+	select {
+	case dirty := <-applied:
+		if !reflect.DeepEqual(dirty, []int{0, 1}) {
+			t.Fatalf("unexpected dirty rows %v", dirty)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the batch to be applied after its delay")
+	}
+	term.Lock()
+	defer term.Unlock()
+	if got := extractStr(term, 0, 0, 1); got != "x" {
+		t.Fatalf("expected x on row 1, got %q", got)
+	}
+}
+
+func TestCoalescerClose(t *testing.T) {
+	term := New(WithSize(10, 3))
+	c := NewCoalescer(term)
+	c.Write([]byte("hi"))
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := extractStr(term, 0, 1, 0); got != "hi" {
+		t.Fatalf("expected Close to apply the pending batch, got %q", got)
+	}
+	if _, err := c.Write([]byte("x")); !errors.Is(err, ErrCoalescerClosed) {
+		t.Fatalf("expected ErrCoalescerClosed after Close, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package vt10x
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Codec compresses serialized terminal state. Encoded data records the codec's name, so decoding picks the codec
+// itself from those registered with RegisterCodec. GzipCodec is built in; others, such as zstd, can be supplied by
+// wrapping a third-party package.
+type Codec interface {
+	// Name identifies the codec in encoded data. It must be non-empty, at most 255 bytes, and never change.
+	Name() string
+
+	// NewWriter returns a writer that compresses to w. Closing it must flush everything but not close w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+
+	// NewReader returns a reader that decompresses from r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// ErrUnknownCodec is returned when decoding data compressed with a codec that has not been registered.
+var ErrUnknownCodec = errors.New("vt10x: unknown compression codec")
+
+// GzipCodec compresses with compress/gzip at the default level.
+var GzipCodec Codec = gzipCodec{}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{GzipCodec.Name(): GzipCodec}
+)
+
+// RegisterCodec makes c available for decoding data that names it, replacing any codec registered under the same
+// name. It panics if the name is empty or longer than 255 bytes.
+func RegisterCodec(c Codec) {
+	name := c.Name()
+	if name == "" || len(name) > 255 {
+		panic(fmt.Sprintf("vt10x: invalid codec name %q", name))
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[name] = c
+}
+
+func lookupCodec(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCodec, name)
+	}
+	return c, nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return "gzip"
+}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// nopWriteCloser passes writes through uncompressed.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// writeCodecHeader records the name of c, or that the data is uncompressed if c is nil, and returns a writer that
+// compresses the rest of the data with c.
+func writeCodecHeader(w io.Writer, c Codec) (io.WriteCloser, error) {
+	name := ""
+	if c != nil {
+		name = c.Name()
+		if name == "" || len(name) > 255 {
+			return nil, fmt.Errorf("vt10x: invalid codec name %q", name)
+		}
+	}
+	if _, err := w.Write(append([]byte{byte(len(name))}, name...)); err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nopWriteCloser{w}, nil
+	}
+	return c.NewWriter(w)
+}
+
+// readCodecHeader reads the header written by writeCodecHeader and returns a reader that decompresses the rest of
+// the data.
+func readCodecHeader(r io.Reader) (io.ReadCloser, error) {
+	var n [1]byte
+	if _, err := io.ReadFull(r, n[:]); err != nil {
+		return nil, err
+	}
+	if n[0] == 0 {
+		return io.NopCloser(r), nil
+	}
+	name := make([]byte, n[0])
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, err
+	}
+	c, err := lookupCodec(string(name))
+	if err != nil {
+		return nil, err
+	}
+	return c.NewReader(r)
+}
@@ -0,0 +1,98 @@
+package vt10x
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/gob"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// flateCodec is a third-party style codec registered by the tests.
+type flateCodec struct{}
+
+func (flateCodec) Name() string {
+	return "test-flate"
+}
+
+func (flateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return flate.NewWriter(w, flate.BestSpeed)
+}
+
+func (flateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+func codecTestState(t *testing.T) TerminalState {
+	t.Helper()
+
+	term := New(WithSize(80, 24))
+	writeSeq(t, term, strings.Repeat("\033[32mcompressible text\033[m\r\n", 30))
+	return term.DumpState()
+}
+
+func TestSnapshotCompression(t *testing.T) {
+	RegisterCodec(flateCodec{})
+	want := codecTestState(t)
+
+	var plain bytes.Buffer
+	if err := EncodeSnapshot(&plain, want); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Codec{GzipCodec, flateCodec{}} {
+		var buf bytes.Buffer
+		if err := EncodeSnapshot(&buf, want, WithCompression(c)); err != nil {
+			t.Fatalf("%s: EncodeSnapshot returned error: %v", c.Name(), err)
+		}
+		if buf.Len() >= plain.Len() {
+			t.Fatalf("%s: expected compression to shrink the snapshot, got %d bytes from %d", c.Name(), buf.Len(), plain.Len())
+		}
+		got, err := DecodeSnapshot(&buf)
+		if err != nil {
+			t.Fatalf("%s: DecodeSnapshot returned error: %v", c.Name(), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: state differs after round trip", c.Name())
+		}
+	}
+}
+
+func TestSnapshotUnknownCodec(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeSnapshot(&buf, codecTestState(t), WithCompression(unregisteredCodec{})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeSnapshot(&buf); !errors.Is(err, ErrUnknownCodec) {
+		t.Fatalf("expected ErrUnknownCodec, got %v", err)
+	}
+}
+
+type unregisteredCodec struct {
+	flateCodec
+}
+
+func (unregisteredCodec) Name() string {
+	return "test-unregistered"
+}
+
+// TestSnapshotVersion1 ensures snapshots written before the codec header was added still decode.
+func TestSnapshotVersion1(t *testing.T) {
+	want := codecTestState(t)
+
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	buf.WriteByte(1)
+	if err := gob.NewEncoder(&buf).Encode(&snapshotV1{State: want}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("DecodeSnapshot returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("state differs after decoding a version 1 snapshot")
+	}
+}
@@ -27,6 +27,8 @@ const (
 	DefaultFG Color = 1<<24 + iota
 	DefaultBG
 	DefaultCursor
+	// DefaultUnderline draws underlines in the cell's foreground color.
+	DefaultUnderline
 )
 
 // Color maps to the ANSI colors [0, 16) and the xterm colors [16, 256).
@@ -36,3 +38,96 @@ type Color uint32
 func (c Color) ANSI() bool {
 	return (c < 16)
 }
+
+// Default RGB values of the default colors in DefaultPalette. The cursor follows the foreground.
+const (
+	defaultForeground Color = 0xe5e5e5
+	defaultBackground Color = 0x000000
+)
+
+// Palette maps the 256 indexed colors and the default colors to 24-bit RGB values (0xRRGGBB). It is the base that
+// OSC 4/10/11/12 overrides apply on top of, and that OSC 104/110/111/112 restore.
+type Palette struct {
+	Indexed    [256]Color
+	Foreground Color
+	Background Color
+	Cursor     Color
+}
+
+// DefaultPalette returns xterm's palette with a light grey on black default foreground and background.
+func DefaultPalette() Palette {
+	return Palette{
+		Indexed:    xtermPalette,
+		Foreground: defaultForeground,
+		Background: defaultBackground,
+		Cursor:     defaultForeground,
+	}
+}
+
+// resolve maps c to RGB using this palette.
+func (p *Palette) resolve(c Color) Color {
+	switch {
+	case c < 256:
+		return p.Indexed[c]
+	case c == DefaultFG, c == DefaultUnderline:
+		return p.Foreground
+	case c == DefaultBG:
+		return p.Background
+	case c == DefaultCursor:
+		return p.Cursor
+	}
+	return c
+}
+
+// splitRGB splits a 24-bit RGB color into its components.
+func splitRGB(c Color) (r, g, b uint8) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c)
+}
+
+// ResolveColor returns the RGB components of c as it appears in the snapshot's buffers: indexed and default colors
+// are looked up in Palette and the default colors (so palette overrides in effect when the state was dumped are
+// honored), and 24-bit colors are returned as is. Bold brightening has already been applied to the stored glyphs.
+func (s TerminalState) ResolveColor(c Color) (r, g, b uint8) {
+	p := Palette{
+		Foreground: s.ForegroundColor,
+		Background: s.BackgroundColor,
+		Cursor:     s.CursorColor,
+	}
+	copy(p.Indexed[:], s.Palette)
+	return splitRGB(p.resolve(c))
+}
+
+// ReverseVideoColor returns the color c is shown in on a screen in reverse video (DECSCNM, reported as
+// TerminalState.ReverseVideo and ModeReverse): the default foreground and background colors trade places, and other
+// colors are shown as they are. A cell reversed with SGR 7 already has its colors swapped, so one in the default
+// colors flips back to normal, as in xterm.
+func ReverseVideoColor(c Color) Color {
+	switch c {
+	case DefaultFG:
+		return DefaultBG
+	case DefaultBG:
+		return DefaultFG
+	}
+	return c
+}
+
+// xtermPalette holds the RGB values of xterm's 256 colors: the 16 ANSI colors, the 6x6x6 color cube, and the
+// 24-step grayscale ramp.
+var xtermPalette = func() (p [256]Color) {
+	ansi := [16]Color{
+		0x000000, 0xcd0000, 0x00cd00, 0xcdcd00, 0x0000ee, 0xcd00cd, 0x00cdcd, 0xe5e5e5,
+		0x7f7f7f, 0xff0000, 0x00ff00, 0xffff00, 0x5c5cff, 0xff00ff, 0x00ffff, 0xffffff,
+	}
+	copy(p[:], ansi[:])
+
+	levels := [6]Color{0x00, 0x5f, 0x87, 0xaf, 0xd7, 0xff}
+	for i := 0; i < 216; i++ {
+		p[16+i] = levels[i/36]<<16 | levels[i/6%6]<<8 | levels[i%6]
+	}
+
+	for i := 0; i < 24; i++ {
+		v := Color(8 + 10*i)
+		p[232+i] = v<<16 | v<<8 | v
+	}
+	return p
+}()
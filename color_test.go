@@ -0,0 +1,111 @@
+package vt10x
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDefaultPalette(t *testing.T) {
+	p := DefaultPalette()
+
+	cases := []struct {
+		c    Color
+		want Color
+	}{
+		{Black, 0x000000},
+		{Red, 0xcd0000},
+		{White, 0xffffff},
+		{16, 0x000000},
+		{21, 0x0000ff},
+		{196, 0xff0000},
+		{231, 0xffffff},
+		{232, 0x080808},
+		{255, 0xeeeeee},
+	}
+	for _, tc := range cases {
+		if got := p.Indexed[tc.c]; got != tc.want {
+			t.Errorf("color %d: expected %#06x, got %#06x", tc.c, tc.want, got)
+		}
+	}
+}
+
+func TestResolveColor(t *testing.T) {
+	p := DefaultPalette()
+	p.Indexed[Red] = 0x112233
+	p.Foreground = 0xaabbcc
+	p.Background = 0x010203
+	p.Cursor = 0x445566
+	term := New(WithPalette(p))
+	st := term.(*terminal)
+
+	cases := []struct {
+		name    string
+		c       Color
+		r, g, b uint8
+	}{
+		{"indexed", Red, 0x11, 0x22, 0x33},
+		{"cube", 196, 0xff, 0x00, 0x00},
+		{"rgb", 0x808182, 0x80, 0x81, 0x82},
+		{"default fg", DefaultFG, 0xaa, 0xbb, 0xcc},
+		{"default bg", DefaultBG, 0x01, 0x02, 0x03},
+		{"cursor", DefaultCursor, 0x44, 0x55, 0x66},
+		{"underline", DefaultUnderline, 0xaa, 0xbb, 0xcc},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, g, b := st.ResolveColor(tc.c)
+			if r != tc.r || g != tc.g || b != tc.b {
+				t.Fatalf("State.ResolveColor: expected (%#x,%#x,%#x), got (%#x,%#x,%#x)", tc.r, tc.g, tc.b, r, g, b)
+			}
+
+			r, g, b = term.DumpState().ResolveColor(tc.c)
+			if r != tc.r || g != tc.g || b != tc.b {
+				t.Fatalf("TerminalState.ResolveColor: expected (%#x,%#x,%#x), got (%#x,%#x,%#x)", tc.r, tc.g, tc.b, r, g, b)
+			}
+		})
+	}
+}
+
+func TestResolveColorBoldBrightening(t *testing.T) {
+	term := New()
+	writeSeq(t, term, "\033[1;31mX")
+
+	// Bold brightening is applied when the glyph is stored, so resolving the stored color gives the bright red.
+	r, g, b := term.DumpState().ResolveColor(term.Cell(0, 0).FG)
+	if r != 0xff || g != 0 || b != 0 {
+		t.Fatalf("expected bold red to resolve to bright red, got (%#x,%#x,%#x)", r, g, b)
+	}
+}
+
+func TestPaletteResetRestoresConfigured(t *testing.T) {
+	p := DefaultPalette()
+	p.Indexed[Green] = 0x00aa00
+	p.Background = 0x202020
+
+	var buf bytes.Buffer
+	term := New(WithPalette(p), WithWriter(&buf))
+
+	writeSeq(t, term, "\033]4;2;#ffffff\007\033]11;#ffffff\007\033]104;2\007\033]111\007")
+	state := term.DumpState()
+	if state.Palette[Green] != 0x00aa00 || state.BackgroundColor != 0x202020 {
+		t.Fatalf("expected resets to restore the configured palette, got green=%#x bg=%#x",
+			state.Palette[Green], state.BackgroundColor)
+	}
+
+	writeSeq(t, term, "\033]11;?\007")
+	if got, want := buf.String(), "\033]11;rgb:2020/2020/2020\007"; got != want {
+		t.Fatalf("expected query reply %q, got %q", want, got)
+	}
+}
+
+// TestReverseVideoColorSGR7 checks that a cell reversed with SGR 7 in the default colors is shown normally under
+// DECSCNM.
+func TestReverseVideoColorSGR7(t *testing.T) {
+	term := New(WithSize(2, 1))
+	writeSeq(t, term, "\033[?5h\033[7mx")
+
+	g := term.Cell(0, 0)
+	if fg, bg := ReverseVideoColor(g.FG), ReverseVideoColor(g.BG); fg != DefaultFG || bg != DefaultBG {
+		t.Fatalf("expected the default colors, got fg=%v bg=%v", fg, bg)
+	}
+}
@@ -0,0 +1,34 @@
+package vt10x
+
+// WithColumnSwitching makes DECCOLM (DECSET 3 and DECRST 3) resize the terminal to 132 or 80 columns, keeping its
+// rows, and tell the resize handler set with WithResizeHandler, as Resize does, so embedders that can change the width
+// of their window and pty follow it. Without it the terminal keeps its width, and DECCOLM only has its other effects.
+func WithColumnSwitching() TerminalOption {
+	return func(info *TerminalInfo) {
+		info.columnSwitching = true
+	}
+}
+
+// setColumnMode implements DECCOLM: it resets the scroll region, clears the screen unless DECNCSM (DECSET 95) is set,
+// and homes the cursor, after switching to 132 columns, or back to 80, if column switching is enabled. Like a VT100,
+// and unlike xterm, it does not need mode 40 (allow 80/132 columns) to be set first.
+func (t *State) setColumnMode(set bool) {
+	if t.columnSwitching {
+		cols := 80
+		if set {
+			cols = 132
+		}
+		if cols != t.cols {
+			t.resize(cols, t.rows)
+			t.resizePending = true
+			if on, _ := t.privMode(2048); on {
+				t.reportInBandSize()
+			}
+		}
+	}
+	t.setScroll(0, t.rows-1)
+	if noClear, _ := t.privMode(95); !noClear {
+		t.clearAll()
+	}
+	t.moveAbsTo(0, 0)
+}
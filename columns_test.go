@@ -0,0 +1,54 @@
+package vt10x
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDECCOLM(t *testing.T) {
+	term := New(WithSize(80, 24))
+
+	writeSeq(t, term, "\033[5;10rhello\033[10;10H\033[?3h")
+	if cols, rows := term.Size(); cols != 80 || rows != 24 {
+		t.Fatalf("expected the size kept without column switching, got %dx%d", cols, rows)
+	}
+	if c := term.Cell(0, 4); c.Char != ' ' {
+		t.Fatalf("expected the screen cleared, got %q", c.Char)
+	}
+	if cur := term.Cursor(); cur.X != 0 || cur.Y != 0 {
+		t.Fatalf("expected the cursor homed, got %d,%d", cur.X, cur.Y)
+	}
+	if s := term.(MetaDumper).DumpMeta(); s.ScrollTop != 0 || s.ScrollBottom != 23 {
+		t.Fatalf("expected the scroll region reset, got %d-%d", s.ScrollTop, s.ScrollBottom)
+	}
+	if !term.(MetaDumper).DumpMeta().Modes[3] {
+		t.Fatal("expected DECCOLM to be reported set")
+	}
+
+	writeSeq(t, term, "\033[?95hkept\033[?3l")
+	if c := term.Cell(0, 0); c.Char != 'k' {
+		t.Fatalf("expected DECNCSM to keep the screen, got %q", c.Char)
+	}
+}
+
+func TestColumnSwitching(t *testing.T) {
+	var sizes [][2]int
+	var term Terminal
+	term = New(WithSize(80, 24), WithColumnSwitching(), WithResizeHandler(func(cols, rows int) {
+		// The handler is called unlocked, so it may use the terminal.
+		cols, rows = term.Size()
+		sizes = append(sizes, [2]int{cols, rows})
+	}))
+
+	writeSeq(t, term, "\033[?3h")
+	if cols, rows := term.Size(); cols != 132 || rows != 24 {
+		t.Fatalf("expected 132 columns, got %dx%d", cols, rows)
+	}
+	writeSeq(t, term, "\033[?3h\033[?3l")
+	if cols, _ := term.Size(); cols != 80 {
+		t.Fatalf("expected 80 columns, got %d", cols)
+	}
+	if want := [][2]int{{132, 24}, {80, 24}}; !slices.Equal(sizes, want) {
+		t.Fatalf("expected the handler called with %v, got %v", want, sizes)
+	}
+}
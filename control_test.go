@@ -0,0 +1,88 @@
+package vt10x
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCANAbortsSequences(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want string // what the stream leaves on screen
+	}{
+		{"csi", "\033[31\030mx", "mx"},
+		{"csi intermediate", "\033[2 \030qx", "qx"},
+		{"esc", "\033\030cx", "cx"},
+		{"charset", "\033(\0300x", "0x"},
+		{"osc", "\033]0;title\030x", "x"},
+		{"dcs", "\033Pq#1~\030x", "x"},
+		{"apc", "\033_Ga=T\030x", "x"},
+		{"string terminator", "\033]0;title\033\030\\x", "\\x"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			term := New(WithSize(10, 2))
+			writeSeq(t, term, tc.seq)
+
+			// The aborted sequence has no effect and everything after CAN is parsed from the ground state.
+			if got := extractStr(term, 0, len(tc.want)-1, 0); got != tc.want {
+				t.Fatalf("expected %q on screen, got %q", tc.want, got)
+			}
+			if g := term.Cell(0, 0); g.FG != DefaultFG || g.Mode&attrGfx != 0 {
+				t.Fatalf("expected the aborted sequence not to change attributes, got %+v", g)
+			}
+			if term.Title() != "" {
+				t.Fatalf("expected no title, got %q", term.Title())
+			}
+			if imgs := term.DumpState().Images; len(imgs) != 0 {
+				t.Fatalf("expected no images, got %d", len(imgs))
+			}
+		})
+	}
+}
+
+func TestSUBShowsReplacement(t *testing.T) {
+	term := New(WithSize(10, 2))
+
+	writeSeq(t, term, "a\033[1\032b\032c")
+	if got := extractStr(term, 0, 4, 0); got != "a�b�c" {
+		t.Fatalf("expected SUB to abort the CSI and display replacement characters, got %q", got)
+	}
+	if g := term.Cell(2, 0); g.Mode&attrBold != 0 {
+		t.Fatal("expected the aborted SGR not to apply")
+	}
+}
+
+func TestENQAnswerback(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithWriter(&buf), WithAnswerback("vt10x\r"))
+
+	writeSeq(t, term, "a\005b")
+	if got := buf.String(); got != "vt10x\r" {
+		t.Fatalf("expected the answerback in reply to ENQ, got %q", got)
+	}
+	if got := extractStr(term, 0, 1, 0); got != "ab" {
+		t.Fatalf("expected ENQ not to print, got %q", got)
+	}
+
+	// ENQ is a control code, so it is answered inside a CSI too without disturbing the sequence.
+	buf.Reset()
+	writeSeq(t, term, "\033[3\0051mc")
+	if got := buf.String(); got != "vt10x\r" {
+		t.Fatalf("expected the answerback from inside a CSI, got %q", got)
+	}
+	if g := term.Cell(2, 0); g.Char != 'c' || g.FG != Red {
+		t.Fatalf("expected the interrupted SGR to still apply, got %+v", g)
+	}
+}
+
+func TestENQNoAnswerback(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithWriter(&buf))
+
+	writeSeq(t, term, "\005")
+	if buf.Len() != 0 {
+		t.Fatalf("expected no reply without an answerback, got %q", buf.String())
+	}
+}
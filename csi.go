@@ -2,24 +2,38 @@ package vt10x
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 )
 
+// maxCSIArg caps numeric parameters, as xterm does, so that a recorded stream can't hand the handlers values large
+// enough to overflow the arithmetic they do on them.
+const maxCSIArg = 1<<16 - 1
+
 // CSI (Control Sequence Introducer)
 // ESC+[
 type csiEscape struct {
-	buf  []byte
-	args []int
-	mode byte
-	priv bool
+	buf   []byte
+	args  []int
+	subs  [][]int // colon-separated sub-parameters following each arg, e.g. 3 in CSI 4:3 m
+	mode  byte
+	inter byte // intermediate byte preceding mode, e.g. ' ' in CSI Ps SP q
+	priv  bool
+	mark  byte // private parameter marker other than '?': '<', '=' or '>', as in CSI > Ps u
+}
+
+// String returns the sequence as received, for logging.
+func (c *csiEscape) String() string {
+	return "\033[" + string(c.buf)
 }
 
 func (c *csiEscape) reset() {
 	c.buf = c.buf[:0]
 	c.args = c.args[:0]
+	c.subs = c.subs[:0]
 	c.mode = 0
+	c.inter = 0
 	c.priv = false
+	c.mark = 0
 }
 
 func (c *csiEscape) put(b byte) bool {
@@ -35,7 +49,10 @@ func (c *csiEscape) parse() {
 	if len(c.buf) == 0 {
 		c.mode = 0
 		c.args = c.args[:0]
+		c.subs = c.subs[:0]
+		c.inter = 0
 		c.priv = false
+		c.mark = 0
 		return
 	}
 	c.mode = c.buf[len(c.buf)-1]
@@ -44,25 +61,85 @@ func (c *csiEscape) parse() {
 	}
 	s := string(c.buf)
 	c.args = c.args[:0]
-	if s[0] == '?' {
+	c.subs = c.subs[:0]
+	switch s[0] {
+	case '?':
 		c.priv = true
 		s = s[1:]
+	case '<', '=', '>':
+		c.mark = s[0]
+		s = s[1:]
 	}
 	if len(s) == 0 {
 		return
 	}
 	s = s[:len(s)-1]
+	// Intermediate bytes (0x20-0x2F) sit between the parameters and the final byte.
+	for len(s) > 0 && s[len(s)-1] >= 0x20 && s[len(s)-1] <= 0x2F {
+		c.inter = s[len(s)-1]
+		s = s[:len(s)-1]
+	}
+	if s == "" {
+		return
+	}
 	ss := strings.Split(s, ";")
 	for _, p := range ss {
-		i, err := strconv.Atoi(p)
-		if err != nil {
-			//t.logf("invalid CSI arg '%s'\n", p)
+		var sub []int
+		if j := strings.IndexByte(p, ':'); j >= 0 {
+			sub = parseSubArgs(p[j+1:])
+			p = p[:j]
+		}
+		i, ok := parseArg(p)
+		if !ok {
+			//t.warnf("invalid CSI arg '%s'", p)
 			break
 		}
 		c.args = append(c.args, i)
+		c.subs = append(c.subs, sub)
 	}
 }
 
+// parseSubArgs parses colon-separated sub-parameters. An empty sub-parameter, as in the omitted color space of
+// CSI 38:2::r:g:b m, is recorded as -1.
+func parseSubArgs(s string) []int {
+	ss := strings.Split(s, ":")
+	sub := make([]int, 0, len(ss))
+	for _, p := range ss {
+		if p == "" {
+			sub = append(sub, -1)
+			continue
+		}
+		i, ok := parseArg(p)
+		if !ok {
+			break
+		}
+		sub = append(sub, i)
+	}
+	return sub
+}
+
+// parseArg parses a parameter of decimal digits, clamping it to maxCSIArg. An empty parameter, as in CSI ;5 H, is 0,
+// which sequences take as their default. ECMA-48 parameters are unsigned, so a sign, like any other non-digit, makes
+// it invalid.
+func parseArg(s string) (int, bool) {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		n = min(n*10+int(s[i]-'0'), maxCSIArg)
+	}
+	return n, true
+}
+
+// sub returns the colon sub-parameters of arg i, if any.
+func (c *csiEscape) sub(i int) []int {
+	if i >= len(c.subs) || i < 0 {
+		return nil
+	}
+	return c.subs[i]
+}
+
 func (c *csiEscape) arg(i, def int) int {
 	if i >= len(c.args) || i < 0 {
 		return def
@@ -75,41 +152,58 @@ func (c *csiEscape) maxarg(i, def int) int {
 	return max(c.arg(i, def), def)
 }
 
+// margins returns the 0-based first and last lines of a margin pair, as given to DECSTBM for rows (and DECSLRM for
+// columns) on a screen of size lines. Like xterm, a missing or zero first line means the first line, and a missing,
+// zero, or out-of-range last line means the last; a region that does not span at least two lines is invalid and ok
+// is false, in which case the sequence must be ignored.
+func (c *csiEscape) margins(size int) (first, last int, ok bool) {
+	first = max(c.arg(0, 1), 1)
+	last = c.arg(1, size)
+	if last < 1 || last > size {
+		last = size
+	}
+	if first >= last {
+		return 0, 0, false
+	}
+	return first - 1, last - 1, true
+}
+
 func (t *State) handleCSI() {
 	c := &t.csi
+	t.stats.CSI++
+	if c.mark != 0 {
+		t.handleMarkedCSI()
+		return
+	}
 	switch c.mode {
 	default:
 		goto unknown
 	case '@': // ICH - insert <n> blank char
 		t.insertBlanks(c.arg(0, 1))
 	case 'A': // CUU - cursor <n> up
-		t.moveTo(t.cur.X, t.cur.Y-clamp(c.maxarg(0, 1), 1, t.rows))
+		t.moveRows(t.cur.X, -clamp(c.maxarg(0, 1), 1, t.rows))
 	case 'B', 'e': // CUD, VPR - cursor <n> down
-		t.moveTo(t.cur.X, t.cur.Y+clamp(c.maxarg(0, 1), 1, t.rows))
+		t.moveRows(t.cur.X, clamp(c.maxarg(0, 1), 1, t.rows))
 	case 'c': // DA - device attributes
-		if c.arg(0, 0) == 0 {
-			// TODO: write vt102 id
+		if !c.priv {
+			t.deviceAttributes(0, c.arg(0, 0))
 		}
 	case 'C', 'a': // CUF, HPR - cursor <n> forward
 		t.moveTo(t.cur.X+clamp(c.maxarg(0, 1), 1, t.cols), t.cur.Y)
 	case 'D': // CUB - cursor <n> backward
 		t.moveTo(t.cur.X-clamp(c.maxarg(0, 1), 1, t.cols), t.cur.Y)
 	case 'E': // CNL - cursor <n> down and first col
-		t.moveTo(0, t.cur.Y+clamp(c.arg(0, 1), 0, t.rows))
+		t.moveRows(0, clamp(c.maxarg(0, 1), 1, t.rows))
 	case 'F': // CPL - cursor <n> up and first col
-		t.moveTo(0, t.cur.Y-clamp(c.arg(0, 1), 0, t.rows))
+		t.moveRows(0, -clamp(c.maxarg(0, 1), 1, t.rows))
 	case 'g': // TBC - tabulation clear
 		switch c.arg(0, 0) {
 		// clear current tab stop
 		case 0:
-			if t.cur.X >= 0 && t.cur.X < len(t.tabs) {
-				t.tabs[t.cur.X] = false
-			}
-		// clear all tabs
-		case 3:
-			for i := range t.tabs {
-				t.tabs[i] = false
-			}
+			t.setTab(false)
+		// clear all tabs; tab stops apply to every line, so 2 (the line's stops) and 5 (every stop) do the same
+		case 2, 3, 5:
+			t.clearTabs()
 		default:
 			goto unknown
 		}
@@ -119,36 +213,47 @@ func (t *State) handleCSI() {
 		t.moveAbsTo(c.arg(1, 1)-1, c.arg(0, 1)-1)
 	case 'I': // CHT - cursor forward tabulation <n> tab stops
 		// Clamp to cols: putTab stops at the margin, so more iterations are wasted work and would hang on INT_MAX.
-		n := clamp(c.arg(0, 1), 0, t.cols)
+		// A count of 0 means 1.
+		n := clamp(c.arg(0, 1), 1, t.cols)
 		for i := 0; i < n; i++ {
 			t.putTab(true)
 		}
-	case 'J': // ED - clear screen
+	case 'J': // ED - clear screen, DECSED - selective erase in display
 		// TODO: sel.ob.x = -1
+		erase := t.clear
+		if c.priv {
+			erase = t.selectiveErase
+		}
 		switch c.arg(0, 0) {
 		case 0: // below
-			t.clear(t.cur.X, t.cur.Y, t.cols-1, t.cur.Y)
+			erase(t.cur.X, t.cur.Y, t.cols-1, t.cur.Y)
 			if t.cur.Y < t.rows-1 {
-				t.clear(0, t.cur.Y+1, t.cols-1, t.rows-1)
+				erase(0, t.cur.Y+1, t.cols-1, t.rows-1)
 			}
 		case 1: // above
-			if t.cur.Y > 1 {
-				t.clear(0, 0, t.cols-1, t.cur.Y-1)
+			if t.cur.Y > 0 {
+				erase(0, 0, t.cols-1, t.cur.Y-1)
 			}
-			t.clear(0, t.cur.Y, t.cur.X, t.cur.Y)
+			erase(0, t.cur.Y, t.cur.X, t.cur.Y)
 		case 2: // all
-			t.clear(0, 0, t.cols-1, t.rows-1)
+			erase(0, 0, t.cols-1, t.rows-1)
 		default:
 			goto unknown
 		}
-	case 'K': // EL - clear line
+	case 'K': // EL - clear line, DECSEL - selective erase in line
+		// Like xterm, erasing cancels a pending wrap.
+		t.cur.State &^= cursorWrapNext
+		erase := t.clearInLine
+		if c.priv {
+			erase = func(x0, x1 int) { t.selectiveErase(x0, t.cur.Y, x1, t.cur.Y) }
+		}
 		switch c.arg(0, 0) {
 		case 0: // right
-			t.clear(t.cur.X, t.cur.Y, t.cols-1, t.cur.Y)
+			erase(t.cur.X, t.cols-1)
 		case 1: // left
-			t.clear(0, t.cur.Y, t.cur.X, t.cur.Y)
+			erase(0, t.cur.X)
 		case 2: // all
-			t.clear(0, t.cur.Y, t.cols-1, t.cur.Y)
+			erase(0, t.cols-1)
 		}
 	case 'S': // SU - scroll <n> lines up
 		t.scrollUp(t.top, c.arg(0, 1), true)
@@ -161,22 +266,45 @@ func (t *State) handleCSI() {
 	case 'M': // DL - delete <n> lines
 		t.deleteLines(c.arg(0, 1))
 	case 'X': // ECH - erase <n> chars
+		t.cur.State &^= cursorWrapNext
 		n := clamp(c.arg(0, 1), 1, t.cols-t.cur.X)
-		t.clear(t.cur.X, t.cur.Y, t.cur.X+n-1, t.cur.Y)
+		t.clearInLine(t.cur.X, t.cur.X+n-1)
 	case 'P': // DCH - delete <n> chars
 		t.deleteChars(c.arg(0, 1))
 	case 'Z': // CBT - cursor backward tabulation <n> tab stops
 		// Clamp: see CHT above.
-		n := clamp(c.arg(0, 1), 0, t.cols)
+		n := clamp(c.arg(0, 1), 1, t.cols)
 		for i := 0; i < n; i++ {
 			t.putTab(false)
 		}
+	case 'W':
+		switch {
+		case c.priv && c.arg(0, 0) == 5: // DECST8C - set a tab stop every 8 columns
+			t.defaultTabs()
+		case c.priv:
+			goto unknown
+		default: // CTC - cursor tabulation control
+			switch c.arg(0, 0) {
+			case 0: // set a tab stop at the cursor
+				t.setTab(true)
+			case 2: // clear the tab stop at the cursor
+				t.setTab(false)
+			case 4, 5: // clear the line's tab stops, clear every tab stop
+				t.clearTabs()
+			default:
+				goto unknown
+			}
+		}
 	case 'd': // VPA - move to <row>
 		t.moveAbsTo(t.cur.X, max(c.arg(0, 1), 1)-1)
 	case 'h': // SM - set terminal mode
 		t.setMode(c.priv, true, c.args)
-	case 'm': // SGR - terminal attribute (color)
-		t.setAttr(c.args)
+	case 'm':
+		if c.priv { // XTQMODKEYS - query key modifier options
+			t.reportModifyKeys(c.arg(0, -1))
+		} else { // SGR - terminal attribute (color)
+			t.setAttr(c.args, c.subs)
+		}
 	case 'n':
 		if t.w == nil {
 			break
@@ -184,23 +312,81 @@ func (t *State) handleCSI() {
 		switch c.arg(0, 0) {
 		case 5: // DSR - device status report
 			t.w.Write([]byte("\033[0n"))
-		case 6: // CPR - cursor position report
-			t.w.Write([]byte(fmt.Sprintf("\033[%d;%dR", t.cur.Y+1, t.cur.X+1)))
+		case 6: // CPR - cursor position report, relative to the scroll region in origin mode
+			y := t.cur.Y
+			if t.cur.State&cursorOrigin != 0 {
+				y -= t.top
+			}
+			t.w.Write([]byte(fmt.Sprintf("\033[%d;%dR", y+1, t.cur.X+1)))
+		}
+	case 'p':
+		switch c.inter {
+		case '$': // DECRQM - request mode
+			t.reportMode(c.priv, c.arg(0, 0))
+		case '!': // DECSTR - soft terminal reset
+			t.softReset()
+		default:
+			goto unknown
+		}
+	case 'q':
+		switch c.inter {
+		case ' ': // DECSCUSR - set cursor style
+			if !t.setCursorStyle(c.arg(0, 0)) {
+				goto unknown
+			}
+		case '"': // DECSCA - select character protection attribute
+			if !t.setProtected(c.arg(0, 0)) {
+				goto unknown
+			}
+		default:
+			goto unknown
 		}
 	case 'r': // DECSTBM - set scrolling region
 		if c.priv {
 			goto unknown
 		} else {
-			t.setScroll(c.arg(0, 1)-1, c.arg(1, t.rows)-1)
+			top, bottom, ok := c.margins(t.rows)
+			if !ok {
+				t.warnf("invalid scroll region %q", c)
+				break
+			}
+			t.setScroll(top, bottom)
 			t.moveAbsTo(0, 0)
 		}
 	case 's': // DECSC - save cursor position (ANSI.SYS)
 		t.saveCursor()
-	case 'u': // DECRC - restore cursor position (ANSI.SYS)
-		t.restoreCursor()
+	case 'u':
+		if c.priv { // query keyboard protocol flags
+			t.reportKeyboardFlags()
+		} else { // DECRC - restore cursor position (ANSI.SYS)
+			t.restoreCursor()
+		}
+	case 't': // XTWINOPS - window manipulation
+		t.windowOp(c)
 	}
 	return
 unknown: // TODO: get rid of this goto
-	t.logf("unknown CSI sequence '%c'\n", c.mode)
-	// TODO: c.dump()
+	t.warnf("unknown CSI sequence %q", c)
+}
+
+// handleMarkedCSI handles the sequences whose parameters start with a private marker other than '?', which would
+// otherwise be taken for the unmarked sequence with the same final byte.
+func (t *State) handleMarkedCSI() {
+	c := &t.csi
+	switch {
+	case c.mark == '>' && c.mode == 'u': // push keyboard protocol flags
+		t.pushKeyboardFlags(KeyboardFlags(c.arg(0, 0)))
+	case c.mark == '<' && c.mode == 'u': // pop keyboard protocol flags
+		t.popKeyboardFlags(c.arg(0, 1))
+	case c.mark == '=' && c.mode == 'u': // set keyboard protocol flags
+		t.setKeyboardFlags(KeyboardFlags(c.arg(0, 0)), c.arg(1, 1))
+	case c.mark == '>' && c.mode == 'm': // XTMODKEYS - set key modifier options
+		t.setModifyKeys(c.arg(0, -1), c.arg(1, -1))
+	case c.mark == '>' && c.mode == 'n': // XTMODKEYS - disable key modifier options
+		t.setModifyKeys(c.arg(0, -1), 0)
+	case c.mark == '>' && c.mode == 'c', c.mark == '=' && c.mode == 'c': // DA2, DA3 - device attributes
+		t.deviceAttributes(c.mark, c.arg(0, 0))
+	default:
+		t.warnf("unknown CSI sequence %q", c)
+	}
 }
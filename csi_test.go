@@ -34,3 +34,39 @@ func TestCSIParse(t *testing.T) {
 		t.Fatal("CSI parse mismatch")
 	}
 }
+
+func TestCSIParseIntermediate(t *testing.T) {
+	var csi csiEscape
+	csi.reset()
+	csi.buf = []byte("5 q")
+	csi.parse()
+	if csi.mode != 'q' || csi.inter != ' ' || csi.arg(0, 0) != 5 || len(csi.args) != 1 {
+		t.Fatal("CSI parse mismatch")
+	}
+
+	csi.reset()
+	csi.buf = []byte(" q")
+	csi.parse()
+	if csi.mode != 'q' || csi.inter != ' ' || len(csi.args) != 0 {
+		t.Fatal("CSI parse mismatch")
+	}
+}
+
+func TestCSIParseSubParams(t *testing.T) {
+	var csi csiEscape
+	csi.reset()
+	csi.buf = []byte("1;4:3;58:2::10:20:30m")
+	csi.parse()
+	if csi.mode != 'm' || len(csi.args) != 3 || csi.arg(1, 0) != 4 || csi.arg(2, 0) != 58 {
+		t.Fatalf("CSI parse mismatch: args %v", csi.args)
+	}
+	if sub := csi.sub(0); len(sub) != 0 {
+		t.Fatalf("expected no sub-parameters for arg 0, got %v", sub)
+	}
+	if sub := csi.sub(1); len(sub) != 1 || sub[0] != 3 {
+		t.Fatalf("expected sub-parameters [3] for arg 1, got %v", sub)
+	}
+	if sub := csi.sub(2); len(sub) != 5 || sub[0] != 2 || sub[1] != -1 || sub[4] != 30 {
+		t.Fatalf("expected sub-parameters [2 -1 10 20 30] for arg 2, got %v", sub)
+	}
+}
@@ -0,0 +1,148 @@
+package vt10x
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCursorStyleDECSCUSR(t *testing.T) {
+	cases := []struct {
+		ps   int
+		want CursorStyle
+	}{
+		{0, CursorStyle{Shape: CursorShapeBlock, Blink: true}},
+		{1, CursorStyle{Shape: CursorShapeBlock, Blink: true}},
+		{2, CursorStyle{Shape: CursorShapeBlock}},
+		{3, CursorStyle{Shape: CursorShapeUnderline, Blink: true}},
+		{4, CursorStyle{Shape: CursorShapeUnderline}},
+		{5, CursorStyle{Shape: CursorShapeBar, Blink: true}},
+		{6, CursorStyle{Shape: CursorShapeBar}},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprint(tc.ps), func(t *testing.T) {
+			term := New()
+			writeSeq(t, term, "\033[2 q") // start from a non-default style
+			writeSeq(t, term, fmt.Sprintf("\033[%d q", tc.ps))
+
+			if got := term.DumpState().CursorStyle; got != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCursorStyleDefaultAndReset(t *testing.T) {
+	term := New()
+	if got := term.DumpState().CursorStyle; got != defaultCursorStyle {
+		t.Fatalf("expected default style %+v, got %+v", defaultCursorStyle, got)
+	}
+
+	writeSeq(t, term, "\033[6 q\033c")
+	if got := term.DumpState().CursorStyle; got != defaultCursorStyle {
+		t.Fatalf("expected RIS to restore %+v, got %+v", defaultCursorStyle, got)
+	}
+}
+
+func TestCursorStyleBlinkMode(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033[4 q\033[?12h")
+	want := CursorStyle{Shape: CursorShapeUnderline, Blink: true}
+	if got := term.DumpState().CursorStyle; got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	writeSeq(t, term, "\033[?12l")
+	want.Blink = false
+	if got := term.DumpState().CursorStyle; got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestCursorStyleUnknownIgnored(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033[5 q\033[9 q\033[5q")
+	want := CursorStyle{Shape: CursorShapeBar, Blink: true}
+	if got := term.DumpState().CursorStyle; got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestCursorWrapPendingState(t *testing.T) {
+	term := New(WithSize(5, 3))
+	writeSeq(t, term, "abcde")
+	s := term.DumpState()
+	if !s.WrapPending || s.CursorX != 4 {
+		t.Fatalf("expected a pending wrap in the last column, got %v at column %d", s.WrapPending, s.CursorX)
+	}
+
+	// A terminal restored from the state wraps the next character just as the original does.
+	restored := New(WithState(s))
+	writeSeq(t, term, "f")
+	writeSeq(t, restored, "f")
+	if got, want := restored.Cursor(), term.Cursor(); got.X != want.X || got.Y != want.Y {
+		t.Fatalf("expected the restored cursor at (%d,%d), got (%d,%d)", want.X, want.Y, got.X, got.Y)
+	}
+	if term.DumpState().WrapPending {
+		t.Fatal("expected the wrap to clear the pending state")
+	}
+
+	writeSeq(t, term, "ghij\r")
+	if term.DumpState().WrapPending {
+		t.Fatal("expected CR to cancel the pending wrap")
+	}
+}
+
+func TestSaveCursorAttributes(t *testing.T) {
+	term := New(WithSize(10, 5))
+
+	// Save with red bold text, origin mode in a region, and line drawing in G0, then change them all.
+	writeSeq(t, term, "\033[2;4r\033[?6h\033[2;3H\033[1;31m\033(0\0337")
+	writeSeq(t, term, "\033[m\033(B\033[?6l\033[5;1H")
+	writeSeq(t, term, "\0338q")
+
+	if g := term.Cell(2, 2); g.Char != '─' || g.Mode&attrBold == 0 {
+		t.Fatalf("expected a bold line drawing character, got %+v", g)
+	}
+	if fg := term.Cursor().Attr.FG; fg != Red {
+		t.Fatalf("expected the red foreground restored, got %v", fg)
+	}
+	s := term.(MetaDumper).DumpMeta()
+	if !s.Origin || s.CursorX != 3 || s.CursorY != 2 {
+		t.Fatalf("expected origin mode and the position restored, got origin %v at (%d,%d)", s.Origin, s.CursorX, s.CursorY)
+	}
+}
+
+func TestSaveCursorShiftedCharset(t *testing.T) {
+	term := New(WithSize(10, 2))
+
+	// Line drawing is designated G1 and invoked with SO; SI returns to G0.
+	writeSeq(t, term, "\033)0\016q\0337\017\0338q\017q")
+	if got := string([]rune(term.String())[:3]); got != "──q" {
+		t.Fatalf("expected the shifted charset saved and restored, got %q", got)
+	}
+}
+
+func TestSaveCursorPerScreen(t *testing.T) {
+	term := New(WithSize(10, 5))
+
+	writeSeq(t, term, "\033[2;2H\033[32m\0337")
+	writeSeq(t, term, "\033[?1049h\033[4;4H\033[34m\0337\033[1;1H\0338")
+	s := term.(MetaDumper).DumpMeta()
+	if s.CursorX != 3 || s.CursorY != 3 || term.Cursor().Attr.FG != Blue {
+		t.Fatalf("expected the alternate screen's own saved cursor, got (%d,%d) %v", s.CursorX, s.CursorY, term.Cursor().Attr.FG)
+	}
+	if s.SavedCursorX != 3 || s.AlternateSavedCursorX != 1 {
+		t.Fatalf("expected both slots reported, got %d and %d", s.SavedCursorX, s.AlternateSavedCursorX)
+	}
+
+	writeSeq(t, term, "\033[?1049l")
+	if c := term.Cursor(); c.X != 1 || c.Y != 1 || c.Attr.FG != Green {
+		t.Fatalf("expected the primary screen's saved cursor after 1049, got (%d,%d) %v", c.X, c.Y, c.Attr.FG)
+	}
+	writeSeq(t, term, "\033[5;5H\0338")
+	if c := term.Cursor(); c.X != 1 || c.Y != 1 {
+		t.Fatalf("expected the primary slot untouched by the alternate screen, got (%d,%d)", c.X, c.Y)
+	}
+}
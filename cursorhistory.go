@@ -0,0 +1,72 @@
+package vt10x
+
+import "time"
+
+// CursorMove is a position the cursor moved to, recorded with WithCursorHistory.
+type CursorMove struct {
+	// X and Y are the cell the cursor moved to.
+	X, Y int
+
+	// Seq numbers the moves from 0 in the order they happened, so gaps show where moves were dropped.
+	Seq uint64
+
+	// Time is when the cursor moved, if line timestamps were enabled with WithLineTimestamps.
+	Time time.Time
+}
+
+// WithCursorHistory records the positions the cursor moves to, retrievable with TakeCursorHistory, so analysis tools
+// can tell where on the screen a session typed and edited. Only the newest limit moves are kept between calls; older
+// ones are counted as dropped. A position is recorded once for each rune of input that leaves the cursor somewhere new,
+// so a sequence that moves the cursor several times records only where it ends up. A non-positive limit disables the
+// history (the default).
+func WithCursorHistory(limit int) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.cursorHistory = max(limit, 0)
+	}
+}
+
+// cursorHistory holds the cursor moves not yet taken. moves grows to twice limit before the oldest are discarded,
+// so that discarding them costs little per move.
+type cursorHistory struct {
+	limit   int
+	moves   []CursorMove
+	dropped int
+	seq     uint64
+	x, y    int // the position last recorded
+}
+
+// TakeCursorHistory returns the cursor moves recorded since the last call, oldest first, and the number of moves
+// dropped because there were more than the limit set with WithCursorHistory, then resets both. Without
+// WithCursorHistory it always returns (nil, 0).
+func (t *State) TakeCursorHistory() (moves []CursorMove, dropped int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h := &t.cursorHistory
+	moves, dropped = h.moves, h.dropped
+	if n := len(moves) - h.limit; n > 0 {
+		moves, dropped = moves[n:], dropped+n
+	}
+	h.moves, h.dropped = nil, 0
+	return moves, dropped
+}
+
+// recordCursor records the cursor's position if it has moved since it was last recorded.
+func (t *State) recordCursor() {
+	h := &t.cursorHistory
+	if t.cur.X == h.x && t.cur.Y == h.y {
+		return
+	}
+	h.x, h.y = t.cur.X, t.cur.Y
+	if len(h.moves) == 2*h.limit {
+		n := copy(h.moves, h.moves[h.limit:])
+		h.moves = h.moves[:n]
+		h.dropped += h.limit
+	}
+	m := CursorMove{X: h.x, Y: h.y, Seq: h.seq}
+	if t.lineClock != nil {
+		m.Time = t.lineClock()
+	}
+	h.moves = append(h.moves, m)
+	h.seq++
+}
@@ -0,0 +1,60 @@
+package vt10x
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorHistory(t *testing.T) {
+	term := New(WithCursorHistory(10))
+	writeSeq(t, term, "ab\033[5;10H\033[1m\r")
+	moves, dropped := term.(CursorRecorder).TakeCursorHistory()
+	want := []CursorMove{{X: 1, Y: 0, Seq: 0}, {X: 2, Y: 0, Seq: 1}, {X: 9, Y: 4, Seq: 2}, {X: 0, Y: 4, Seq: 3}}
+	if dropped != 0 || len(moves) != len(want) {
+		t.Fatalf("expected %+v, got %+v, %d dropped", want, moves, dropped)
+	}
+	for i := range want {
+		if moves[i] != want[i] {
+			t.Errorf("move %d: expected %+v, got %+v", i, want[i], moves[i])
+		}
+	}
+	if moves, dropped := term.(CursorRecorder).TakeCursorHistory(); len(moves) != 0 || dropped != 0 {
+		t.Errorf("expected the history reset, got %+v, %d dropped", moves, dropped)
+	}
+}
+
+func TestCursorHistoryLimit(t *testing.T) {
+	term := New(WithCursorHistory(3))
+	writeSeq(t, term, "abcdefghij")
+	moves, dropped := term.(CursorRecorder).TakeCursorHistory()
+	if dropped != 7 || len(moves) != 3 {
+		t.Fatalf("expected 3 moves and 7 dropped, got %+v, %d dropped", moves, dropped)
+	}
+	for i, m := range moves {
+		if m.X != 8+i || m.Seq != uint64(7+i) {
+			t.Errorf("move %d: expected column %d, seq %d, got %+v", i, 8+i, 7+i, m)
+		}
+	}
+
+	writeSeq(t, term, "\n")
+	if moves, _ := term.(CursorRecorder).TakeCursorHistory(); len(moves) != 1 || moves[0].Seq != 10 {
+		t.Errorf("expected numbering to continue after a take, got %+v", moves)
+	}
+}
+
+func TestCursorHistoryTimes(t *testing.T) {
+	now := time.Unix(100, 0)
+	term := New(WithCursorHistory(10), WithLineTimestamps(func() time.Time { return now }))
+	writeSeq(t, term, "a")
+	if moves, _ := term.(CursorRecorder).TakeCursorHistory(); len(moves) != 1 || !moves[0].Time.Equal(now) {
+		t.Errorf("expected the move timestamped, got %+v", moves)
+	}
+}
+
+func TestCursorHistoryDisabled(t *testing.T) {
+	term := New()
+	writeSeq(t, term, "abc")
+	if moves, dropped := term.(CursorRecorder).TakeCursorHistory(); moves != nil || dropped != 0 {
+		t.Errorf("expected no history, got %+v, %d dropped", moves, dropped)
+	}
+}
@@ -0,0 +1,57 @@
+package vt10x
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxDirectoryHistory caps the working directories the terminal remembers; reporting more discards the oldest.
+const maxDirectoryHistory = 100
+
+// WorkingDirectory is a working directory a shell reported with OSC 7, or iTerm2's OSC 1337 ; CurrentDir.
+type WorkingDirectory struct {
+	// Host is the host name the shell runs on, empty if it did not say.
+	Host string
+
+	// Path is the directory.
+	Path string
+
+	// Time is when it was reported, from the clock given to WithLineTimestamps if there is one.
+	Time time.Time
+}
+
+// WithDirectoryHandler sets a function called with each working directory a shell reports with OSC 7, so that
+// embedders can show where the user is. It is called while the terminal is locked, so it must not call back into the
+// terminal.
+func WithDirectoryHandler(fn func(WorkingDirectory)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onDirectory = fn
+	}
+}
+
+// setWorkingDirectory handles OSC 7 ; file://host/path, and kitty's kitty-shell-cwd://host/path. A report of the
+// directory already current is recorded again only if its host changed.
+func (t *State) setWorkingDirectory(s *strEscape) {
+	u, err := url.Parse(strings.Join(s.args[1:], ";"))
+	if err != nil || u.Scheme != "file" && u.Scheme != "kitty-shell-cwd" || u.Path == "" {
+		t.warnf("invalid working directory %q", s)
+		return
+	}
+	t.addWorkingDirectory(u.Host, u.Path)
+}
+
+// addWorkingDirectory records that the shell on host is in the directory path.
+func (t *State) addWorkingDirectory(host, path string) {
+	d := WorkingDirectory{Host: host, Path: path, Time: t.now()}
+	if n := len(t.directories); n > 0 && t.directories[n-1].Host == d.Host && t.directories[n-1].Path == d.Path {
+		return
+	}
+	if len(t.directories) == maxDirectoryHistory {
+		t.directories = append(t.directories[:0], t.directories[1:]...)
+	}
+	t.directories = append(t.directories, d)
+	if t.onDirectory != nil {
+		t.onDirectory(d)
+	}
+}
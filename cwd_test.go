@@ -0,0 +1,51 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWorkingDirectory(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var reported []WorkingDirectory
+	term := New(WithLineTimestamps(func() time.Time { return now }),
+		WithDirectoryHandler(func(d WorkingDirectory) { reported = append(reported, d) }))
+	if s := term.(MetaDumper).DumpMeta(); s.WorkingDirectory != "" || s.DirectoryHistory != nil {
+		t.Fatalf("expected no working directory, got %q %v", s.WorkingDirectory, s.DirectoryHistory)
+	}
+
+	writeSeq(t, term, "\033]7;file://box/home/me\007")
+	now = now.Add(time.Minute)
+	writeSeq(t, term, "\033]7;file://box/home/me\007\033]7;file://box/tmp/a%20b;c\033\\")
+	writeSeq(t, term, "\033]7;kitty-shell-cwd:///srv\007\033]7;http://box/x\007\033]7;not a url\007")
+
+	want := []WorkingDirectory{
+		{Host: "box", Path: "/home/me", Time: now.Add(-time.Minute)},
+		{Host: "box", Path: "/tmp/a b;c", Time: now},
+		{Path: "/srv", Time: now},
+	}
+	s := term.(MetaDumper).DumpMeta()
+	if s.WorkingDirectory != "/srv" || !reflect.DeepEqual(s.DirectoryHistory, want) {
+		t.Fatalf("expected /srv with history %+v, got %q %+v", want, s.WorkingDirectory, s.DirectoryHistory)
+	}
+	if !reflect.DeepEqual(reported, want) {
+		t.Fatalf("expected the handler told %+v, got %+v", want, reported)
+	}
+
+	restored := New(WithState(term.DumpState()))
+	if got := restored.(MetaDumper).DumpMeta().DirectoryHistory; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the history restored, got %+v", got)
+	}
+}
+
+func TestWorkingDirectoryHistoryLimit(t *testing.T) {
+	term := New()
+	for i := 0; i <= maxDirectoryHistory; i++ {
+		writeSeq(t, term, "\033]7;file:///d"+string(rune('a'+i%26))+"\007")
+	}
+	s := term.(MetaDumper).DumpMeta()
+	if len(s.DirectoryHistory) != maxDirectoryHistory || s.DirectoryHistory[0].Path != "/db" {
+		t.Fatalf("expected the oldest directory dropped, got %d starting with %+v", len(s.DirectoryHistory), s.DirectoryHistory[0])
+	}
+}
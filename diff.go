@@ -0,0 +1,137 @@
+package vt10x
+
+import "fmt"
+
+// CellDiff is the change of the active screen from one TerminalState to a later one: the cells that differ, and the
+// cursor. A consumer holding a copy of the screen can follow the terminal by applying diffs instead of fetching every
+// cell each time.
+type CellDiff struct {
+	// Cols and Rows are the size of the later state. When it differs from the earlier one, Cells holds every cell.
+	Cols, Rows int
+
+	CursorX, CursorY int
+	CursorVisible    bool
+
+	// Cells are the changed cells, top to bottom and left to right.
+	Cells []CellChange
+}
+
+// CellChange is the new content of the cell at X, Y.
+type CellChange struct {
+	X, Y  int
+	Glyph Glyph
+}
+
+// DiffStates returns the change of PrimaryBuffer, which holds the active screen, from one state to a later one.
+func DiffStates(from, to TerminalState) CellDiff {
+	d := CellDiff{
+		Cols: to.Cols, Rows: to.Rows,
+		CursorX: to.CursorX, CursorY: to.CursorY, CursorVisible: to.CursorVisible,
+	}
+	resized := from.Cols != to.Cols || from.Rows != to.Rows
+	for y, row := range to.PrimaryBuffer {
+		for x, g := range row {
+			if !resized && y < len(from.PrimaryBuffer) && x < len(from.PrimaryBuffer[y]) && from.PrimaryBuffer[y][x] == g {
+				continue
+			}
+			d.Cells = append(d.Cells, CellChange{X: x, Y: y, Glyph: g})
+		}
+	}
+	return d
+}
+
+// Apply updates s, which holds the state d was computed from, to the later state's size, PrimaryBuffer and cursor.
+// Its other fields are left alone, and changes outside the screen are ignored.
+func (d CellDiff) Apply(s *TerminalState) {
+	if d.Cols != s.Cols || d.Rows != s.Rows || len(s.PrimaryBuffer) != d.Rows {
+		buf := make([][]Glyph, d.Rows)
+		for y := range buf {
+			buf[y] = make([]Glyph, d.Cols)
+		}
+		s.PrimaryBuffer, s.Cols, s.Rows = buf, d.Cols, d.Rows
+	}
+	s.CursorX, s.CursorY, s.CursorVisible = d.CursorX, d.CursorY, d.CursorVisible
+	for _, c := range d.Cells {
+		if c.Y >= 0 && c.Y < len(s.PrimaryBuffer) && c.X >= 0 && c.X < len(s.PrimaryBuffer[c.Y]) {
+			s.PrimaryBuffer[c.Y][c.X] = c.Glyph
+		}
+	}
+}
+
+// MarshalProto encodes d as the CellDiff message of proto/vt10x.proto, with adjacent changed cells of a row that share
+// their attributes in one run.
+func (d CellDiff) MarshalProto() ([]byte, error) {
+	var e protoEncoder
+	e.int(1, d.Cols)
+	e.int(2, d.Rows)
+	e.int(3, d.CursorX)
+	e.int(4, d.CursorY)
+	e.bool(5, d.CursorVisible)
+	for i := 0; i < len(d.Cells); {
+		start := d.Cells[i]
+		text := []rune{start.Glyph.Char}
+		for i++; i < len(d.Cells); i++ {
+			c := d.Cells[i]
+			if c.Y != start.Y || c.X != start.X+len(text) || !sameAttrs(c.Glyph, start.Glyph) {
+				break
+			}
+			text = append(text, c.Glyph.Char)
+		}
+		run := newRun(string(text), start.Glyph)
+		e.message(6, func(e *protoEncoder) {
+			e.int(1, start.X)
+			e.int(2, start.Y)
+			e.message(3, func(e *protoEncoder) { putRun(e, run) })
+		})
+	}
+	return e.b, nil
+}
+
+// UnmarshalProto decodes a CellDiff message, as encoded by MarshalProto.
+func (d *CellDiff) UnmarshalProto(data []byte) error {
+	var diff CellDiff
+	dec := protoDecoder{b: data}
+	for dec.next() {
+		switch dec.field {
+		case 1:
+			diff.Cols = dec.int()
+		case 2:
+			diff.Rows = dec.int()
+		case 3:
+			diff.CursorX = dec.int()
+		case 4:
+			diff.CursorY = dec.int()
+		case 5:
+			diff.CursorVisible = dec.bool()
+		case 6:
+			var (
+				x, y  int
+				cells []Glyph
+			)
+			dec.message(func(d *protoDecoder) {
+				for d.next() {
+					switch d.field {
+					case 1:
+						x = d.int()
+					case 2:
+						y = d.int()
+					case 3:
+						cells = getRun(d)
+					default:
+						d.skip()
+					}
+				}
+			})
+			for i, g := range cells {
+				diff.Cells = append(diff.Cells, CellChange{X: x + i, Y: y, Glyph: g})
+			}
+		default:
+			dec.skip()
+		}
+	}
+	if dec.err != nil {
+		return fmt.Errorf("vt10x: decoding cell diff: %w", dec.err)
+	}
+	*d = diff
+	return nil
+}
@@ -5,5 +5,22 @@ muxing, a terminal emulation frontend, or wherever else you need
 terminal emulation.
 
 In development, but very usable.
+
+# API stability
+
+This is version 1 of the API, and it is not restructured: Terminal, View, State, the options, and Update keep their
+current shape. Changes that would break it wait for a v2 module, github.com/hinshun/vt10x/v2, which does not exist
+yet; until then the package follows semantic versioning, and within a major version:
+
+  - Exported functions, types, constants, struct fields, and options are not removed or changed incompatibly.
+    TerminalState and Glyph may gain fields, so construct them with keyed literals.
+  - Terminal and View, which the emulator returned by New implements, do not gain methods, so implementations
+    written outside this package, such as test doubles, keep compiling. Features are added as small interfaces
+    instead, such as Searcher and Subscriber, that the emulator also implements; type-assert a Terminal to the ones
+    you use.
+  - Escape sequence handling may change to match real terminals more closely. Such fixes are not breaking changes,
+    even when they alter the resulting screen contents.
+  - Identifiers marked Deprecated keep working until the next major version, which removes them. The deprecation
+    notice names the replacement, if any.
 */
 package vt10x
@@ -0,0 +1,177 @@
+package vt10x
+
+import (
+	"testing"
+)
+
+// screenRows returns the text of each row of term.
+func screenRows(term Terminal) []string {
+	cols, rows := term.Size()
+	lines := make([]string, rows)
+	for y := range lines {
+		lines[y] = extractStr(term, 0, cols-1, y)
+	}
+	return lines
+}
+
+func assertRows(t *testing.T, term Terminal, want ...string) {
+	t.Helper()
+
+	got := screenRows(term)
+	for y := range want {
+		if got[y] != want[y] {
+			t.Fatalf("row %d: expected %q, got %q (screen %q)", y, want[y], got[y], got)
+		}
+	}
+}
+
+func TestEditCharacters(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want string
+		x    int // cursor column afterwards
+	}{
+		{"ICH", "\033[3G\033[2@", "ab  cdef", 2},
+		{"ICH default", "\033[3G\033[@", "ab cdefg", 2},
+		{"ICH zero means one", "\033[3G\033[0@", "ab cdefg", 2},
+		{"ICH past margin", "\033[3G\033[99@", "ab      ", 2},
+		{"DCH", "\033[3G\033[2P", "abefgh  ", 2},
+		{"DCH zero means one", "\033[3G\033[0P", "abdefgh ", 2},
+		{"DCH past margin", "\033[3G\033[99P", "ab      ", 2},
+		{"DCH at last column", "\033[8G\033[P", "abcdefg ", 7},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			term := New(WithSize(8, 3))
+			writeSeq(t, term, "abcdefgh"+tc.seq)
+			assertRows(t, term, tc.want)
+			if cur := term.Cursor(); cur.X != tc.x || cur.Y != 0 {
+				t.Fatalf("expected cursor at (%d,0), got (%d,%d)", tc.x, cur.X, cur.Y)
+			}
+		})
+	}
+}
+
+// TestEditCharactersClearPendingWrap checks that ICH and DCH at the last column cancel the pending wrap, so the next
+// character overwrites the last column instead of wrapping.
+func TestEditCharactersClearPendingWrap(t *testing.T) {
+	for _, seq := range []string{"\033[@", "\033[P"} {
+		term := New(WithSize(8, 3))
+		writeSeq(t, term, "abcdefgh"+seq+"X")
+		assertRows(t, term, "abcdefgX", "        ")
+	}
+}
+
+func TestEditLines(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want []string
+		y    int // cursor row afterwards; the cursor always ends in column 0
+	}{
+		{"IL", "\033[2;3H\033[L", []string{"0", " ", "1", "2", "3", "5"}, 1},
+		{"IL zero means one", "\033[2;3H\033[0L", []string{"0", " ", "1", "2", "3", "5"}, 1},
+		{"IL past margin", "\033[2;3H\033[9L", []string{"0", " ", " ", " ", " ", "5"}, 1},
+		{"DL", "\033[2;3H\033[2M", []string{"0", "3", "4", " ", " ", "5"}, 1},
+		{"DL at bottom margin", "\033[5;3H\033[M", []string{"0", "1", "2", "3", " ", "5"}, 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			term := New(WithSize(3, 6))
+			writeSeq(t, term, "0\r\n1\r\n2\r\n3\r\n4\r\n5\033[2;5r"+tc.seq)
+			for y, want := range tc.want {
+				if got := extractStr(term, 0, 0, y); got != want {
+					t.Fatalf("row %d: expected %q, got %q", y, want, got)
+				}
+			}
+			if cur := term.Cursor(); cur.X != 0 || cur.Y != tc.y {
+				t.Fatalf("expected cursor at (0,%d), got (%d,%d)", tc.y, cur.X, cur.Y)
+			}
+		})
+	}
+}
+
+// TestEditLinesOutsideRegion checks that IL and DL with the cursor above or below the scroll region change nothing,
+// not even the cursor position.
+func TestEditLinesOutsideRegion(t *testing.T) {
+	for _, seq := range []string{"\033[1;2H\033[L", "\033[1;2H\033[M", "\033[6;2H\033[3L", "\033[6;2H\033[3M"} {
+		term := New(WithSize(3, 6))
+		writeSeq(t, term, "0\r\n1\r\n2\r\n3\r\n4\r\n5\033[2;5r"+seq)
+		assertRows(t, term, "0  ", "1  ", "2  ", "3  ", "4  ", "5  ")
+		if cur := term.Cursor(); cur.X != 1 {
+			t.Fatalf("%q: expected the cursor to stay in column 1, got %d", seq, cur.X)
+		}
+	}
+}
+
+// TestEditKeepsWrapFlag ensures editing a wrapped row leaves the autowrap flag on its last cell, where consumers
+// joining wrapped rows look for it.
+func TestEditKeepsWrapFlag(t *testing.T) {
+	for _, seq := range []string{"\033[1;3H\033[2@", "\033[1;3H\033[2P", "\033[1;3H\033[4hXY", "\033[1;5H\033[P"} {
+		term := New(WithSize(8, 3))
+		writeSeq(t, term, "abcdefghij"+seq)
+
+		for x := 0; x < 8; x++ {
+			if got, want := IsWrap(term.Cell(x, 0).Mode), x == 7; got != want {
+				t.Fatalf("%q: expected the wrap flag only on the last cell, found it %v at column %d", seq, got, x)
+			}
+		}
+	}
+
+	// Rows that did not wrap do not gain the flag.
+	term := New(WithSize(8, 3))
+	writeSeq(t, term, "abcdefgh\033[1;3H\033[2P\033[3@")
+	for x := 0; x < 8; x++ {
+		if IsWrap(term.Cell(x, 0).Mode) {
+			t.Fatalf("expected no wrap flag on an unwrapped row, found one at column %d", x)
+		}
+	}
+}
+
+func TestSelectiveErase(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want []string
+	}{
+		{"DECSEL right", "\033[1;3H\033[?K", []string{"abCD    ", "efGH    "}},
+		{"DECSEL left", "\033[1;6H\033[?1K", []string{"  CD    ", "efGH    "}},
+		{"DECSEL all", "\033[?2K", []string{"  CD    ", "efGH    "}},
+		{"DECSED all", "\033[?2J", []string{"  CD    ", "  GH    "}},
+		{"DECSED below", "\033[1;4H\033[?J", []string{"abCD    ", "  GH    "}},
+		{"DECSED above", "\033[2;1H\033[?1J", []string{"  CD    ", " fGH    "}},
+		{"EL ignores protection", "\033[1;1H\033[K", []string{"        ", "efGH    "}},
+		{"ED ignores protection", "\033[2J", []string{"        ", "        "}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			term := New(WithSize(8, 3))
+			writeSeq(t, term, "ab\033[1\"qCD\033[0\"q\r\nef\033[1\"qGH\033[2\"q\033[1;1H")
+			writeSeq(t, term, tc.seq)
+			assertRows(t, term, tc.want...)
+		})
+	}
+}
+
+func TestSelectiveEraseKeepsAttributes(t *testing.T) {
+	term := New(WithSize(8, 3))
+	writeSeq(t, term, "\033[1;31mab\033[1\"qCD\033[m\033[1;1H\033[?K")
+
+	if g := term.Cell(0, 0); g.Char != ' ' || g.FG != Red+8 || !IsBold(g.Mode) {
+		t.Fatalf("expected the erased cell to keep its attributes, got %+v", g)
+	}
+	if g := term.Cell(2, 0); g.Char != 'C' || !IsProtected(g.Mode) {
+		t.Fatalf("expected a protected cell, got %+v", g)
+	}
+
+	// Cells cleared while protection is on are not protected, and SGR 0 leaves protection alone.
+	writeSeq(t, term, "\033[2;1HX\033[1\"q\033[2;1H\033[K\033[m")
+	if g := term.Cell(0, 1); IsProtected(g.Mode) {
+		t.Fatalf("expected an erased cell unprotected, got %+v", g)
+	}
+	writeSeq(t, term, "Y\033[2;1H\033[?K")
+	if g := term.Cell(0, 1); g.Char != 'Y' {
+		t.Fatalf("expected SGR 0 to leave protection on, got %+v", g)
+	}
+}
@@ -0,0 +1,42 @@
+package vt10x
+
+// Encoding is a legacy single-byte character set, used to decode input that is not UTF-8. Bytes below 0x80 are
+// ASCII in every Encoding; the table gives the runes of the bytes 0x80-0xFF.
+type Encoding struct {
+	high [128]rune
+}
+
+// NewEncoding returns the Encoding whose bytes 0x80-0xFF decode to the runes in high, in order.
+func NewEncoding(high [128]rune) *Encoding {
+	return &Encoding{high: high}
+}
+
+var (
+	// Latin1 is ISO-8859-1, in which every byte is the rune of the same value, so bytes 0x80-0x9F are the C1
+	// controls; see WithC1Controls.
+	Latin1 = func() *Encoding {
+		var high [128]rune
+		for i := range high {
+			high[i] = rune(0x80 + i)
+		}
+		return NewEncoding(high)
+	}()
+
+	// CP437 is the character set of the IBM PC, in which DOS programs and BBSes drew with box drawing characters.
+	CP437 = newEncodingFromString("ÇüéâäàåçêëèïîìÄÅ" + "ÉæÆôöòûùÿÖÜ¢£¥₧ƒ" + "áíóúñÑªº¿⌐¬½¼¡«»" + "░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+		"└┴┬├─┼╞╟╚╔╩╦╠═╬╧" + "╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" + "αßΓπΣσµτΦΘΩδ∞φε∩" + "≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ ")
+)
+
+func newEncodingFromString(s string) *Encoding {
+	var high [128]rune
+	copy(high[:], []rune(s))
+	return NewEncoding(high)
+}
+
+// Decode returns the rune of b.
+func (e *Encoding) Decode(b byte) rune {
+	if b < 0x80 {
+		return rune(b)
+	}
+	return e.high[b-0x80]
+}
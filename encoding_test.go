@@ -0,0 +1,60 @@
+package vt10x
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestInputEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		e    *Encoding
+		in   string
+		want string
+	}{
+		{"latin1", Latin1, "caf\xe9 \xbd\xff", "café ½ÿ"},
+		{"cp437", CP437, "\xc9\xcd\xbb \xb0\xdb \xe1", "╔═╗ ░█ ß"},
+	} {
+		term := New(WithInputEncoding(tc.e))
+		writeSeq(t, term, tc.in)
+		var got []rune
+		for x := range []rune(tc.want) {
+			got = append(got, term.Cell(x, 0).Char)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, string(got))
+		}
+	}
+}
+
+func TestInputEncodingParse(t *testing.T) {
+	term := New(WithInputEncoding(CP437))
+	if err := term.Parse(bufio.NewReader(bytes.NewReader([]byte("\x1b[2C\xc4\xc4")))); err != nil {
+		t.Fatal(err)
+	}
+	if a, b := term.Cell(2, 0), term.Cell(3, 0); a.Char != '─' || b.Char != '─' {
+		t.Errorf("expected box drawing after the CSI, got %q%q", a.Char, b.Char)
+	}
+}
+
+func TestInputEncodingC1Controls(t *testing.T) {
+	// Latin-1 decodes 0x85 to NEL, which WithC1Controls recognizes.
+	term := New(WithInputEncoding(Latin1), WithC1Controls())
+	writeSeq(t, term, "a\x85b")
+	if g := term.Cell(0, 1); g.Char != 'b' {
+		t.Errorf("expected NEL to start the next line, got %q at 0,1", g.Char)
+	}
+}
+
+func TestNewEncoding(t *testing.T) {
+	var high [128]rune
+	high[0] = '€'
+	e := NewEncoding(high)
+	if got := e.Decode(0x80); got != '€' {
+		t.Errorf("expected €, got %q", got)
+	}
+	if got := e.Decode('A'); got != 'A' {
+		t.Errorf("expected ASCII unchanged, got %q", got)
+	}
+}
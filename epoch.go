@@ -0,0 +1,71 @@
+package vt10x
+
+// WithInputEpochs tags each cell the terminal writes with the input epoch current at the time, exposed in
+// TerminalState as PrimaryEpochs and AlternateEpochs, so audit tools can tell which output followed which input
+// without guessing from timing. The epoch starts at 0 and is advanced with AdvanceInputEpoch, typically for each
+// keystroke forwarded to the program. Printing a character, and erasing or shifting cells, all count as writing.
+func WithInputEpochs() TerminalOption {
+	return func(info *TerminalInfo) {
+		info.inputEpochs = true
+	}
+}
+
+// AdvanceInputEpoch starts a new input epoch and returns it. Cells written from now on are tagged with it.
+func (t *State) AdvanceInputEpoch() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inputEpoch++
+	return t.inputEpoch
+}
+
+// tagEpochs tags the cells from x0 through x1 of row y of the active screen with the current input epoch.
+func (t *State) tagEpochs(y, x0, x1 int) {
+	if !t.inputEpochs || y < 0 || y >= len(t.meta) || x0 > x1 {
+		return
+	}
+	m := &t.meta[y]
+	if len(m.epochs) < t.cols {
+		m.epochs = append(m.epochs, make([]uint64, t.cols-len(m.epochs))...)
+	}
+	for x := max(x0, 0); x <= x1 && x < len(m.epochs); x++ {
+		m.epochs[x] = t.inputEpoch
+	}
+}
+
+// shiftEpochs moves the epochs of n cells of row y from column src to dst, as ICH and DCH move the cells.
+func (t *State) shiftEpochs(y, dst, src, n int) {
+	if !t.inputEpochs || y < 0 || y >= len(t.meta) {
+		return
+	}
+	e := t.meta[y].epochs
+	if src >= len(e) || dst >= len(e) {
+		return
+	}
+	n = min(n, len(e)-max(src, dst))
+	copy(e[dst:dst+n], e[src:src+n])
+}
+
+// epochRows returns the epochs of the cells of rows with the given bookkeeping, one row of cols per row of meta.
+func epochRows(meta []rowMeta, cols int) [][]uint64 {
+	rows := make([][]uint64, len(meta))
+	slab := make([]uint64, len(meta)*cols)
+	for y := range meta {
+		rows[y] = slab[y*cols : (y+1)*cols : (y+1)*cols]
+		copy(rows[y], meta[y].epochs)
+	}
+	return rows
+}
+
+// restoreEpochs sets the epochs of the rows of meta from rows, as returned by epochRows, and carries on from the
+// latest of them.
+func (t *State) restoreEpochs(meta []rowMeta, rows [][]uint64) {
+	for y := 0; y < len(meta) && y < len(rows); y++ {
+		meta[y].epochs = append([]uint64(nil), rows[y]...)
+		for _, e := range rows[y] {
+			if e > t.inputEpoch {
+				t.inputEpoch = e
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInputEpochs(t *testing.T) {
+	term := New(WithSize(6, 2), WithInputEpochs())
+	writeSeq(t, term, "ab")
+	if e := term.(EpochAdvancer).AdvanceInputEpoch(); e != 1 {
+		t.Fatalf("expected epoch 1, got %d", e)
+	}
+	writeSeq(t, term, "c\r\nd")
+	term.(EpochAdvancer).AdvanceInputEpoch()
+	// ICH shifts "bc" right, taking their epochs along, and tags the blank it inserts.
+	writeSeq(t, term, "\033[1;2H\033[@")
+
+	s := term.DumpState()
+	want := [][]uint64{{0, 2, 0, 1, 0, 0}, {1, 0, 0, 0, 0, 0}}
+	if !reflect.DeepEqual(s.PrimaryEpochs, want) {
+		t.Errorf("expected epochs %v, got %v", want, s.PrimaryEpochs)
+	}
+	if len(s.AlternateEpochs) != 2 {
+		t.Errorf("expected epochs for the alternate screen, got %v", s.AlternateEpochs)
+	}
+
+	term.(EpochAdvancer).AdvanceInputEpoch()
+	writeSeq(t, term, "\033[2K")
+	if got := term.DumpState().PrimaryEpochs[0]; !reflect.DeepEqual(got, []uint64{3, 3, 3, 3, 3, 3}) {
+		t.Errorf("expected the erased row tagged, got %v", got)
+	}
+}
+
+func TestInputEpochsScroll(t *testing.T) {
+	term := New(WithSize(4, 2), WithInputEpochs())
+	writeSeq(t, term, "a")
+	term.(EpochAdvancer).AdvanceInputEpoch()
+	writeSeq(t, term, "\r\nb\r\n")
+	got := term.DumpState().PrimaryEpochs
+	want := [][]uint64{{1, 0, 0, 0}, {1, 1, 1, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the epochs to scroll with the rows, %v, got %v", want, got)
+	}
+}
+
+func TestInputEpochsDisabled(t *testing.T) {
+	term := New()
+	term.(EpochAdvancer).AdvanceInputEpoch()
+	writeSeq(t, term, "a")
+	if s := term.DumpState(); s.PrimaryEpochs != nil || s.AlternateEpochs != nil {
+		t.Errorf("expected no epochs, got %v", s.PrimaryEpochs)
+	}
+}
+
+func TestInputEpochsRoundTrip(t *testing.T) {
+	term := New(WithSize(3, 1), WithInputEpochs())
+	term.(EpochAdvancer).AdvanceInputEpoch()
+	writeSeq(t, term, "ab")
+	s := term.DumpState()
+
+	data, err := s.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TerminalState
+	if err := decoded.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.PrimaryEpochs, s.PrimaryEpochs) {
+		t.Errorf("expected %v after a round trip, got %v", s.PrimaryEpochs, decoded.PrimaryEpochs)
+	}
+
+	restored := New(WithState(decoded), WithInputEpochs())
+	writeSeq(t, restored, "c")
+	if got := restored.DumpState().PrimaryEpochs[0]; !reflect.DeepEqual(got, []uint64{1, 1, 1}) {
+		t.Errorf("expected restored epochs and the epoch carried on, got %v", got)
+	}
+	if e := restored.(EpochAdvancer).AdvanceInputEpoch(); e != 2 {
+		t.Errorf("expected epoch 2 after the restored 1, got %d", e)
+	}
+}
@@ -0,0 +1,18 @@
+// Package expect runs a command on a pseudo-terminal attached to an emulated terminal and waits for the emulated
+// screen to show what a test expects, in the manner of expect(1) but matching what a user would see rather than the
+// raw output stream:
+//
+//	c, err := expect.Start(exec.Command("bash", "--norc"), vt10x.WithSize(80, 24))
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer c.Close()
+//	c.Send("echo hello\r")
+//	if err := c.WaitForString("hello", 5*time.Second); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// The Wait functions work on any Screen, so code that feeds a terminal some other way, such as the automation
+// package, waits the same way. Pseudo-terminals are supported on Linux, macOS, FreeBSD, NetBSD and OpenBSD; elsewhere
+// Start returns an error.
+package expect
@@ -0,0 +1,147 @@
+package expect
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+// ErrTimeout is returned by the Wait methods when the screen does not match in time.
+var ErrTimeout = errors.New("expect: timed out waiting for the screen to match")
+
+// ErrExited is returned by the Wait methods when the command's output ends without the screen matching.
+var ErrExited = errors.New("expect: command output ended")
+
+// Console is a command running on a pseudo-terminal whose output is fed to an emulated terminal. Its methods are
+// safe for concurrent use.
+type Console struct {
+	term *vt10x.PtyTerminal
+}
+
+// Start starts cmd on a pseudo-terminal the size of an emulated terminal created with opts, and feeds the terminal
+// everything cmd writes. Replies the terminal generates, such as cursor position reports, go back to cmd as input.
+func Start(cmd *exec.Cmd, opts ...vt10x.TerminalOption) (*Console, error) {
+	term, err := vt10x.NewWithCommand(cmd, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("expect: %w", err)
+	}
+	return &Console{term: term}, nil
+}
+
+// Terminal returns the emulated terminal the command draws on. Resizing it resizes the pseudo-terminal too.
+func (c *Console) Terminal() *vt10x.PtyTerminal {
+	return c.term
+}
+
+// Write sends p to the command as input.
+func (c *Console) Write(p []byte) (int, error) {
+	return c.term.Input().Write(p)
+}
+
+// Send sends s to the command as if it were typed. It is sent as it is, so use "\r" for Enter.
+func (c *Console) Send(s string) error {
+	_, err := io.WriteString(c.term.Input(), s)
+	return err
+}
+
+// WaitForString waits until s appears on the screen, read one line per row, for at most timeout.
+func (c *Console) WaitForString(s string, timeout time.Duration) error {
+	return WaitForString(c.term, s, timeout)
+}
+
+// WaitForRegexp waits until the screen text, one line per row, matches re, for at most timeout, and returns the
+// match and its submatches.
+func (c *Console) WaitForRegexp(re *regexp.Regexp, timeout time.Duration) ([]string, error) {
+	return WaitForRegexp(c.term, re, timeout)
+}
+
+// WaitForCursor waits until the cursor is at column x of row y, both zero-based, for at most timeout.
+func (c *Console) WaitForCursor(x, y int, timeout time.Duration) error {
+	return WaitForCursor(c.term, x, y, timeout)
+}
+
+// Screen is an emulated terminal fed a program's output, as the Wait functions watch it. Done is closed once the
+// output ends, and Err then returns the error that ended it early, if any. A *vt10x.PtyTerminal is a Screen, and
+// packages that feed a terminal some other way implement it to wait the same way.
+type Screen interface {
+	vt10x.Terminal
+	vt10x.Subscriber
+	Done() <-chan struct{}
+	Err() error
+}
+
+var _ Screen = (*vt10x.PtyTerminal)(nil)
+
+// WaitForString waits until str appears on the screen of s, read one line per row, for at most timeout.
+func WaitForString(s Screen, str string, timeout time.Duration) error {
+	return WaitFor(s, timeout, fmt.Sprintf("%q", str), func() bool {
+		return strings.Contains(s.String(), str)
+	})
+}
+
+// WaitForRegexp waits until the screen text of s, one line per row, matches re, for at most timeout, and returns the
+// match and its submatches.
+func WaitForRegexp(s Screen, re *regexp.Regexp, timeout time.Duration) ([]string, error) {
+	var m []string
+	err := WaitFor(s, timeout, re.String(), func() bool {
+		m = re.FindStringSubmatch(s.String())
+		return m != nil
+	})
+	return m, err
+}
+
+// WaitForCursor waits until the cursor of s is at column x of row y, both zero-based, for at most timeout.
+func WaitForCursor(s Screen, x, y int, timeout time.Duration) error {
+	return WaitFor(s, timeout, fmt.Sprintf("the cursor at (%d,%d)", x, y), func() bool {
+		s.Lock()
+		cur := s.Cursor()
+		s.Unlock()
+		return cur.X == x && cur.Y == y
+	})
+}
+
+// WaitFor waits until match reports true, checking it again whenever s changes. It returns ErrTimeout, naming what,
+// if that takes longer than timeout, and ErrExited, or the error that ended the output, if the output ends first.
+func WaitFor(s Screen, timeout time.Duration, what string, match func() bool) error {
+	// Subscribe before the first check, so a change in between is not missed.
+	updates, cancel := s.Subscribe()
+	defer cancel()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		if match() {
+			return nil
+		}
+		select {
+		case <-updates:
+		case <-s.Done():
+			// Everything read has been written to the terminal by now.
+			if match() {
+				return nil
+			}
+			if err := s.Err(); err != nil {
+				return fmt.Errorf("expect: reading command output: %w", err)
+			}
+			return fmt.Errorf("%w waiting for %s; screen:\n%s", ErrExited, what, s.String())
+		case <-timer.C:
+			return fmt.Errorf("%w %s after %v; screen:\n%s", ErrTimeout, what, timeout, s.String())
+		}
+	}
+}
+
+// Wait waits for the command to exit and for its output to be read.
+func (c *Console) Wait() error {
+	return c.term.Wait()
+}
+
+// Close kills the command if it is still running, waits for it to exit, and closes the pseudo-terminal.
+func (c *Console) Close() error {
+	return c.term.Close()
+}
@@ -0,0 +1,91 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd
+
+package expect
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+func start(t *testing.T, cmd *exec.Cmd, opts ...vt10x.TerminalOption) *Console {
+	t.Helper()
+
+	c, err := Start(cmd, opts...)
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestWaitForString(t *testing.T) {
+	c := start(t, exec.Command("cat"))
+
+	if err := c.Send("ping\r"); err != nil {
+		t.Fatal(err)
+	}
+	// The pseudo-terminal echoes the input, then cat prints it again.
+	m, err := c.WaitForRegexp(regexp.MustCompile(`(?m)^(\w+) *\n(\w+)`), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m[1] != "ping" || m[2] != "ping" {
+		t.Fatalf("expected the input echoed and printed, got %q", m)
+	}
+	if err := c.WaitForString("ping", time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartSize(t *testing.T) {
+	c := start(t, exec.Command("stty", "size"), vt10x.WithSize(100, 30))
+
+	if err := c.WaitForString("30 100", 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForCursor(t *testing.T) {
+	c := start(t, exec.Command("sh", "-c", `printf 'ab\033[3;5H'; sleep 5`))
+
+	if err := c.WaitForCursor(4, 2, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	c := start(t, exec.Command("sleep", "5"))
+
+	if err := c.WaitForString("never", 20*time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestWaitExited(t *testing.T) {
+	c := start(t, exec.Command("printf", "bye"))
+
+	if err := c.WaitForString("never", 5*time.Second); !errors.Is(err, ErrExited) {
+		t.Fatalf("expected ErrExited, got %v", err)
+	}
+	// Output that arrived just before the end still matches.
+	if err := c.WaitForString("bye", time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplies(t *testing.T) {
+	// The shell asks for the cursor position and prints the reply the terminal sends back.
+	c := start(t, exec.Command("sh", "-c", `stty raw -echo; printf 'ab\033[6n'; r=$(dd bs=1 count=6 2>/dev/null); printf '%s' "$r" | od -c | head -1; sleep 5`))
+
+	if err := c.WaitForString(`033   [   1   ;   3   R`, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
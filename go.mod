@@ -1,5 +1,10 @@
 module github.com/hinshun/vt10x
 
-go 1.24
+go 1.24.0
 
 require pgregory.net/rapid v1.3.0
+
+require (
+	golang.org/x/image v0.34.0
+	golang.org/x/text v0.32.0
+)
@@ -0,0 +1,161 @@
+package vt10x
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the .golden files of TestGolden from the current output")
+
+// goldenSize matches the optional size in the name of a fixture, as in wrap.20x5.in.
+var goldenSize = regexp.MustCompile(`\.(\d+)x(\d+)$`)
+
+// TestGolden feeds each testdata/golden/*.in file, raw bytes as a program would write them, to a terminal and compares
+// a rendering of the resulting state with the .golden file beside it. The terminal is 80x24 unless the name gives a
+// size before the extension, as in wrap.20x5.in. Fixtures named after a scenario and a number, as in
+// origin-decom-homes-to-the-top-margin-2.10x6.in, check it at successive points, each with the input up to there. To
+// add a case, add the .in file and run go test -run TestGolden -update, then check the new .golden file by eye.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/golden/*.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no golden fixtures found")
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".in")
+		t.Run(name, func(t *testing.T) {
+			in, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cols, rows := 80, 24
+			if m := goldenSize.FindStringSubmatch(name); m != nil {
+				cols, _ = strconv.Atoi(m[1])
+				rows, _ = strconv.Atoi(m[2])
+			}
+			var reply bytes.Buffer
+			term := New(WithSize(cols, rows), WithWriter(&reply))
+			if _, err := term.Write(in); err != nil {
+				t.Fatal(err)
+			}
+			got := renderGolden(term.DumpState(), reply.String())
+
+			golden := strings.TrimSuffix(file, ".in") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v; run go test -run TestGolden -update to create it", err)
+			}
+			if got != string(want) {
+				t.Errorf("%s differs from %s; run go test -run TestGolden -update if the change is intended\n"+
+					"got:\n%s\nwant:\n%s", file, golden, got, want)
+			}
+		})
+	}
+}
+
+// renderGolden renders the parts of s that escape-sequence bugs usually disturb as text that diffs well: the cursor,
+// title, modes changed from a new terminal, each row of the active screen with trailing blanks and rows trimmed, and
+// the runs of cells with attributes other than the default.
+func renderGolden(s TerminalState, reply string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cursor %d %d", s.CursorY+1, s.CursorX+1)
+	if !s.CursorVisible {
+		b.WriteString(" hidden")
+	}
+	b.WriteString("\n")
+	if s.Title != "" {
+		fmt.Fprintf(&b, "title %q\n", s.Title)
+	}
+	if s.AltScreen {
+		b.WriteString("alt screen\n")
+	}
+	defaults := New(WithSize(s.Cols, s.Rows)).(MetaDumper).DumpMeta().Modes
+	var modes []int
+	for mode, set := range s.Modes {
+		if set != defaults[mode] {
+			modes = append(modes, mode)
+		}
+	}
+	sort.Ints(modes)
+	for _, mode := range modes {
+		fmt.Fprintf(&b, "mode %d %v\n", mode, s.Modes[mode])
+	}
+	if reply != "" {
+		fmt.Fprintf(&b, "reply %q\n", reply)
+	}
+
+	last := len(s.PrimaryBuffer) - 1
+	for last >= 0 && strings.TrimRight(cellText(s.PrimaryBuffer[last]), " ") == "" {
+		last--
+	}
+	b.WriteString("screen\n")
+	for y := 0; y <= last; y++ {
+		fmt.Fprintf(&b, "%2d|%s\n", y+1, strings.TrimRight(cellText(s.PrimaryBuffer[y]), " "))
+	}
+
+	plain := Glyph{FG: DefaultFG, BG: DefaultBG, UnderlineColor: DefaultUnderline}
+	for y, runs := range encodeRuns(s.PrimaryBuffer) {
+		x := 0
+		for _, r := range runs {
+			n := len([]rune(r.Text))
+			if attrs := r.attrs(); attrs != plain {
+				fmt.Fprintf(&b, "attr %d %d-%d %s\n", y+1, x+1, x+n, goldenAttrs(attrs))
+			}
+			x += n
+		}
+	}
+	return b.String()
+}
+
+// cellText returns the characters of row, with unwritten cells as spaces and without the second halves of wide
+// characters.
+func cellText(row []Glyph) string {
+	text := make([]rune, 0, len(row))
+	for _, g := range row {
+		switch {
+		case IsWide(g.Mode) && g.Char == 0:
+		case g.Char == 0:
+			text = append(text, ' ')
+		default:
+			text = append(text, g.Char)
+		}
+	}
+	return string(text)
+}
+
+// goldenAttrs describes the attributes of g that differ from the default.
+func goldenAttrs(g Glyph) string {
+	var attrs []string
+	if g.FG != DefaultFG {
+		attrs = append(attrs, fmt.Sprintf("fg=%d", g.FG))
+	}
+	if g.BG != DefaultBG {
+		attrs = append(attrs, fmt.Sprintf("bg=%d", g.BG))
+	}
+	if g.Mode != 0 {
+		attrs = append(attrs, fmt.Sprintf("mode=%#x", uint16(g.Mode)))
+	}
+	if g.Underline != UnderlineNone {
+		attrs = append(attrs, fmt.Sprintf("underline=%d", g.Underline))
+	}
+	if g.UnderlineColor != DefaultUnderline {
+		attrs = append(attrs, fmt.Sprintf("underlinecolor=%d", g.UnderlineColor))
+	}
+	return strings.Join(attrs, " ")
+}
@@ -3,7 +3,9 @@ package vt10x
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -222,6 +224,57 @@ func TestCursorMoveOverflowNoPanic(t *testing.T) {
 	}
 }
 
+// TestCSIArgClamp verifies that parameters too large for an int are clamped instead of discarded, and that signed
+// ones are rejected.
+func TestCSIArgClamp(t *testing.T) {
+	cases := []struct {
+		name  string
+		seq   string
+		wantX int
+	}{
+		{"beyond int", "\033[1;40H\033[99999999999999999999999999C", 79},
+		{"sub-parameter beyond int", "\033[1;40H\033[4:99999999999999999999999999m\033[3C", 42},
+		{"negative", "\033[1;40H\033[-5C", 40},
+		{"plus sign", "\033[1;40H\033[+5C", 40},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			vt := New(WithSize(80, 24))
+			vt.Write([]byte(tc.seq))
+			if cur := vt.Cursor(); cur.X != tc.wantX {
+				t.Errorf("cursor at col %d, want %d", cur.X, tc.wantX)
+			}
+		})
+	}
+}
+
+// TestCSIEmptyParams ensures an empty parameter stands for the default rather than ending the parameters.
+func TestCSIEmptyParams(t *testing.T) {
+	term := New(WithSize(80, 24))
+
+	writeSeq(t, term, "\033[3;3H\033[;5H")
+	if cur := term.Cursor(); cur.X != 4 || cur.Y != 0 {
+		t.Fatalf("expected CSI ;5H to move to row 1, column 5, got row %d, column %d", cur.Y+1, cur.X+1)
+	}
+
+	writeSeq(t, term, "\033[1;;3mx")
+	if g := term.Cell(4, 0); g.Mode&attrBold != 0 || g.Mode&attrItalic == 0 {
+		t.Fatalf("expected CSI 1;;3m to reset bold and set italic, got mode %#x", g.Mode)
+	}
+}
+
+// TestUnterminatedStringBounded verifies that a string sequence that never ends, as from a truncated recording, is
+// not buffered without limit.
+func TestUnterminatedStringBounded(t *testing.T) {
+	for _, intro := range []string{"\033]0;", "\033P", "\033_", "\033^"} {
+		vt := New(WithSize(80, 24))
+		vt.Write([]byte(intro + strings.Repeat("a", 1<<20)))
+		if n := len(vt.(*terminal).str.buf); n > 256 {
+			t.Errorf("%q: buffered %d runes of the unterminated string", intro, n)
+		}
+	}
+}
+
 // TestNonASCIIInCSI verifies that a non-ASCII rune whose low byte would dispatch
 // a CSI command (e.g. U+0148 low byte = 0x48 = 'H' = CUP) is discarded instead.
 func TestNonASCIIInCSI(t *testing.T) {
@@ -241,9 +294,9 @@ func TestNonASCIIInCSI(t *testing.T) {
 	}
 }
 
-// FuzzWrite throws arbitrary bytes at a freshly constructed terminal and  fails on any panic. This is the safety net
-// for session-recording replay: a corrupt/truncated stream from a misbehaving storage backend must not  be able to
-// crash auth.
+// FuzzWrite throws arbitrary bytes at a freshly constructed terminal, then reads back, snapshots and restores what they
+// left, and fails on any panic. This is the safety net for session-recording replay: a corrupt/truncated stream from a
+// misbehaving storage backend must not  be able to crash auth.
 func FuzzWrite(f *testing.F) {
 	seeds := []string{
 		// Plain text.
@@ -265,6 +318,14 @@ func FuzzWrite(f *testing.F) {
 		"\x1bH\x1b[8;24;80t",
 		// Scroll region + oversized scroll, exercising scrollback capture clamping.
 		"\x1b[2;4r\x1b[99S",
+		// Parameters beyond int, and a long run of sub-parameters.
+		"\x1b[99999999999999999999999999L\x1b[38:2:99999999999999999999:1:2:3m",
+		"\x1b[4:3:3:3:3:3:3:3:3:3:3:3:3:3:3:3:3:3:3:3:3:3m",
+		// Unterminated and nested string sequences.
+		"\x1b]0;" + strings.Repeat("title", 100),
+		"\x1bPtmux;\x1b\x1bPtmux;\x1b\x1b\x1b\x1b]0;inner\x07\x1b\\\x1b\\",
+		"\x1b]1337;File=inline=1:" + strings.Repeat("AAAA", 64),
+		"\x1b_Ga=T,f=100,m=1;AAAA\x1b\\\x1b_Gm=1;AAAA",
 	}
 
 	for _, s := range seeds {
@@ -272,7 +333,8 @@ func FuzzWrite(f *testing.F) {
 	}
 
 	f.Fuzz(func(t *testing.T, data []byte) {
-		vt := New(WithSize(80, 24), WithScrollbackCapture(32))
+		vt := New(WithSize(80, 24), WithScrollbackCapture(32), WithTmuxPassthrough(), WithCellSize(8, 16),
+			WithWriter(io.Discard))
 		done := make(chan struct{})
 
 		go func() {
@@ -285,6 +347,22 @@ func FuzzWrite(f *testing.F) {
 			}()
 
 			_, _ = vt.Write(data)
+
+			// Whatever the input left behind must be safe to read back, persist and restore.
+			_ = vt.String()
+			vt.TakeScrollback()
+			s := vt.DumpState()
+			var buf bytes.Buffer
+			if err := EncodeSnapshot(&buf, s); err != nil {
+				t.Errorf("EncodeSnapshot after Write(%q) returned error: %v", data, err)
+				return
+			}
+			if _, err := DecodeSnapshot(&buf); err != nil {
+				t.Errorf("DecodeSnapshot after Write(%q) returned error: %v", data, err)
+			}
+			restored := New(WithState(s))
+			_, _ = restored.Write(data)
+			restored.Resize(10, 3)
 		}()
 
 		select {
@@ -0,0 +1,61 @@
+package vt10x
+
+import "slices"
+
+// FNV-1a parameters, applied to whole glyph fields rather than bytes.
+const (
+	hashOffset = 14695981039346656037
+	hashPrime  = 1099511628211
+)
+
+// HashLine returns the content hash of a row of glyphs. Rows with the same characters and attributes hash the same in
+// any terminal and process, so LineHash values can be compared with HashLine of rows from a saved TerminalState.
+func HashLine(row []Glyph) uint64 {
+	h := uint64(hashOffset)
+	for _, g := range row {
+		for _, v := range [...]uint64{uint64(g.Char), uint64(uint16(g.Mode)), uint64(g.Underline), uint64(g.FG),
+			uint64(g.BG), uint64(g.UnderlineColor)} {
+			h ^= v
+			h *= hashPrime
+		}
+	}
+	return h
+}
+
+// LineHash returns the content hash of row y of the screen, as HashLine computes it, or 0 for a row out of range. It
+// is recomputed only after the row changes, so comparing it with an earlier value is a cheap way to tell whether the
+// row has. Like Cell, it reflects the colors as written, not as the palette currently maps them. The terminal must be
+// locked.
+func (t *State) LineHash(y int) uint64 {
+	if y < 0 || y >= len(t.lines) || y >= len(t.meta) {
+		return 0
+	}
+	m := &t.meta[y]
+	if !m.hashed {
+		l := t.lines[y]
+		t.rowGlyphs = slices.Grow(t.rowGlyphs[:0], len(l))[:len(l)]
+		t.unpackLine(t.rowGlyphs, l)
+		m.hash, m.hashed = HashLine(t.rowGlyphs), true
+	}
+	return m.hash
+}
+
+// ScreenHash returns a hash of every row of the screen, combining their LineHash values, so recorders can skip frames
+// identical to the last. It does not cover the cursor. The terminal must be locked.
+func (t *State) ScreenHash() uint64 {
+	return combineLineHashes(len(t.lines), t.LineHash)
+}
+
+// ScreenHash returns the hash State.ScreenHash computes for rows, such as the buffer of a TerminalState.
+func ScreenHash(rows [][]Glyph) uint64 {
+	return combineLineHashes(len(rows), func(y int) uint64 { return HashLine(rows[y]) })
+}
+
+func combineLineHashes(rows int, lineHash func(y int) uint64) uint64 {
+	h := uint64(hashOffset)
+	for y := 0; y < rows; y++ {
+		h ^= lineHash(y)
+		h *= hashPrime
+	}
+	return h
+}
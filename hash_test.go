@@ -0,0 +1,49 @@
+package vt10x
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLineHashTracksChanges feeds the split corpus a byte at a time, checking after each that every cached line hash
+// matches a fresh hash of the row, so no kind of edit leaves a stale one behind.
+func TestLineHashTracksChanges(t *testing.T) {
+	all := strings.Join(splitCorpus, "") + "\033[?1049h\033[2;3Halt\033[?1049l\033[3L\033[2M\033[8;4t\033c"
+	term := New(WithSize(20, 8), WithCellSize(1, 6))
+	for i := 0; i < len(all); i++ {
+		writeSeq(t, term, all[i:i+1])
+		s := term.DumpState()
+		for y, row := range s.PrimaryBuffer {
+			if got, want := term.(Hasher).LineHash(y), HashLine(row); got != want {
+				t.Fatalf("after %q: row %d hash %x is stale, want %x", all[:i+1], y, got, want)
+			}
+		}
+		if got, want := term.(Hasher).ScreenHash(), ScreenHash(s.PrimaryBuffer); got != want {
+			t.Fatalf("after %q: screen hash %x, want %x", all[:i+1], got, want)
+		}
+	}
+}
+
+func TestLineHash(t *testing.T) {
+	term := New(WithSize(10, 3))
+	writeSeq(t, term, "same\r\n\033[1msame\033[m\r\nsame")
+	if term.(Hasher).LineHash(0) != term.(Hasher).LineHash(2) {
+		t.Error("expected identical rows to hash the same")
+	}
+	if term.(Hasher).LineHash(0) == term.(Hasher).LineHash(1) {
+		t.Error("expected rows differing in attributes to hash differently")
+	}
+	if got := term.(Hasher).LineHash(3); got != 0 {
+		t.Errorf("expected 0 for a row out of range, got %x", got)
+	}
+
+	before := term.(Hasher).ScreenHash()
+	writeSeq(t, term, "\033[1;1Hsame")
+	if term.(Hasher).ScreenHash() != before {
+		t.Error("expected rewriting the same text to leave the screen hash unchanged")
+	}
+	writeSeq(t, term, "!")
+	if term.(Hasher).ScreenHash() == before {
+		t.Error("expected a change to alter the screen hash")
+	}
+}
@@ -0,0 +1,61 @@
+package vt10x
+
+import "time"
+
+// WithIdleHandler sets a function called with a snapshot of the terminal once no input has been written to it for
+// after, so recorders can capture the screen between redraws rather than partway through one. It is called once for
+// each pause, from a goroutine of its own and with the terminal unlocked, so it may call back into the terminal. A
+// non-positive after disables it.
+func WithIdleHandler(after time.Duration, fn func(TerminalState)) TerminalOption {
+	return func(info *TerminalInfo) {
+		if after <= 0 {
+			fn = nil
+		}
+		info.idleAfter, info.onIdle = after, fn
+	}
+}
+
+// idleWatch tracks the writes to a terminal to tell when they pause.
+type idleWatch struct {
+	after     time.Duration
+	fn        func(TerminalState)
+	timer     *time.Timer
+	pending   bool      // the timer is running
+	lastWrite time.Time // when input was last written
+}
+
+// wrote notes that input was written, starting the idle timer unless it is already running. The terminal must be
+// locked.
+func (t *State) wrote() {
+	w := &t.idle
+	if w.fn == nil {
+		return
+	}
+	w.lastWrite = time.Now()
+	if w.pending {
+		return
+	}
+	w.pending = true
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.after, t.idleTimeout)
+	} else {
+		w.timer.Reset(w.after)
+	}
+}
+
+// idleTimeout calls the idle handler if there have been no writes since the timer started, and otherwise waits for
+// the rest of the pause after the last one. The timer is not reset on every write, which would cost more than the
+// write on a busy terminal.
+func (t *State) idleTimeout() {
+	t.mu.Lock()
+	w := &t.idle
+	if wait := w.after - time.Since(w.lastWrite); wait > 0 {
+		w.timer.Reset(wait)
+		t.mu.Unlock()
+		return
+	}
+	w.pending = false
+	s := t.dumpState()
+	t.mu.Unlock()
+	w.fn(s)
+}
@@ -0,0 +1,61 @@
+package vt10x
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleHandler(t *testing.T) {
+	states := make(chan TerminalState, 10)
+	term := New(WithIdleHandler(20*time.Millisecond, func(s TerminalState) { states <- s }))
+
+	select {
+	case <-states:
+		t.Fatal("expected no snapshot before any write")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Writes closer together than the idle time make one pause, after the last of them.
+	for _, s := range []string{"a", "b", "c"} {
+		writeSeq(t, term, s)
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case s := <-states:
+		if got := string([]rune{s.PrimaryBuffer[0][0].Char, s.PrimaryBuffer[0][1].Char, s.PrimaryBuffer[0][2].Char}); got != "abc" {
+			t.Errorf("expected the snapshot after the last write, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a snapshot once writes paused")
+	}
+	select {
+	case <-states:
+		t.Fatal("expected one snapshot for the pause")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeSeq(t, term, "d")
+	select {
+	case s := <-states:
+		if s.PrimaryBuffer[0][3].Char != 'd' {
+			t.Errorf("expected the second snapshot to show the new write")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a snapshot after the second pause")
+	}
+}
+
+func TestIdleHandlerMayCallTerminal(t *testing.T) {
+	done := make(chan string, 1)
+	var term Terminal
+	term = New(WithIdleHandler(time.Millisecond, func(TerminalState) { done <- term.Title() }))
+	writeSeq(t, term, "\033]2;t\a")
+	select {
+	case title := <-done:
+		if title != "t" {
+			t.Errorf("expected title t, got %q", title)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a snapshot")
+	}
+}
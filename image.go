@@ -0,0 +1,188 @@
+package vt10x
+
+import (
+	"image"
+)
+
+const (
+	// defaultCellWidth and defaultCellHeight are the pixel size assumed for a cell when mapping images onto the grid,
+	// unless overridden with WithCellSize.
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+
+	// maxImageDim bounds an image's width and height in pixels. Sixel pixels drawn past it are discarded and larger
+	// kitty images are rejected.
+	maxImageDim = 2048
+
+	// maxImagePayload bounds the base64 payload accumulated for one kitty or iTerm2 image: enough for an
+	// uncompressed maxImageDim x maxImageDim RGBA image.
+	maxImagePayload = (maxImageDim*maxImageDim*4 + 2) / 3 * 4
+
+	// maxImages and maxImagePixels bound the images retained per screen by default. Once either is exceeded the
+	// oldest images are dropped, so a program streaming graphics cannot exhaust memory. Limits.ImageMemory overrides
+	// maxImagePixels.
+	maxImages      = 64
+	maxImagePixels = 16 << 20
+)
+
+// ImagePlacement is an image drawn over the cell grid, anchored to the cell under its top-left corner. Placements move
+// with the text as the screen scrolls, and are removed once they scroll out of view or the cells under them are
+// erased.
+type ImagePlacement struct {
+	// X and Y are the cell under the image's top-left corner. Y is negative once the top of the image has scrolled
+	// off the screen.
+	X, Y int
+
+	// Cols and Rows are the number of cells the image covers.
+	Cols, Rows int
+
+	// Image holds the decoded pixels. It is shared with the terminal and must not be modified.
+	Image image.Image
+
+	// ID and PlacementID identify kitty graphics images and their placements; both are 0 for sixel images.
+	ID, PlacementID uint32
+
+	// Z is the kitty z-index: placements with a negative Z are drawn below the text, others above it.
+	Z int32
+}
+
+// imageCells returns the number of cells needed to show img at its pixel size.
+func (t *State) imageCells(img image.Image) (cols, rows int) {
+	b := img.Bounds()
+	return (b.Dx() + t.cellWidth - 1) / t.cellWidth, (b.Dy() + t.cellHeight - 1) / t.cellHeight
+}
+
+// addImage adds p to the active screen, dropping the oldest images over the limits, and returns it.
+func (t *State) addImage(p ImagePlacement) ImagePlacement {
+	t.images = append(t.images, p)
+	pixels, limit := 0, t.limits.imagePixels()
+	for i := len(t.images) - 1; i >= 0; i-- {
+		b := t.images[i].Image.Bounds()
+		pixels += b.Dx() * b.Dy()
+		if pixels > limit || len(t.images)-i > maxImages {
+			if pixels > limit {
+				t.truncated(LimitImageMemory)
+			}
+			// Always keep the newest image, however large; the decoders bound a single image's size.
+			n := copy(t.images, t.images[min(i+1, len(t.images)-1):])
+			clearTail(t.images, n)
+			t.images = t.images[:n]
+			break
+		}
+	}
+
+	t.changed |= ChangedScreen
+	for y := max(p.Y, 0); y < min(p.Y+p.Rows, t.rows); y++ {
+		t.markDirty(y)
+	}
+	return p
+}
+
+// moveToImageEnd moves the cursor from the top-left corner of a cols x rows image just past its right edge, on its
+// last row, scrolling as needed.
+func (t *State) moveToImageEnd(cols, rows int) {
+	x := t.cur.X + cols
+	for i := 1; i < rows; i++ {
+		if t.cur.Y == t.bottom {
+			t.scrollUp(t.top, 1, true)
+		} else {
+			t.moveTo(t.cur.X, t.cur.Y+1)
+		}
+	}
+	t.moveTo(x, t.cur.Y)
+}
+
+// scrollImages moves the active screen's images along with a scroll of n rows (up when n > 0, down when n < 0) of the
+// region starting at orig and ending at t.bottom. A whole-screen scroll keeps images until they leave the screen
+// entirely; within a smaller region an image is dropped as soon as part of it leaves the region, and images
+// straddling the region's edges are left in place.
+func (t *State) scrollImages(orig, n int) {
+	if len(t.images) == 0 {
+		return
+	}
+	whole := orig == 0 && t.bottom == t.rows-1
+
+	images := t.images[:0]
+	for _, p := range t.images {
+		if whole {
+			p.Y -= n
+			if p.Y+p.Rows <= 0 || p.Y >= t.rows {
+				continue
+			}
+		} else if p.Y >= orig && p.Y+p.Rows-1 <= t.bottom {
+			p.Y -= n
+			if p.Y < orig || p.Y+p.Rows-1 > t.bottom {
+				continue
+			}
+		}
+		images = append(images, p)
+	}
+	clearTail(t.images, len(images))
+	t.images = images
+}
+
+// clearImages removes the active screen's images whose visible part lies entirely inside the cleared rectangle.
+func (t *State) clearImages(x0, y0, x1, y1 int) {
+	if len(t.images) == 0 {
+		return
+	}
+
+	images := t.images[:0]
+	for _, p := range t.images {
+		top, bottom := max(p.Y, 0), min(p.Y+p.Rows, t.rows)-1
+		left, right := p.X, min(p.X+p.Cols, t.cols)-1
+		if top >= y0 && bottom <= y1 && left >= x0 && right <= x1 {
+			continue
+		}
+		images = append(images, p)
+	}
+	clearTail(t.images, len(images))
+	t.images = images
+}
+
+// resizeImages moves both screens' images up by slide rows and drops those left outside a cols x rows screen.
+func resizeImages(images []ImagePlacement, slide, cols, rows int) []ImagePlacement {
+	kept := images[:0]
+	for _, p := range images {
+		p.Y -= slide
+		if p.Y+p.Rows <= 0 || p.Y >= rows || p.X >= cols {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	clearTail(images, len(kept))
+	return kept
+}
+
+// clearTail zeroes the placements past n so filtered-out images can be collected.
+func clearTail(images []ImagePlacement, n int) {
+	for i := n; i < len(images); i++ {
+		images[i] = ImagePlacement{}
+	}
+}
+
+// removeImages removes the active screen's images for which drop returns true and returns them.
+func (t *State) removeImages(drop func(p ImagePlacement) bool) []ImagePlacement {
+	var removed []ImagePlacement
+	images := t.images[:0]
+	for _, p := range t.images {
+		if drop(p) {
+			removed = append(removed, p)
+			continue
+		}
+		images = append(images, p)
+	}
+	clearTail(t.images, len(images))
+	t.images = images
+	if len(removed) > 0 {
+		t.dirtyAll()
+	}
+	return removed
+}
+
+func copyImages(images []ImagePlacement) []ImagePlacement {
+	if len(images) == 0 {
+		return nil
+	}
+	return append([]ImagePlacement(nil), images...)
+}
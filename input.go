@@ -0,0 +1,65 @@
+package vt10x
+
+// Focus event reports, sent by terminals whose application enabled them with DECSET 1004.
+var (
+	focusIn  = []byte("\033[I")
+	focusOut = []byte("\033[O")
+)
+
+// EncodeFocusIn returns what to send the application when the terminal gains focus, given the terminal's mode: CSI I
+// if the application enabled focus events (DECSET 1004), and nothing otherwise, so embedders can forward every focus
+// change without confusing applications that did not ask for them.
+func EncodeFocusIn(mode ModeFlag) []byte {
+	return encodeFocus(mode, focusIn)
+}
+
+// EncodeFocusOut returns what to send the application when the terminal loses focus, given the terminal's mode: CSI O
+// if the application enabled focus events (DECSET 1004), and nothing otherwise.
+func EncodeFocusOut(mode ModeFlag) []byte {
+	return encodeFocus(mode, focusOut)
+}
+
+func encodeFocus(mode ModeFlag, report []byte) []byte {
+	if mode&ModeFocus == 0 {
+		return nil
+	}
+	return append([]byte(nil), report...)
+}
+
+// WheelAction is what a scroll-wheel event should do, as decided by EncodeWheel.
+type WheelAction int
+
+const (
+	// WheelScroll scrolls the embedder's view of the scrollback; nothing is sent to the application.
+	WheelScroll WheelAction = iota
+
+	// WheelKeys sends the cursor-key sequences EncodeWheel returns to the application, as xterm does for an
+	// application on the alternate screen that enabled alternate scroll mode (DECSET 1007).
+	WheelKeys
+
+	// WheelMouse reports the wheel to the application as mouse buttons 4 and 5, because it enabled mouse tracking.
+	WheelMouse
+)
+
+// EncodeWheel decides what a scroll-wheel event of lines lines, negative to scroll up (back in history) and
+// positive to scroll down, does given the terminal state, following xterm: an application tracking the mouse gets
+// the wheel as mouse events, one on the alternate screen that enabled alternate scroll mode gets one Up or Down key
+// per line, and otherwise the wheel scrolls the scrollback. The keys are returned only for WheelKeys.
+func EncodeWheel(lines int, s TerminalState) ([]byte, WheelAction) {
+	switch {
+	case s.Mode&ModeMouseMask != 0:
+		return nil, WheelMouse
+	case !s.AltScreen || !s.Modes[1007]:
+		return nil, WheelScroll
+	}
+	key := KeyDown
+	if lines < 0 {
+		key, lines = KeyUp, -lines
+	}
+	seq := EncodeKey(key, 0, s)
+	var b []byte
+	for i := 0; i < lines; i++ {
+		b = append(b, seq...)
+	}
+	return b, WheelKeys
+}
@@ -0,0 +1,57 @@
+package vt10x
+
+import "testing"
+
+func TestEncodeFocus(t *testing.T) {
+	term := New()
+	if in, out := EncodeFocusIn(term.Mode()), EncodeFocusOut(term.Mode()); in != nil || out != nil {
+		t.Fatalf("expected nothing sent before DECSET 1004, got %q and %q", in, out)
+	}
+
+	writeSeq(t, term, "\033[?1004h")
+	if in, out := string(EncodeFocusIn(term.Mode())), string(EncodeFocusOut(term.Mode())); in != "\033[I" || out != "\033[O" {
+		t.Fatalf("expected CSI I and CSI O, got %q and %q", in, out)
+	}
+
+	writeSeq(t, term, "\033[?1004l")
+	if in := EncodeFocusIn(term.Mode()); in != nil {
+		t.Fatalf("expected nothing sent after DECRST 1004, got %q", in)
+	}
+
+	writeSeq(t, term, "\033[?1004h\033c")
+	if in := EncodeFocusIn(term.Mode()); in != nil {
+		t.Fatalf("expected a reset to disable focus events, got %q", in)
+	}
+}
+
+func TestEncodeWheel(t *testing.T) {
+	term := New()
+	if b, action := EncodeWheel(-3, term.(MetaDumper).DumpMeta()); action != WheelScroll || b != nil {
+		t.Fatalf("expected the wheel to scroll the scrollback, got %v %q", action, b)
+	}
+
+	// Alternate scroll mode only applies on the alternate screen.
+	writeSeq(t, term, "\033[?1007h")
+	if _, action := EncodeWheel(-3, term.(MetaDumper).DumpMeta()); action != WheelScroll {
+		t.Fatalf("expected the wheel to scroll the scrollback on the primary screen, got %v", action)
+	}
+
+	writeSeq(t, term, "\033[?1049h")
+	if b, action := EncodeWheel(-3, term.(MetaDumper).DumpMeta()); action != WheelKeys || string(b) != "\033[A\033[A\033[A" {
+		t.Fatalf("expected three Up keys, got %v %q", action, b)
+	}
+	writeSeq(t, term, "\033[?1h")
+	if b, _ := EncodeWheel(2, term.(MetaDumper).DumpMeta()); string(b) != "\033OB\033OB" {
+		t.Fatalf("expected two application Down keys, got %q", b)
+	}
+
+	writeSeq(t, term, "\033[?1000h")
+	if b, action := EncodeWheel(1, term.(MetaDumper).DumpMeta()); action != WheelMouse || b != nil {
+		t.Fatalf("expected the wheel reported as mouse events, got %v %q", action, b)
+	}
+
+	writeSeq(t, term, "\033[?1000l\033[?1007l")
+	if _, action := EncodeWheel(1, term.(MetaDumper).DumpMeta()); action != WheelScroll {
+		t.Fatalf("expected DECRST 1007 to stop sending keys, got %v", action)
+	}
+}
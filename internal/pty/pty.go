@@ -0,0 +1,44 @@
+// Package pty opens pseudo-terminals and starts commands on them, for the packages that run programs under the
+// emulator.
+package pty
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// ErrUnsupported is returned on platforms where pseudo-terminals are not supported.
+var ErrUnsupported = errors.New("pty: unsupported platform")
+
+// Start starts cmd on a new pseudo-terminal of the given size, with it as cmd's standard streams and controlling
+// terminal, and returns the pseudo-terminal's master: reads return cmd's output and writes go to its input. Streams
+// cmd already has set are left as they are; if that includes its standard input, cmd gets no controlling terminal.
+func Start(cmd *exec.Cmd, cols, rows int) (*os.File, error) {
+	ptm, pts, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	// The child has its own copy; closing ours lets reads of ptm end once it exits.
+	defer pts.Close()
+
+	if err := Setsize(ptm, cols, rows); err != nil {
+		ptm.Close()
+		return nil, err
+	}
+	if cmd.Stdin == nil {
+		cmd.Stdin = pts
+		setCtty(cmd)
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = pts
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = pts
+	}
+	if err := cmd.Start(); err != nil {
+		ptm.Close()
+		return nil, err
+	}
+	return ptm, nil
+}
@@ -0,0 +1,41 @@
+package pty
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Open returns the master and slave ends of a new pseudo-terminal.
+func Open() (ptm, pts *os.File, err error) {
+	// The master is left in blocking mode, as kqueue does not report pseudo-terminal masters as ready reliably.
+	fd, err := syscall.Open("/dev/ptmx", syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	ptm = os.NewFile(uintptr(fd), "/dev/ptmx")
+	if err := ioctl(ptm, syscall.TIOCPTYGRANT, 0); err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	if err := ioctl(ptm, syscall.TIOCPTYUNLK, 0); err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	var name [128]byte
+	if err := ioctl(ptm, syscall.TIOCPTYGNAME, uintptr(unsafe.Pointer(&name))); err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	if i := bytes.IndexByte(name[:], 0); i >= 0 {
+		pts, err = os.OpenFile(string(name[:i]), os.O_RDWR|syscall.O_NOCTTY, 0)
+	} else {
+		err = syscall.ENAMETOOLONG
+	}
+	if err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	return ptm, pts, nil
+}
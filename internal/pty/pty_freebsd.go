@@ -0,0 +1,29 @@
+package pty
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// Open returns the master and slave ends of a new pseudo-terminal.
+func Open() (ptm, pts *os.File, err error) {
+	// posix_openpt is a system call here, and grantpt and unlockpt have nothing left to do once it returns.
+	fd, _, errno := syscall.Syscall(syscall.SYS_POSIX_OPENPT, syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0, 0)
+	if errno != 0 {
+		return nil, nil, errno
+	}
+	ptm = os.NewFile(fd, "/dev/ptmx")
+	var n uint32
+	if err := ioctl(ptm, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	pts, err = os.OpenFile("/dev/pts/"+strconv.FormatUint(uint64(n), 10), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	return ptm, pts, nil
+}
@@ -0,0 +1,45 @@
+package pty
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ptmget is the argument of TIOCPTSNAME, with room for names of up to PATH_MAX bytes.
+type ptmget struct {
+	cfd, sfd int32
+	cn, sn   [1024]byte
+}
+
+// tiocptsname is TIOCPTSNAME for the current ptmget, which syscall has only in its old, shorter form.
+const tiocptsname = 0x48087448
+
+// Open returns the master and slave ends of a new pseudo-terminal.
+func Open() (ptm, pts *os.File, err error) {
+	ptm, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	// unlockpt does nothing here, so only grantpt is needed.
+	if err := ioctl(ptm, syscall.TIOCGRANTPT, 0); err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	var pm ptmget
+	if err := ioctl(ptm, tiocptsname, uintptr(unsafe.Pointer(&pm))); err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	if i := bytes.IndexByte(pm.sn[:], 0); i >= 0 {
+		pts, err = os.OpenFile(string(pm.sn[:i]), os.O_RDWR|syscall.O_NOCTTY, 0)
+	} else {
+		err = syscall.ENAMETOOLONG
+	}
+	if err != nil {
+		ptm.Close()
+		return nil, nil, err
+	}
+	return ptm, pts, nil
+}
@@ -0,0 +1,42 @@
+package pty
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ptmget is the argument of PTMGET, which returns both ends of a new pseudo-terminal already open.
+type ptmget struct {
+	cfd, sfd int32
+	cn, sn   [16]byte
+}
+
+// ptmGet is PTMGET, _IOR('t', 1, struct ptmget).
+const ptmGet = 0x40287401
+
+// Open returns the master and slave ends of a new pseudo-terminal.
+func Open() (ptm, pts *os.File, err error) {
+	f, err := os.OpenFile("/dev/ptm", os.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var pm ptmget
+	if err := ioctl(f, ptmGet, uintptr(unsafe.Pointer(&pm))); err != nil {
+		return nil, nil, err
+	}
+	ptm = os.NewFile(uintptr(pm.cfd), cstring(pm.cn[:]))
+	pts = os.NewFile(uintptr(pm.sfd), cstring(pm.sn[:]))
+	return ptm, pts, nil
+}
+
+// cstring returns the NUL-terminated string at the start of b.
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,20 @@
+//go:build !(darwin || freebsd || linux || netbsd || openbsd)
+
+package pty
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Open returns ErrUnsupported.
+func Open() (ptm, pts *os.File, err error) {
+	return nil, nil, ErrUnsupported
+}
+
+// Setsize returns ErrUnsupported.
+func Setsize(f *os.File, cols, rows int) error {
+	return ErrUnsupported
+}
+
+func setCtty(cmd *exec.Cmd) {}
@@ -0,0 +1,46 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd
+
+package pty
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// Setsize sets the size of the pseudo-terminal f, a master or slave, which signals SIGWINCH to its foreground
+// process group.
+func Setsize(f *os.File, cols, rows int) error {
+	ws := struct{ row, col, xpix, ypix uint16 }{row: uint16(rows), col: uint16(cols)}
+	return ioctl(f, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws)))
+}
+
+// ioctl runs an ioctl on f without f.Fd, which would put f in blocking mode and so keep Close from interrupting a
+// pending Read.
+func ioctl(f *os.File, req, arg uintptr) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setCtty makes cmd start a new session with its standard input, the pseudo-terminal, as the controlling terminal.
+func setCtty(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+}
@@ -1,5 +1,4 @@
-//go:build plan9 || nacl || windows
-// +build plan9 nacl windows
+//go:build !(linux || darwin || dragonfly || solaris || openbsd || netbsd || freebsd)
 
 package vt10x
 
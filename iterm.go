@@ -0,0 +1,173 @@
+package vt10x
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/gif"  // register decoders for the formats imgcat sends
+	_ "image/jpeg" // register decoders for the formats imgcat sends
+	_ "image/png"  // register decoders for the formats imgcat sends
+	"strconv"
+	"strings"
+)
+
+// inlineImageIntro starts an iTerm2 inline image, OSC 1337 ; File=[args] : <base64 data> ST.
+const inlineImageIntro = "1337;File="
+
+// maxInlineImageArgs bounds the argument list of an inline image.
+const maxInlineImageArgs = 4096
+
+func isInlineImageIntro(buf []rune) bool {
+	return len(buf) == len(inlineImageIntro) && string(buf) == inlineImageIntro
+}
+
+// inlineImageParser collects the arguments and payload of an iTerm2 inline image as they arrive. Payload beyond
+// maxImagePayload is counted but not kept.
+type inlineImageParser struct {
+	args    []byte
+	data    []byte
+	inData  bool
+	dataLen int
+}
+
+func (p *inlineImageParser) put(c rune) {
+	if c >= 0x80 {
+		return
+	}
+	switch {
+	case p.inData:
+		p.dataLen++
+		if len(p.data) < maxImagePayload {
+			p.data = append(p.data, byte(c))
+		}
+	case c == ':':
+		p.inData = true
+	case len(p.args) < maxInlineImageArgs:
+		p.args = append(p.args, byte(c))
+	}
+}
+
+// inlineImageArgs are the ;-separated key=value arguments of an inline image.
+type inlineImageArgs struct {
+	name           string
+	size           int
+	width, height  string
+	preserveAspect bool
+	inline         bool
+}
+
+func parseInlineImageArgs(buf []byte) inlineImageArgs {
+	args := inlineImageArgs{width: "auto", height: "auto", preserveAspect: true}
+	for _, kv := range strings.Split(string(buf), ";") {
+		key, val, _ := strings.Cut(kv, "=")
+		switch key {
+		case "name":
+			if name, err := base64.StdEncoding.DecodeString(val); err == nil {
+				args.name = string(name)
+			}
+		case "size":
+			args.size, _ = strconv.Atoi(val)
+		case "width":
+			args.width = val
+		case "height":
+			args.height = val
+		case "preserveAspectRatio":
+			args.preserveAspect = val != "0"
+		case "inline":
+			args.inline = val == "1"
+		}
+	}
+	return args
+}
+
+// inlineImageDim converts a width or height hint, N cells, Npx pixels, N% of the screen, or auto, to pixels. ok is
+// false for auto or an unparsable hint.
+func inlineImageDim(hint string, cell, screen int) (px int, ok bool) {
+	var n int
+	var err error
+	switch {
+	case hint == "auto" || hint == "":
+		return 0, false
+	case strings.HasSuffix(hint, "px"):
+		n, err = strconv.Atoi(strings.TrimSuffix(hint, "px"))
+	case strings.HasSuffix(hint, "%"):
+		n, err = strconv.Atoi(strings.TrimSuffix(hint, "%"))
+		n = n * screen * cell / 100
+	default:
+		n, err = strconv.Atoi(hint)
+		n *= cell
+	}
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return min(n, maxResizeDim*cell), true
+}
+
+// inlineImageCells works out the cells an image covers from its size hints, scaling an auto dimension, or both
+// dimensions when preserveAspect is set, to keep the image's aspect ratio.
+func (t *State) inlineImageCells(img image.Image, args inlineImageArgs) (cols, rows int) {
+	b := img.Bounds()
+	iw, ih := b.Dx(), b.Dy()
+	w, wok := inlineImageDim(args.width, t.cellWidth, t.cols)
+	h, hok := inlineImageDim(args.height, t.cellHeight, t.rows)
+
+	switch {
+	case !wok && !hok:
+		w, h = iw, ih
+	case !hok:
+		h = ih * w / iw
+	case !wok:
+		w = iw * h / ih
+	case args.preserveAspect:
+		// Fit inside the requested box.
+		if w*ih < h*iw {
+			h = ih * w / iw
+		} else {
+			w = iw * h / ih
+		}
+	}
+	return max((w+t.cellWidth-1)/t.cellWidth, 1), max((h+t.cellHeight-1)/t.cellHeight, 1)
+}
+
+// drawInlineImage places the iTerm2 inline image in the OSC 1337 sequence just ended at the cursor, and moves the
+// cursor past it. Files sent for download rather than display are ignored, and payloads too large to keep are
+// reported to the handler set with WithOversizedImageHandler.
+func (t *State) drawInlineImage() {
+	p := t.inline
+	t.inline = nil
+
+	args := parseInlineImageArgs(p.args)
+	if p.dataLen > len(p.data) {
+		if t.onOversizedImage != nil {
+			size := args.size
+			if size <= 0 {
+				size = p.dataLen / 4 * 3
+			}
+			t.onOversizedImage(args.name, size)
+		}
+		return
+	}
+	if !args.inline {
+		return
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(string(bytes.TrimRight(p.data, "=")))
+	if err != nil {
+		t.warnf("invalid inline image payload")
+		return
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !between(cfg.Width, 1, maxImageDim) || !between(cfg.Height, 1, maxImageDim) {
+		t.warnf("unsupported inline image")
+		return
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.warnf("invalid inline image: %v", err)
+		return
+	}
+
+	cols, rows := t.inlineImageCells(img, args)
+	t.addImage(ImagePlacement{X: t.cur.X, Y: t.cur.Y, Cols: cols, Rows: rows, Image: img})
+	t.moveToImageEnd(cols, rows)
+}
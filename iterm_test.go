@@ -0,0 +1,106 @@
+package vt10x
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// inlineImage returns an OSC 1337 File= sequence carrying a w x h PNG with the given arguments.
+func inlineImage(t *testing.T, w, h int, args string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return "\033]1337;File=" + args + ":" + base64.StdEncoding.EncodeToString(buf.Bytes()) + "\007"
+}
+
+func TestInlineImagePlacement(t *testing.T) {
+	term := New(WithSize(40, 20), WithCellSize(10, 20))
+
+	writeSeq(t, term, "\033[2;3H"+inlineImage(t, 35, 50, "name=dGVzdC5wbmc=;inline=1"))
+	imgs := term.DumpState().Images
+	if len(imgs) != 1 {
+		t.Fatalf("expected one image, got %d", len(imgs))
+	}
+	if p := imgs[0]; p.X != 2 || p.Y != 1 || p.Cols != 4 || p.Rows != 3 {
+		t.Fatalf("unexpected placement %+v", p)
+	}
+	if cur := term.Cursor(); cur.X != 6 || cur.Y != 3 {
+		t.Fatalf("expected cursor at (6,3), got (%d,%d)", cur.X, cur.Y)
+	}
+	if s := strings.TrimSpace(term.String()); s != "" {
+		t.Fatalf("expected no payload on screen, got %q", s)
+	}
+}
+
+func TestInlineImageSizeHints(t *testing.T) {
+	tests := []struct {
+		args       string
+		cols, rows int
+	}{
+		{"width=8;height=2;preserveAspectRatio=0", 8, 2},
+		{"width=8", 8, 2},               // auto height keeps the 2:1 aspect ratio
+		{"height=100px", 20, 5},         // auto width from a pixel height
+		{"width=50%;height=50%", 20, 5}, // aspect ratio preserved inside a 20x10 cell box
+	}
+	for _, tc := range tests {
+		t.Run(tc.args, func(t *testing.T) {
+			term := New(WithSize(40, 20), WithCellSize(10, 20))
+			writeSeq(t, term, inlineImage(t, 400, 200, tc.args+";inline=1"))
+			p := term.DumpState().Images[0]
+			if p.Cols != tc.cols || p.Rows != tc.rows {
+				t.Fatalf("expected %dx%d cells, got %dx%d", tc.cols, tc.rows, p.Cols, p.Rows)
+			}
+		})
+	}
+}
+
+func TestInlineImageDownloadIgnored(t *testing.T) {
+	term := New(WithSize(40, 20))
+
+	writeSeq(t, term, inlineImage(t, 4, 4, "name=Zm9v")+"ok")
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected a download not to be displayed, got %d images", len(imgs))
+	}
+	if s := strings.TrimSpace(term.String()); s != "ok" {
+		t.Fatalf("expected only the trailing text on screen, got %q", s)
+	}
+}
+
+func TestInlineImageOversized(t *testing.T) {
+	var gotName string
+	var gotSize int
+	term := New(WithOversizedImageHandler(func(name string, size int) {
+		gotName, gotSize = name, size
+	}))
+
+	payload := strings.Repeat("A", maxImagePayload+400)
+	writeSeq(t, term, "\033]1337;File=name=YmlnLnBuZw==;size=123456789;inline=1:"+payload+"\033\\ok")
+	if gotName != "big.png" || gotSize != 123456789 {
+		t.Fatalf("expected the handler to get big.png and its declared size, got %q, %d", gotName, gotSize)
+	}
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected the oversized image to be dropped, got %d", len(imgs))
+	}
+	if s := strings.TrimSpace(term.String()); s != "ok" {
+		t.Fatalf("expected the payload not to reach the screen, got %q", s)
+	}
+}
+
+func TestInlineImageOtherCommands(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033]1337;SetMark\007\033]1337;File=inline=1:!!!\007ok")
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected no images, got %d", len(imgs))
+	}
+	if s := extractStr(term, 0, 1, 0); s != "ok" {
+		t.Fatalf("expected text after the sequences, got %q", s)
+	}
+}
@@ -0,0 +1,131 @@
+package vt10x
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// glyphRun is the JSON encoding of consecutive cells of a row with the same attributes, which is far more compact than
+// an object per cell. Text holds one rune per cell.
+type glyphRun struct {
+	Text      string         `json:"text"`
+	FG        Color          `json:"fg"`
+	BG        Color          `json:"bg"`
+	Mode      int16          `json:"mode,omitempty"`
+	Underline UnderlineStyle `json:"underline,omitempty"`
+
+	// UnderlineColor is omitted for DefaultUnderline, by far the most common.
+	UnderlineColor *Color `json:"underlineColor,omitempty"`
+}
+
+// attrs returns the attributes the run's cells share.
+func (r glyphRun) attrs() Glyph {
+	g := Glyph{Mode: r.Mode, Underline: r.Underline, FG: r.FG, BG: r.BG, UnderlineColor: DefaultUnderline}
+	if r.UnderlineColor != nil {
+		g.UnderlineColor = *r.UnderlineColor
+	}
+	return g
+}
+
+// newRun returns a run of the cells of text with the attributes of g.
+func newRun(text string, g Glyph) glyphRun {
+	r := glyphRun{Text: text, FG: g.FG, BG: g.BG, Mode: g.Mode, Underline: g.Underline}
+	if g.UnderlineColor != DefaultUnderline {
+		c := g.UnderlineColor
+		r.UnderlineColor = &c
+	}
+	return r
+}
+
+// encodeRuns run-length encodes the rows of a screen buffer.
+func encodeRuns(rows [][]Glyph) [][]glyphRun {
+	if rows == nil {
+		return nil
+	}
+	out := make([][]glyphRun, len(rows))
+	for y, row := range rows {
+		runs := []glyphRun{}
+		var text []rune
+		for x, g := range row {
+			text = append(text, g.Char)
+			if x+1 < len(row) && sameAttrs(row[x+1], g) {
+				continue
+			}
+			runs = append(runs, newRun(string(text), g))
+			text = text[:0]
+		}
+		out[y] = runs
+	}
+	return out
+}
+
+// sameAttrs reports whether a and b differ only in their character.
+func sameAttrs(a, b Glyph) bool {
+	a.Char = b.Char
+	return a == b
+}
+
+// decodeRuns expands rows encoded by encodeRuns.
+func decodeRuns(rows [][]glyphRun) [][]Glyph {
+	if rows == nil {
+		return nil
+	}
+	out := make([][]Glyph, len(rows))
+	for y, runs := range rows {
+		row := []Glyph{}
+		for _, r := range runs {
+			g := r.attrs()
+			for _, c := range r.Text {
+				g.Char = c
+				row = append(row, g)
+			}
+		}
+		out[y] = row
+	}
+	return out
+}
+
+// jsonState is the JSON encoding of TerminalState: its fields as encoding/json would write them, except that the
+// buffers are run-length encoded and images are PNG.
+type jsonState struct {
+	plainState
+	PrimaryBuffer   [][]glyphRun
+	AlternateBuffer [][]glyphRun
+	Images          []snapshotImage
+}
+
+// plainState is TerminalState without its JSON methods.
+type plainState TerminalState
+
+// MarshalJSON encodes s compactly for shipping to other processes, such as a browser: each buffer row is a list of
+// runs of cells with the same attributes, {"text", "fg", "bg", "mode", ...}, instead of an object per cell, and
+// images are PNG data. UnmarshalJSON reads it back.
+func (s TerminalState) MarshalJSON() ([]byte, error) {
+	images, err := encodeImages(s.Images)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonState{
+		plainState:      plainState(s),
+		PrimaryBuffer:   encodeRuns(s.PrimaryBuffer),
+		AlternateBuffer: encodeRuns(s.AlternateBuffer),
+		Images:          images,
+	})
+}
+
+// UnmarshalJSON decodes a state encoded by MarshalJSON.
+func (s *TerminalState) UnmarshalJSON(data []byte) error {
+	var js jsonState
+	if err := json.Unmarshal(data, &js); err != nil {
+		return fmt.Errorf("vt10x: decoding terminal state: %w", err)
+	}
+	images, err := decodeImages(js.Images)
+	if err != nil {
+		return err
+	}
+	*s = TerminalState(js.plainState)
+	s.PrimaryBuffer = decodeRuns(js.PrimaryBuffer)
+	s.AlternateBuffer = decodeRuns(js.AlternateBuffer)
+	s.Images = images
+	return nil
+}
@@ -0,0 +1,65 @@
+package vt10x
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateJSONRoundTrip(t *testing.T) {
+	now := func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	term := New(WithSize(20, 6), WithCellSize(1, 6), WithLineTimestamps(now))
+	writeSeq(t, term, "\033]0;title\007\033[1;31mred\033[m plain \033[4:3;58;5;2mcurly\033[m\r\nhéllo ✓"+
+		"\033Pq#1~~\033\\\033[?2004h\033[?1049halt")
+	want := term.DumpState()
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got TerminalState
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Images) != len(want.Images) {
+		t.Fatalf("expected %d images, got %d", len(want.Images), len(got.Images))
+	}
+	got.Images, want.Images = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("state differs after round trip:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestStateJSONRuns(t *testing.T) {
+	term := New(WithSize(12, 2))
+	writeSeq(t, term, "\033[31mred\033[m plain")
+
+	data, err := json.Marshal(term.DumpState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		PrimaryBuffer [][]map[string]any
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	row := raw.PrimaryBuffer[0]
+	if len(row) != 2 || row[0]["text"] != "red" || row[0]["fg"] != float64(Red) || row[1]["text"] != " plain   " {
+		t.Fatalf("unexpected runs %v", row)
+	}
+	if len(raw.PrimaryBuffer[1]) != 1 {
+		t.Fatalf("expected a blank row to be one run, got %v", raw.PrimaryBuffer[1])
+	}
+
+	// An object per cell is what the runs replace.
+	naive, err := json.Marshal(plainState(term.DumpState()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data)*2 > len(naive) {
+		t.Fatalf("expected the run encoding to be far smaller than one object per cell: %d vs %d bytes", len(data),
+			len(naive))
+	}
+}
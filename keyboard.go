@@ -0,0 +1,116 @@
+package vt10x
+
+import "fmt"
+
+// KeyboardFlags are the progressive enhancements of the kitty keyboard protocol an application enabled, which decide
+// how EncodeKey encodes keys.
+type KeyboardFlags uint8
+
+// Kitty keyboard protocol flags.
+const (
+	// KeyboardDisambiguate sends keys that are ambiguous in the legacy encoding, such as Escape, Alt+key and Ctrl+key,
+	// as CSI u sequences.
+	KeyboardDisambiguate KeyboardFlags = 1 << iota
+
+	// KeyboardReportEvents reports repeats and releases as well as presses.
+	KeyboardReportEvents
+
+	// KeyboardReportAlternates reports the shifted key along with the key.
+	KeyboardReportAlternates
+
+	// KeyboardReportAllKeys sends every key as an escape sequence, even those that produce text.
+	KeyboardReportAllKeys
+
+	// KeyboardReportText reports the text a key produces along with the key.
+	KeyboardReportText
+
+	keyboardFlagsMask = 1<<iota - 1
+)
+
+// maxKeyboardStack caps the flags an application can push on each screen's stack; pushing more drops the oldest, so
+// a program pushing without popping cannot exhaust memory.
+const maxKeyboardStack = 32
+
+// keyboardStack is a screen's kitty keyboard protocol flags and the flags pushed below them.
+type keyboardStack struct {
+	flags KeyboardFlags
+	saved []KeyboardFlags
+}
+
+// pushKeyboardFlags saves the active screen's flags and replaces them with flags (CSI > flags u).
+func (t *State) pushKeyboardFlags(flags KeyboardFlags) {
+	k := &t.keyboard
+	if len(k.saved) >= maxKeyboardStack {
+		k.saved = append(k.saved[:0], k.saved[1:]...)
+	}
+	k.saved = append(k.saved, k.flags)
+	k.flags = flags & keyboardFlagsMask
+}
+
+// popKeyboardFlags restores the flags saved by the last n pushes (CSI < n u). Popping everything that was pushed, or
+// more, resets the flags.
+func (t *State) popKeyboardFlags(n int) {
+	k := &t.keyboard
+	if n <= 0 {
+		return
+	}
+	if n > len(k.saved) {
+		k.flags, k.saved = 0, k.saved[:0]
+		return
+	}
+	k.flags = k.saved[len(k.saved)-n]
+	k.saved = k.saved[:len(k.saved)-n]
+}
+
+// setKeyboardFlags changes the active screen's flags without saving them (CSI = flags ; mode u): mode 1 replaces
+// them with flags, 2 sets the bits of flags and 3 clears them.
+func (t *State) setKeyboardFlags(flags KeyboardFlags, mode int) {
+	flags &= keyboardFlagsMask
+	switch mode {
+	case 1:
+		t.keyboard.flags = flags
+	case 2:
+		t.keyboard.flags |= flags
+	case 3:
+		t.keyboard.flags &^= flags
+	default:
+		t.warnf("unknown keyboard flags mode %d", mode)
+	}
+}
+
+// reportKeyboardFlags answers a query for the active screen's flags (CSI ? u) with CSI ? flags u.
+func (t *State) reportKeyboardFlags() {
+	t.w.Write([]byte(fmt.Sprintf("\033[?%du", t.keyboard.flags)))
+}
+
+// maxModifyOtherKeys is the highest modifyOtherKeys level; xterm defines 0 to 2.
+const maxModifyOtherKeys = 2
+
+// setModifyKeys sets xterm's key modifier option resource to value (XTMODKEYS, CSI > resource ; value m), or resets it
+// if value is omitted; a value of -1 stands for one omitted. Only modifyOtherKeys, resource 4, changes how keys are encoded here; the others are accepted
+// and ignored.
+func (t *State) setModifyKeys(resource, value int) {
+	switch resource {
+	case -1, 4: // an omitted resource resets them all
+		if value < 0 {
+			value = 0
+		}
+		if value > maxModifyOtherKeys {
+			t.warnf("unsupported modifyOtherKeys level %d", value)
+			return
+		}
+		t.modifyOtherKeys = value
+	case 0, 1, 2, 3, 6, 7:
+	default:
+		t.warnf("unknown key modifier option %d", resource)
+	}
+}
+
+// reportModifyKeys answers XTQMODKEYS (CSI ? resource m) for modifyOtherKeys with CSI > 4 ; level m.
+func (t *State) reportModifyKeys(resource int) {
+	if resource != 4 {
+		t.warnf("unsupported key modifier option query %d", resource)
+		return
+	}
+	t.w.Write([]byte(fmt.Sprintf("\033[>4;%dm", t.modifyOtherKeys)))
+}
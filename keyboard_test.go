@@ -0,0 +1,173 @@
+package vt10x
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestKeyboardFlags(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithWriter(&buf))
+	flags := func() KeyboardFlags {
+		return term.(MetaDumper).DumpMeta().KeyboardFlags
+	}
+
+	writeSeq(t, term, "\033[?u")
+	if buf.String() != "\033[?0u" {
+		t.Fatalf("expected no flags reported at first, got %q", buf.String())
+	}
+
+	writeSeq(t, term, "\033[>1u\033[>11u")
+	if got := flags(); got != KeyboardDisambiguate|KeyboardReportEvents|KeyboardReportAllKeys {
+		t.Fatalf("expected the pushed flags, got %b", got)
+	}
+	writeSeq(t, term, "\033[<u")
+	if got := flags(); got != KeyboardDisambiguate {
+		t.Fatalf("expected the pop to restore the first push, got %b", got)
+	}
+
+	writeSeq(t, term, "\033[=8;2u")
+	if got := flags(); got != KeyboardDisambiguate|KeyboardReportAllKeys {
+		t.Fatalf("expected mode 2 to add flags, got %b", got)
+	}
+	writeSeq(t, term, "\033[=1;3u")
+	if got := flags(); got != KeyboardReportAllKeys {
+		t.Fatalf("expected mode 3 to remove flags, got %b", got)
+	}
+	writeSeq(t, term, "\033[=255u")
+	if got := flags(); got != 31 {
+		t.Fatalf("expected unknown flags dropped, got %b", got)
+	}
+
+	buf.Reset()
+	writeSeq(t, term, "\033[?u")
+	if buf.String() != "\033[?31u" {
+		t.Fatalf("expected the flags reported, got %q", buf.String())
+	}
+
+	writeSeq(t, term, "\033[<5u")
+	if got := flags(); got != 0 {
+		t.Fatalf("expected popping past the bottom to reset the flags, got %b", got)
+	}
+}
+
+func TestKeyboardFlagsPerScreen(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033[>1u\033[?1049h")
+	if got := term.(MetaDumper).DumpMeta().KeyboardFlags; got != 0 {
+		t.Fatalf("expected the alternate screen to have its own flags, got %b", got)
+	}
+	writeSeq(t, term, "\033[>8u\033[?1049l")
+	if got := term.(MetaDumper).DumpMeta().KeyboardFlags; got != KeyboardDisambiguate {
+		t.Fatalf("expected the primary screen's flags back, got %b", got)
+	}
+	writeSeq(t, term, "\033[?1049h")
+	if got := term.(MetaDumper).DumpMeta().KeyboardFlags; got != KeyboardReportAllKeys {
+		t.Fatalf("expected the alternate screen's flags kept, got %b", got)
+	}
+
+	writeSeq(t, term, "\033c")
+	if got := term.(MetaDumper).DumpMeta().KeyboardFlags; got != 0 {
+		t.Fatalf("expected a reset to clear the flags, got %b", got)
+	}
+	writeSeq(t, term, "\033[?1049h")
+	if got := term.(MetaDumper).DumpMeta().KeyboardFlags; got != 0 {
+		t.Fatalf("expected a reset to clear the alternate screen's flags, got %b", got)
+	}
+}
+
+func TestKeyboardStackLimit(t *testing.T) {
+	term := New()
+
+	for i := 0; i < maxKeyboardStack+10; i++ {
+		writeSeq(t, term, "\033[>1u")
+	}
+	writeSeq(t, term, "\033[>2u")
+	if n := len(term.(*terminal).keyboard.saved); n != maxKeyboardStack {
+		t.Fatalf("expected the stack capped at %d, got %d", maxKeyboardStack, n)
+	}
+	writeSeq(t, term, "\033[<u")
+	if got := term.(MetaDumper).DumpMeta().KeyboardFlags; got != KeyboardDisambiguate {
+		t.Fatalf("expected the previous flags, got %b", got)
+	}
+}
+
+func TestMarkedCSINotMistaken(t *testing.T) {
+	term := New()
+
+	// CSI > u pushes flags rather than restoring the cursor, and a marked SGR does not reset the attributes.
+	writeSeq(t, term, "\033[3;3H\033[s\033[1;1H\033[>0u")
+	if c := term.Cursor(); c.X != 0 || c.Y != 0 {
+		t.Fatalf("expected the cursor left alone, got (%d,%d)", c.X, c.Y)
+	}
+	writeSeq(t, term, "\033[1m\033[>4;2mx")
+	if g := term.Cell(0, 0); g.Mode&attrBold == 0 {
+		t.Fatalf("expected the bold attribute kept, got %+v", g)
+	}
+}
+
+func TestKeyboardFlagsSnapshot(t *testing.T) {
+	term := New()
+	writeSeq(t, term, "\033[>5u")
+
+	restored := New(WithState(term.DumpState()))
+	if got := restored.(MetaDumper).DumpMeta().KeyboardFlags; got != 5 {
+		t.Fatalf("expected the flags restored, got %b", got)
+	}
+
+	data, err := term.DumpState().MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s TerminalState
+	if err := s.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	if s.KeyboardFlags != 5 {
+		t.Fatalf("expected the flags to round-trip through protobuf, got %b", s.KeyboardFlags)
+	}
+}
+
+func TestModifyOtherKeys(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithWriter(&buf))
+	level := func() int {
+		return term.(MetaDumper).DumpMeta().ModifyOtherKeys
+	}
+
+	writeSeq(t, term, "\033[?4m")
+	if buf.String() != "\033[>4;0m" {
+		t.Fatalf("expected level 0 reported at first, got %q", buf.String())
+	}
+	if c := term.Cursor(); c.Attr.Mode&attrUnderline != 0 {
+		t.Fatal("expected the query not to be taken for SGR 4")
+	}
+
+	writeSeq(t, term, "\033[>4;2m")
+	if got := level(); got != 2 {
+		t.Fatalf("expected level 2, got %d", got)
+	}
+	buf.Reset()
+	writeSeq(t, term, "\033[?4m")
+	if buf.String() != "\033[>4;2m" {
+		t.Fatalf("expected level 2 reported, got %q", buf.String())
+	}
+
+	writeSeq(t, term, "\033[>4;9m")
+	if got := level(); got != 2 {
+		t.Fatalf("expected an unsupported level ignored, got %d", got)
+	}
+	writeSeq(t, term, "\033[>1;2m\033[>4m")
+	if got := level(); got != 0 {
+		t.Fatalf("expected an omitted value to reset the level, got %d", got)
+	}
+	writeSeq(t, term, "\033[>4;1m\033[>4n")
+	if got := level(); got != 0 {
+		t.Fatalf("expected CSI > 4 n to disable it, got %d", got)
+	}
+	writeSeq(t, term, "\033[>4;1m\033c")
+	if got := level(); got != 0 {
+		t.Fatalf("expected a reset to disable it, got %d", got)
+	}
+}
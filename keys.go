@@ -0,0 +1,297 @@
+package vt10x
+
+import (
+	"strconv"
+	"unicode"
+)
+
+// Key is a key on the keyboard: the character of a key that types one, as it is typed with any Shift applied, or one
+// of the Key constants for the keys that do not. The constants use the code points of the kitty keyboard protocol.
+type Key rune
+
+// Keys that do not type a character.
+const (
+	KeyTab       Key = '\t'
+	KeyEnter     Key = '\r'
+	KeyEscape    Key = '\x1b'
+	KeyBackspace Key = '\x7f'
+
+	KeyInsert   Key = 57348
+	KeyDelete   Key = 57349
+	KeyLeft     Key = 57350
+	KeyRight    Key = 57351
+	KeyUp       Key = 57352
+	KeyDown     Key = 57353
+	KeyPageUp   Key = 57354
+	KeyPageDown Key = 57355
+	KeyHome     Key = 57356
+	KeyEnd      Key = 57357
+
+	KeyF1  Key = 57364
+	KeyF2  Key = 57365
+	KeyF3  Key = 57366
+	KeyF4  Key = 57367
+	KeyF5  Key = 57368
+	KeyF6  Key = 57369
+	KeyF7  Key = 57370
+	KeyF8  Key = 57371
+	KeyF9  Key = 57372
+	KeyF10 Key = 57373
+	KeyF11 Key = 57374
+	KeyF12 Key = 57375
+
+	KeyKP0        Key = 57399
+	KeyKP1        Key = 57400
+	KeyKP2        Key = 57401
+	KeyKP3        Key = 57402
+	KeyKP4        Key = 57403
+	KeyKP5        Key = 57404
+	KeyKP6        Key = 57405
+	KeyKP7        Key = 57406
+	KeyKP8        Key = 57407
+	KeyKP9        Key = 57408
+	KeyKPDecimal  Key = 57409
+	KeyKPDivide   Key = 57410
+	KeyKPMultiply Key = 57411
+	KeyKPSubtract Key = 57412
+	KeyKPAdd      Key = 57413
+	KeyKPEnter    Key = 57414
+	KeyKPEqual    Key = 57415
+)
+
+// KeyMod is a set of modifier keys held down with a key.
+type KeyMod uint8
+
+// Modifier keys, with the bits of the xterm and kitty modifier parameter, which is one more than the set.
+const (
+	ModShift KeyMod = 1 << iota
+	ModAlt
+	ModCtrl
+	ModSuper
+)
+
+// funcKey is how a key that does not type a character is sent: CSI num ; mods final, with num left out when it is 1
+// and there are no modifiers. Keys with a letter for final are sent as SS3 final instead in some modes.
+type funcKey struct {
+	num   int
+	final byte
+}
+
+var funcKeys = map[Key]funcKey{
+	KeyUp:       {1, 'A'},
+	KeyDown:     {1, 'B'},
+	KeyRight:    {1, 'C'},
+	KeyLeft:     {1, 'D'},
+	KeyHome:     {1, 'H'},
+	KeyEnd:      {1, 'F'},
+	KeyInsert:   {2, '~'},
+	KeyDelete:   {3, '~'},
+	KeyPageUp:   {5, '~'},
+	KeyPageDown: {6, '~'},
+	KeyF1:       {1, 'P'},
+	KeyF2:       {1, 'Q'},
+	KeyF3:       {1, 'R'},
+	KeyF4:       {1, 'S'},
+	KeyF5:       {15, '~'},
+	KeyF6:       {17, '~'},
+	KeyF7:       {18, '~'},
+	KeyF8:       {19, '~'},
+	KeyF9:       {20, '~'},
+	KeyF10:      {21, '~'},
+	KeyF11:      {23, '~'},
+	KeyF12:      {24, '~'},
+}
+
+// keypadKey is how a keypad key is sent: its character in numeric keypad mode (DECKPNM), and SS3 final in application
+// keypad mode (DECKPAM).
+type keypadKey struct {
+	char  byte
+	final byte
+}
+
+var keypadKeys = map[Key]keypadKey{
+	KeyKP0:        {'0', 'p'},
+	KeyKP1:        {'1', 'q'},
+	KeyKP2:        {'2', 'r'},
+	KeyKP3:        {'3', 's'},
+	KeyKP4:        {'4', 't'},
+	KeyKP5:        {'5', 'u'},
+	KeyKP6:        {'6', 'v'},
+	KeyKP7:        {'7', 'w'},
+	KeyKP8:        {'8', 'x'},
+	KeyKP9:        {'9', 'y'},
+	KeyKPDecimal:  {'.', 'n'},
+	KeyKPDivide:   {'/', 'o'},
+	KeyKPMultiply: {'*', 'j'},
+	KeyKPSubtract: {'-', 'm'},
+	KeyKPAdd:      {'+', 'k'},
+	KeyKPEnter:    {'\r', 'M'},
+	KeyKPEqual:    {'=', 'X'},
+}
+
+// EncodeKey returns what a terminal in state s, as returned by DumpMeta, sends the application when key is pressed
+// with mods held down. Applications that enabled the kitty keyboard protocol get its CSI u encoding for the keys
+// their KeyboardFlags cover, those that set xterm's modifyOtherKeys get CSI 27 ; mods ; key ~ for the modified keys
+// their level covers, and the others the legacy xterm encoding, so embedders forwarding keystrokes need not track
+// which the application asked for. Cursor keys follow application cursor mode (DECCKM), keypad keys application
+// keypad mode (DECKPAM), and Enter newline mode (LNM). Only presses are encoded; KeyboardReportEvents adds nothing to
+// them.
+func EncodeKey(key Key, mods KeyMod, s TerminalState) []byte {
+	flags := s.KeyboardFlags
+	if fk, ok := funcKeys[key]; ok {
+		return fk.encode(key, mods, s.Mode, flags)
+	}
+	if kk, ok := keypadKeys[key]; ok {
+		if flags&KeyboardReportAllKeys != 0 || flags&KeyboardDisambiguate != 0 && mods&^ModShift != 0 {
+			return encodeCSIu(key, mods, flags)
+		}
+		return kk.encode(mods, s)
+	}
+
+	all := flags&KeyboardReportAllKeys != 0
+	switch key {
+	case KeyEscape:
+		if flags&KeyboardDisambiguate != 0 || all {
+			return encodeCSIu(key, mods, flags)
+		}
+	case KeyEnter, KeyTab, KeyBackspace:
+		if all || flags&KeyboardDisambiguate != 0 && mods != 0 {
+			return encodeCSIu(key, mods, flags)
+		}
+	default:
+		if all || flags&KeyboardDisambiguate != 0 && mods&^ModShift != 0 {
+			return encodeCSIu(key, mods, flags)
+		}
+	}
+	if modifiesOtherKey(key, mods, s.ModifyOtherKeys) {
+		b := strconv.AppendInt([]byte("\033[27;"), int64(mods)+1, 10)
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(key), 10)
+		return append(b, '~')
+	}
+	if key == KeyEnter && mods == 0 && s.Mode&ModeCRLF != 0 {
+		return []byte("\r\n")
+	}
+	b := encodeLegacyKey(key, mods)
+	if key == KeyBackspace && s.Modes[67] {
+		// DECBKM swaps what backspace sends, alone and with Ctrl.
+		if b[len(b)-1] == 0x7f {
+			b[len(b)-1] = '\b'
+		} else {
+			b[len(b)-1] = 0x7f
+		}
+	}
+	return b
+}
+
+// encode sends a keypad key. In application keypad mode modifiers are sent as with function keys, CSI 1 ; mods
+// final; in numeric keypad mode the key is sent as the character it types.
+func (kk keypadKey) encode(mods KeyMod, s TerminalState) []byte {
+	if s.Mode&ModeAppKeypad != 0 {
+		if mods == 0 {
+			return []byte{'\033', 'O', kk.final}
+		}
+		return funcKey{1, kk.final}.encode(0, mods, s.Mode, 0)
+	}
+	if kk.char == '\r' {
+		return EncodeKey(KeyEnter, mods, s)
+	}
+	return EncodeKey(Key(kk.char), mods, s)
+}
+
+// modifiesOtherKey reports whether modifyOtherKeys level has key sent with its modifiers spelled out. Level 1 covers
+// the combinations with Ctrl that the legacy encoding loses, such as Ctrl+Shift+key and Ctrl+1, and level 2 every
+// key with a modifier other than Shift alone.
+func modifiesOtherKey(key Key, mods KeyMod, level int) bool {
+	switch {
+	case level >= 2:
+		return mods&^ModShift != 0
+	case level == 1:
+		return mods&ModCtrl != 0 && (mods&ModShift != 0 || !hasControlChar(key))
+	}
+	return false
+}
+
+// hasControlChar reports whether Ctrl turns key into a control character.
+func hasControlChar(key Key) bool {
+	return key >= 'a' && key <= 'z' || key >= '@' && key <= '_' || key == ' ' || key == '?' || key == KeyBackspace
+}
+
+// encode sends a key that does not type a character. The kitty protocol keeps the legacy encoding of these keys,
+// except that F1 to F4 are never sent as SS3, and F3 is CSI 13 ~ so as not to be mistaken for a cursor position
+// report.
+func (fk funcKey) encode(key Key, mods KeyMod, mode ModeFlag, flags KeyboardFlags) []byte {
+	kitty := flags&(KeyboardDisambiguate|KeyboardReportAllKeys) != 0
+	fkey := key >= KeyF1 && key <= KeyF4
+	if kitty && key == KeyF3 {
+		fk = funcKey{13, '~'}
+	}
+	if mods == 0 && fk.final != '~' {
+		if fkey && !kitty || !fkey && mode&ModeAppCursor != 0 {
+			return []byte{'\033', 'O', fk.final}
+		}
+		return []byte{'\033', '[', fk.final}
+	}
+
+	b := []byte("\033[")
+	if mods != 0 || fk.num != 1 {
+		b = strconv.AppendInt(b, int64(fk.num), 10)
+	}
+	if mods != 0 {
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(mods)+1, 10)
+	}
+	return append(b, fk.final)
+}
+
+// encodeLegacyKey sends a key as xterm does by default: Ctrl turns the key into a control character where there is
+// one, and Alt prefixes it with ESC.
+func encodeLegacyKey(key Key, mods KeyMod) []byte {
+	var b []byte
+	if mods&ModAlt != 0 {
+		b = append(b, '\033')
+	}
+	switch {
+	case key == KeyTab && mods&ModShift != 0:
+		return append(b, "\033[Z"...)
+	case key == KeyBackspace && mods&ModCtrl != 0:
+		return append(b, '\b')
+	case mods&ModCtrl != 0 && hasControlChar(key):
+		switch key {
+		case ' ':
+			return append(b, 0)
+		case '?':
+			return append(b, 0x7f)
+		default:
+			return append(b, byte(key)&0x1f)
+		}
+	}
+	return append(b, string(rune(key))...)
+}
+
+// encodeCSIu sends a key in the kitty protocol's CSI code ; mods ; text u form. The code is that of the key without
+// Shift, followed by the shifted key if flags ask for alternates.
+func encodeCSIu(key Key, mods KeyMod, flags KeyboardFlags) []byte {
+	code := rune(key)
+	if mods&ModShift != 0 {
+		code = unicode.ToLower(code)
+	}
+	b := strconv.AppendInt([]byte("\033["), int64(code), 10)
+	if flags&KeyboardReportAlternates != 0 && code != rune(key) {
+		b = append(b, ':')
+		b = strconv.AppendInt(b, int64(key), 10)
+	}
+
+	// Keys that type a character report it, unless a modifier other than Shift stops them from typing it.
+	text := flags&KeyboardReportAllKeys != 0 && flags&KeyboardReportText != 0 && mods&^ModShift == 0 &&
+		unicode.IsPrint(rune(key))
+	if mods != 0 || text {
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(mods)+1, 10)
+	}
+	if text {
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(key), 10)
+	}
+	return append(b, 'u')
+}
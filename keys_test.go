@@ -0,0 +1,177 @@
+package vt10x
+
+import "testing"
+
+func TestEncodeKey(t *testing.T) {
+	const (
+		disambiguate = KeyboardDisambiguate
+		all          = KeyboardDisambiguate | KeyboardReportAllKeys
+	)
+	tests := []struct {
+		name  string
+		key   Key
+		mods  KeyMod
+		mode  ModeFlag
+		flags KeyboardFlags
+		want  string
+	}{
+		{"text", 'a', 0, 0, 0, "a"},
+		{"shifted text", 'A', ModShift, 0, 0, "A"},
+		{"ctrl letter", 'c', ModCtrl, 0, 0, "\x03"},
+		{"ctrl space", ' ', ModCtrl, 0, 0, "\x00"},
+		{"alt letter", 'x', ModAlt, 0, 0, "\033x"},
+		{"ctrl alt letter", 'x', ModCtrl | ModAlt, 0, 0, "\033\x18"},
+		{"enter", KeyEnter, 0, 0, 0, "\r"},
+		{"shift tab", KeyTab, ModShift, 0, 0, "\033[Z"},
+		{"backspace", KeyBackspace, 0, 0, 0, "\x7f"},
+		{"ctrl backspace", KeyBackspace, ModCtrl, 0, 0, "\b"},
+		{"escape", KeyEscape, 0, 0, 0, "\033"},
+		{"up", KeyUp, 0, 0, 0, "\033[A"},
+		{"up in application cursor mode", KeyUp, 0, ModeAppCursor, 0, "\033OA"},
+		{"ctrl right", KeyRight, ModCtrl, ModeAppCursor, 0, "\033[1;5C"},
+		{"home", KeyHome, 0, 0, 0, "\033[H"},
+		{"delete", KeyDelete, 0, 0, 0, "\033[3~"},
+		{"shift page up", KeyPageUp, ModShift, 0, 0, "\033[5;2~"},
+		{"f1", KeyF1, 0, 0, 0, "\033OP"},
+		{"shift f3", KeyF3, ModShift, 0, 0, "\033[1;2R"},
+		{"f5", KeyF5, 0, 0, 0, "\033[15~"},
+		{"alt f12", KeyF12, ModAlt, 0, 0, "\033[24;3~"},
+
+		{"disambiguated text", 'a', 0, 0, disambiguate, "a"},
+		{"disambiguated shifted text", 'A', ModShift, 0, disambiguate, "A"},
+		{"disambiguated ctrl letter", 'c', ModCtrl, 0, disambiguate, "\033[99;5u"},
+		{"disambiguated alt shifted letter", 'X', ModAlt | ModShift, 0, disambiguate, "\033[120;4u"},
+		{"disambiguated escape", KeyEscape, 0, 0, disambiguate, "\033[27u"},
+		{"disambiguated enter", KeyEnter, 0, 0, disambiguate, "\r"},
+		{"disambiguated ctrl enter", KeyEnter, ModCtrl, 0, disambiguate, "\033[13;5u"},
+		{"disambiguated up", KeyUp, 0, ModeAppCursor, disambiguate, "\033OA"},
+		{"disambiguated f1", KeyF1, 0, 0, disambiguate, "\033[P"},
+		{"disambiguated f3", KeyF3, 0, 0, disambiguate, "\033[13~"},
+		{"disambiguated ctrl f3", KeyF3, ModCtrl, 0, disambiguate, "\033[13;5~"},
+
+		{"all keys text", 'a', 0, 0, all, "\033[97u"},
+		{"all keys enter", KeyEnter, 0, 0, all, "\033[13u"},
+		{"all keys shifted text", 'A', ModShift, 0, all, "\033[97;2u"},
+		{"alternates", 'A', ModShift, 0, all | KeyboardReportAlternates, "\033[97:65;2u"},
+		{"reported text", 'A', ModShift, 0, all | KeyboardReportText, "\033[97;2;65u"},
+		{"text of unmodified key", 'a', 0, 0, all | KeyboardReportText, "\033[97;1;97u"},
+		{"no text with ctrl", 'a', ModCtrl, 0, all | KeyboardReportText, "\033[97;5u"},
+		{"events add nothing to presses", 'c', ModCtrl, 0, disambiguate | KeyboardReportEvents, "\033[99;5u"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := TerminalState{Mode: tc.mode, KeyboardFlags: tc.flags}
+			if got := string(EncodeKey(tc.key, tc.mods, s)); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeKeyFollowsTerminal(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033[?1h\033[>1u")
+	if got := string(EncodeKey(KeyEscape, 0, term.(MetaDumper).DumpMeta())); got != "\033[27u" {
+		t.Fatalf("expected the kitty encoding once the application pushes flags, got %q", got)
+	}
+	if got := string(EncodeKey(KeyDown, 0, term.(MetaDumper).DumpMeta())); got != "\033OB" {
+		t.Fatalf("expected application cursor keys, got %q", got)
+	}
+
+	writeSeq(t, term, "\033[<u")
+	if got := string(EncodeKey(KeyEscape, 0, term.(MetaDumper).DumpMeta())); got != "\033" {
+		t.Fatalf("expected the legacy encoding once the application pops them, got %q", got)
+	}
+}
+
+func TestEncodeKeyModifyOtherKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   Key
+		mods  KeyMod
+		level int
+		want  string
+	}{
+		{"level 1 ctrl letter", 'a', ModCtrl, 1, "\x01"},
+		{"level 1 ctrl shift letter", 'A', ModCtrl | ModShift, 1, "\033[27;6;65~"},
+		{"level 1 ctrl digit", '1', ModCtrl, 1, "\033[27;5;49~"},
+		{"level 1 ctrl enter", KeyEnter, ModCtrl, 1, "\033[27;5;13~"},
+		{"level 1 alt letter", 'a', ModAlt, 1, "\033a"},
+		{"level 2 ctrl letter", 'a', ModCtrl, 2, "\033[27;5;97~"},
+		{"level 2 alt letter", 'a', ModAlt, 2, "\033[27;3;97~"},
+		{"level 2 shifted letter", 'A', ModShift, 2, "A"},
+		{"level 2 function key", KeyUp, ModCtrl, 2, "\033[1;5A"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := TerminalState{ModifyOtherKeys: tc.level}
+			if got := string(EncodeKey(tc.key, tc.mods, s)); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	// The kitty protocol takes precedence.
+	s := TerminalState{ModifyOtherKeys: 2, KeyboardFlags: KeyboardDisambiguate}
+	if got := string(EncodeKey('a', ModCtrl, s)); got != "\033[97;5u" {
+		t.Fatalf("expected the kitty encoding, got %q", got)
+	}
+}
+
+func TestEncodeKeypad(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   Key
+		mods  KeyMod
+		mode  ModeFlag
+		flags KeyboardFlags
+		want  string
+	}{
+		{"numeric digit", KeyKP5, 0, 0, 0, "5"},
+		{"numeric enter", KeyKPEnter, 0, 0, 0, "\r"},
+		{"numeric enter in newline mode", KeyKPEnter, 0, ModeCRLF, 0, "\r\n"},
+		{"numeric ctrl minus", KeyKPSubtract, ModCtrl, 0, 0, "-"},
+		{"application digit", KeyKP0, 0, ModeAppKeypad, 0, "\033Op"},
+		{"application enter", KeyKPEnter, 0, ModeAppKeypad, 0, "\033OM"},
+		{"application equal", KeyKPEqual, 0, ModeAppKeypad, 0, "\033OX"},
+		{"application shift plus", KeyKPAdd, ModShift, ModeAppKeypad, 0, "\033[1;2k"},
+		{"kitty all keys", KeyKP1, 0, ModeAppKeypad, KeyboardReportAllKeys, "\033[57400u"},
+		{"kitty disambiguated ctrl", KeyKP1, ModCtrl, 0, KeyboardDisambiguate, "\033[57400;5u"},
+		{"kitty disambiguated plain", KeyKP1, 0, 0, KeyboardDisambiguate, "1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := TerminalState{Mode: tc.mode, KeyboardFlags: tc.flags}
+			if got := string(EncodeKey(tc.key, tc.mods, s)); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestKeyModesState(t *testing.T) {
+	term := New()
+
+	s := term.(MetaDumper).DumpMeta()
+	if s.AppCursor || s.AppKeypad {
+		t.Fatalf("expected normal cursor keys and keypad at first, got %v and %v", s.AppCursor, s.AppKeypad)
+	}
+	writeSeq(t, term, "\033[?1h\033=")
+	s = term.(MetaDumper).DumpMeta()
+	if !s.AppCursor || !s.AppKeypad {
+		t.Fatalf("expected application cursor keys and keypad, got %v and %v", s.AppCursor, s.AppKeypad)
+	}
+	if got := string(EncodeKey(KeyLeft, 0, s)) + string(EncodeKey(KeyKP9, 0, s)); got != "\033OD\033Oy" {
+		t.Fatalf("expected application sequences, got %q", got)
+	}
+
+	writeSeq(t, term, "\033>")
+	if s = term.(MetaDumper).DumpMeta(); s.AppKeypad {
+		t.Fatal("expected DECKPNM to return the keypad to numeric mode")
+	}
+	writeSeq(t, term, "\033[?1l")
+	if s = term.(MetaDumper).DumpMeta(); s.AppCursor {
+		t.Fatal("expected DECRST 1 to return the cursor keys to normal mode")
+	}
+}
@@ -0,0 +1,452 @@
+package vt10x
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// kittyCommand is a parsed kitty graphics protocol command, ESC _ G <key>=<value>,... ; <payload> ESC \.
+type kittyCommand struct {
+	action      byte // a: t transmit, T transmit and place, p place, d delete, q query
+	format      int  // f: 24 RGB, 32 RGBA, 100 PNG
+	medium      byte // t: only d (direct) is supported
+	compression byte // o: z for zlib
+	delete      byte // d: what a=d deletes
+	more        bool // m=1: further chunks follow
+	quiet       int  // q: 1 suppresses OK replies, 2 errors as well
+
+	id, number, placement uint32 // i, I, p
+	width, height         int    // s, v: pixel size of raw data
+	cols, rows            int    // c, r: cells to display over
+	cropX, cropY          int    // x, y
+	cropW, cropH          int    // w, h
+	cellX, cellY          int    // delete by position: x, y (1-based)
+	noMove                bool   // C=1: leave the cursor in place
+	z                     int32
+
+	payload []byte
+}
+
+// kittyImage is image data transmitted with a=t or a=T, kept for later placements.
+type kittyImage struct {
+	id, number uint32
+	img        image.Image
+}
+
+// subImager is implemented by the standard library's image types, which crop without copying.
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// kittyParser collects the body of an APC G sequence as it arrives.
+type kittyParser struct {
+	buf []byte
+}
+
+func (k *kittyParser) put(c rune) {
+	if c < 0x80 && len(k.buf) < maxImagePayload+4096 {
+		k.buf = append(k.buf, byte(c))
+	}
+}
+
+func parseKittyCommand(buf []byte) (*kittyCommand, error) {
+	cmd := &kittyCommand{action: 't', format: 32, medium: 'd', delete: 'a'}
+	control, payload, _ := bytes.Cut(buf, []byte{';'})
+	cmd.payload = payload
+
+	for _, kv := range bytes.Split(control, []byte{','}) {
+		if len(kv) == 0 {
+			continue
+		}
+		if len(kv) < 3 || kv[1] != '=' {
+			return cmd, fmt.Errorf("EINVAL:malformed key %q", kv)
+		}
+		key, val := kv[0], string(kv[2:])
+		switch key {
+		case 'a', 't', 'o', 'd':
+			if len(val) != 1 {
+				return cmd, fmt.Errorf("EINVAL:bad value for %c", key)
+			}
+			switch key {
+			case 'a':
+				cmd.action = val[0]
+			case 't':
+				cmd.medium = val[0]
+			case 'o':
+				cmd.compression = val[0]
+			case 'd':
+				cmd.delete = val[0]
+			}
+			continue
+		}
+
+		n, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			return cmd, fmt.Errorf("EINVAL:bad value for %c", key)
+		}
+		switch key {
+		case 'f':
+			cmd.format = int(n)
+		case 'm':
+			cmd.more = n == 1
+		case 'q':
+			cmd.quiet = int(n)
+		case 'i':
+			cmd.id = uint32(n)
+		case 'I':
+			cmd.number = uint32(n)
+		case 'p':
+			cmd.placement = uint32(n)
+		case 's':
+			cmd.width = int(n)
+		case 'v':
+			cmd.height = int(n)
+		case 'c':
+			cmd.cols = int(n)
+		case 'r':
+			cmd.rows = int(n)
+		case 'x':
+			cmd.cropX, cmd.cellX = int(n), int(n)
+		case 'y':
+			cmd.cropY, cmd.cellY = int(n), int(n)
+		case 'w':
+			cmd.cropW = int(n)
+		case 'h':
+			cmd.cropH = int(n)
+		case 'C':
+			cmd.noMove = n == 1
+		case 'z':
+			cmd.z = int32(n)
+		}
+	}
+	return cmd, nil
+}
+
+// decode turns the command's accumulated payload into an image.
+func (cmd *kittyCommand) decode() (image.Image, error) {
+	if cmd.medium != 'd' {
+		// Reading files or shared memory named by the program's output is not something an emulator fed untrusted
+		// streams should do.
+		return nil, errors.New("EINVAL:unsupported transmission medium")
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(string(bytes.TrimRight(cmd.payload, "=")))
+	if err != nil {
+		return nil, errors.New("EINVAL:bad base64 payload")
+	}
+	if cmd.compression == 'z' {
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("EINVAL:bad zlib payload")
+		}
+		data, err = io.ReadAll(io.LimitReader(zr, maxImageDim*maxImageDim*4+1))
+		if err != nil {
+			return nil, errors.New("EINVAL:bad zlib payload")
+		}
+	} else if cmd.compression != 0 {
+		return nil, errors.New("EINVAL:unsupported compression")
+	}
+
+	switch cmd.format {
+	case 24, 32:
+		bpp := cmd.format / 8
+		if !between(cmd.width, 1, maxImageDim) || !between(cmd.height, 1, maxImageDim) {
+			return nil, errors.New("EINVAL:bad image size")
+		}
+		if len(data) != cmd.width*cmd.height*bpp {
+			return nil, errors.New("ENODATA:payload does not match image size")
+		}
+		img := image.NewNRGBA(image.Rect(0, 0, cmd.width, cmd.height))
+		for i, j := 0, 0; i < len(data); i, j = i+bpp, j+4 {
+			copy(img.Pix[j:j+3], data[i:i+3])
+			img.Pix[j+3] = 0xff
+			if bpp == 4 {
+				img.Pix[j+3] = data[i+3]
+			}
+		}
+		return img, nil
+	case 100:
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("EBADPNG:bad png data")
+		}
+		if !between(cfg.Width, 1, maxImageDim) || !between(cfg.Height, 1, maxImageDim) {
+			return nil, errors.New("EINVAL:bad image size")
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("EBADPNG:bad png data")
+		}
+		return img, nil
+	default:
+		return nil, errors.New("EINVAL:unsupported format")
+	}
+}
+
+// handleKitty runs the kitty graphics command in the APC sequence just ended.
+func (t *State) handleKitty() {
+	buf := t.kitty.buf
+	t.kitty = nil
+
+	cmd, err := parseKittyCommand(buf)
+	if up := t.kittyUpload; up != nil {
+		// Continuation chunks only carry m (and perhaps q); every other key comes from the first chunk.
+		if err == nil {
+			if len(up.payload)+len(cmd.payload) > maxImagePayload {
+				err = errors.New("EFBIG:image too large")
+			} else {
+				up.payload = append(up.payload, cmd.payload...)
+			}
+			if cmd.more && err == nil {
+				return
+			}
+		}
+		t.kittyUpload = nil
+		up.quiet = max(up.quiet, cmd.quiet)
+		cmd = up
+	} else if err == nil && cmd.more && (cmd.action == 't' || cmd.action == 'T' || cmd.action == 'q') {
+		cmd.payload = append([]byte(nil), cmd.payload...)
+		t.kittyUpload = cmd
+		return
+	}
+
+	if err == nil {
+		err = t.runKitty(cmd)
+	}
+	t.kittyReply(cmd, err)
+}
+
+func (t *State) runKitty(cmd *kittyCommand) error {
+	switch cmd.action {
+	case 't', 'T', 'q':
+		if cmd.id != 0 && cmd.number != 0 {
+			return errors.New("EINVAL:both i and I given")
+		}
+		img, err := cmd.decode()
+		if err != nil || cmd.action == 'q' {
+			return err
+		}
+		if cmd.id == 0 {
+			cmd.id = t.kittyNextID()
+		}
+		t.storeKittyImage(kittyImage{id: cmd.id, number: cmd.number, img: img})
+		if cmd.action == 'T' {
+			return t.placeKitty(cmd)
+		}
+		return nil
+	case 'p':
+		return t.placeKitty(cmd)
+	case 'd':
+		t.deleteKitty(cmd)
+		return nil
+	default:
+		return fmt.Errorf("EINVAL:unknown action %c", cmd.action)
+	}
+}
+
+// kittyNextID picks an unused image id for an image transmitted with only a number, or with neither.
+func (t *State) kittyNextID() uint32 {
+	for {
+		t.kittyID++
+		if t.kittyID == 0 {
+			t.kittyID = 1 << 31
+		}
+		if t.findKittyImage(t.kittyID, 0) == nil {
+			return t.kittyID
+		}
+	}
+}
+
+// findKittyImage returns the stored image with the given id or, if id is 0, the newest image with the given number.
+func (t *State) findKittyImage(id, number uint32) *kittyImage {
+	for i := len(t.kittyImages) - 1; i >= 0; i-- {
+		k := &t.kittyImages[i]
+		if (id != 0 && k.id == id) || (id == 0 && number != 0 && k.number == number) {
+			return k
+		}
+	}
+	return nil
+}
+
+// storeKittyImage stores k, replacing any image with the same id, and evicts the oldest images over the limits.
+func (t *State) storeKittyImage(k kittyImage) {
+	t.freeKittyImages(func(old kittyImage) bool { return old.id == k.id })
+	t.kittyImages = append(t.kittyImages, k)
+
+	pixels, limit := 0, t.limits.imagePixels()
+	for i := len(t.kittyImages) - 1; i >= 0; i-- {
+		b := t.kittyImages[i].img.Bounds()
+		pixels += b.Dx() * b.Dy()
+		if pixels > limit || len(t.kittyImages)-i > maxImages {
+			if pixels > limit {
+				t.truncated(LimitImageMemory)
+			}
+			t.kittyImages = append(t.kittyImages[:0], t.kittyImages[i+1:]...)
+			break
+		}
+	}
+}
+
+func (t *State) freeKittyImages(drop func(k kittyImage) bool) {
+	images := t.kittyImages[:0]
+	for _, k := range t.kittyImages {
+		if !drop(k) {
+			images = append(images, k)
+		}
+	}
+	for i := len(images); i < len(t.kittyImages); i++ {
+		t.kittyImages[i] = kittyImage{}
+	}
+	t.kittyImages = images
+}
+
+// placeKitty displays a stored image at the cursor.
+func (t *State) placeKitty(cmd *kittyCommand) error {
+	k := t.findKittyImage(cmd.id, cmd.number)
+	if k == nil {
+		return errors.New("ENOENT:no such image")
+	}
+	cmd.id = k.id
+
+	img := k.img
+	if cmd.cropX != 0 || cmd.cropY != 0 || cmd.cropW != 0 || cmd.cropH != 0 {
+		b := img.Bounds()
+		r := image.Rect(cmd.cropX, cmd.cropY, b.Max.X, b.Max.Y)
+		if cmd.cropW > 0 {
+			r.Max.X = min(r.Max.X, r.Min.X+cmd.cropW)
+		}
+		if cmd.cropH > 0 {
+			r.Max.Y = min(r.Max.Y, r.Min.Y+cmd.cropH)
+		}
+		if sub, ok := img.(subImager); ok && !r.Intersect(b).Empty() {
+			img = sub.SubImage(r.Intersect(b))
+		}
+	}
+
+	cols, rows := t.imageCells(img)
+	if cmd.cols > 0 {
+		cols = min(cmd.cols, maxResizeDim)
+	}
+	if cmd.rows > 0 {
+		rows = min(cmd.rows, maxResizeDim)
+	}
+
+	if cmd.placement != 0 {
+		t.removeImages(func(p ImagePlacement) bool {
+			return p.ID == cmd.id && p.PlacementID == cmd.placement
+		})
+	}
+	t.addImage(ImagePlacement{
+		X:           t.cur.X,
+		Y:           t.cur.Y,
+		Cols:        cols,
+		Rows:        rows,
+		Image:       img,
+		ID:          k.id,
+		PlacementID: cmd.placement,
+		Z:           cmd.z,
+	})
+
+	if !cmd.noMove {
+		t.moveToImageEnd(cols, rows)
+	}
+	return nil
+}
+
+// deleteKitty removes the placements selected by the command's d key. Upper-case selectors also free the image data
+// of the deleted placements, and of images selected by id or number, once nothing else shows them.
+func (t *State) deleteKitty(cmd *kittyCommand) {
+	covers := func(p ImagePlacement, x, y int) bool {
+		return x >= p.X && x < p.X+p.Cols && y >= p.Y && y < p.Y+p.Rows
+	}
+
+	var drop func(p ImagePlacement) bool
+	switch cmd.delete | 0x20 { // lower case
+	case 'a':
+		drop = func(ImagePlacement) bool { return true }
+	case 'i', 'n':
+		id := cmd.id
+		if cmd.delete|0x20 == 'n' {
+			id = 0
+			if k := t.findKittyImage(0, cmd.number); k != nil {
+				id = k.id
+			}
+		}
+		drop = func(p ImagePlacement) bool {
+			return p.ID != 0 && p.ID == id && (cmd.placement == 0 || p.PlacementID == cmd.placement)
+		}
+		if cmd.delete == 'I' || cmd.delete == 'N' {
+			defer t.freeKittyImages(func(k kittyImage) bool { return k.id == id && !t.kittyImageShown(id) })
+		}
+	case 'c':
+		x, y := t.cur.X, t.cur.Y
+		drop = func(p ImagePlacement) bool { return covers(p, x, y) }
+	case 'p':
+		x, y := cmd.cellX-1, cmd.cellY-1
+		drop = func(p ImagePlacement) bool { return covers(p, x, y) }
+	case 'x':
+		x := cmd.cellX - 1
+		drop = func(p ImagePlacement) bool { return x >= p.X && x < p.X+p.Cols }
+	case 'y':
+		y := cmd.cellY - 1
+		drop = func(p ImagePlacement) bool { return y >= p.Y && y < p.Y+p.Rows }
+	case 'z':
+		drop = func(p ImagePlacement) bool { return p.Z == cmd.z }
+	default:
+		return
+	}
+
+	removed := t.removeImages(func(p ImagePlacement) bool { return p.ID != 0 && drop(p) })
+	if cmd.delete >= 'A' && cmd.delete <= 'Z' {
+		for _, p := range removed {
+			id := p.ID
+			t.freeKittyImages(func(k kittyImage) bool { return k.id == id && !t.kittyImageShown(id) })
+		}
+	}
+}
+
+// kittyImageShown reports whether either screen still has a placement of image id.
+func (t *State) kittyImageShown(id uint32) bool {
+	for _, images := range [][]ImagePlacement{t.images, t.altImages} {
+		for _, p := range images {
+			if p.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// kittyReply answers a command through the Responder: OK or the error, as the q key allows. Commands that named
+// neither an image id nor a number get no reply, as in kitty.
+func (t *State) kittyReply(cmd *kittyCommand, err error) {
+	if cmd.id == 0 && cmd.number == 0 {
+		return
+	}
+	if (err == nil && cmd.quiet >= 1) || (err != nil && cmd.quiet >= 2) {
+		return
+	}
+	if err == nil && cmd.action == 'd' {
+		return
+	}
+
+	msg := "OK"
+	if err != nil {
+		msg = err.Error()
+	}
+	keys := "i=" + strconv.FormatUint(uint64(cmd.id), 10)
+	if cmd.number != 0 {
+		keys += ",I=" + strconv.FormatUint(uint64(cmd.number), 10)
+	}
+	if cmd.placement != 0 {
+		keys += ",p=" + strconv.FormatUint(uint64(cmd.placement), 10)
+	}
+	t.w.Write([]byte("\033_G" + keys + ";" + msg + "\033\\"))
+}
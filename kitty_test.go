@@ -0,0 +1,184 @@
+package vt10x
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// kittyRGBA returns the base64 payload of a w x h RGBA image filled with c.
+func kittyRGBA(w, h int, c color.NRGBA) string {
+	data := bytes.Repeat([]byte{c.R, c.G, c.B, c.A}, w*h)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestKittyTransmitAndPlace(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithSize(20, 10), WithCellSize(2, 4), WithWriter(&buf))
+
+	writeSeq(t, term, "\033[3;4H\033_Ga=T,f=32,s=4,v=8,i=7;"+kittyRGBA(4, 8, color.NRGBA{G: 0xff, A: 0xff})+"\033\\")
+
+	if got, want := buf.String(), "\033_Gi=7;OK\033\\"; got != want {
+		t.Fatalf("expected reply %q, got %q", want, got)
+	}
+	imgs := term.DumpState().Images
+	if len(imgs) != 1 {
+		t.Fatalf("expected one placement, got %d", len(imgs))
+	}
+	p := imgs[0]
+	if p.ID != 7 || p.X != 3 || p.Y != 2 || p.Cols != 2 || p.Rows != 2 {
+		t.Fatalf("unexpected placement %+v", p)
+	}
+	if c := color.NRGBAModel.Convert(p.Image.At(3, 7)); c != (color.NRGBA{G: 0xff, A: 0xff}) {
+		t.Fatalf("expected green pixels, got %+v", c)
+	}
+
+	// The cursor moves past the image's right edge on its last row.
+	if cur := term.Cursor(); cur.X != 5 || cur.Y != 3 {
+		t.Fatalf("expected cursor at (5,3), got (%d,%d)", cur.X, cur.Y)
+	}
+}
+
+func TestKittyChunkedPNG(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithSize(20, 10), WithWriter(&buf))
+
+	img := image.NewNRGBA(image.Rect(0, 0, 30, 10))
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, img); err != nil {
+		t.Fatal(err)
+	}
+	payload := base64.StdEncoding.EncodeToString(pngBuf.Bytes())
+
+	// Transmit in 16-byte chunks, then place separately with a placement id and C=1.
+	const chunk = 16
+	for off := 0; off < len(payload); off += chunk {
+		end := min(off+chunk, len(payload))
+		more := "1"
+		if end == len(payload) {
+			more = "0"
+		}
+		keys := "m=" + more
+		if off == 0 {
+			keys = "a=t,f=100,i=3,q=1," + keys
+		}
+		writeSeq(t, term, "\033_G"+keys+";"+payload[off:end]+"\033\\")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected q=1 to suppress the OK reply, got %q", buf.String())
+	}
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected a=t to store without placing, got %d placements", len(imgs))
+	}
+
+	writeSeq(t, term, "\033_Ga=p,i=3,p=9,C=1\033\\")
+	writeSeq(t, term, "\033_Ga=p,i=3,p=9,C=1,z=-1\033\\")
+	imgs := term.DumpState().Images
+	if len(imgs) != 1 || imgs[0].PlacementID != 9 || imgs[0].Z != -1 {
+		t.Fatalf("expected the second placement to replace the first, got %+v", imgs)
+	}
+	if b := imgs[0].Image.Bounds(); b.Dx() != 30 || b.Dy() != 10 {
+		t.Fatalf("expected a 30x10 image, got %v", b)
+	}
+	if cur := term.Cursor(); cur.X != 0 || cur.Y != 0 {
+		t.Fatalf("expected C=1 to keep the cursor in place, got (%d,%d)", cur.X, cur.Y)
+	}
+	if got, want := buf.String(), strings.Repeat("\033_Gi=3,p=9;OK\033\\", 2); got != want {
+		t.Fatalf("expected replies %q, got %q", want, got)
+	}
+}
+
+func TestKittyCompressedRGB(t *testing.T) {
+	term := New(WithSize(20, 10))
+
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(bytes.Repeat([]byte{0xff, 0, 0}, 4))
+	zw.Close()
+
+	writeSeq(t, term, "\033_Ga=T,f=24,o=z,s=2,v=2;"+base64.StdEncoding.EncodeToString(z.Bytes())+"\033\\")
+	imgs := term.DumpState().Images
+	if len(imgs) != 1 {
+		t.Fatalf("expected one placement, got %d", len(imgs))
+	}
+	if c := color.NRGBAModel.Convert(imgs[0].Image.At(1, 1)); c != (color.NRGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("expected red pixels, got %+v", c)
+	}
+}
+
+func TestKittyQueryAndErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want string
+	}{
+		{"query", "\033_Ga=q,i=31,s=1,v=1;" + kittyRGBA(1, 1, color.NRGBA{}) + "\033\\", "\033_Gi=31;OK\033\\"},
+		{"size mismatch", "\033_Ga=t,i=2,s=2,v=2;" + kittyRGBA(1, 1, color.NRGBA{}) + "\033\\", "\033_Gi=2;ENODATA:payload does not match image size\033\\"},
+		{"file medium", "\033_Ga=t,t=f,i=4;L2V0Yy9wYXNzd2Q=\033\\", "\033_Gi=4;EINVAL:unsupported transmission medium\033\\"},
+		{"missing image", "\033_Ga=p,i=99\033\\", "\033_Gi=99;ENOENT:no such image\033\\"},
+		{"quiet errors", "\033_Ga=p,i=99,q=2\033\\", ""},
+		{"no id", "\033_Ga=p\033\\", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			term := New(WithWriter(&buf))
+			writeSeq(t, term, tc.seq)
+			if buf.String() != tc.want {
+				t.Fatalf("expected reply %q, got %q", tc.want, buf.String())
+			}
+			if imgs := term.DumpState().Images; len(imgs) != 0 {
+				t.Fatalf("expected nothing placed, got %d", len(imgs))
+			}
+		})
+	}
+}
+
+func TestKittyDelete(t *testing.T) {
+	term := New(WithSize(20, 10), WithCellSize(1, 1))
+	pixel := kittyRGBA(1, 1, color.NRGBA{A: 0xff})
+
+	writeSeq(t, term, "\033_Ga=t,i=1,s=1,v=1;"+pixel+"\033\\")
+	writeSeq(t, term, "\033[1;1H\033_Ga=p,i=1,p=1\033\\\033[5;5H\033_Ga=p,i=1,p=2\033\\")
+	writeSeq(t, term, "\033_Ga=T,i=2,s=1,v=1,z=5;"+pixel+"\033\\")
+
+	writeSeq(t, term, "\033_Ga=d,d=p,x=5,y=5\033\\")
+	if imgs := term.DumpState().Images; len(imgs) != 2 {
+		t.Fatalf("expected delete by cell to remove one placement, got %d left", len(imgs))
+	}
+
+	writeSeq(t, term, "\033_Ga=d,d=z,z=5\033\\")
+	if imgs := term.DumpState().Images; len(imgs) != 1 || imgs[0].ID != 1 {
+		t.Fatalf("expected delete by z-index to leave image 1, got %+v", imgs)
+	}
+
+	writeSeq(t, term, "\033_Ga=d,d=I,i=1\033\\")
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected delete by id to remove the placement, got %d", len(imgs))
+	}
+	var buf bytes.Buffer
+	s := term.(*terminal).State
+	s.w = &buf
+	writeSeq(t, term, "\033_Ga=p,i=1\033\\")
+	if !strings.Contains(buf.String(), "ENOENT") {
+		t.Fatalf("expected d=I to free the image data, got reply %q", buf.String())
+	}
+}
+
+func TestKittyIgnoresSixelAndText(t *testing.T) {
+	term := New(WithSize(20, 5))
+
+	// Sixel placements are not kitty placements, and a non-graphics APC is ignored.
+	writeSeq(t, term, "\033Pq~\033\\\033_Ga=d\033\\\033_other\033\\ok")
+	if imgs := term.DumpState().Images; len(imgs) != 1 {
+		t.Fatalf("expected the sixel image to survive a kitty delete, got %d", len(imgs))
+	}
+	if s := strings.TrimSpace(term.String()); s != "ok" {
+		t.Fatalf("expected only text on screen, got %q", s)
+	}
+}
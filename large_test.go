@@ -0,0 +1,118 @@
+package vt10x
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestLargeResizeExtendsTabStops ensures widening a terminal gives the new columns default tab stops, rather than only
+// the columns that existed before the resize.
+func TestLargeResizeExtendsTabStops(t *testing.T) {
+	term := New(WithSize(80, 24))
+	term.Resize(2000, 24)
+
+	stops := term.DumpState().TabStops
+	if len(stops) != 249 {
+		t.Fatalf("expected 249 tab stops, got %d", len(stops))
+	}
+	for i, stop := range stops {
+		if want := (i + 1) * tabspaces; stop != want {
+			t.Fatalf("tab stop %d: expected column %d, got %d", i, want, stop)
+		}
+	}
+
+	writeSeq(t, term, "\033[1;1H"+strings.Repeat("\t", 200))
+	if cur := term.Cursor(); cur.X != 1600 {
+		t.Fatalf("expected 200 tabs to reach column 1600, got %d", cur.X)
+	}
+}
+
+func TestLargeWriteFarColumns(t *testing.T) {
+	term := New(WithSize(maxResizeDim, 8))
+
+	writeSeq(t, term, fmt.Sprintf("\033[3;%dHxyz", maxResizeDim-2))
+	if s := extractStr(term, maxResizeDim-3, maxResizeDim-1, 2); s != "xyz" {
+		t.Fatalf("expected xyz at the right margin, got %q", s)
+	}
+
+	// The next printable wraps onto the following row.
+	writeSeq(t, term, "!")
+	if s := extractStr(term, 0, 0, 3); s != "!" {
+		t.Fatalf("expected wrapped character on row 3, got %q", s)
+	}
+}
+
+func TestLargeScrollTallTerminal(t *testing.T) {
+	const rows = maxResizeDim
+	term := New(WithSize(16, rows), WithScrollbackCapture(rows))
+
+	for i := 0; i < rows+100; i++ {
+		writeSeq(t, term, fmt.Sprintf("line%d\r\n", i))
+	}
+
+	if s := strings.TrimSpace(extractStr(term, 0, 15, rows-2)); s != fmt.Sprintf("line%d", rows+99) {
+		t.Fatalf("expected the last line above the cursor, got %q", s)
+	}
+
+	lines, dropped := term.TakeScrollback()
+	if len(lines) != 101 || dropped != 0 {
+		t.Fatalf("expected 101 scrolled lines and 0 dropped, got %d and %d", len(lines), dropped)
+	}
+}
+
+// TestLargeResizeAllocs ensures resizing a blank terminal does not allocate per row.
+func TestLargeResizeAllocs(t *testing.T) {
+	s := New(WithSize(100, 1000)).(*terminal).State
+
+	wide := true
+	allocs := testing.AllocsPerRun(10, func() {
+		if wide {
+			s.resize(200, 1000)
+		} else {
+			s.resize(100, 1000)
+		}
+		wide = !wide
+	})
+	if allocs > 10 {
+		t.Fatalf("expected a constant number of allocations per resize, got %v", allocs)
+	}
+}
+
+func BenchmarkLargeWrite(b *testing.B) {
+	data := loadBenchFixture(b, "sgr")
+	term := New(WithSize(maxResizeDim, 512))
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := term.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLargeResize(b *testing.B) {
+	term := New(WithSize(1000, 500))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%2 == 0 {
+			term.Resize(maxResizeDim, 1000)
+		} else {
+			term.Resize(1000, 500)
+		}
+	}
+}
+
+func BenchmarkLargeDumpState(b *testing.B) {
+	term := New(WithSize(maxResizeDim, 512))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = term.DumpState()
+	}
+}
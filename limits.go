@@ -0,0 +1,75 @@
+package vt10x
+
+const (
+	// defaultStringLength is the default for Limits.StringLength, long enough for any title, color or query
+	// sequence in practice.
+	defaultStringLength = 256
+
+	// defaultImageMemory is the default for Limits.ImageMemory.
+	defaultImageMemory = maxImagePixels * 4
+)
+
+// Limits caps the memory the terminal spends on what a program sends it, so a malicious or buggy program can't
+// balloon it, for instance by streaming an unterminated OSC. A zero field keeps its default.
+type Limits struct {
+	// StringLength caps the runes of an OSC, DCS, APC or PM string kept for handling; the rest of the string is
+	// discarded. Image payloads are bounded separately. The default is 256.
+	StringLength int
+
+	// TitleLength caps the runes of the window title. By default the title is bounded only by StringLength.
+	TitleLength int
+
+	// ImageMemory caps the bytes of decoded pixels, counting 4 per pixel, retained for the images placed on each
+	// screen and for the kitty images stored for later display. Once it is exceeded the oldest images are dropped.
+	// The default is 64 MiB.
+	ImageMemory int
+}
+
+// Limit identifies one of the Limits when reporting a truncation.
+type Limit int
+
+const (
+	// LimitString reports an OSC, DCS, APC or PM string cut short at Limits.StringLength.
+	LimitString Limit = iota
+
+	// LimitTitle reports a title cut short at Limits.TitleLength.
+	LimitTitle
+
+	// LimitImageMemory reports images dropped to stay within Limits.ImageMemory.
+	LimitImageMemory
+)
+
+func (l Limit) String() string {
+	switch l {
+	case LimitString:
+		return "string length"
+	case LimitTitle:
+		return "title length"
+	case LimitImageMemory:
+		return "image memory"
+	}
+	return "unknown limit"
+}
+
+func (l Limits) stringLength() int {
+	if l.StringLength > 0 {
+		return l.StringLength
+	}
+	return defaultStringLength
+}
+
+// imagePixels returns the number of pixels ImageMemory leaves room for.
+func (l Limits) imagePixels() int {
+	if l.ImageMemory > 0 {
+		return l.ImageMemory / 4
+	}
+	return defaultImageMemory / 4
+}
+
+// truncated reports that input was cut short or discarded to stay within limit.
+func (t *State) truncated(limit Limit) {
+	t.warnf("truncated to stay within the %s limit", limit)
+	if t.onTruncate != nil {
+		t.onTruncate(limit)
+	}
+}
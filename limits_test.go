@@ -0,0 +1,57 @@
+package vt10x
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringLimit(t *testing.T) {
+	var got []Limit
+	term := New(WithSize(20, 4), WithTruncationHandler(func(l Limit) { got = append(got, l) }))
+	writeSeq(t, term, "\033]0;"+strings.Repeat("a", 1000)+"\007")
+	if n := len(term.Title()); n != defaultStringLength-2 {
+		t.Fatalf("expected the title cut at the default string length, got %d runes", n)
+	}
+	if !reflect.DeepEqual(got, []Limit{LimitString}) {
+		t.Fatalf("expected one string truncation, got %v", got)
+	}
+
+	term = New(WithSize(20, 4), WithLimits(Limits{StringLength: 2000}))
+	writeSeq(t, term, "\033]0;"+strings.Repeat("a", 1000)+"\007")
+	if n := len(term.Title()); n != 1000 {
+		t.Fatalf("expected the raised limit to keep the whole title, got %d runes", n)
+	}
+}
+
+func TestTitleLimit(t *testing.T) {
+	var got []Limit
+	term := New(WithSize(20, 4), WithLimits(Limits{TitleLength: 5}),
+		WithTruncationHandler(func(l Limit) { got = append(got, l) }))
+	writeSeq(t, term, "\033]2;héllo world\007")
+	if title := term.Title(); title != "héllo" {
+		t.Fatalf("expected the title cut to 5 runes, got %q", title)
+	}
+	writeSeq(t, term, "\033]2;short\007")
+	if !reflect.DeepEqual(got, []Limit{LimitTitle}) {
+		t.Fatalf("expected one title truncation, got %v", got)
+	}
+}
+
+func TestImageMemoryLimit(t *testing.T) {
+	var got []Limit
+	// Each image is 2x6 pixels, or 48 bytes, so two fit.
+	term := New(WithSize(20, 6), WithCellSize(1, 6), WithLimits(Limits{ImageMemory: 100}),
+		WithTruncationHandler(func(l Limit) { got = append(got, l) }))
+	writeSeq(t, term, "\033Pq#1~~\033\\\033Pq#2~~\033\\")
+	if n := len(term.DumpState().Images); n != 2 || len(got) != 0 {
+		t.Fatalf("expected both images kept, got %d and truncations %v", n, got)
+	}
+	writeSeq(t, term, "\033Pq#3~~\033\\")
+	if n := len(term.DumpState().Images); n != 2 {
+		t.Fatalf("expected the oldest image dropped, got %d images", n)
+	}
+	if !reflect.DeepEqual(got, []Limit{LimitImageMemory}) {
+		t.Fatalf("expected one image memory truncation, got %v", got)
+	}
+}
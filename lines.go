@@ -0,0 +1,161 @@
+package vt10x
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// LogicalLine is a line of text as a program wrote it: the rows it wrapped across at the right margin joined back
+// together, without the blanks after the end of its last row. Rows it continues on because of a line feed, or after
+// the cursor moved, are separate lines.
+type LogicalLine struct {
+	Text string
+
+	// Y is the row the line starts on, and Rows the number of rows it spans. Rows in the scrollback are numbered as
+	// Find numbers them, from -1, the row just above the screen, upwards.
+	Y, Rows int
+
+	// starts holds the cells of the line in order, each with the offset in Text of its first byte.
+	starts []cellStart
+}
+
+// cellStart is a cell of a LogicalLine and where its text starts.
+type cellStart struct {
+	offset, x, y int
+}
+
+// Position returns the cell holding byte offset of Text. Offsets past the end of Text give the cell after the last,
+// which may be past the right margin.
+func (l LogicalLine) Position(offset int) (x, y int) {
+	if len(l.starts) == 0 {
+		return 0, l.Y
+	}
+	if offset >= len(l.Text) {
+		last := l.starts[len(l.starts)-1]
+		return last.x + 1, last.y
+	}
+	i := sort.Search(len(l.starts), func(i int) bool { return l.starts[i].offset > offset }) - 1
+	return l.starts[max(i, 0)].x, l.starts[max(i, 0)].y
+}
+
+// Offset returns the offset in Text of the first byte of cell (x, y), or -1 if the line does not cover the cell. A
+// cell after the end of the text on the line's last row gives len(Text).
+func (l LogicalLine) Offset(x, y int) int {
+	if y < l.Y || y >= l.Y+l.Rows {
+		return -1
+	}
+	i := sort.Search(len(l.starts), func(i int) bool {
+		s := l.starts[i]
+		return s.y > y || s.y == y && s.x >= x
+	})
+	if i < len(l.starts) && l.starts[i].x == x && l.starts[i].y == y {
+		return l.starts[i].offset
+	}
+	if x >= 0 && y == l.Y+l.Rows-1 {
+		return len(l.Text)
+	}
+	return -1
+}
+
+// lineBuilder joins rows into LogicalLines.
+type lineBuilder struct {
+	lines  []LogicalLine
+	cur    LogicalLine
+	text   []byte
+	starts []cellStart
+}
+
+// spacerRune stands in the chars given to add for the second half of a wide character, which adds no text.
+const spacerRune = -1
+
+// add adds row y, whose cells hold chars, and which continues on the next row if wrapped is set.
+func (b *lineBuilder) add(y int, chars []rune, wrapped bool) {
+	if b.cur.Rows == 0 {
+		b.cur.Y = y
+	}
+	b.cur.Rows++
+
+	// The blanks at the end of a row that did not wrap are not part of the text.
+	end := len(chars)
+	if !wrapped {
+		for end > 0 && (chars[end-1] == ' ' || chars[end-1] == 0) {
+			end--
+		}
+	}
+	for x, c := range chars[:end] {
+		if c == spacerRune {
+			continue
+		}
+		if c == 0 {
+			c = ' '
+		}
+		b.starts = append(b.starts, cellStart{offset: len(b.text), x: x, y: y})
+		b.text = utf8.AppendRune(b.text, c)
+	}
+	if !wrapped {
+		b.flush()
+	}
+}
+
+// flush ends the line being built.
+func (b *lineBuilder) flush() {
+	if b.cur.Rows == 0 {
+		return
+	}
+	b.cur.Text, b.cur.starts = string(b.text), b.starts
+	b.lines = append(b.lines, b.cur)
+	b.cur, b.text, b.starts = LogicalLine{}, b.text[:0], nil
+}
+
+// LogicalLines returns the lines of rows, such as a buffer of TerminalState, joining rows that wrapped, as marked by
+// IsWrap on their last cell. Rows are numbered from 0.
+func LogicalLines(rows [][]Glyph) []LogicalLine {
+	var (
+		b     lineBuilder
+		chars []rune
+	)
+	for y, row := range rows {
+		chars = chars[:0]
+		for _, g := range row {
+			if isSpacerGlyph(g) {
+				chars = append(chars, spacerRune)
+				continue
+			}
+			chars = append(chars, g.Char)
+		}
+		b.add(y, chars, len(row) > 0 && IsWrap(row[len(row)-1].Mode))
+	}
+	b.flush()
+	return b.lines
+}
+
+// LogicalLines returns the lines on the screen, and with scrollback those in the scrollback not yet taken before
+// them, joining rows that wrapped. A line that wrapped from the scrollback onto the primary screen is one line.
+func (t *State) LogicalLines(scrollback bool) []LogicalLine {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b lineBuilder
+	if scrollback {
+		for i, l := range t.scrollback {
+			b.add(i-len(t.scrollback), l.Text, l.Wrapped)
+		}
+		if t.mode&ModeAltScreen != 0 {
+			b.flush()
+		}
+	}
+	var chars []rune
+	for y, row := range t.lines {
+		chars = chars[:0]
+		for x := range row {
+			if isWideSpacer(row[x]) {
+				chars = append(chars, spacerRune)
+				continue
+			}
+			chars = append(chars, row[x].char)
+		}
+		b.add(y, chars, len(row) > 0 && row[len(row)-1].attrs&attrWrap != 0)
+	}
+	b.flush()
+	return b.lines
+}
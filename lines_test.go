@@ -0,0 +1,69 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+// lineTexts returns the text, first row and row count of each line.
+func lineTexts(lines []LogicalLine) [][3]any {
+	got := make([][3]any, len(lines))
+	for i, l := range lines {
+		got[i] = [3]any{l.Text, l.Y, l.Rows}
+	}
+	return got
+}
+
+func TestLogicalLines(t *testing.T) {
+	term := New(WithSize(5, 6))
+	writeSeq(t, term, "hello world\r\nab  \r\n\r\ncé")
+
+	want := [][3]any{{"hello world", 0, 3}, {"ab", 3, 1}, {"", 4, 1}, {"cé", 5, 1}}
+	lines := term.(LineJoiner).LogicalLines(false)
+	if got := lineTexts(lines); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := lineTexts(LogicalLines(term.DumpState().PrimaryBuffer)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the same lines from the buffer, got %v", got)
+	}
+
+	// Offsets map back to cells and cells to offsets, across the wrap and past a multi-byte character.
+	l := lines[0]
+	for _, tc := range []struct{ offset, x, y int }{{0, 0, 0}, {4, 4, 0}, {5, 0, 1}, {10, 0, 2}, {11, 1, 2}} {
+		if x, y := l.Position(tc.offset); x != tc.x || y != tc.y {
+			t.Errorf("Position(%d): expected (%d,%d), got (%d,%d)", tc.offset, tc.x, tc.y, x, y)
+		}
+		if tc.offset < len(l.Text) {
+			if off := l.Offset(tc.x, tc.y); off != tc.offset {
+				t.Errorf("Offset(%d,%d): expected %d, got %d", tc.x, tc.y, tc.offset, off)
+			}
+		}
+	}
+	if off := l.Offset(3, 2); off != len(l.Text) {
+		t.Errorf("expected a blank cell after the text to give the end, got %d", off)
+	}
+	if off := l.Offset(0, 3); off != -1 {
+		t.Errorf("expected a cell of another line to give -1, got %d", off)
+	}
+	if x, y := lines[3].Position(2); x != 1 || y != 5 {
+		t.Errorf("expected the second byte of é in its cell, got (%d,%d)", x, y)
+	}
+}
+
+func TestLogicalLinesScrollback(t *testing.T) {
+	term := New(WithSize(4, 2), WithScrollbackCapture(10))
+	writeSeq(t, term, "one\r\nabcdefghij")
+
+	want := [][3]any{{"one", -2, 1}, {"abcdefghij", -1, 3}}
+	lines := term.(LineJoiner).LogicalLines(true)
+	if got := lineTexts(lines); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the line wrapped from the scrollback onto the screen joined, got %v", got)
+	}
+	if x, y := lines[1].Position(9); x != 1 || y != 1 {
+		t.Fatalf("expected the last character on the screen, got (%d,%d)", x, y)
+	}
+
+	if got := lineTexts(term.(LineJoiner).LogicalLines(false)); !reflect.DeepEqual(got, [][3]any{{"efghij", 0, 2}}) {
+		t.Fatalf("expected only the screen without scrollback, got %v", got)
+	}
+}
@@ -0,0 +1,95 @@
+package vt10x
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Link is a URL found in the text on the screen.
+type Link struct {
+	// Match is the span of cells the URL covers.
+	Match
+
+	// URL is the text of the URL.
+	URL string
+}
+
+// linkPattern matches URL-looking text: a URL with a scheme commonly found in terminal output, or a web address
+// starting with www. Trailing punctuation is trimmed from matches afterwards.
+var linkPattern = regexp.MustCompile(`(?:(?:https?|ftp|file)://|mailto:|www\.)[^\s<>"'` + "`" + `]+`)
+
+// FindLinks returns the URLs in rows, such as a buffer of TerminalState, top to bottom and left to right, numbering
+// rows from 0.
+func FindLinks(rows [][]Glyph) []Link {
+	var links []Link
+	var s searcher
+	for y, row := range rows {
+		s.reset()
+		for _, g := range row {
+			s.addGlyph(g)
+		}
+		links = s.findLinks(y, links)
+	}
+	return links
+}
+
+// Links returns the URLs on the screen, top to bottom and left to right, so that frontends can make them clickable
+// without searching every frame themselves. The links of each row are kept until the row changes, so only changed
+// rows are searched again. URLs are found in the text of each row on its own: one that wraps onto the next row is cut
+// short at the wrap. OSC 8 hyperlinks are not tracked, so only URLs that appear in the text are found.
+func (t *State) Links() []Link {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var links []Link
+	var s searcher
+	for y := range t.lines {
+		if y >= len(t.meta) {
+			break
+		}
+		m := &t.meta[y]
+		if !m.linked {
+			s.reset()
+			for _, c := range t.lines[y] {
+				s.addCell(c)
+			}
+			m.links, m.linked = s.findLinks(y, nil), true
+		}
+		for _, l := range m.links {
+			// The row may have moved since it was searched.
+			l.Y = y
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// findLinks appends the links in the row, which is row y, to links.
+func (s *searcher) findLinks(y int, links []Link) []Link {
+	for _, loc := range linkPattern.FindAllIndex(s.text, -1) {
+		url := trimLink(string(s.text[loc[0]:loc[1]]))
+		end := loc[0] + len(url)
+		if url == "" || end <= loc[0] {
+			continue
+		}
+		links = append(links, Link{Match: Match{Y: y, X: s.cells[loc[0]], EndX: s.endCell(end)}, URL: url})
+	}
+	return links
+}
+
+// trimLink trims from url the punctuation that ends the sentence around it rather than the URL itself, and closing
+// brackets without an opening one in the URL, as when a URL is written in parentheses.
+func trimLink(url string) string {
+	for url != "" {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", last) >= 0:
+		case last == ')' && strings.Count(url, "(") < strings.Count(url, ")"),
+			last == ']' && strings.Count(url, "[") < strings.Count(url, "]"):
+		default:
+			return url
+		}
+		url = url[:len(url)-1]
+	}
+	return url
+}
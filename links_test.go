@@ -0,0 +1,54 @@
+package vt10x
+
+import (
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	term := New(WithSize(60, 4))
+	writeSeq(t, term, "see https://example.com/a?b=1. or (www.example.org/x)\r\n")
+	writeSeq(t, term, "mail mailto:me@example.com, file file:///tmp/f(1).txt")
+	want := []Link{
+		{Match: Match{Y: 0, X: 4, EndX: 29}, URL: "https://example.com/a?b=1"},
+		{Match: Match{Y: 0, X: 35, EndX: 52}, URL: "www.example.org/x"},
+		{Match: Match{Y: 1, X: 5, EndX: 26}, URL: "mailto:me@example.com"},
+		{Match: Match{Y: 1, X: 33, EndX: 53}, URL: "file:///tmp/f(1).txt"},
+	}
+	got := term.(LinkFinder).Links()
+	if len(got) != len(want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("link %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	if fromState := FindLinks(term.DumpState().PrimaryBuffer); len(fromState) != len(want) || fromState[3] != want[3] {
+		t.Errorf("expected FindLinks to find the same links, got %+v", fromState)
+	}
+}
+
+func TestLinksFollowChanges(t *testing.T) {
+	term := New(WithSize(30, 3))
+	writeSeq(t, term, "\r\nhttp://a.example\r\n")
+	if got := term.(LinkFinder).Links(); len(got) != 1 || got[0].Y != 1 {
+		t.Fatalf("expected a link on row 1, got %+v", got)
+	}
+
+	// Scrolling moves the link along with its row.
+	writeSeq(t, term, "\r\n")
+	if got := term.(LinkFinder).Links(); len(got) != 1 || got[0].Y != 0 || got[0].URL != "http://a.example" {
+		t.Fatalf("expected the link scrolled to row 0, got %+v", got)
+	}
+
+	// Overwriting part of it changes it.
+	writeSeq(t, term, "\033[1;8Hc")
+	if got := term.(LinkFinder).Links(); len(got) != 1 || got[0].URL != "http://c.example" {
+		t.Errorf("expected the rewritten link, got %+v", got)
+	}
+	writeSeq(t, term, "\033[2J")
+	if got := term.(LinkFinder).Links(); len(got) != 0 {
+		t.Errorf("expected no links after clearing, got %+v", got)
+	}
+}
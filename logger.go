@@ -0,0 +1,91 @@
+package vt10x
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+)
+
+// Logger receives the terminal's diagnostics, so they can be routed, sampled or silenced per terminal. Messages that
+// concern a sequence quote it. Logger methods are called while the terminal is locked, so they must not call back
+// into the terminal.
+type Logger interface {
+	// Debugf traces the input as it is parsed, one message per rune. It is verbose enough to slow parsing down, so
+	// loggers should only format the message when debug output is wanted.
+	Debugf(format string, args ...any)
+
+	// Warnf reports input the terminal could not handle: invalid UTF-8, unknown or malformed sequences, and input cut
+	// short to stay within its Limits.
+	Warnf(format string, args ...any)
+}
+
+// WithLogger sets the Logger the terminal reports its diagnostics to. By default they are discarded.
+func WithLogger(l Logger) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.logger = l
+	}
+}
+
+// SlogLogger returns a Logger writing debug and warning messages to l at slog.LevelDebug and slog.LevelWarn. Messages
+// are only formatted for the levels l is enabled for.
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debugf(format string, args ...any) {
+	s.logf(slog.LevelDebug, format, args...)
+}
+
+func (s slogLogger) Warnf(format string, args ...any) {
+	s.logf(slog.LevelWarn, format, args...)
+}
+
+func (s slogLogger) logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if s.l.Enabled(ctx, level) {
+		s.l.Log(ctx, level, fmt.Sprintf(format, args...))
+	}
+}
+
+// stdLogger adapts the deprecated State.DebugLogger to Logger.
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Debugf(format string, args ...any) {
+	s.l.Printf(format, args...)
+}
+
+func (s stdLogger) Warnf(format string, args ...any) {
+	s.l.Printf(format, args...)
+}
+
+// log returns the Logger to report to, or nil if diagnostics are discarded.
+func (t *State) log() Logger {
+	if t.logger != nil {
+		return t.logger
+	}
+	if t.DebugLogger != nil {
+		return stdLogger{t.DebugLogger}
+	}
+	return nil
+}
+
+// trace logs c at debug level as it is parsed. Its arguments are only built when there is a logger, since it runs for
+// every rune.
+func (t *State) trace(c rune) {
+	if l := t.log(); l != nil {
+		l.Debugf("%q", string(c))
+	}
+}
+
+func (t *State) warnf(format string, args ...any) {
+	if l := t.log(); l != nil {
+		l.Warnf(format, args...)
+	}
+}
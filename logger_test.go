@@ -0,0 +1,84 @@
+package vt10x
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type recordingLogger struct {
+	debug, warn []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...any) {
+	l.debug = append(l.debug, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warnf(format string, args ...any) {
+	l.warn = append(l.warn, fmt.Sprintf(format, args...))
+}
+
+func TestLoggerWarnings(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"\033[5y", `unknown CSI sequence "\x1b[5y"`},
+		{"\033]99;x\007", `unknown OSC command "\x1b]99;x"`},
+		{"a\xffb", `invalid utf8 sequence "\xff"`},
+		{"\xe2\x82a", `invalid utf8 sequence "\xe2"`},
+	} {
+		l := &recordingLogger{}
+		term := New(WithSize(20, 4), WithLogger(l))
+		writeSeq(t, term, tc.in)
+		if len(l.warn) == 0 || l.warn[0] != tc.want {
+			t.Errorf("%q: expected warning %s, got %q", tc.in, tc.want, l.warn)
+		}
+	}
+}
+
+func TestLoggerTrace(t *testing.T) {
+	l := &recordingLogger{}
+	term := New(WithSize(20, 4), WithLogger(l))
+	writeSeq(t, term, "hi\033[m")
+	if got := strings.Join(l.debug, " "); got != `"h" "i" "\x1b" "[" "m"` {
+		t.Fatalf("unexpected trace %s", got)
+	}
+	if len(l.warn) != 0 {
+		t.Fatalf("expected no warnings, got %q", l.warn)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+	term := New(WithSize(20, 4), WithLogger(SlogLogger(slog.New(h))))
+	writeSeq(t, term, "ok\033[5y")
+	if got := buf.String(); !strings.Contains(got, "level=WARN") || !strings.Contains(got, `unknown CSI sequence`) ||
+		strings.Contains(got, "DEBUG") {
+		t.Fatalf("expected only the warning to be logged, got %q", got)
+	}
+}
+
+func TestDebugLoggerFallback(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithSize(20, 4)).(*terminal)
+	term.DebugLogger = log.New(&buf, "", 0)
+	writeSeq(t, term, "\033[5y")
+	if !strings.Contains(buf.String(), "unknown CSI sequence") {
+		t.Fatalf("expected the deprecated DebugLogger to receive warnings, got %q", buf.String())
+	}
+}
+
+// TestWriteDoesNotAllocate ensures that without a logger, tracing costs nothing on the hot path of plain text.
+func TestWriteDoesNotAllocate(t *testing.T) {
+	term := New(WithSize(80, 24))
+	// Overwrite the same row, since each row scrolled in is allocated when first written.
+	line := []byte("\r" + strings.Repeat("x", 40))
+	term.Write(line)
+	if n := testing.AllocsPerRun(100, func() { term.Write(line) }); n != 0 {
+		t.Fatalf("expected Write not to allocate, got %v allocations", n)
+	}
+}
@@ -0,0 +1,123 @@
+package vt10x
+
+import "unsafe"
+
+// MemoryUsage estimates the memory a terminal holds, in bytes, so that a server hosting many terminals can hold each
+// session to a budget. The estimates count the data the terminal keeps, not allocator overhead.
+type MemoryUsage struct {
+	// Screens is the cells of the primary and alternate screens and the bookkeeping of their rows. Blank rows share
+	// storage, so a mostly empty screen costs little.
+	Screens int64
+	// Scrollback is the lines captured with WithScrollbackCapture and not yet taken.
+	Scrollback int64
+	// Images is the decoded pixels of the images placed on either screen, at 4 bytes a pixel, counted for each
+	// placement.
+	Images int64
+}
+
+// Total returns the sum of the estimates.
+func (m MemoryUsage) Total() int64 {
+	return m.Screens + m.Scrollback + m.Images
+}
+
+var (
+	cellSize            = int64(unsafe.Sizeof(cell{}))
+	lineSize            = int64(unsafe.Sizeof(line(nil)))
+	rowMetaSize         = int64(unsafe.Sizeof(rowMeta{}))
+	scrollbackLineSize  = int64(unsafe.Sizeof(ScrollbackLine{}))
+	runeSize            = int64(unsafe.Sizeof(rune(0)))
+	imagePlacementBytes = int64(unsafe.Sizeof(ImagePlacement{}))
+)
+
+// MemoryUsage returns an estimate of the memory the terminal holds.
+func (t *State) MemoryUsage() MemoryUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.memoryUsage()
+}
+
+// Trim drops the oldest scrollback lines, then the oldest images, hidden screen's first, until the terminal's
+// estimated memory is at most maxBytes or nothing is left to drop, and returns the estimate after. Dropped lines are
+// counted as dropped by TakeScrollback. The screens themselves are never trimmed, so the result can exceed maxBytes.
+func (t *State) Trim(maxBytes int64) MemoryUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.memoryUsage()
+	over := m.Total() - maxBytes
+	if over <= 0 {
+		return m
+	}
+
+	n := 0
+	for n < len(t.scrollback) && over > 0 {
+		size := scrollbackLineBytes(t.scrollback[n])
+		over -= size
+		m.Scrollback -= size
+		n++
+	}
+	if n > 0 {
+		// Copy what is kept, so that the dropped lines' storage can be freed.
+		t.scrollback = append([]ScrollbackLine(nil), t.scrollback[n:]...)
+		t.scrollbackDropped += n
+	}
+
+	trimImages := func(images []ImagePlacement) []ImagePlacement {
+		n := 0
+		for n < len(images) && over > 0 {
+			size := imageBytes(images[n])
+			over -= size
+			m.Images -= size
+			n++
+		}
+		if n == 0 {
+			return images
+		}
+		return copyImages(images[n:])
+	}
+	t.altImages = trimImages(t.altImages)
+	if images := trimImages(t.images); len(images) != len(t.images) {
+		t.images = images
+		t.changed |= ChangedScreen
+		t.dirtyAll()
+	}
+	return m
+}
+
+// memoryUsage is MemoryUsage with the terminal locked.
+func (t *State) memoryUsage() MemoryUsage {
+	var m MemoryUsage
+	m.Screens = int64(len(t.blank)) * cellSize
+	for _, lines := range [][]line{t.lines, t.altLines} {
+		for _, l := range lines {
+			m.Screens += lineSize
+			if !isSameLine(l, t.blank) {
+				m.Screens += int64(len(l)) * cellSize
+			}
+		}
+	}
+	m.Screens += int64(len(t.meta)+len(t.altMeta)) * rowMetaSize
+	for _, l := range t.scrollback {
+		m.Scrollback += scrollbackLineBytes(l)
+	}
+	for _, images := range [][]ImagePlacement{t.images, t.altImages} {
+		for _, p := range images {
+			m.Images += imageBytes(p)
+		}
+	}
+	return m
+}
+
+func scrollbackLineBytes(l ScrollbackLine) int64 {
+	return scrollbackLineSize + int64(len(l.Text))*runeSize
+}
+
+func imageBytes(p ImagePlacement) int64 {
+	size := imagePlacementBytes
+	if p.Image != nil {
+		b := p.Image.Bounds()
+		size += int64(b.Dx()) * int64(b.Dy()) * 4
+	}
+	return size
+}
@@ -0,0 +1,77 @@
+package vt10x
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestMemoryUsageScreens(t *testing.T) {
+	term := New(WithSize(80, 24))
+	before := term.(MemoryManager).MemoryUsage()
+	if before.Screens <= 0 || before.Scrollback != 0 || before.Images != 0 {
+		t.Fatalf("unexpected usage of a blank terminal %+v", before)
+	}
+	writeSeq(t, term, "hello")
+	after := term.(MemoryManager).MemoryUsage()
+	if got := after.Screens - before.Screens; got != 80*cellSize {
+		t.Errorf("expected writing a row to cost %d bytes, got %d", 80*cellSize, got)
+	}
+	if after.Total() != after.Screens {
+		t.Errorf("expected Total %d, got %d", after.Screens, after.Total())
+	}
+}
+
+func TestTrimScrollback(t *testing.T) {
+	term := New(WithSize(10, 2), WithScrollback(100))
+	for i := 0; i < 11; i++ {
+		writeSeq(t, term, fmt.Sprintf("line %d\r\n", i))
+	}
+	m := term.(MemoryManager).MemoryUsage()
+	per := scrollbackLineBytes(ScrollbackLine{Text: make([]rune, 10)})
+	if m.Scrollback != 10*per {
+		t.Fatalf("expected 10 lines of scrollback, %d bytes, got %d", 10*per, m.Scrollback)
+	}
+
+	if got := term.(MemoryManager).Trim(m.Total()); got != m {
+		t.Errorf("expected Trim within budget to change nothing, got %+v", got)
+	}
+	got := term.(MemoryManager).Trim(m.Total() - 3*per + 1)
+	if got.Scrollback != 7*per {
+		t.Errorf("expected 3 lines trimmed, got %d bytes of scrollback", got.Scrollback)
+	}
+	lines, dropped := term.TakeScrollback()
+	if dropped != 3 || len(lines) != 7 || string(lines[0][:6]) != "line 3" {
+		t.Errorf("expected the oldest 3 lines dropped, got %d lines from %q, %d dropped", len(lines), string(lines[0]), dropped)
+	}
+}
+
+func TestTrimImages(t *testing.T) {
+	s := New(WithSize(10, 5)).DumpState()
+	img := image.NewRGBA(image.Rect(0, 0, 10, 20))
+	s.Images = []ImagePlacement{
+		{X: 0, Y: 2, Cols: 1, Rows: 1, Image: img},
+		{X: 1, Y: 3, Cols: 1, Rows: 1, Image: img},
+	}
+	term := New(WithState(s), WithScrollback(10))
+	writeSeq(t, term, "\033[5Ha\r\n")
+	m := term.(MemoryManager).MemoryUsage()
+	per := imageBytes(s.Images[0])
+	if m.Images != 2*per || m.Scrollback == 0 {
+		t.Fatalf("expected two images and a scrollback line, got %+v", m)
+	}
+
+	// Trimming the scrollback does not fit the budget, so the older image goes too.
+	got := term.(MemoryManager).Trim(m.Screens + per)
+	if got.Scrollback != 0 || got.Images != per {
+		t.Errorf("expected the scrollback and one image dropped, got %+v", got)
+	}
+	if images := term.DumpState().Images; len(images) != 1 || images[0].Y != 2 {
+		t.Errorf("expected the newer image, scrolled to row 2, kept; got %+v", images)
+	}
+
+	got = term.(MemoryManager).Trim(0)
+	if got.Images != 0 || got.Screens != m.Screens || got.Total() != got.Screens {
+		t.Errorf("expected only the screens left, got %+v", got)
+	}
+}
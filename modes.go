@@ -0,0 +1,143 @@
+package vt10x
+
+import (
+	"fmt"
+)
+
+// decMode describes a DEC private mode the terminal recognizes. Modes the terminal implements report their state
+// through get; the others have no effect, but their state is still tracked so that queries report what the
+// application last set.
+type decMode struct {
+	name string
+	get  func(t *State) bool
+}
+
+// flagMode returns a getter for a mode backed by a ModeFlag bit.
+func flagMode(bit ModeFlag) func(t *State) bool {
+	return func(t *State) bool { return t.mode&bit != 0 }
+}
+
+func altScreenMode(t *State) bool {
+	return t.mode&ModeAltScreen != 0
+}
+
+// decModes is the registry of DEC private modes, keyed by mode number.
+var decModes = map[int]decMode{
+	1:    {"DECCKM", flagMode(ModeAppCursor)},
+	2:    {"DECANM", nil},
+	3:    {"DECCOLM", nil},
+	4:    {"DECSCLM", flagMode(ModeSmoothScroll)},
+	5:    {"DECSCNM", flagMode(ModeReverse)},
+	6:    {"DECOM", func(t *State) bool { return t.cur.State&cursorOrigin != 0 }},
+	7:    {"DECAWM", flagMode(ModeWrap)},
+	8:    {"DECARM", nil},
+	9:    {"X10 mouse", flagMode(ModeMouseX10)},
+	12:   {"cursor blink", func(t *State) bool { return t.cursorStyle.Blink }},
+	18:   {"DECPFF", nil},
+	19:   {"DECPEX", nil},
+	25:   {"DECTCEM", func(t *State) bool { return t.mode&ModeHide == 0 }},
+	38:   {"DECTEK", func(t *State) bool { return t.vector != nil && t.vector.kind == VectorTektronix }},
+	40:   {"allow 80/132 columns", nil},
+	42:   {"DECNRCM", nil},
+	45:   {"reverse wraparound", nil},
+	47:   {"alternate screen", altScreenMode},
+	66:   {"DECNKM", flagMode(ModeAppKeypad)},
+	67:   {"DECBKM", nil},
+	69:   {"DECLRMM", nil},
+	80:   {"DECSDM", nil},
+	95:   {"DECNCSM", nil},
+	1000: {"mouse button tracking", flagMode(ModeMouseButton)},
+	1001: {"mouse highlight tracking", nil},
+	1002: {"mouse motion tracking", flagMode(ModeMouseMotion)},
+	1003: {"mouse any-event tracking", flagMode(ModeMouseMany)},
+	1004: {"focus events", flagMode(ModeFocus)},
+	1005: {"UTF-8 mouse", nil},
+	1006: {"SGR mouse", flagMode(ModeMouseSgr)},
+	1007: {"alternate scroll", nil},
+	1015: {"urxvt mouse", nil},
+	1016: {"SGR pixel mouse", nil},
+	1034: {"8-bit meta", flagMode(Mode8bit)},
+	1035: {"num lock modifiers", nil},
+	1036: {"meta sends escape", nil},
+	1039: {"alt sends escape", nil},
+	1047: {"alternate screen", altScreenMode},
+	1048: {"save cursor", nil},
+	1049: {"alternate screen and save cursor", altScreenMode},
+	2004: {"bracketed paste", nil},
+	2026: {"synchronized output", nil},
+	2048: {"in-band resize", nil},
+}
+
+// ansiModes lists the ANSI modes (set with CSI Pm h) the terminal reports through DECRQM.
+var ansiModes = map[int]ModeFlag{
+	2:  ModeKeyboardLock,
+	4:  ModeInsert,
+	12: ModeEcho,
+	20: ModeCRLF,
+}
+
+// trackPrivMode records the state of a registered DEC private mode that has no getter.
+func (t *State) trackPrivMode(a int, set bool) {
+	m, ok := decModes[a]
+	if !ok || m.get != nil {
+		return
+	}
+	if t.privModes == nil {
+		t.privModes = make(map[int]bool)
+	}
+	t.privModes[a] = set
+}
+
+// privMode returns whether DEC private mode a is set, and whether it is registered at all.
+func (t *State) privMode(a int) (set, known bool) {
+	m, ok := decModes[a]
+	if !ok {
+		return false, false
+	}
+	if m.get != nil {
+		return m.get(t), true
+	}
+	return t.privModes[a], true
+}
+
+// reportMode answers DECRQM for mode a with DECRPM: CSI [?] Ps ; Pm $ y, where Pm is 1 for set, 2 for reset, and 0
+// for a mode the terminal does not recognize.
+func (t *State) reportMode(priv bool, a int) {
+	if t.noModeReports {
+		return
+	}
+	var set, known bool
+	prefix := ""
+	if priv {
+		prefix = "?"
+		set, known = t.privMode(a)
+	} else {
+		var bit ModeFlag
+		bit, known = ansiModes[a]
+		set = t.mode&bit != 0
+	}
+
+	pm := 0
+	switch {
+	case known && set:
+		pm = 1
+	case known:
+		pm = 2
+	}
+	t.w.Write([]byte(fmt.Sprintf("\033[%s%d;%d$y", prefix, a, pm)))
+}
+
+// dumpModes returns the state of every registered DEC private mode.
+func (t *State) dumpModes() map[int]bool {
+	modes := make(map[int]bool, len(decModes))
+	for a := range decModes {
+		modes[a], _ = t.privMode(a)
+	}
+	return modes
+}
+
+// DECModeName returns the name of DEC private mode a, such as "DECAWM" for 7, or "" if the terminal does not
+// recognize it.
+func DECModeName(a int) string {
+	return decModes[a].name
+}
@@ -0,0 +1,133 @@
+package vt10x
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDECRQM(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want string
+	}{
+		{"implemented default set", "\033[?7$p", "\033[?7;1$y"},
+		{"implemented reset", "\033[?7l\033[?7$p", "\033[?7;2$y"},
+		{"cursor visible", "\033[?25$p", "\033[?25;1$y"},
+		{"alternate screen", "\033[?1049h\033[?1049$p\033[?47$p", "\033[?1049;1$y\033[?47;1$y"},
+		{"origin", "\033[?6h\033[?6$p", "\033[?6;1$y"},
+		{"tracked unimplemented", "\033[?2004$p\033[?2004h\033[?2004$p", "\033[?2004;2$y\033[?2004;1$y"},
+		{"unknown", "\033[?31337h\033[?31337$p", "\033[?31337;0$y"},
+		{"ansi mode", "\033[20h\033[20$p\033[4$p", "\033[20;1$y\033[4;2$y"},
+		{"ansi unknown", "\033[99$p", "\033[99;0$y"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			term := New(WithWriter(&buf))
+			writeSeq(t, term, tc.seq)
+			if got := buf.String(); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestModesState(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033[?1h\033[?2004h\033[?25l")
+	modes := term.DumpState().Modes
+	for a, want := range map[int]bool{1: true, 2004: true, 25: false, 7: true, 1000: false} {
+		if got, ok := modes[a]; !ok || got != want {
+			t.Fatalf("mode %d (%s): expected %v, got %v (present %v)", a, DECModeName(a), want, got, ok)
+		}
+	}
+	if _, ok := modes[31337]; ok {
+		t.Fatal("expected unknown modes to be left out")
+	}
+
+	writeSeq(t, term, "\033c")
+	if term.DumpState().Modes[2004] {
+		t.Fatal("expected RIS to reset tracked modes")
+	}
+}
+
+func TestLNM(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []TerminalOption
+		seq  string
+		want int
+	}{
+		{"line feed keeps the column", nil, "ab\ncd", 2},
+		{"newline mode", nil, "\033[20hab\ncd", 0},
+		{"newline mode reset", nil, "\033[20h\033[20lab\ncd", 2},
+		{"vertical tab and form feed", nil, "\033[20hab\v\fcd", 0},
+		{"reset by RIS", nil, "\033[20h\033cab\ncd", 2},
+		{"translation", []TerminalOption{WithLineFeedTranslation()}, "\033[20lab\ncd", 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			term := New(append([]TerminalOption{WithSize(10, 4)}, tc.opts...)...)
+			writeSeq(t, term, tc.seq)
+			if c := term.Cell(tc.want, term.Cursor().Y); c.Char != 'c' {
+				t.Fatalf("expected cd at column %d, got %q there", tc.want, c.Char)
+			}
+		})
+	}
+
+	term := New()
+	writeSeq(t, term, "\033[20h")
+	if term.Mode()&ModeCRLF == 0 || term.(MetaDumper).DumpMeta().Mode&ModeCRLF == 0 {
+		t.Fatal("expected newline mode to be reported")
+	}
+	restored := New(WithState(term.DumpState()))
+	writeSeq(t, restored, "ab\ncd")
+	if c := restored.Cell(0, 1); c.Char != 'c' {
+		t.Fatalf("expected newline mode to survive a restore, got %q", c.Char)
+	}
+}
+
+func TestDECSCLM(t *testing.T) {
+	var buf bytes.Buffer
+	l := &recordingLogger{}
+	term := New(WithWriter(&buf), WithLogger(l))
+
+	writeSeq(t, term, "\033[?4h\033[?4$p")
+	if term.Mode()&ModeSmoothScroll == 0 || !term.(MetaDumper).DumpMeta().Modes[4] {
+		t.Fatal("expected smooth scroll to be reported set")
+	}
+	writeSeq(t, term, "\033[?4l\033[?4$p")
+	if term.Mode()&ModeSmoothScroll != 0 {
+		t.Fatal("expected smooth scroll to be reported reset")
+	}
+	if want := "\033[?4;1$y\033[?4;2$y"; buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+	if len(l.warn) > 0 {
+		t.Fatalf("expected no warnings, got %q", l.warn)
+	}
+}
+
+func TestDECNKM(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033[?66h")
+	if term.Mode()&ModeAppKeypad == 0 {
+		t.Fatal("expected DECNKM to select the application keypad")
+	}
+	writeSeq(t, term, "\033[?66l")
+	if term.Mode()&ModeAppKeypad != 0 {
+		t.Fatal("expected DECNKM reset to select the numeric keypad")
+	}
+}
+
+func TestDECModeName(t *testing.T) {
+	if got := DECModeName(7); got != "DECAWM" {
+		t.Fatalf("expected DECAWM, got %q", got)
+	}
+	if got := DECModeName(31337); got != "" {
+		t.Fatalf("expected no name for an unknown mode, got %q", got)
+	}
+}
@@ -0,0 +1,53 @@
+package vt10x
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Notification is a desktop notification a program asked the terminal to show.
+type Notification struct {
+	// Title is the notification's title, empty for OSC 9, which carries only a message.
+	Title string
+
+	// Body is the notification's message.
+	Body string
+}
+
+// WithNotificationHandler sets a function called with each desktop notification a program sends, as iTerm2's OSC 9 ;
+// message ST or urxvt's OSC 777 ; notify ; title ; body ST, so embedders can show notifications from long-running
+// jobs. Without a handler notifications are ignored. It is called while the terminal is locked, so it must not call
+// back into the terminal.
+func WithNotificationHandler(fn func(Notification)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onNotify = fn
+	}
+}
+
+// showNotification handles OSC 9 and OSC 777.
+func (t *State) showNotification(s *strEscape) {
+	var n Notification
+	switch s.arg(0, 0) {
+	case 9:
+		// ConEmu reuses OSC 9 for commands such as 9;4;state;progress, which are not notifications.
+		if len(s.args) > 2 {
+			if _, err := strconv.Atoi(s.args[1]); err == nil {
+				t.warnf("unhandled ConEmu OSC 9 command %q", s)
+				return
+			}
+		}
+		n.Body = strings.Join(s.args[1:], ";")
+	case 777:
+		if s.argString(1, "") != "notify" {
+			t.warnf("unknown OSC 777 command %q", s)
+			return
+		}
+		n.Title = s.argString(2, "")
+		if len(s.args) > 3 {
+			n.Body = strings.Join(s.args[3:], ";")
+		}
+	}
+	if t.onNotify != nil {
+		t.onNotify(n)
+	}
+}
@@ -0,0 +1,39 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNotifications(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want []Notification
+	}{
+		{"OSC 9", "\033]9;build done\007", []Notification{{Body: "build done"}}},
+		{"OSC 9 with semicolons", "\033]9;a;b\033\\", []Notification{{Body: "a;b"}}},
+		{"OSC 777", "\033]777;notify;make;done; 0 errors\007", []Notification{{Title: "make", Body: "done; 0 errors"}}},
+		{"OSC 777 without a body", "\033]777;notify;make\007", []Notification{{Title: "make"}}},
+		{"OSC 777 other command", "\033]777;preexec\007", nil},
+		{"ConEmu progress", "\033]9;4;1;50\007", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []Notification
+			term := New(WithNotificationHandler(func(n Notification) { got = append(got, n) }))
+			writeSeq(t, term, tc.seq)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNotificationsWithoutHandler(t *testing.T) {
+	term := New(WithSize(10, 2))
+	writeSeq(t, term, "\033]9;hello\007x")
+	if got := term.Cell(0, 0).Char; got != 'x' {
+		t.Fatalf("expected the notification consumed, got %q in the first cell", got)
+	}
+}
@@ -1,31 +1,79 @@
 package vt10x
 
+import "unicode"
+
 func isControlCode(c rune) bool {
 	return c < 0x20 || c == 0177
 }
 
+// isC1Control reports whether c is an 8-bit C1 control.
+func isC1Control(c rune) bool {
+	return c >= 0x80 && c <= 0x9f
+}
+
+// putC1 parses the C1 control c as ESC followed by c-0x40, its 7-bit form. Apart from ST, which ends the string it
+// is in, a C1 control first abandons any sequence or string in progress, as it does on a VT500.
+func (t *State) putC1(c rune) {
+	if c != 0x9c && t.passthrough == nil {
+		t.csi.reset()
+		t.str.reset()
+		t.endStr()
+		t.state = t.parse
+	}
+	t.put(033)
+	t.put(c - 0x40)
+}
+
 func (t *State) parse(c rune) {
-	t.logf("%q", string(c))
+	t.trace(c)
 	if isControlCode(c) {
 		if t.handleControlCodes(c) || t.cur.Attr.Mode&attrGfx == 0 {
 			return
 		}
 	}
+	t.print(c)
+}
+
+// print writes c at the cursor with the current attributes and advances the cursor. A wide character takes the
+// cursor's cell and the next; one that does not fit before the right edge wraps first, or without autowrap is written
+// over the last two columns.
+func (t *State) print(c rune) {
 	// TODO: update selection; see st.c:2450
 
+	w := runeWidth(c)
+	if w > t.cols {
+		w = 1
+	}
 	if t.mode&ModeWrap != 0 && t.cur.State&cursorWrapNext != 0 && t.cur.Y >= 0 && t.cur.Y < len(t.lines) && t.cur.X >= 0 && t.cur.X < len(t.lines[t.cur.Y]) {
-		t.lines[t.cur.Y][t.cur.X].Mode |= attrWrap
+		t.writableLine(t.cur.Y)[t.cur.X].attrs |= attrWrap
 		t.newline(true)
 	}
+	if t.cur.X+w > t.cols {
+		if t.mode&ModeWrap != 0 && t.cur.Y >= 0 && t.cur.Y < len(t.lines) {
+			t.writableLine(t.cur.Y)[t.cols-1].attrs |= attrWrap
+			t.newline(true)
+		} else {
+			t.moveTo(t.cols-w, t.cur.Y)
+		}
+	}
 
-	if t.mode&ModeInsert != 0 && t.cur.X+1 < t.cols {
-		// TODO: move shiz, look at st.c:2458
-		t.logln("insert mode not implemented")
+	if t.mode&ModeInsert != 0 {
+		// IRM shifts the rest of the line right to make room, losing whatever is pushed past the right margin.
+		t.insertBlanks(w)
 	}
 
-	t.setChar(c, &t.cur.Attr, t.cur.X, t.cur.Y)
-	if t.cur.X+1 < t.cols {
-		t.moveTo(t.cur.X+1, t.cur.Y)
+	attr := &t.cur.Attr
+	if t.redaction != nil {
+		c, attr = t.redactGlyph(c, attr)
+	}
+	t.splitWide(t.cur.Y, t.cur.X, t.cur.X+w-1)
+	t.setChar(c, attr, t.cur.X, t.cur.Y)
+	if w == 2 {
+		t.setSpacer(t.cur.X, t.cur.Y)
+	}
+	t.stats.Cells++
+	if t.cur.X+w < t.cols {
+		t.moveTo(t.cur.X+w, t.cur.Y)
 	} else {
 		t.cur.State |= cursorWrapNext
 	}
@@ -36,7 +84,7 @@ func (t *State) parseEsc(c rune) {
 		return
 	}
 	next := t.parse
-	t.logf("%q", string(c))
+	t.trace(c)
 	switch c {
 	case '[':
 		next = t.parseEscCSI
@@ -49,12 +97,18 @@ func (t *State) parseEsc(c rune) {
 		'k': // old title set compatibility
 		t.str.reset()
 		t.str.typ = c
+		t.endStr()
 		next = t.parseEscStr
-	case '(': // set primary charset G0
+	case '(', // set primary charset G0
+		')', // set secondary charset G1
+		'*', // set tertiary charset G2
+		'+': // set quaternary charset G3
+		t.charsetG = int(c - '(')
 		next = t.parseEscAltCharset
-	case ')', // set secondary charset G1 (ignored)
-		'*', // set tertiary charset G2 (ignored)
-		'+': // set quaternary charset G3 (ignored)
+	case 'n': // LS2 - invoke G2 into GL
+		t.invokeCharset(2)
+	case 'o': // LS3 - invoke G3 into GL
+		t.invokeCharset(3)
 	case 'D': // IND - linefeed
 		if t.cur.Y == t.bottom {
 			t.scrollUp(t.top, 1, true)
@@ -64,9 +118,7 @@ func (t *State) parseEsc(c rune) {
 	case 'E': // NEL - next line
 		t.newline(true)
 	case 'H': // HTS - horizontal tab stop
-		if t.cur.X >= 0 && t.cur.X < len(t.tabs) {
-			t.tabs[t.cur.X] = true
-		}
+		t.setTab(true)
 	case 'M': // RI - reverse index
 		if t.cur.Y == t.top {
 			t.scrollDown(t.top, 1)
@@ -87,7 +139,7 @@ func (t *State) parseEsc(c rune) {
 		t.restoreCursor()
 	case '\\': // ST - stop
 	default:
-		t.logf("unknown ESC sequence '%c'\n", c)
+		t.warnf("unknown ESC sequence '%c'", c)
 	}
 	t.state = next
 }
@@ -99,7 +151,7 @@ func (t *State) parseEscCSI(c rune) {
 	if c > 0x7F {
 		return
 	}
-	t.logf("%q", string(c))
+	t.trace(c)
 	if t.csi.put(byte(c)) {
 		t.state = t.parse
 		t.handleCSI()
@@ -107,15 +159,46 @@ func (t *State) parseEscCSI(c rune) {
 }
 
 func (t *State) parseEscStr(c rune) {
-	t.logf("%q", string(c))
+	t.trace(c)
 	switch c {
 	case '\033':
 		t.state = t.parseEscStrEnd
 	case '\a': // backwards compatiblity to xterm
 		t.state = t.parse
 		t.handleSTR()
+		t.endStr()
+	case 030, 032: // CAN, SUB abort the string
+		t.handleControlCodes(c)
 	default:
-		t.str.put(c)
+		switch {
+		case t.sixel != nil:
+			t.sixel.put(c)
+		case t.kitty != nil:
+			t.kitty.put(c)
+		case t.inline != nil:
+			t.inline.put(c)
+		case t.vector != nil:
+			t.vector.size++
+		case c == 'q' && t.str.typ == 'P' && isSixelIntro(t.str.buf):
+			// Sixel data can run to megabytes, so it is decoded as it arrives instead of collected in t.str.
+			t.sixel = newSixelDecoder(sixelParams(t.str.buf))
+		case c == 'p' && t.str.typ == 'P' && isSixelIntro(t.str.buf):
+			// ReGIS, whose numeric parameters look like those of sixel; its commands are skipped.
+			t.startVector(VectorReGIS)
+		case c == 'G' && t.str.typ == '_' && len(t.str.buf) == 0:
+			// Likewise kitty graphics and iTerm2 inline image payloads, which t.str would truncate.
+			t.kitty = &kittyParser{}
+		default:
+			if !t.str.put(c, t.limits.stringLength()) && !t.str.truncated {
+				t.str.truncated = true
+				t.truncated(LimitString)
+			}
+			if t.str.typ == ']' && isInlineImageIntro(t.str.buf) {
+				t.inline = &inlineImageParser{}
+			} else if t.str.typ == 'P' && t.tmuxPassthrough && t.passthrough == nil && isTmuxPassthroughIntro(t.str.buf) {
+				t.startPassthrough()
+			}
+		}
 	}
 }
 
@@ -123,31 +206,20 @@ func (t *State) parseEscStrEnd(c rune) {
 	if t.handleControlCodes(c) {
 		return
 	}
-	t.logf("%q", string(c))
+	t.trace(c)
 	t.state = t.parse
 	if c == '\\' {
 		t.handleSTR()
 	}
+	t.endStr()
 }
 
 func (t *State) parseEscAltCharset(c rune) {
 	if t.handleControlCodes(c) {
 		return
 	}
-	t.logf("%q", string(c))
-	switch c {
-	case '0': // line drawing set
-		t.cur.Attr.Mode |= attrGfx
-	case 'B': // USASCII
-		t.cur.Attr.Mode &^= attrGfx
-	case 'A', // UK (ignored)
-		'<', // multinational (ignored)
-		'5', // Finnish (ignored)
-		'C', // Finnish (ignored)
-		'K': // German (ignored)
-	default:
-		t.logf("unknown alt. charset '%c'\n", c)
-	}
+	t.trace(c)
+	t.designateCharset(t.charsetG, c)
 	t.state = t.parse
 }
 
@@ -155,13 +227,17 @@ func (t *State) parseEscTest(c rune) {
 	if t.handleControlCodes(c) {
 		return
 	}
-	// DEC screen alignment test
-	if c == '8' {
-		for y := 0; y < t.rows; y++ {
-			for x := 0; x < t.cols; x++ {
-				t.setChar('E', &t.cur.Attr, x, y)
-			}
-		}
+	switch c {
+	case '3': // DECDHL - double-height line, top half
+		t.setRendition(LineDoubleHeightTop)
+	case '4': // DECDHL - double-height line, bottom half
+		t.setRendition(LineDoubleHeightBottom)
+	case '5': // DECSWL - single-width line
+		t.setRendition(LineSingle)
+	case '6': // DECDWL - double-width line
+		t.setRendition(LineDoubleWidth)
+	case '8': // DECALN - screen alignment test
+		t.alignmentTest()
 	}
 	t.state = t.parse
 }
@@ -183,24 +259,36 @@ func (t *State) handleControlCodes(c rune) bool {
 	// LF, VT, LF
 	case '\f', '\v', '\n':
 		// go to first col if mode is set
-		t.newline(t.mode&ModeCRLF != 0)
+		t.newline(t.mode&ModeCRLF != 0 || t.translateLF)
 	// BEL
 	case '\a':
-		// TODO: emit sound
-		// TODO: window alert if not focused
+		t.ringBell()
 	// ESC
 	case 033:
 		t.csi.reset()
 		t.state = t.parseEsc
-	// SO, SI
-	case 016, 017:
-		// different charsets not supported. apps should use the correct
-		// alt charset escapes, probably for line drawing
-	// SUB, CAN
-	case 032, 030:
+	// SO - invoke G1 into GL
+	case 016:
+		t.invokeCharset(1)
+	// SI - invoke G0 into GL
+	case 017:
+		t.invokeCharset(0)
+	// CAN, SUB abort the sequence in progress, discarding it; SUB also shows that something was lost
+	case 030, 032:
 		t.csi.reset()
-	// ignore ENQ, NUL, XON, XOFF, DEL
-	case 005, 000, 021, 023, 0177:
+		t.str.reset()
+		t.endStr()
+		t.state = t.parse
+		if c == 032 {
+			t.print(unicode.ReplacementChar)
+		}
+	// ENQ
+	case 005:
+		if t.answerback != "" {
+			t.w.Write([]byte(t.answerback))
+		}
+	// ignore NUL, XON, XOFF, DEL
+	case 000, 021, 023, 0177:
 	default:
 		return false
 	}
@@ -0,0 +1,52 @@
+package vt10x
+
+// tmuxPassthroughIntro starts a tmux passthrough, DCS tmux; <sequence with each ESC doubled> ST, which tmux uses to
+// send sequences it does not understand on to the outer terminal.
+const tmuxPassthroughIntro = "tmux;"
+
+func isTmuxPassthroughIntro(buf []rune) bool {
+	return len(buf) == len(tmuxPassthroughIntro) && string(buf) == tmuxPassthroughIntro
+}
+
+// passthrough is an unwrapped tmux passthrough in progress. Its payload is fed to the parser as it arrives, so
+// wrapped images need not be buffered.
+type passthrough struct {
+	esc bool // an ESC was seen that the next rune decides the meaning of
+}
+
+// startPassthrough begins unwrapping a tmux passthrough whose introducer has just been parsed. Passthroughs nested
+// inside one another are not unwrapped.
+func (t *State) startPassthrough() {
+	t.passthrough = &passthrough{}
+	t.str.reset()
+	t.state = t.parse
+}
+
+// putPassthrough feeds one rune of a tmux passthrough to the parser, undoubling ESCs and ending the passthrough at
+// ST.
+func (t *State) putPassthrough(c rune) {
+	p := t.passthrough
+	if !p.esc {
+		if c == '\033' {
+			p.esc = true
+			return
+		}
+		t.state(c)
+		return
+	}
+
+	p.esc = false
+	switch c {
+	case '\033':
+		t.state(c)
+	case '\\':
+		t.passthrough = nil
+		t.state = t.parse
+	default:
+		// tmux doubles every ESC, so a lone one means the passthrough was cut short; end it and parse what
+		// follows as ordinary output.
+		t.passthrough = nil
+		t.state = t.parseEsc
+		t.put(c)
+	}
+}
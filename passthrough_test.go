@@ -0,0 +1,74 @@
+package vt10x
+
+import (
+	"strings"
+	"testing"
+)
+
+// tmuxWrap wraps seq in a tmux passthrough, doubling its ESCs the way tmux does.
+func tmuxWrap(seq string) string {
+	return "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+}
+
+func TestTmuxPassthrough(t *testing.T) {
+	term := New(WithSize(20, 5), WithTmuxPassthrough())
+
+	writeSeq(t, term, tmuxWrap("\033]2;inner title\033\\\033[31mred")+"\033[mplain")
+	if got := term.Title(); got != "inner title" {
+		t.Fatalf("expected the wrapped OSC to set the title, got %q", got)
+	}
+	if got := extractStr(term, 0, 7, 0); got != "redplain" {
+		t.Fatalf("expected wrapped and following text, got %q", got)
+	}
+	if g := term.Cell(0, 0); g.FG != Red {
+		t.Fatalf("expected the wrapped SGR to apply, got %+v", g)
+	}
+	if g := term.Cell(3, 0); g.FG != DefaultFG {
+		t.Fatalf("expected the SGR reset after the passthrough to apply, got %+v", g)
+	}
+}
+
+func TestTmuxPassthroughImage(t *testing.T) {
+	term := New(WithSize(20, 5), WithTmuxPassthrough())
+
+	writeSeq(t, term, tmuxWrap("\033Pq#1~\033\\"))
+	if imgs := term.DumpState().Images; len(imgs) != 1 {
+		t.Fatalf("expected the wrapped sixel to be drawn, got %d images", len(imgs))
+	}
+}
+
+func TestTmuxPassthroughSplit(t *testing.T) {
+	seq := tmuxWrap("\033[2;3Hx\033]0;t\007") + "y"
+
+	want := New(WithSize(20, 5), WithTmuxPassthrough())
+	writeChunks(t, want, seq)
+	for i := 1; i < len(seq); i++ {
+		got := New(WithSize(20, 5), WithTmuxPassthrough())
+		writeChunks(t, got, seq[:i], seq[i:])
+		if got.String() != want.String() || got.Title() != want.Title() {
+			t.Fatalf("split at %d: got %q, title %q", i, got.String(), got.Title())
+		}
+	}
+}
+
+func TestTmuxPassthroughCutShort(t *testing.T) {
+	term := New(WithSize(20, 5), WithTmuxPassthrough())
+
+	// A lone ESC ends the passthrough and is parsed as the start of an ordinary sequence.
+	writeSeq(t, term, "\033Ptmux;ab\033[31mc")
+	if got := extractStr(term, 0, 2, 0); got != "abc" {
+		t.Fatalf("expected %q, got %q", "abc", got)
+	}
+	if g := term.Cell(2, 0); g.FG != Red {
+		t.Fatalf("expected the sequence after the lone ESC to apply, got %+v", g)
+	}
+}
+
+func TestTmuxPassthroughDisabled(t *testing.T) {
+	term := New(WithSize(20, 5))
+
+	writeSeq(t, term, tmuxWrap("\033]2;inner title\033\\"))
+	if got := term.Title(); got != "" {
+		t.Fatalf("expected passthroughs not to be unwrapped without WithTmuxPassthrough, got title %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package vt10x
+
+import "io"
+
+// Profile is a group of behaviors that goes with a TERM value, so that an embedder can make the emulation match the
+// terminfo entry it advertises to the programs it runs.
+type Profile struct {
+	// Term is the TERM value the profile goes with.
+	Term string
+
+	// ID is the terminal the profile answers device attribute requests as.
+	ID TerminalID
+
+	// Colors is the number of colors SGR can select: 256, which allows direct colors too, 16, 8, or 0 for none.
+	// Selecting one beyond them leaves the color as it was. The default colors are always available.
+	Colors int
+
+	// BackspaceBS makes the backspace key send BS rather than DEL, by starting the terminal with DECBKM set, for a
+	// terminfo entry with kbs=^H.
+	BackspaceBS bool
+
+	// ModeReports makes the terminal answer DECRQM mode queries.
+	ModeReports bool
+
+	// Silent makes the terminal send no replies at all, as a dumb terminal answers nothing.
+	Silent bool
+}
+
+// Profiles of common TERM values.
+var (
+	ProfileXterm256Color = Profile{
+		Term: "xterm-256color", ID: DefaultTerminalID, Colors: 256, BackspaceBS: true, ModeReports: true,
+	}
+	ProfileScreen = Profile{Term: "screen", ID: TerminalID{Model: ModelScreen, Version: 40800}, Colors: 8, BackspaceBS: true}
+	ProfileLinux  = Profile{Term: "linux", ID: TerminalID{Model: ModelVT102}, Colors: 8}
+	ProfileDumb   = Profile{Term: "dumb", ID: TerminalID{Model: ModelVT100}, BackspaceBS: true, Silent: true}
+)
+
+// ProfileFor returns the profile of the TERM value term, or false if there is none.
+func ProfileFor(term string) (Profile, bool) {
+	for _, p := range []Profile{ProfileXterm256Color, ProfileScreen, ProfileLinux, ProfileDumb} {
+		if p.Term == term {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// WithProfile makes the terminal behave as p describes. Without it a terminal answers as DefaultTerminalID, has
+// every color, answers mode queries, and its backspace key sends DEL. The terminal ID can still be set apart with
+// WithTerminalID, given after WithProfile.
+func WithProfile(p Profile) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.profile = &p
+		info.terminalID = &p.ID
+	}
+}
+
+// applyProfile sets up the terminal to behave as p describes.
+func (t *State) applyProfile(p Profile) {
+	t.limitColors, t.colorLimit = p.Colors < 256, p.Colors
+	t.backspaceBS = p.BackspaceBS
+	t.noModeReports = !p.ModeReports
+	if p.Silent {
+		t.w = io.Discard
+	}
+}
+
+// colorAllowed reports whether SGR may select c under the terminal's profile.
+func (t *State) colorAllowed(c Color) bool {
+	return !t.limitColors || c >= DefaultFG && c <= DefaultUnderline || c < Color(t.colorLimit)
+}
@@ -0,0 +1,100 @@
+package vt10x
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfileFor(t *testing.T) {
+	for _, term := range []string{"xterm-256color", "screen", "linux", "dumb"} {
+		if p, ok := ProfileFor(term); !ok || p.Term != term {
+			t.Errorf("expected a profile for %s, got %+v, %v", term, p, ok)
+		}
+	}
+	if _, ok := ProfileFor("vt52"); ok {
+		t.Error("expected no profile for vt52")
+	}
+}
+
+func TestProfileColors(t *testing.T) {
+	for _, tc := range []struct {
+		p      *Profile
+		fg, bg Color
+	}{
+		{nil, 0x010203, 200},
+		{&ProfileXterm256Color, 0x010203, 200},
+		// 91 is beyond 8 colors, so the foreground stays red; the background stays default.
+		{&ProfileLinux, Red, DefaultBG},
+		{&ProfileDumb, DefaultFG, DefaultBG},
+	} {
+		var opts []TerminalOption
+		if tc.p != nil {
+			opts = append(opts, WithProfile(*tc.p))
+		}
+		term := New(opts...)
+		writeSeq(t, term, "\033[31;91;38;2;1;2;3;48;5;200mx")
+		if g := term.Cell(0, 0); g.FG != tc.fg || g.BG != tc.bg {
+			t.Errorf("%+v: expected colors %d on %d, got %d on %d", tc.p, tc.fg, tc.bg, g.FG, g.BG)
+		}
+		writeSeq(t, term, "\033[39;49my")
+		if g := term.Cell(1, 0); g.FG != DefaultFG || g.BG != DefaultBG {
+			t.Errorf("%+v: expected the default colors restored, got %d on %d", tc.p, g.FG, g.BG)
+		}
+	}
+}
+
+func TestProfileReplies(t *testing.T) {
+	for _, tc := range []struct {
+		p          Profile
+		da, decrqm string
+	}{
+		{ProfileXterm256Color, "\033[?64;4;6;22c", "\033[?7;1$y"},
+		{ProfileScreen, "\033[?1;2c", ""},
+		{ProfileLinux, "\033[?6c", ""},
+		{ProfileDumb, "", ""},
+	} {
+		var replies strings.Builder
+		term := New(WithWriter(&replies), WithProfile(tc.p))
+		writeSeq(t, term, "\033[c")
+		if got := replies.String(); got != tc.da {
+			t.Errorf("%s: expected DA %q, got %q", tc.p.Term, tc.da, got)
+		}
+		replies.Reset()
+		writeSeq(t, term, "\033[?7$p")
+		if got := replies.String(); got != tc.decrqm {
+			t.Errorf("%s: expected DECRQM answer %q, got %q", tc.p.Term, tc.decrqm, got)
+		}
+	}
+
+	var replies strings.Builder
+	term := New(WithWriter(&replies), WithProfile(ProfileScreen))
+	writeSeq(t, term, "\033[>c")
+	if got := replies.String(); got != "\033[>83;40800;0c" {
+		t.Errorf("expected screen's DA2, got %q", got)
+	}
+}
+
+func TestProfileBackspace(t *testing.T) {
+	term := New(WithProfile(ProfileScreen))
+	s := term.DumpState()
+	if got := string(EncodeKey(KeyBackspace, 0, s)); got != "\b" {
+		t.Fatalf("expected BS for screen, got %q", got)
+	}
+	if got := string(EncodeKey(KeyBackspace, ModCtrl, s)); got != "\x7f" {
+		t.Fatalf("expected DEL for Ctrl+Backspace, got %q", got)
+	}
+
+	// DECBKM can be reset, and RIS sets it again.
+	writeSeq(t, term, "\033[?67l")
+	if got := string(EncodeKey(KeyBackspace, 0, term.DumpState())); got != "\x7f" {
+		t.Fatalf("expected DEL once DECBKM is reset, got %q", got)
+	}
+	writeSeq(t, term, "\033c")
+	if got := string(EncodeKey(KeyBackspace, ModAlt, term.DumpState())); got != "\033\b" {
+		t.Fatalf("expected ESC BS after RIS, got %q", got)
+	}
+
+	if got := string(EncodeKey(KeyBackspace, 0, New(WithProfile(ProfileLinux)).DumpState())); got != "\x7f" {
+		t.Fatalf("expected DEL for linux, got %q", got)
+	}
+}
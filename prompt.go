@@ -0,0 +1,125 @@
+package vt10x
+
+import "strings"
+
+// Prompt is a shell prompt the cursor is at.
+type Prompt struct {
+	// Text is the text of the prompt, with its rows joined by newlines and trailing spaces trimmed.
+	Text string
+
+	// X and Y are where the command line starts, just past the prompt.
+	X, Y int
+
+	// Input is what has been typed at the prompt so far, up to the cursor or, if the cursor is elsewhere, to the end
+	// of the row. Commands are safe to inject when it is empty.
+	Input string
+
+	// Marked is set if the shell marked the prompt with OSC 133, rather than a PromptDetector finding it.
+	Marked bool
+}
+
+// PromptDetector reports whether the cursor of s is at a shell prompt, and the prompt if it is. It is only consulted
+// for shells that do not mark their prompts with OSC 133.
+type PromptDetector func(s ScreenView) (Prompt, bool)
+
+// promptEnds are the characters common shell prompts end with.
+const promptEnds = "$#%>❯»"
+
+// DefaultPromptDetector guesses that the cursor is at a prompt if it is on the primary screen, the text before it on
+// its row ends with one of the characters most shell prompts end with ($, #, %, >, ❯ or »), and nothing follows it on
+// the row. It finds prompts only until something is typed at them, so it reports Input empty.
+func DefaultPromptDetector(s ScreenView) (Prompt, bool) {
+	if s.Mode()&ModeAltScreen != 0 {
+		return Prompt{}, false
+	}
+	cur := s.Cursor()
+	cols, _ := s.Size()
+	text := []rune(strings.TrimRight(viewText(s, 0, cur.X, cur.Y), " "))
+	if len(text) == 0 || !strings.ContainsRune(promptEnds, text[len(text)-1]) {
+		return Prompt{}, false
+	}
+	if strings.TrimSpace(viewText(s, cur.X, cols, cur.Y)) != "" {
+		return Prompt{}, false
+	}
+	return Prompt{Text: string(text), X: cur.X, Y: cur.Y}, true
+}
+
+// WithPromptDetector sets how AtPrompt finds prompts of shells that do not mark them with OSC 133, in place of
+// DefaultPromptDetector. It is called while the terminal is locked, with the terminal as its ScreenView.
+func WithPromptDetector(d PromptDetector) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.promptDetector = d
+	}
+}
+
+// AtPrompt reports whether the cursor is at a shell prompt, where an automation layer can type a command, and the
+// prompt if it is. For shells that mark their prompts with OSC 133 the marks decide: the cursor is at a prompt from
+// its start (OSC 133 ; A) until the command is executed (OSC 133 ; C) or finishes. For others the terminal's
+// PromptDetector guesses.
+func (t *State) AtPrompt() (Prompt, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.mode&ModeAltScreen != 0 {
+		return Prompt{}, false
+	}
+	if n := len(t.marks.commands); n > 0 {
+		return t.markedPrompt(t.marks.commands[n-1])
+	}
+	detect := t.promptDetector
+	if detect == nil {
+		detect = DefaultPromptDetector
+	}
+	return detect(t)
+}
+
+// markedPrompt returns the prompt of c, the last command marked, if the shell is still waiting at it.
+func (t *State) markedPrompt(c Command) (Prompt, bool) {
+	if c.Executed || c.Finished {
+		return Prompt{}, false
+	}
+	py, iy := c.PromptRow-t.marks.scrolled, c.InputRow-t.marks.scrolled
+	px, ix := t.marks.promptX, t.marks.inputX
+	if ix < 0 {
+		// Without OSC 133 ; B, the prompt runs to the cursor.
+		ix, iy = t.cur.X, t.cur.Y
+	}
+	if py < 0 {
+		py, px = 0, 0
+	}
+	var rows []string
+	for y := py; y <= iy && y < t.rows; y++ {
+		x0, x1 := 0, t.cols
+		if y == py {
+			x0 = px
+		}
+		if y == iy {
+			x1 = ix
+		}
+		rows = append(rows, strings.TrimRight(viewText(t, x0, x1, y), " "))
+	}
+	p := Prompt{Text: strings.Join(rows, "\n"), X: ix, Y: iy, Marked: true}
+	end := t.cols
+	if t.cur.Y == iy && t.cur.X >= ix {
+		end = t.cur.X
+	}
+	p.Input = strings.TrimRight(viewText(t, ix, end, iy), " ")
+	return p, true
+}
+
+// viewText returns the text of the cells from x0 up to x1 of row y of s.
+func viewText(s ScreenView, x0, x1, y int) string {
+	var b strings.Builder
+	for x := max(x0, 0); x < x1; x++ {
+		g := s.Cell(x, y)
+		if isSpacerGlyph(g) {
+			continue
+		}
+		c := g.Char
+		if c == 0 {
+			c = ' '
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package vt10x
+
+import "testing"
+
+func TestDefaultPromptDetector(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		prompt string
+		ok     bool
+	}{
+		{"user@host:~$ ", "user@host:~$", true},
+		{"root@host:/# ", "root@host:/#", true},
+		{"~/src ❯ ", "~/src ❯", true},
+		{"PS C:\\> ", "PS C:\\>", true},
+		{"user@host:~$ ls", "", false},
+		{"Compiling...", "", false},
+		{"user@host:~$ ls\033[3D", "", false},
+		{"", "", false},
+		{"\033[?1049h$ ", "", false},
+	} {
+		term := New(WithSize(40, 3))
+		writeSeq(t, term, "output\r\n"+tc.in)
+		p, ok := term.(PromptReporter).AtPrompt()
+		if ok != tc.ok || p.Text != tc.prompt {
+			t.Errorf("%q: expected %q, %v, got %q, %v", tc.in, tc.prompt, tc.ok, p.Text, ok)
+		}
+		if ok && (p.Marked || p.Y != 1 || p.Input != "") {
+			t.Errorf("%q: unexpected prompt %+v", tc.in, p)
+		}
+	}
+}
+
+func TestMarkedPrompt(t *testing.T) {
+	term := New(WithSize(40, 4))
+	writeSeq(t, term, "\033]133;A\a> ")
+	p, ok := term.(PromptReporter).AtPrompt()
+	if !ok || !p.Marked || p.Text != ">" || p.X != 2 || p.Y != 0 {
+		t.Fatalf("expected the marked prompt without B to run to the cursor, got %+v, %v", p, ok)
+	}
+
+	// A two-line prompt whose last line ends with no usual prompt character.
+	writeSeq(t, term, "\r\n\033]133;D;0\a\033]133;A\a~/src\r\nready\033]133;B\a")
+	p, ok = term.(PromptReporter).AtPrompt()
+	if !ok || p.Text != "~/src\nready" || p.X != 5 || p.Y != 2 || p.Input != "" {
+		t.Fatalf("expected the two-line prompt, got %+v, %v", p, ok)
+	}
+	writeSeq(t, term, "make")
+	if p, ok = term.(PromptReporter).AtPrompt(); !ok || p.Input != "make" {
+		t.Fatalf("expected the typed input, got %+v, %v", p, ok)
+	}
+
+	// While the command runs the marks say it is not at a prompt, whatever the output looks like.
+	writeSeq(t, term, "\r\n\033]133;C\a$ ")
+	if p, ok := term.(PromptReporter).AtPrompt(); ok {
+		t.Errorf("expected no prompt while a command runs, got %+v", p)
+	}
+	writeSeq(t, term, "\033]133;D;0\a")
+	if _, ok := term.(PromptReporter).AtPrompt(); ok {
+		t.Error("expected no prompt between commands")
+	}
+}
+
+func TestWithPromptDetector(t *testing.T) {
+	term := New(WithPromptDetector(func(s ScreenView) (Prompt, bool) {
+		c := s.Cursor()
+		return Prompt{Text: "custom", X: c.X, Y: c.Y}, s.Cell(0, 0).Char == 'λ'
+	}))
+	writeSeq(t, term, "λ ")
+	if p, ok := term.(PromptReporter).AtPrompt(); !ok || p.Text != "custom" || p.X != 2 {
+		t.Errorf("expected the custom detector's prompt, got %+v, %v", p, ok)
+	}
+}
@@ -0,0 +1,501 @@
+package vt10x
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MarshalProto encodes s as the TerminalState message of proto/vt10x.proto, for consumers in other languages that
+// generate their code from the schema. Like MarshalJSON, rows are runs of cells with the same attributes and images
+// are PNG data. UnmarshalProto reads it back.
+func (s TerminalState) MarshalProto() ([]byte, error) {
+	images, err := encodeImages(s.Images)
+	if err != nil {
+		return nil, err
+	}
+
+	var e protoEncoder
+	e.int(1, s.Cols)
+	e.int(2, s.Rows)
+	e.int(3, s.CursorX)
+	e.int(4, s.CursorY)
+	e.bool(5, s.CursorVisible)
+	e.message(6, func(e *protoEncoder) {
+		e.uint(1, uint64(s.CursorStyle.Shape))
+		e.bool(2, s.CursorStyle.Blink)
+	})
+	putRows(&e, 7, s.PrimaryBuffer)
+	putRows(&e, 8, s.AlternateBuffer)
+	e.bool(9, s.AltScreen)
+	e.int(10, s.ScrollTop)
+	e.int(11, s.ScrollBottom)
+	tabs := make([]uint64, len(s.TabStops))
+	for i, x := range s.TabStops {
+		tabs[i] = uint64(int64(x))
+	}
+	e.packed(12, tabs)
+	e.bool(13, s.Wrap)
+	e.bool(14, s.WrapPending)
+	e.bool(15, s.Insert)
+	e.bool(16, s.Origin)
+	e.bool(17, s.ReverseVideo)
+	e.uint(18, uint64(s.Mode))
+	e.string(19, s.Title)
+	for _, title := range s.TitleStack {
+		e.stringAlways(20, title)
+	}
+	e.int(21, s.SavedCursorX)
+	e.int(22, s.SavedCursorY)
+	palette := make([]uint64, len(s.Palette))
+	for i, c := range s.Palette {
+		palette[i] = uint64(c)
+	}
+	e.packed(23, palette)
+	e.uint(24, uint64(s.ForegroundColor))
+	e.uint(25, uint64(s.BackgroundColor))
+	e.uint(26, uint64(s.CursorColor))
+
+	// Map order is unspecified in protobuf; sort it so equal states encode identically.
+	modes := make([]int, 0, len(s.Modes))
+	for mode := range s.Modes {
+		modes = append(modes, mode)
+	}
+	sort.Ints(modes)
+	for _, mode := range modes {
+		e.message(27, func(e *protoEncoder) {
+			e.int(1, mode)
+			e.bool(2, s.Modes[mode])
+		})
+	}
+
+	for _, img := range images {
+		e.message(28, func(e *protoEncoder) {
+			e.int(1, img.X)
+			e.int(2, img.Y)
+			e.int(3, img.Cols)
+			e.int(4, img.Rows)
+			e.uint(5, uint64(img.ID))
+			e.uint(6, uint64(img.PlacementID))
+			e.int(7, int(img.Z))
+			e.bytes(8, img.PNG)
+		})
+	}
+	putTimes(&e, 29, s.PrimaryModified)
+	putTimes(&e, 30, s.AlternateModified)
+	e.uint(31, uint64(s.KeyboardFlags))
+	e.int(32, s.ModifyOtherKeys)
+	e.bool(33, s.AppCursor)
+	e.bool(34, s.AppKeypad)
+	e.int(35, s.AlternateSavedCursorX)
+	e.int(36, s.AlternateSavedCursorY)
+	e.int(37, s.AltScreenMode)
+	e.packed(38, packRenditions(s.PrimaryRendition))
+	e.packed(39, packRenditions(s.AlternateRendition))
+	e.int(40, s.BellCount)
+	if !s.LastBell.IsZero() {
+		putTimes(&e, 41, []time.Time{s.LastBell})
+	}
+	e.bool(42, s.VisualBell)
+	e.string(43, s.WorkingDirectory)
+	putRowInfos(&e, 46, s.PrimaryRows)
+	putRowInfos(&e, 47, s.AlternateRows)
+	putEpochs(&e, 48, s.PrimaryEpochs)
+	putEpochs(&e, 49, s.AlternateEpochs)
+	names := make([]string, 0, len(s.Variables))
+	for name := range s.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e.message(45, func(e *protoEncoder) {
+			e.string(1, name)
+			e.string(2, s.Variables[name])
+		})
+	}
+	for _, d := range s.DirectoryHistory {
+		e.message(44, func(e *protoEncoder) {
+			e.string(1, d.Host)
+			e.string(2, d.Path)
+			if !d.Time.IsZero() {
+				putTimes(e, 3, []time.Time{d.Time})
+			}
+		})
+	}
+	return e.b, nil
+}
+
+// UnmarshalProto decodes a TerminalState message, as encoded by MarshalProto. Fields it does not know are skipped.
+func (s *TerminalState) UnmarshalProto(data []byte) error {
+	var (
+		st     TerminalState
+		images []snapshotImage
+	)
+	d := protoDecoder{b: data}
+	for d.next() {
+		switch d.field {
+		case 1:
+			st.Cols = d.int()
+		case 2:
+			st.Rows = d.int()
+		case 3:
+			st.CursorX = d.int()
+		case 4:
+			st.CursorY = d.int()
+		case 5:
+			st.CursorVisible = d.bool()
+		case 6:
+			d.message(func(d *protoDecoder) {
+				for d.next() {
+					switch d.field {
+					case 1:
+						st.CursorStyle.Shape = CursorShape(d.uint())
+					case 2:
+						st.CursorStyle.Blink = d.bool()
+					default:
+						d.skip()
+					}
+				}
+			})
+		case 7:
+			st.PrimaryBuffer = append(st.PrimaryBuffer, getRow(&d))
+		case 8:
+			st.AlternateBuffer = append(st.AlternateBuffer, getRow(&d))
+		case 9:
+			st.AltScreen = d.bool()
+		case 10:
+			st.ScrollTop = d.int()
+		case 11:
+			st.ScrollBottom = d.int()
+		case 12:
+			d.packed(func(v uint64) { st.TabStops = append(st.TabStops, int(int32(v))) })
+		case 13:
+			st.Wrap = d.bool()
+		case 14:
+			st.WrapPending = d.bool()
+		case 15:
+			st.Insert = d.bool()
+		case 16:
+			st.Origin = d.bool()
+		case 17:
+			st.ReverseVideo = d.bool()
+		case 18:
+			st.Mode = ModeFlag(d.uint())
+		case 19:
+			st.Title = d.string()
+		case 20:
+			st.TitleStack = append(st.TitleStack, d.string())
+		case 21:
+			st.SavedCursorX = d.int()
+		case 22:
+			st.SavedCursorY = d.int()
+		case 23:
+			d.packed(func(v uint64) { st.Palette = append(st.Palette, Color(v)) })
+		case 24:
+			st.ForegroundColor = Color(d.uint())
+		case 25:
+			st.BackgroundColor = Color(d.uint())
+		case 26:
+			st.CursorColor = Color(d.uint())
+		case 27:
+			var (
+				mode int
+				set  bool
+			)
+			d.message(func(d *protoDecoder) {
+				for d.next() {
+					switch d.field {
+					case 1:
+						mode = d.int()
+					case 2:
+						set = d.bool()
+					default:
+						d.skip()
+					}
+				}
+			})
+			if st.Modes == nil {
+				st.Modes = make(map[int]bool)
+			}
+			st.Modes[mode] = set
+		case 28:
+			var img snapshotImage
+			d.message(func(d *protoDecoder) {
+				for d.next() {
+					switch d.field {
+					case 1:
+						img.X = d.int()
+					case 2:
+						img.Y = d.int()
+					case 3:
+						img.Cols = d.int()
+					case 4:
+						img.Rows = d.int()
+					case 5:
+						img.ID = uint32(d.uint())
+					case 6:
+						img.PlacementID = uint32(d.uint())
+					case 7:
+						img.Z = int32(d.uint())
+					case 8:
+						img.PNG = d.bytes()
+					default:
+						d.skip()
+					}
+				}
+			})
+			images = append(images, img)
+		case 29:
+			st.PrimaryModified = append(st.PrimaryModified, getTime(&d))
+		case 30:
+			st.AlternateModified = append(st.AlternateModified, getTime(&d))
+		case 31:
+			st.KeyboardFlags = KeyboardFlags(d.uint())
+		case 32:
+			st.ModifyOtherKeys = d.int()
+		case 33:
+			st.AppCursor = d.bool()
+		case 34:
+			st.AppKeypad = d.bool()
+		case 35:
+			st.AlternateSavedCursorX = d.int()
+		case 36:
+			st.AlternateSavedCursorY = d.int()
+		case 37:
+			st.AltScreenMode = d.int()
+		case 38:
+			d.packed(func(v uint64) { st.PrimaryRendition = append(st.PrimaryRendition, LineRendition(v)) })
+		case 39:
+			d.packed(func(v uint64) { st.AlternateRendition = append(st.AlternateRendition, LineRendition(v)) })
+		case 40:
+			st.BellCount = d.int()
+		case 41:
+			st.LastBell = getTime(&d)
+		case 42:
+			st.VisualBell = d.bool()
+		case 43:
+			st.WorkingDirectory = d.string()
+		case 44:
+			var wd WorkingDirectory
+			d.message(func(d *protoDecoder) {
+				for d.next() {
+					switch d.field {
+					case 1:
+						wd.Host = d.string()
+					case 2:
+						wd.Path = d.string()
+					case 3:
+						wd.Time = getTime(d)
+					default:
+						d.skip()
+					}
+				}
+			})
+			st.DirectoryHistory = append(st.DirectoryHistory, wd)
+		case 46:
+			st.PrimaryRows = append(st.PrimaryRows, getRowInfo(&d))
+		case 47:
+			st.AlternateRows = append(st.AlternateRows, getRowInfo(&d))
+		case 48:
+			st.PrimaryEpochs = append(st.PrimaryEpochs, getEpochs(&d))
+		case 49:
+			st.AlternateEpochs = append(st.AlternateEpochs, getEpochs(&d))
+		case 45:
+			var name, value string
+			d.message(func(d *protoDecoder) {
+				for d.next() {
+					switch d.field {
+					case 1:
+						name = d.string()
+					case 2:
+						value = d.string()
+					default:
+						d.skip()
+					}
+				}
+			})
+			if st.Variables == nil {
+				st.Variables = make(map[string]string)
+			}
+			st.Variables[name] = value
+		default:
+			d.skip()
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("vt10x: decoding terminal state: %w", d.err)
+	}
+
+	var err error
+	if st.Images, err = decodeImages(images); err != nil {
+		return err
+	}
+	st.AutoWrap = st.Wrap
+	*s = st
+	return nil
+}
+
+// putRows encodes rows as the repeated Row field.
+func putRows(e *protoEncoder, field int, rows [][]Glyph) {
+	for _, runs := range encodeRuns(rows) {
+		e.message(field, func(e *protoEncoder) {
+			for _, r := range runs {
+				e.message(1, func(e *protoEncoder) { putRun(e, r) })
+			}
+		})
+	}
+}
+
+// getRow decodes a Row message.
+func getRow(d *protoDecoder) []Glyph {
+	row := []Glyph{}
+	d.message(func(d *protoDecoder) {
+		for d.next() {
+			if d.field != 1 {
+				d.skip()
+				continue
+			}
+			row = append(row, getRun(d)...)
+		}
+	})
+	return row
+}
+
+// putRun encodes the fields of a GlyphRun message.
+func putRun(e *protoEncoder, r glyphRun) {
+	e.string(1, r.Text)
+	e.uint(2, uint64(r.FG))
+	e.uint(3, uint64(r.BG))
+	e.int(4, int(r.Mode))
+	e.uint(5, uint64(r.Underline))
+	if r.UnderlineColor != nil {
+		e.uintAlways(6, uint64(*r.UnderlineColor))
+	}
+}
+
+// getRun decodes a GlyphRun message into its cells.
+func getRun(d *protoDecoder) []Glyph {
+	var r glyphRun
+	d.message(func(d *protoDecoder) {
+		for d.next() {
+			switch d.field {
+			case 1:
+				r.Text = d.string()
+			case 2:
+				r.FG = Color(d.uint())
+			case 3:
+				r.BG = Color(d.uint())
+			case 4:
+				r.Mode = int16(d.int())
+			case 5:
+				r.Underline = UnderlineStyle(d.uint())
+			case 6:
+				c := Color(d.uint())
+				r.UnderlineColor = &c
+			default:
+				d.skip()
+			}
+		}
+	})
+	var cells []Glyph
+	g := r.attrs()
+	for _, c := range r.Text {
+		g.Char = c
+		cells = append(cells, g)
+	}
+	return cells
+}
+
+// putTimes encodes times as a repeated google.protobuf.Timestamp field. The zero time is 0001-01-01T00:00:00Z, the
+// earliest Timestamp.
+func putTimes(e *protoEncoder, field int, times []time.Time) {
+	for _, t := range times {
+		e.message(field, func(e *protoEncoder) {
+			e.uint(1, uint64(t.Unix()))
+			e.int(2, t.Nanosecond())
+		})
+	}
+}
+
+// putRowInfos encodes infos as repeated RowInfo messages.
+func putRowInfos(e *protoEncoder, field int, infos []RowInfo) {
+	for _, info := range infos {
+		e.message(field, func(e *protoEncoder) {
+			e.uint(1, info.ID)
+			e.bool(2, info.Wrapped)
+			e.bool(3, info.Prompt)
+		})
+	}
+}
+
+// getRowInfo decodes a RowInfo message.
+func getRowInfo(d *protoDecoder) RowInfo {
+	var info RowInfo
+	d.message(func(d *protoDecoder) {
+		for d.next() {
+			switch d.field {
+			case 1:
+				info.ID = d.uint()
+			case 2:
+				info.Wrapped = d.bool()
+			case 3:
+				info.Prompt = d.bool()
+			default:
+				d.skip()
+			}
+		}
+	})
+	return info
+}
+
+// putEpochs encodes the rows of epochs as repeated CellEpochs messages.
+func putEpochs(e *protoEncoder, field int, rows [][]uint64) {
+	for _, row := range rows {
+		e.message(field, func(e *protoEncoder) {
+			e.packed(1, row)
+		})
+	}
+}
+
+// getEpochs decodes a CellEpochs message.
+func getEpochs(d *protoDecoder) []uint64 {
+	epochs := []uint64{}
+	d.message(func(d *protoDecoder) {
+		for d.next() {
+			switch d.field {
+			case 1:
+				d.packed(func(v uint64) { epochs = append(epochs, v) })
+			default:
+				d.skip()
+			}
+		}
+	})
+	return epochs
+}
+
+// packRenditions returns rs as the values of a packed enum field.
+func packRenditions(rs []LineRendition) []uint64 {
+	vs := make([]uint64, len(rs))
+	for i, r := range rs {
+		vs[i] = uint64(r)
+	}
+	return vs
+}
+
+// getTime decodes a google.protobuf.Timestamp message, in UTC.
+func getTime(d *protoDecoder) time.Time {
+	var sec, nsec int64
+	d.message(func(d *protoDecoder) {
+		for d.next() {
+			switch d.field {
+			case 1:
+				sec = int64(d.uint())
+			case 2:
+				nsec = int64(d.int())
+			default:
+				d.skip()
+			}
+		}
+	})
+	return time.Unix(sec, nsec).UTC()
+}
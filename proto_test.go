@@ -0,0 +1,114 @@
+package vt10x
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateProtoRoundTrip(t *testing.T) {
+	now := func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 5, time.UTC) }
+	term := New(WithSize(20, 6), WithCellSize(1, 6), WithLineTimestamps(now))
+	writeSeq(t, term, "\033]0;title\007\033[22;0t\033[1;31mred\033[m plain \033[4:3;58;5;0mcurly\033[m\r\n\033#6héllo ✓"+
+		"\033Pq#1~~\033\\\033[?2004h\033[6 q\033]7;file://host/tmp\007\033]1337;SetUserVar=k=dg==\007\033[?1049halt")
+	want := term.DumpState()
+
+	data, err := want.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got TerminalState
+	if err := got.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Images) != len(want.Images) {
+		t.Fatalf("expected %d images, got %d", len(want.Images), len(got.Images))
+	}
+	got.Images, want.Images = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("state differs after round trip:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestStateProtoWireFormat(t *testing.T) {
+	s := TerminalState{Cols: 80, CursorY: -1, Title: "hi", Palette: []Color{0, 300}, Modes: map[int]bool{25: true}}
+	data, err := s.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x08, 80, // cols
+		0x20, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, // cursor_y, sign-extended
+		0x32, 0x00, // cursor_style, empty
+		0x9a, 0x01, 2, 'h', 'i', // title
+		0xba, 0x01, 3, 0x00, 0xac, 0x02, // palette, packed
+		0xda, 0x01, 4, 0x08, 25, 0x10, 0x01, // modes entry
+	}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("unexpected encoding\ngot  % x\nwant % x", data, want)
+	}
+
+	// Unknown fields of every wire type are skipped, and unpacked repeated scalars accepted.
+	data = append(data, 0xf8, 0x3e, 7, 0xf9, 0x3e, 1, 2, 3, 4, 5, 6, 7, 8, 0xfa, 0x3e, 1, 'x', 0xfd, 0x3e, 1, 2, 3, 4,
+		0xb8, 0x01, 5)
+	var got TerminalState
+	if err := got.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	s.Palette = append(s.Palette, 5)
+	if !reflect.DeepEqual(got, s) {
+		t.Fatalf("unexpected decoding %+v", got)
+	}
+
+	for _, bad := range [][]byte{{0x08}, {0x9a, 0x01, 3, 'h'}, {0x0a, 0}, {0x00, 1}, {0x0b}} {
+		if err := got.UnmarshalProto(bad); err == nil {
+			t.Errorf("expected an error decoding % x", bad)
+		}
+	}
+}
+
+func TestCellDiff(t *testing.T) {
+	term := New(WithSize(10, 3))
+	writeSeq(t, term, "hello\r\nworld")
+	from := term.DumpState()
+	writeSeq(t, term, "\033[1;2H\033[31mEL\033[m\033[3;1Hx")
+	to := term.DumpState()
+
+	d := DiffStates(from, to)
+	if len(d.Cells) != 3 || d.Cells[0].X != 1 || d.Cells[0].Glyph.Char != 'E' || d.Cells[2].Y != 2 {
+		t.Fatalf("unexpected diff %+v", d.Cells)
+	}
+	if d.CursorX != 1 || d.CursorY != 2 {
+		t.Fatalf("unexpected diff cursor %d,%d", d.CursorX, d.CursorY)
+	}
+
+	data, err := d.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got CellDiff
+	if err := got.UnmarshalProto(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, d) {
+		t.Fatalf("diff differs after round trip:\ngot  %+v\nwant %+v", got, d)
+	}
+
+	got.Apply(&from)
+	if !reflect.DeepEqual(from.PrimaryBuffer, to.PrimaryBuffer) || from.CursorX != to.CursorX {
+		t.Fatal("applying the diff did not reproduce the later screen")
+	}
+
+	// A resize sends every cell.
+	term.Resize(4, 2)
+	resized := term.DumpState()
+	d = DiffStates(to, resized)
+	if len(d.Cells) != 8 {
+		t.Fatalf("expected a resize to send all 8 cells, got %d", len(d.Cells))
+	}
+	d.Apply(&to)
+	if to.Cols != 4 || !reflect.DeepEqual(to.PrimaryBuffer, resized.PrimaryBuffer) {
+		t.Fatal("applying the resize diff did not reproduce the later screen")
+	}
+}
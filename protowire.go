@@ -0,0 +1,215 @@
+package vt10x
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// The protocol buffers wire format, enough of it to encode the messages of proto/vt10x.proto without generated code.
+
+// Wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errProtoMalformed = errors.New("malformed protobuf message")
+
+// protoEncoder appends fields to a message. Like proto3, the scalar methods omit fields holding the zero value.
+type protoEncoder struct {
+	b []byte
+}
+
+func (e *protoEncoder) key(field, wire int) {
+	e.b = binary.AppendUvarint(e.b, uint64(field)<<3|uint64(wire))
+}
+
+func (e *protoEncoder) uint(field int, v uint64) {
+	if v != 0 {
+		e.uintAlways(field, v)
+	}
+}
+
+// uintAlways encodes v even when it is 0, as an optional field that is set.
+func (e *protoEncoder) uintAlways(field int, v uint64) {
+	e.key(field, wireVarint)
+	e.b = binary.AppendUvarint(e.b, v)
+}
+
+// int encodes an int32 or int64 field, which protobuf sign-extends to 64 bits.
+func (e *protoEncoder) int(field int, v int) {
+	e.uint(field, uint64(int64(v)))
+}
+
+func (e *protoEncoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+func (e *protoEncoder) string(field int, s string) {
+	if s != "" {
+		e.stringAlways(field, s)
+	}
+}
+
+// stringAlways encodes s even when it is empty, as an element of a repeated field.
+func (e *protoEncoder) stringAlways(field int, s string) {
+	e.key(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+	e.b = append(e.b, s...)
+}
+
+func (e *protoEncoder) bytes(field int, b []byte) {
+	if len(b) != 0 {
+		e.stringAlways(field, string(b))
+	}
+}
+
+// packed encodes the varints of a repeated scalar field.
+func (e *protoEncoder) packed(field int, vs []uint64) {
+	if len(vs) == 0 {
+		return
+	}
+	var sub protoEncoder
+	for _, v := range vs {
+		sub.b = binary.AppendUvarint(sub.b, v)
+	}
+	e.bytes(field, sub.b)
+}
+
+// message encodes the embedded message that fields writes, present even when empty.
+func (e *protoEncoder) message(field int, fields func(*protoEncoder)) {
+	var sub protoEncoder
+	fields(&sub)
+	e.stringAlways(field, string(sub.b))
+}
+
+// protoDecoder reads the fields of a message in turn: next advances to a field, which one of the value methods or
+// skip then consumes. The first error stops decoding and is kept in err.
+type protoDecoder struct {
+	b     []byte
+	field int
+	wire  int
+	err   error
+}
+
+func (d *protoDecoder) fail() {
+	if d.err == nil {
+		d.err = errProtoMalformed
+	}
+	d.b = nil
+}
+
+func (d *protoDecoder) varint() uint64 {
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// next reads the key of the next field, reporting false at the end of the message or on error.
+func (d *protoDecoder) next() bool {
+	if len(d.b) == 0 || d.err != nil {
+		return false
+	}
+	k := d.varint()
+	d.field, d.wire = int(k>>3), int(k&7)
+	if d.err != nil || d.field == 0 || k>>3 > math.MaxInt32 {
+		d.fail()
+		return false
+	}
+	return true
+}
+
+func (d *protoDecoder) uint() uint64 {
+	if d.wire != wireVarint {
+		d.fail()
+		return 0
+	}
+	return d.varint()
+}
+
+// int decodes an int32 field.
+func (d *protoDecoder) int() int {
+	return int(int32(d.uint()))
+}
+
+func (d *protoDecoder) bool() bool {
+	return d.uint() != 0
+}
+
+func (d *protoDecoder) bytes() []byte {
+	if d.wire != wireBytes {
+		d.fail()
+		return nil
+	}
+	n := d.varint()
+	if n > uint64(len(d.b)) {
+		d.fail()
+		return nil
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *protoDecoder) string() string {
+	return string(d.bytes())
+}
+
+// message decodes an embedded message with fields, which reads it with the decoder it is passed.
+func (d *protoDecoder) message(fields func(*protoDecoder)) {
+	sub := protoDecoder{b: d.bytes()}
+	if d.err != nil {
+		return
+	}
+	fields(&sub)
+	if sub.err != nil {
+		d.err = sub.err
+		d.b = nil
+	}
+}
+
+// packed decodes an element of a repeated scalar field, which parsers must accept both packed and not.
+func (d *protoDecoder) packed(elem func(uint64)) {
+	if d.wire == wireVarint {
+		elem(d.varint())
+		return
+	}
+	sub := protoDecoder{b: d.bytes()}
+	for len(sub.b) > 0 && sub.err == nil {
+		elem(sub.varint())
+	}
+	if sub.err != nil {
+		d.fail()
+	}
+}
+
+// skip consumes a field the message does not define, as protobuf requires of parsers.
+func (d *protoDecoder) skip() {
+	switch d.wire {
+	case wireVarint:
+		d.varint()
+	case wireFixed64, wireFixed32:
+		n := 8
+		if d.wire == wireFixed32 {
+			n = 4
+		}
+		if len(d.b) < n {
+			d.fail()
+			return
+		}
+		d.b = d.b[n:]
+	case wireBytes:
+		d.bytes()
+	default:
+		d.fail()
+	}
+}
@@ -0,0 +1,161 @@
+package vt10x
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+
+	"github.com/hinshun/vt10x/internal/pty"
+)
+
+// PtyTerminal is a terminal showing a command that runs on a pseudo-terminal. The command's output is written to the
+// terminal as it arrives, and resizing the terminal resizes the pseudo-terminal, which signals SIGWINCH to the
+// command. Its methods are safe for concurrent use.
+type PtyTerminal struct {
+	// Terminal is the terminal as New returns it, which type-asserts to the optional interfaces, such as Subscriber.
+	Terminal
+	term *terminal
+
+	cmd  *exec.Cmd
+	pty  *os.File
+	wait func() error // waits for cmd once, however many callers
+
+	resizeMu sync.Mutex
+
+	done    chan struct{} // closed once the command's output ends
+	readErr error         // set before done is closed
+
+	replyMu sync.Mutex
+	replies [][]byte
+	sending bool
+}
+
+// NewWithCommand starts cmd on a pseudo-terminal the size of a new terminal created with opts, and returns the
+// terminal, which shows what cmd writes. Streams cmd already has set are left as they are. Replies the terminal
+// generates, such as cursor position reports, go back to cmd as input, in place of any writer set with WithWriter.
+// Pseudo-terminals are supported on Linux, macOS, FreeBSD, NetBSD and OpenBSD.
+func NewWithCommand(cmd *exec.Cmd, opts ...TerminalOption) (*PtyTerminal, error) {
+	t := &PtyTerminal{cmd: cmd, done: make(chan struct{})}
+	t.term = New(append(opts, WithWriter(ptyReplies{t}))...).(*terminal)
+	t.Terminal = t.term
+	cols, rows := t.term.Size()
+
+	f, err := pty.Start(cmd, cols, rows)
+	if err != nil {
+		return nil, fmt.Errorf("vt10x: starting %s: %w", cmd.Path, err)
+	}
+	t.pty = f
+	t.wait = sync.OnceValue(cmd.Wait)
+	go t.read()
+	return t, nil
+}
+
+// read feeds the command's output to the terminal until it ends.
+func (t *PtyTerminal) read() {
+	_, err := t.term.ReadFrom(t.pty)
+	// Most systems report the other end closing, once the command and its children have exited, as EIO.
+	if err != nil && !errors.Is(err, syscall.EIO) && !errors.Is(err, os.ErrClosed) {
+		t.readErr = err
+	}
+	close(t.done)
+}
+
+// Input returns the writer that sends input to the command, as if it were typed.
+func (t *PtyTerminal) Input() io.Writer {
+	return t.pty
+}
+
+// Resize changes the size of the terminal and then of the pseudo-terminal, so the command redraws for a screen that
+// already has its new size. Concurrent resizes reach both in the same order, and sizes the terminal ignores are not
+// passed on. Failing to resize the pseudo-terminal is reported to the terminal's Logger. Any resize handler is called
+// once both are resized.
+func (t *PtyTerminal) Resize(cols, rows int) {
+	t.resizeMu.Lock()
+	defer t.resizeMu.Unlock()
+
+	if !t.term.setSize(cols, rows) {
+		return
+	}
+	if err := pty.Setsize(t.pty, cols, rows); err != nil {
+		t.term.lock()
+		t.term.warnf("resizing pseudo-terminal to %dx%d: %v", cols, rows, err)
+		t.term.unlock()
+	}
+	t.term.resized(cols, rows)
+}
+
+// Subscribe returns the terminal's updates, as Subscriber describes.
+func (t *PtyTerminal) Subscribe() (updates <-chan Update, cancel func()) {
+	return t.term.Subscribe()
+}
+
+// Done returns a channel that is closed once the command's output ends, which is when the command and every process
+// sharing its pseudo-terminal have exited, or the terminal is closed.
+func (t *PtyTerminal) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err returns the error that ended the command's output early, if any, once Done is closed.
+func (t *PtyTerminal) Err() error {
+	select {
+	case <-t.done:
+		return t.readErr
+	default:
+		return nil
+	}
+}
+
+// Wait waits for the command to exit and for its output to be read, and returns its exit error.
+func (t *PtyTerminal) Wait() error {
+	<-t.done
+	return t.wait()
+}
+
+// Close kills the command if it is still running, waits for it to exit, and closes the pseudo-terminal.
+func (t *PtyTerminal) Close() error {
+	t.cmd.Process.Kill()
+	t.wait()
+	err := t.pty.Close()
+	<-t.done
+	return err
+}
+
+// ptyReplies queues the terminal's replies for the command. The terminal writes them while locked, so they are sent
+// from another goroutine to keep a command that is not reading its input from stalling the terminal.
+type ptyReplies struct {
+	t *PtyTerminal
+}
+
+func (w ptyReplies) Write(p []byte) (int, error) {
+	t := w.t
+	t.replyMu.Lock()
+	defer t.replyMu.Unlock()
+
+	t.replies = append(t.replies, append([]byte(nil), p...))
+	if !t.sending {
+		t.sending = true
+		go t.sendReplies()
+	}
+	return len(p), nil
+}
+
+// sendReplies sends queued replies in order until the queue is empty.
+func (t *PtyTerminal) sendReplies() {
+	for {
+		t.replyMu.Lock()
+		if len(t.replies) == 0 {
+			t.sending = false
+			t.replyMu.Unlock()
+			return
+		}
+		p := t.replies[0]
+		t.replies = t.replies[1:]
+		t.replyMu.Unlock()
+
+		t.pty.Write(p)
+	}
+}
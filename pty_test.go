@@ -0,0 +1,77 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd
+
+package vt10x
+
+import (
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startPty(t *testing.T, cmd *exec.Cmd, opts ...TerminalOption) *PtyTerminal {
+	t.Helper()
+
+	term, err := NewWithCommand(cmd, opts...)
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { term.Close() })
+	return term
+}
+
+// waitForText waits until the screen shows text n times.
+func waitForText(t *testing.T, term Terminal, text string, n int) {
+	t.Helper()
+
+	updates, cancel := term.(Subscriber).Subscribe()
+	defer cancel()
+	timeout := time.After(5 * time.Second)
+	for strings.Count(term.String(), text) < n {
+		select {
+		case <-updates:
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q %d times; screen:\n%s", text, n, term.String())
+		}
+	}
+}
+
+func TestNewWithCommandResize(t *testing.T) {
+	term := startPty(t, exec.Command("sh", "-c", "while read l; do stty size; done"), WithSize(60, 10))
+
+	io.WriteString(term.Input(), "\r")
+	waitForText(t, term.Terminal, "10 60", 1)
+
+	term.Resize(100, 30)
+	if cols, rows := term.Size(); cols != 100 || rows != 30 {
+		t.Fatalf("expected the terminal resized to 100x30, got %dx%d", cols, rows)
+	}
+	io.WriteString(term.Input(), "\r")
+	waitForText(t, term.Terminal, "30 100", 1)
+
+	// Sizes the terminal ignores are not passed on.
+	term.Resize(0, 0)
+	io.WriteString(term.Input(), "\r")
+	waitForText(t, term.Terminal, "30 100", 2)
+}
+
+func TestNewWithCommandWait(t *testing.T) {
+	term := startPty(t, exec.Command("sh", "-c", `printf 'done'; exit 3`))
+
+	err := term.Wait()
+	if ee, ok := err.(*exec.ExitError); !ok || ee.ExitCode() != 3 {
+		t.Fatalf("expected exit status 3, got %v", err)
+	}
+	if !strings.HasPrefix(term.String(), "done") {
+		t.Fatalf("expected the output on the screen, got:\n%s", term.String())
+	}
+	select {
+	case <-term.Done():
+	default:
+		t.Fatal("expected Done closed after Wait")
+	}
+	if err := term.Err(); err != nil {
+		t.Fatalf("expected the output to end cleanly, got %v", err)
+	}
+}
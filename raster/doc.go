@@ -0,0 +1,13 @@
+// Package raster draws terminal states as images, such as PNG thumbnails of recorded sessions.
+//
+// A Renderer lays the screen out in a grid of cells sized to its font, Go Mono unless configured otherwise, and draws
+// each cell's text with its colors and attributes, the line renditions set by DECDWL and DECDHL, and the cursor:
+//
+//	r, err := raster.New(raster.WithFontSize(12))
+//	if err != nil {
+//		return err
+//	}
+//	return r.WritePNG(f, term.DumpState())
+//
+// It is a separate package so that programs that do not draw images do not link the font data.
+package raster
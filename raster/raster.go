@@ -0,0 +1,260 @@
+package raster
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"github.com/hinshun/vt10x"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonobolditalic"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultFontSize is the font size, in pixels, a Renderer draws with unless configured otherwise.
+const DefaultFontSize = 14
+
+// Font styles, indexing Renderer.faces.
+const (
+	regular = iota
+	bold
+	italic
+	boldItalic
+)
+
+// Renderer draws terminal states as images. Its font faces keep caches, so it must not draw two images at once.
+type Renderer struct {
+	fonts      [4][]byte
+	size       float64
+	palette    *vt10x.Palette
+	hideCursor bool
+
+	faces          [4]font.Face
+	cellW, cellH   int
+	ascent         int
+	underlineThick int
+}
+
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// WithFont draws text in the TrueType or OpenType fonts whose data is given for each style. bold, italic and
+// boldItalic may be nil to draw those styles in regular, or for boldItalic, in bold. The regular font's advance sets
+// the width of a cell, so it should be monospace.
+func WithFont(regularFont, boldFont, italicFont, boldItalicFont []byte) Option {
+	return func(r *Renderer) {
+		if boldItalicFont == nil {
+			boldItalicFont = boldFont
+		}
+		r.fonts = [4][]byte{regularFont, boldFont, italicFont, boldItalicFont}
+	}
+}
+
+// WithFontSize sets the font size in pixels.
+func WithFontSize(px float64) Option {
+	return func(r *Renderer) {
+		if px > 0 {
+			r.size = px
+		}
+	}
+}
+
+// WithPalette draws with p in place of the palette and default colors in effect when each state was dumped.
+func WithPalette(p vt10x.Palette) Option {
+	return func(r *Renderer) {
+		r.palette = &p
+	}
+}
+
+// WithoutCursor leaves out the cursor, which is otherwise drawn as a block if the state shows it.
+func WithoutCursor() Option {
+	return func(r *Renderer) {
+		r.hideCursor = true
+	}
+}
+
+// New returns a Renderer configured with opts. It fails if a font given with WithFont cannot be parsed.
+func New(opts ...Option) (*Renderer, error) {
+	r := &Renderer{
+		fonts: [4][]byte{gomono.TTF, gomonobold.TTF, gomonoitalic.TTF, gomonobolditalic.TTF},
+		size:  DefaultFontSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	for i, data := range r.fonts {
+		if data == nil {
+			r.faces[i] = r.faces[i&bold]
+			continue
+		}
+		f, err := opentype.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("raster: parsing font: %w", err)
+		}
+		r.faces[i], err = opentype.NewFace(f, &opentype.FaceOptions{Size: r.size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return nil, fmt.Errorf("raster: parsing font: %w", err)
+		}
+	}
+
+	m := r.faces[regular].Metrics()
+	adv, _ := r.faces[regular].GlyphAdvance('M')
+	r.cellW, r.cellH, r.ascent = adv.Ceil(), m.Height.Ceil(), m.Ascent.Ceil()
+	r.underlineThick = max(1, int(r.size/14+0.5))
+	return r, nil
+}
+
+// CellSize returns the size in pixels of a cell.
+func (r *Renderer) CellSize() (width, height int) {
+	return r.cellW, r.cellH
+}
+
+// WritePNG writes the image of s, as drawn by Image, to w as a PNG.
+func (r *Renderer) WritePNG(w io.Writer, s vt10x.TerminalState) error {
+	return png.Encode(w, r.Image(s))
+}
+
+// Image returns an image of the active screen of s: its text, with the colors, attributes and line renditions of each
+// cell, and the cursor. Characters wider than a cell, such as East Asian ones in a font that has them, spill into the
+// cell after them if it is blank, and are clipped to their cell otherwise. A screen in reverse video is drawn with the
+// default colors swapped.
+func (r *Renderer) Image(s vt10x.TerminalState) *image.RGBA {
+	if r.palette != nil {
+		s.Palette = r.palette.Indexed[:]
+		s.ForegroundColor, s.BackgroundColor, s.CursorColor = r.palette.Foreground, r.palette.Background, r.palette.Cursor
+	}
+	img := image.NewRGBA(image.Rect(0, 0, s.Cols*r.cellW, s.Rows*r.cellH))
+	draw.Draw(img, img.Bounds(), image.NewUniform(r.color(s, vt10x.DefaultBG)), image.Point{}, draw.Src)
+
+	var scaled *image.RGBA
+	for y, row := range s.PrimaryBuffer {
+		rendition := vt10x.LineSingle
+		if y < len(s.PrimaryRendition) {
+			rendition = s.PrimaryRendition[y]
+		}
+		cursor := -1
+		if y == s.CursorY && s.CursorVisible && !r.hideCursor {
+			cursor = s.CursorX
+		}
+		if rendition == vt10x.LineSingle {
+			r.drawRow(img, y*r.cellH, row, cursor, s)
+			continue
+		}
+
+		// Other renditions draw the row at single width and scale its left half up to fill the row.
+		if scaled == nil {
+			scaled = image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), r.cellH))
+		}
+		draw.Draw(scaled, scaled.Bounds(), image.NewUniform(r.color(s, vt10x.DefaultBG)), image.Point{}, draw.Src)
+		r.drawRow(scaled, 0, row, cursor, s)
+		src := image.Rect(0, 0, scaled.Bounds().Dx()/2, r.cellH)
+		switch rendition {
+		case vt10x.LineDoubleHeightTop:
+			src.Max.Y = r.cellH / 2
+		case vt10x.LineDoubleHeightBottom:
+			src.Min.Y = r.cellH / 2
+		}
+		dst := image.Rect(0, y*r.cellH, img.Bounds().Dx(), (y+1)*r.cellH)
+		xdraw.NearestNeighbor.Scale(img, dst, scaled, src, draw.Src, nil)
+	}
+	return img
+}
+
+// drawRow draws row at pixel row top of img. The cell in column cursor, if any, is drawn as the cursor.
+func (r *Renderer) drawRow(img *image.RGBA, top int, row []vt10x.Glyph, cursor int, s vt10x.TerminalState) {
+	for x, g := range row {
+		if x == cursor {
+			g.FG, g.BG = vt10x.DefaultBG, vt10x.DefaultCursor
+		}
+		cell := image.Rect(x*r.cellW, top, (x+1)*r.cellW, top+r.cellH)
+		if g.BG != vt10x.DefaultBG {
+			draw.Draw(img, cell, image.NewUniform(r.color(s, g.BG)), image.Point{}, draw.Src)
+		}
+		if vt10x.IsInvisible(g.Mode) {
+			continue
+		}
+		fg := r.color(s, g.FG)
+		if vt10x.IsDim(g.Mode) {
+			fg = blend(fg, r.color(s, g.BG))
+		}
+		src := image.NewUniform(fg)
+
+		if g.Char != ' ' && g.Char != 0 {
+			style := regular
+			if vt10x.IsBold(g.Mode) {
+				style |= bold
+			}
+			if vt10x.IsItalic(g.Mode) {
+				style |= italic
+			}
+			face := r.faces[style]
+			clip := cell
+			if adv, ok := face.GlyphAdvance(g.Char); ok && adv.Ceil() > r.cellW && x+1 < len(row) && x+1 != cursor &&
+				(row[x+1].Char == ' ' || row[x+1].Char == 0) && row[x+1].BG == g.BG {
+				clip.Max.X += r.cellW
+			}
+			d := font.Drawer{
+				Dst:  img.SubImage(clip).(*image.RGBA),
+				Src:  src,
+				Face: face,
+				Dot:  fixed.P(cell.Min.X, top+r.ascent),
+			}
+			d.DrawString(string(g.Char))
+		}
+
+		hline := func(y int, src image.Image, dash int) {
+			for px := cell.Min.X; px < cell.Max.X; px++ {
+				if dash > 0 && (px-cell.Min.X)/dash%2 == 1 {
+					continue
+				}
+				draw.Draw(img, image.Rect(px, y, px+1, y+r.underlineThick), src, image.Point{}, draw.Src)
+			}
+		}
+		if vt10x.IsUnderline(g.Mode) {
+			ul := src
+			if g.UnderlineColor != vt10x.DefaultUnderline {
+				ul = image.NewUniform(r.color(s, g.UnderlineColor))
+			}
+			y := min(top+r.ascent+r.underlineThick, top+r.cellH-r.underlineThick)
+			switch g.Underline {
+			case vt10x.UnderlineDouble:
+				hline(y-2*r.underlineThick, ul, 0)
+				hline(y, ul, 0)
+			case vt10x.UnderlineCurly, vt10x.UnderlineDashed:
+				hline(y, ul, 3*r.underlineThick)
+			case vt10x.UnderlineDotted:
+				hline(y, ul, r.underlineThick)
+			default:
+				hline(y, ul, 0)
+			}
+		}
+		if vt10x.IsStrikethrough(g.Mode) {
+			hline(top+r.cellH/2, src, 0)
+		}
+		if vt10x.IsOverline(g.Mode) {
+			hline(top, src, 0)
+		}
+	}
+}
+
+// color returns the RGB value of c in s, with the default colors swapped if s is in reverse video.
+func (r *Renderer) color(s vt10x.TerminalState, c vt10x.Color) color.RGBA {
+	if s.ReverseVideo {
+		c = vt10x.ReverseVideoColor(c)
+	}
+	red, green, blue := s.ResolveColor(c)
+	return color.RGBA{red, green, blue, 0xff}
+}
+
+// blend returns the color halfway between a and b, which dim text is drawn in.
+func blend(a, b color.RGBA) color.RGBA {
+	return color.RGBA{uint8((int(a.R) + int(b.R)) / 2), uint8((int(a.G) + int(b.G)) / 2), uint8((int(a.B) + int(b.B)) / 2), 0xff}
+}
@@ -0,0 +1,141 @@
+package raster
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/hinshun/vt10x"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func newRenderer(t *testing.T, opts ...Option) *Renderer {
+	t.Helper()
+	r, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// cellColors counts the pixels of each color in the cell at x, y.
+func cellColors(r *Renderer, img *image.RGBA, x, y int) map[color.RGBA]int {
+	w, h := r.CellSize()
+	colors := map[color.RGBA]int{}
+	for py := y * h; py < (y+1)*h; py++ {
+		for px := x * w; px < (x+1)*w; px++ {
+			colors[img.RGBAAt(px, py)]++
+		}
+	}
+	return colors
+}
+
+var (
+	black = color.RGBA{0, 0, 0, 0xff}
+	grey  = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	red   = color.RGBA{0xcd, 0, 0, 0xff}
+	green = color.RGBA{0, 0xcd, 0, 0xff}
+)
+
+func TestImage(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(4, 2))
+	term.Write([]byte("\033[31;42mA\033[m \033[4mx\033[?25l"))
+	r := newRenderer(t)
+	img := r.Image(term.DumpState())
+
+	w, h := r.CellSize()
+	if w == 0 || h == 0 || img.Bounds() != image.Rect(0, 0, 4*w, 2*h) {
+		t.Fatalf("expected a 4x2 grid of %dx%d cells, got %v", w, h, img.Bounds())
+	}
+	if c := cellColors(r, img, 0, 0); c[green] == 0 || c[red] == 0 || c[black] != 0 {
+		t.Fatalf("expected red text on green, got %v", c)
+	}
+	if c := cellColors(r, img, 1, 0); len(c) != 1 || c[black] == 0 {
+		t.Fatalf("expected a blank cell, got %v", c)
+	}
+	// The underline spans the whole cell.
+	y := r.ascent + r.underlineThick
+	for px := 2 * w; px < 3*w; px++ {
+		if got := img.RGBAAt(px, y); got != grey {
+			t.Fatalf("expected the underline at (%d,%d), got %v", px, y, got)
+		}
+	}
+}
+
+func TestImageReverseVideo(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(2, 1))
+	term.Write([]byte("\033[?5h\033[?25l\033[42m \033[m"))
+	r := newRenderer(t)
+	img := r.Image(term.DumpState())
+
+	if c := cellColors(r, img, 0, 0); len(c) != 1 || c[green] == 0 {
+		t.Fatalf("expected an explicit background kept, got %v", c)
+	}
+	if c := cellColors(r, img, 1, 0); len(c) != 1 || c[grey] == 0 {
+		t.Fatalf("expected the default foreground as the background, got %v", c)
+	}
+}
+
+func TestImageCursorAndPalette(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(2, 1))
+	p := vt10x.DefaultPalette()
+	p.Background = 0x102030
+	p.Cursor = 0x00ff00
+
+	img := newRenderer(t, WithPalette(p)).Image(term.DumpState())
+	r := newRenderer(t)
+	if c := cellColors(r, img, 0, 0); c[color.RGBA{0, 0xff, 0, 0xff}] == 0 {
+		t.Fatalf("expected a cursor block, got %v", c)
+	}
+	if c := cellColors(r, img, 1, 0); len(c) != 1 || c[color.RGBA{0x10, 0x20, 0x30, 0xff}] == 0 {
+		t.Fatalf("expected the palette's background, got %v", c)
+	}
+
+	img = newRenderer(t, WithoutCursor()).Image(term.DumpState())
+	if c := cellColors(r, img, 0, 0); len(c) != 1 || c[black] == 0 {
+		t.Fatalf("expected no cursor, got %v", c)
+	}
+}
+
+func TestImageDoubleWidth(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(4, 1))
+	term.Write([]byte("\033#6\033[41m \033[m\033[?25l"))
+	r := newRenderer(t)
+	img := r.Image(term.DumpState())
+	// The first cell is drawn over the first two cells of the image.
+	if c := cellColors(r, img, 1, 0); len(c) != 1 || c[red] == 0 {
+		t.Fatalf("expected the first cell doubled, got %v", c)
+	}
+	if c := cellColors(r, img, 2, 0); len(c) != 1 || c[black] == 0 {
+		t.Fatalf("expected the second cell at the third, got %v", c)
+	}
+}
+
+func TestWithFont(t *testing.T) {
+	if _, err := New(WithFont([]byte("not a font"), nil, nil, nil)); err == nil {
+		t.Fatal("expected an error for a bad font")
+	}
+	r := newRenderer(t, WithFont(goregular.TTF, nil, nil, nil), WithFontSize(20))
+	mono := newRenderer(t, WithFontSize(20))
+	if w, _ := r.CellSize(); w == 0 {
+		t.Fatal("expected a cell width")
+	}
+	if _, h := mono.CellSize(); h < 20 {
+		t.Fatalf("expected cells at least the font size high, got %d", h)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WritePNG(&buf, vt10x.New(vt10x.WithSize(3, 2)).DumpState()); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, h := r.CellSize()
+	if img.Bounds() != image.Rect(0, 0, 3*w, 2*h) {
+		t.Fatalf("expected a %dx%d PNG, got %v", 3*w, 2*h, img.Bounds())
+	}
+}
@@ -0,0 +1,130 @@
+package vt10x
+
+import "regexp"
+
+// Redaction masks sensitive output before it is written to the screen buffers, so that no state dumped or recorded
+// from the terminal contains it. Masked cells keep their attributes; only their characters are replaced.
+type Redaction struct {
+	// Patterns are masked wherever they match a row once it is complete, which is when the cursor leaves it with a
+	// newline, before the row can scroll into the scrollback. Each row is matched on its own, so a match cannot span
+	// a wrapped line.
+	Patterns []*regexp.Regexp
+
+	// Prompts mask what follows them: once a write leaves the cursor just past a match of one of them, such as
+	// "Password: ", the characters written on that row from the cursor on are masked until the cursor leaves the row
+	// with a newline. Matches are against the row's text up to the cursor, ignoring trailing spaces.
+	Prompts []*regexp.Regexp
+
+	// Mask is the character masked cells are given. The default is '*'.
+	Mask rune
+
+	// Filter, if set, is called with each character about to be written at column x and row y, after masking, and
+	// returns the glyph to write instead. It is called while the terminal is locked, so it must not call back into
+	// the terminal.
+	Filter func(x, y int, g Glyph) Glyph
+}
+
+// WithRedaction masks sensitive output as set out in r.
+func WithRedaction(r Redaction) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.redaction = &r
+	}
+}
+
+// redaction is a Redaction in effect, with the row being masked after a prompt.
+type redaction struct {
+	Redaction
+	maskY, maskX int // the row and first column masked after a prompt; maskY is -1 if none is
+	glyph        Glyph
+}
+
+func newRedaction(r Redaction) *redaction {
+	if r.Mask == 0 {
+		r.Mask = '*'
+	}
+	return &redaction{Redaction: r, maskY: -1}
+}
+
+// redactGlyph returns the character and attributes to write in place of c at the cursor.
+func (t *State) redactGlyph(c rune, attr *Glyph) (rune, *Glyph) {
+	r := t.redaction
+	if t.cur.Y == r.maskY && t.cur.X >= r.maskX {
+		c = r.Mask
+	}
+	if r.Filter == nil {
+		return c, attr
+	}
+	g := *attr
+	g.Char = c
+	r.glyph = r.Filter(t.cur.X, t.cur.Y, g)
+	return r.glyph.Char, &r.glyph
+}
+
+// redactRow masks the matches of the redaction's patterns on row y of the active screen, which is complete, and ends
+// masking after a prompt on it.
+func (t *State) redactRow(y int) {
+	r := t.redaction
+	if y == r.maskY {
+		r.maskY = -1
+	}
+	if len(r.Patterns) == 0 || y < 0 || y >= len(t.lines) || isSameLine(t.lines[y], t.blank) {
+		return
+	}
+	text, cols := rowText(t.lines[y], len(t.lines[y]))
+	var l line
+	for _, re := range r.Patterns {
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			if l == nil {
+				l = t.writableLine(y)
+				t.markDirty(y)
+				t.changed |= ChangedScreen
+			}
+			for i := m[0]; i < m[1]; i++ {
+				if x := cols[i]; x >= 0 {
+					l[x].char = r.Mask
+				}
+			}
+		}
+	}
+}
+
+// checkPrompts starts masking the rest of the cursor's row if the text before the cursor ends with a prompt. It is
+// called at the end of each write, where a program that prompted waits for input.
+func (t *State) checkPrompts() {
+	r := t.redaction
+	y := t.cur.Y
+	if len(r.Prompts) == 0 || y == r.maskY || y < 0 || y >= len(t.lines) || t.cur.X <= 0 {
+		return
+	}
+	text, _ := rowText(t.lines[y], min(t.cur.X, len(t.lines[y])))
+	for len(text) > 0 && text[len(text)-1] == ' ' {
+		text = text[:len(text)-1]
+	}
+	for _, re := range r.Prompts {
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			if m[1] == len(text) && m[1] > m[0] {
+				r.maskY, r.maskX = y, t.cur.X
+				return
+			}
+		}
+	}
+}
+
+// rowText returns the text of the first n cells of l, and the column of each of its bytes: the cell the rune it
+// belongs to was in.
+func rowText(l line, n int) (string, []int) {
+	var b []byte
+	var cols []int
+	for x := 0; x < n; x++ {
+		c := l[x].char
+		if c == 0 {
+			c = ' '
+		}
+		before := len(b)
+		b = append(b, string(c)...)
+		for range len(b) - before {
+			cols = append(cols, x)
+		}
+	}
+	return string(b), cols
+}
@@ -0,0 +1,83 @@
+package vt10x
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// rowString returns the text of row y of term.
+func rowString(term Terminal, y int) string {
+	cols, _ := term.Size()
+	var b strings.Builder
+	for x := 0; x < cols; x++ {
+		b.WriteRune(term.Cell(x, y).Char)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func TestRedactionPatterns(t *testing.T) {
+	term := New(WithSize(40, 3), WithScrollback(10), WithRedaction(Redaction{
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`AKIA[0-9A-Z]{8}`)},
+	}))
+	writeSeq(t, term, "\033[1mkey=AKIA1234ABCD ok")
+	if got := rowString(term, 0); got != "key=AKIA1234ABCD ok" {
+		t.Errorf("expected an incomplete row left alone, got %q", got)
+	}
+	writeSeq(t, term, "\r\n")
+	if got := rowString(term, 0); got != "key=************ ok" {
+		t.Errorf("expected the key masked, got %q", got)
+	}
+	if term.Cell(4, 0).Mode&attrBold == 0 {
+		t.Error("expected masked cells to keep their attributes")
+	}
+
+	writeSeq(t, term, "AKIA00000000\r\n\r\n")
+	lines, _ := term.TakeScrollback()
+	if len(lines) != 1 || strings.TrimRight(string(lines[0]), " ") != "key=************ ok" {
+		t.Errorf("expected the scrollback to hold the masked row, got %q", lines)
+	}
+	if got := rowString(term, 0); got != "************" {
+		t.Errorf("expected the second key masked, got %q", got)
+	}
+}
+
+func TestRedactionPrompts(t *testing.T) {
+	term := New(WithSize(40, 3), WithRedaction(Redaction{
+		Prompts: []*regexp.Regexp{regexp.MustCompile(`(?i)password:`)},
+		Mask:    '#',
+	}))
+	writeSeq(t, term, "Password: ")
+	writeSeq(t, term, "hunter2")
+	writeSeq(t, term, "\r\nhunter2")
+	if got := rowString(term, 0); got != "Password: #######" {
+		t.Errorf("expected the password masked, got %q", got)
+	}
+	if got := rowString(term, 1); got != "hunter2" {
+		t.Errorf("expected masking to end with the row, got %q", got)
+	}
+
+	// A prompt in the middle of a write's output is not waiting for input.
+	writeSeq(t, term, "\r\npassword: shown\r\n")
+	if got := rowString(term, 1); got != "password: shown" {
+		t.Errorf("expected no masking, got %q", got)
+	}
+}
+
+func TestRedactionFilter(t *testing.T) {
+	term := New(WithRedaction(Redaction{
+		Filter: func(x, y int, g Glyph) Glyph {
+			if g.Char >= '0' && g.Char <= '9' {
+				g.Char, g.FG = 'X', Red
+			}
+			return g
+		},
+	}))
+	writeSeq(t, term, "pin 1234")
+	if got := rowString(term, 0); got != "pin XXXX" {
+		t.Errorf("expected digits replaced, got %q", got)
+	}
+	if g := term.Cell(4, 0); g.FG != Red {
+		t.Errorf("expected the filter's colors, got %d", g.FG)
+	}
+}
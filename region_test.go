@@ -0,0 +1,97 @@
+package vt10x
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDECSTBMValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		seq         string
+		top, bottom int
+		home        bool // whether the cursor moved home
+	}{
+		{"default", "\033[r", 0, 9, true},
+		{"explicit", "\033[3;7r", 2, 6, true},
+		{"zeros are defaults", "\033[0;0r", 0, 9, true},
+		{"top only", "\033[4r", 3, 9, true},
+		{"bottom beyond screen", "\033[2;99r", 1, 9, true},
+		{"two lines", "\033[9;10r", 8, 9, true},
+		{"single line", "\033[5;5r", 1, 4, false},
+		{"inverted", "\033[7;3r", 1, 4, false},
+		{"top beyond screen", "\033[20r", 1, 4, false},
+		{"top beyond clamped bottom", "\033[12;99r", 1, 4, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			term := New(WithSize(10, 10))
+			// Start from a known region and a cursor away from home.
+			writeSeq(t, term, "\033[2;5r\033[4;6H"+tc.seq)
+
+			state := term.DumpState()
+			if state.ScrollTop != tc.top || state.ScrollBottom != tc.bottom {
+				t.Fatalf("expected region %d-%d, got %d-%d", tc.top, tc.bottom, state.ScrollTop, state.ScrollBottom)
+			}
+			wantX, wantY := 5, 3
+			if tc.home {
+				wantX, wantY = 0, 0
+			}
+			if cur := term.Cursor(); cur.X != wantX || cur.Y != wantY {
+				t.Fatalf("expected cursor at (%d,%d), got (%d,%d)", wantX, wantY, cur.X, cur.Y)
+			}
+		})
+	}
+}
+
+func TestDECSTBMHomeWithOrigin(t *testing.T) {
+	term := New(WithSize(10, 10))
+
+	writeSeq(t, term, "\033[?6h\033[4;8r")
+	if cur := term.Cursor(); cur.X != 0 || cur.Y != 3 {
+		t.Fatalf("expected the cursor at the region's home (0,3) in origin mode, got (%d,%d)", cur.X, cur.Y)
+	}
+}
+
+func TestDECSTBMScrollsOnlyRegion(t *testing.T) {
+	term := New(WithSize(5, 5))
+
+	writeSeq(t, term, "a\r\nb\r\nc\r\nd\r\ne\033[2;4r\033[4;1H\n")
+	for y, want := range []string{"a", "c", "d", " ", "e"} {
+		if got := extractStr(term, 0, 0, y); got != want {
+			t.Fatalf("row %d: expected %q, got %q", y, want, got)
+		}
+	}
+}
+
+func TestRegionScrollMultipleRows(t *testing.T) {
+	for _, tc := range []struct {
+		seq  string
+		want []string
+	}{
+		{"\033[2;5r\033[2S", []string{"a", "d", "e", " ", " ", "f"}},
+		{"\033[2;5r\033[2T", []string{"a", " ", " ", "b", "c", "f"}},
+		{"\033[2;5r\033[9T", []string{"a", " ", " ", " ", " ", "f"}},
+		{"\033[3S\033[1T", []string{" ", "d", "e", "f", " ", " "}},
+	} {
+		term := New(WithSize(5, 6))
+		writeSeq(t, term, "a\r\nb\r\nc\r\nd\r\ne\r\nf"+tc.seq)
+		for y, want := range tc.want {
+			if got := extractStr(term, 0, 0, y); got != want {
+				t.Errorf("%q: row %d: expected %q, got %q", tc.seq, y, want, got)
+			}
+		}
+	}
+}
+
+// TestScrollReusesRows ensures that scrolling text up the screen moves rows rather than allocating new ones.
+func TestScrollReusesRows(t *testing.T) {
+	term := New(WithSize(80, 24))
+	line := []byte(strings.Repeat("x", 40) + "\r\n")
+	for range 24 {
+		term.Write(line)
+	}
+	if n := testing.AllocsPerRun(100, func() { term.Write(line) }); n != 0 {
+		t.Fatalf("expected scrolling not to allocate, got %v allocations", n)
+	}
+}
@@ -0,0 +1,36 @@
+// Package render draws an emulated terminal onto a cell-based display, such as a tcell.Screen or termbox, for programs
+// that embed vt10x in a text user interface.
+//
+// A Renderer remembers the cells it last drew and, on each Draw, sets only those that changed since, so following a
+// busy terminal costs the display backend no more than the changes themselves. The display is reached through the
+// small Screen interface, whose methods match a tcell.Screen's but for the style, so the glue for a backend is the
+// mapping from a Style to the backend's own:
+//
+//	type tcellScreen struct{ tcell.Screen }
+//
+//	func (s tcellScreen) SetContent(x, y int, ch rune, st render.Style) {
+//		style := tcell.StyleDefault.Foreground(tcellColor(st.FG)).Background(tcellColor(st.BG)).
+//			Bold(st.Attrs&render.AttrBold != 0).Italic(st.Attrs&render.AttrItalic != 0) // and so on
+//		s.Screen.SetContent(x, y, ch, nil, style)
+//	}
+//
+//	func tcellColor(c vt10x.Color) tcell.Color {
+//		switch {
+//		case c == vt10x.DefaultFG, c == vt10x.DefaultBG:
+//			return tcell.ColorDefault
+//		case c < 256:
+//			return tcell.PaletteColor(int(c))
+//		}
+//		return tcell.NewHexColor(int32(c))
+//	}
+//
+// Driving it from the terminal's updates then redraws after every burst of output:
+//
+//	r := render.New(term, tcellScreen{screen})
+//	updates, cancel := term.(vt10x.Subscriber).Subscribe()
+//	defer cancel()
+//	r.Draw()
+//	for u := range updates {
+//		r.DrawUpdate(u)
+//	}
+package render
@@ -0,0 +1,212 @@
+package render
+
+import "github.com/hinshun/vt10x"
+
+// Screen is the display a Renderer draws on. Cells set with SetContent and the cursor need only appear once Show is
+// called, as with a tcell.Screen.
+type Screen interface {
+	// SetContent sets the cell at column x of row y, both zero-based, to ch drawn in style.
+	SetContent(x, y int, ch rune, style Style)
+
+	// ShowCursor shows the cursor at column x of row y.
+	ShowCursor(x, y int)
+
+	// HideCursor hides the cursor.
+	HideCursor()
+
+	// Show makes the changes since the last call visible.
+	Show()
+}
+
+// AttrMask is a set of text attributes.
+type AttrMask uint16
+
+// Text attributes of a Style. Reverse video is not among them: the terminal has already swapped the colors of
+// reversed cells.
+const (
+	AttrBold AttrMask = 1 << iota
+	AttrDim
+	AttrItalic
+	AttrUnderline
+	AttrBlink
+	AttrStrikethrough
+	AttrOverline
+	AttrInvisible
+)
+
+// Style is the look of a cell, in terms a display backend can map to its own style model.
+type Style struct {
+	// FG and BG are the colors of the text and of the cell: an indexed color below 256, vt10x.DefaultFG or
+	// vt10x.DefaultBG for the display's default colors, or otherwise a 24-bit 0xRRGGBB color.
+	FG, BG vt10x.Color
+
+	Attrs AttrMask
+
+	// Underline is the kind of underline when Attrs has AttrUnderline, and UnderlineColor its color, which is
+	// vt10x.DefaultUnderline to draw it in FG.
+	Underline      vt10x.UnderlineStyle
+	UnderlineColor vt10x.Color
+}
+
+// StyleOf returns the style of a cell holding g.
+func StyleOf(g vt10x.Glyph) Style {
+	s := Style{FG: g.FG, BG: g.BG, UnderlineColor: g.UnderlineColor}
+	for _, a := range []struct {
+		is   func(int16) bool
+		attr AttrMask
+	}{
+		{vt10x.IsBold, AttrBold},
+		{vt10x.IsDim, AttrDim},
+		{vt10x.IsItalic, AttrItalic},
+		{vt10x.IsUnderline, AttrUnderline},
+		{vt10x.IsBlink, AttrBlink},
+		{vt10x.IsStrikethrough, AttrStrikethrough},
+		{vt10x.IsOverline, AttrOverline},
+		{vt10x.IsInvisible, AttrInvisible},
+	} {
+		if a.is(g.Mode) {
+			s.Attrs |= a.attr
+		}
+	}
+	if s.Attrs&AttrUnderline != 0 {
+		s.Underline = g.Underline
+		if s.Underline == vt10x.UnderlineNone {
+			s.Underline = vt10x.UnderlineSingle
+		}
+	}
+	return s
+}
+
+// drawnCell is a cell as a Renderer last drew it.
+type drawnCell struct {
+	ch    rune
+	style Style
+}
+
+// Renderer draws a terminal onto a Screen, setting only the cells that changed since it last drew.
+type Renderer struct {
+	term   vt10x.View
+	screen Screen
+
+	// cells are the cells last drawn, one row per row of the terminal; nil until the first Draw and after a resize.
+	cells [][]drawnCell
+
+	cursorX, cursorY int
+	cursorVisible    bool
+
+	// reverse is whether the cells were last drawn in reverse video (DECSCNM), with the default colors swapped.
+	reverse bool
+}
+
+// New returns a Renderer that draws term onto screen. Nothing is drawn until the first Draw.
+func New(term vt10x.View, screen Screen) *Renderer {
+	return &Renderer{term: term, screen: screen}
+}
+
+// Draw brings the screen up to date with the terminal, comparing every cell with the one last drawn there, and shows
+// it. The first Draw, and the first after the terminal is resized, sets every cell.
+func (r *Renderer) Draw() {
+	r.term.Lock()
+	cols, rows := r.term.Size()
+	r.resize(cols, rows)
+	r.reversed()
+	for y := range r.cells {
+		r.drawRow(y)
+	}
+	r.drawCursor()
+	r.term.Unlock()
+	r.screen.Show()
+}
+
+// DrawUpdate brings the screen up to date with the terminal after u, comparing only the rows u names, and shows it.
+// The updates must come from a subscription taken before the previous Draw or DrawUpdate, or changes in between are
+// missed. If the terminal was resized or switched in or out of reverse video, or nothing has been drawn yet, it draws
+// every cell as Draw does.
+func (r *Renderer) DrawUpdate(u vt10x.Update) {
+	r.term.Lock()
+	cols, rows := r.term.Size()
+	if resized := r.resize(cols, rows); r.reversed() || resized {
+		for y := range r.cells {
+			r.drawRow(y)
+		}
+	} else {
+		for _, y := range u.Rows {
+			if y >= 0 && y < len(r.cells) {
+				r.drawRow(y)
+			}
+		}
+	}
+	r.drawCursor()
+	r.term.Unlock()
+	r.screen.Show()
+}
+
+// Invalidate forgets what was drawn, so the next Draw or DrawUpdate sets every cell, such as after the screen was
+// cleared by something else.
+func (r *Renderer) Invalidate() {
+	r.cells = nil
+}
+
+// resize makes cells hold cols by rows cells, reporting whether it had to, in which case every cell must be drawn.
+func (r *Renderer) resize(cols, rows int) bool {
+	if r.cells != nil && len(r.cells) == rows && (rows == 0 || len(r.cells[0]) == cols) {
+		return false
+	}
+	r.cells = make([][]drawnCell, rows)
+	for y := range r.cells {
+		r.cells[y] = make([]drawnCell, cols)
+		for x := range r.cells[y] {
+			// No cell holds a negative rune, so every cell differs and is drawn.
+			r.cells[y][x] = drawnCell{ch: -1}
+		}
+	}
+	r.cursorVisible = false
+	r.cursorX, r.cursorY = -1, -1
+	return true
+}
+
+// reversed notes whether the terminal is in reverse video, reporting whether that changed since the cells were last
+// drawn. The terminal must be locked.
+func (r *Renderer) reversed() bool {
+	reverse := r.term.Mode()&vt10x.ModeReverse != 0
+	changed := reverse != r.reverse
+	r.reverse = reverse
+	return changed
+}
+
+// drawRow sets the cells of row y that changed since they were last drawn. The terminal must be locked.
+func (r *Renderer) drawRow(y int) {
+	row := r.cells[y]
+	for x := range row {
+		g := r.term.Cell(x, y)
+		c := drawnCell{ch: g.Char, style: StyleOf(g)}
+		if r.reverse {
+			c.style.FG, c.style.BG = vt10x.ReverseVideoColor(c.style.FG), vt10x.ReverseVideoColor(c.style.BG)
+		}
+		if c.ch == 0 {
+			c.ch = ' '
+		}
+		if c != row[x] {
+			row[x] = c
+			// The second half of a wide character is drawn with the first, as SetContent draws wide runes over two
+			// cells.
+			if !vt10x.IsWide(g.Mode) || g.Char != 0 {
+				r.screen.SetContent(x, y, c.ch, c.style)
+			}
+		}
+	}
+}
+
+// drawCursor moves, shows or hides the cursor if it changed since it was last drawn. The terminal must be locked.
+func (r *Renderer) drawCursor() {
+	cur, visible := r.term.Cursor(), r.term.CursorVisible()
+	switch {
+	case !visible:
+		if r.cursorVisible || r.cursorX < 0 {
+			r.screen.HideCursor()
+		}
+	case !r.cursorVisible || cur.X != r.cursorX || cur.Y != r.cursorY:
+		r.screen.ShowCursor(cur.X, cur.Y)
+	}
+	r.cursorX, r.cursorY, r.cursorVisible = cur.X, cur.Y, visible
+}
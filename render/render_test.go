@@ -0,0 +1,148 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/hinshun/vt10x"
+)
+
+type cellPos struct{ x, y int }
+
+// fakeScreen records what a Renderer draws.
+type fakeScreen struct {
+	cells   map[cellPos]drawnCell
+	set     int
+	cursor  cellPos
+	visible bool
+	shown   int
+}
+
+func newFakeScreen() *fakeScreen {
+	return &fakeScreen{cells: make(map[cellPos]drawnCell)}
+}
+
+func (s *fakeScreen) SetContent(x, y int, ch rune, style Style) {
+	s.cells[cellPos{x, y}] = drawnCell{ch, style}
+	s.set++
+}
+
+func (s *fakeScreen) ShowCursor(x, y int) { s.cursor, s.visible = cellPos{x, y}, true }
+func (s *fakeScreen) HideCursor()         { s.visible = false }
+func (s *fakeScreen) Show()               { s.shown++ }
+
+func (s *fakeScreen) row(y, cols int) string {
+	var r []rune
+	for x := 0; x < cols; x++ {
+		r = append(r, s.cells[cellPos{x, y}].ch)
+	}
+	return string(r)
+}
+
+func TestDrawSetsOnlyChangedCells(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(4, 2))
+	screen := newFakeScreen()
+	r := New(term, screen)
+
+	r.Draw()
+	if screen.set != 8 || screen.shown != 1 {
+		t.Fatalf("expected the first draw to set all 8 cells and show them, got %d cells, %d shows", screen.set, screen.shown)
+	}
+	if !screen.visible || screen.cursor != (cellPos{0, 0}) {
+		t.Fatalf("expected the cursor shown at the origin, got %+v visible %v", screen.cursor, screen.visible)
+	}
+
+	screen.set = 0
+	term.Write([]byte("\033[2;2H\033[1;31mhi"))
+	r.Draw()
+	if screen.set != 2 || screen.row(1, 4) != " hi " {
+		t.Fatalf("expected 2 cells set for %q, got %d and %q", "hi", screen.set, screen.row(1, 4))
+	}
+	want := Style{FG: vt10x.LightRed, BG: vt10x.DefaultBG, Attrs: AttrBold, UnderlineColor: vt10x.DefaultUnderline}
+	if got := screen.cells[cellPos{1, 1}].style; got != want {
+		t.Fatalf("expected style %+v, got %+v", want, got)
+	}
+	if screen.cursor != (cellPos{3, 1}) {
+		t.Fatalf("expected the cursor after the text, got %+v", screen.cursor)
+	}
+
+	screen.set = 0
+	r.Draw()
+	if screen.set != 0 {
+		t.Fatalf("expected nothing set without changes, got %d cells", screen.set)
+	}
+
+	term.Write([]byte("\033[?25l"))
+	r.Draw()
+	if screen.visible {
+		t.Fatal("expected the cursor hidden")
+	}
+}
+
+func TestDrawUpdate(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(4, 3))
+	screen := newFakeScreen()
+	r := New(term, screen)
+	updates, cancel := term.(vt10x.Subscriber).Subscribe()
+	defer cancel()
+
+	r.Draw()
+	screen.set = 0
+	term.Write([]byte("\033[3;1Hab"))
+	r.DrawUpdate(<-updates)
+	if screen.set != 2 || screen.row(2, 4) != "ab  " {
+		t.Fatalf("expected the changed row drawn, got %d cells and %q", screen.set, screen.row(2, 4))
+	}
+
+	// A resize draws everything again.
+	screen.set = 0
+	term.Resize(5, 2)
+	r.DrawUpdate(<-updates)
+	if screen.set != 10 {
+		t.Fatalf("expected every cell drawn after a resize, got %d", screen.set)
+	}
+
+	screen.set = 0
+	r.Invalidate()
+	r.Draw()
+	if screen.set != 10 {
+		t.Fatalf("expected every cell drawn after Invalidate, got %d", screen.set)
+	}
+}
+
+func TestDrawReverseVideo(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(2, 1))
+	screen := newFakeScreen()
+	r := New(term, screen)
+	updates, cancel := term.(vt10x.Subscriber).Subscribe()
+	defer cancel()
+
+	term.Write([]byte("\033[31mx"))
+	r.Draw()
+	<-updates
+	screen.set = 0
+	term.Write([]byte("\033[?5h"))
+	r.DrawUpdate(<-updates)
+	if screen.set != 2 {
+		t.Fatalf("expected every cell drawn after switching to reverse video, got %d", screen.set)
+	}
+	if got := screen.cells[cellPos{0, 0}].style; got.FG != vt10x.Red || got.BG != vt10x.DefaultFG {
+		t.Fatalf("expected red text on the default foreground, got %+v", got)
+	}
+	if got := screen.cells[cellPos{1, 0}].style; got.FG != vt10x.DefaultBG || got.BG != vt10x.DefaultFG {
+		t.Fatalf("expected the default colors swapped, got %+v", got)
+	}
+}
+
+func TestStyleOf(t *testing.T) {
+	term := vt10x.New(vt10x.WithSize(4, 1))
+	term.Write([]byte("\033[2;3;4:3;9;53;58;5;4m\033[38;2;1;2;3mx"))
+	got := StyleOf(term.Cell(0, 0))
+	want := Style{
+		FG: 0x010203, BG: vt10x.DefaultBG,
+		Attrs:     AttrDim | AttrItalic | AttrUnderline | AttrStrikethrough | AttrOverline,
+		Underline: vt10x.UnderlineCurly, UnderlineColor: vt10x.Blue,
+	}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
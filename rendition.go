@@ -0,0 +1,49 @@
+package vt10x
+
+// LineRendition is how a line is drawn: at normal size, or with its characters twice as wide, and for double height,
+// with the line showing the top or bottom half of them. Renderers draw the first half of the columns of a line that
+// is not single width, doubled; the emulator itself keeps every line the full width of the screen.
+type LineRendition uint8
+
+const (
+	LineSingle             LineRendition = iota // DECSWL
+	LineDoubleWidth                             // DECDWL
+	LineDoubleHeightTop                         // DECDHL, top half
+	LineDoubleHeightBottom                      // DECDHL, bottom half
+)
+
+// setRendition performs ESC # 3 to ESC # 6, setting the rendition of the cursor row.
+func (t *State) setRendition(r LineRendition) {
+	y := t.cur.Y
+	if y < 0 || y >= len(t.meta) || t.meta[y].rendition == r {
+		return
+	}
+	t.meta[y].rendition = r
+	t.markDirty(y)
+	t.changed |= ChangedScreen
+}
+
+// renditions returns the rendition of each row of meta, or nil if every row is single width.
+func renditions(meta []rowMeta) []LineRendition {
+	var rs []LineRendition
+	for y := range meta {
+		if meta[y].rendition == LineSingle {
+			continue
+		}
+		if rs == nil {
+			rs = make([]LineRendition, len(meta))
+		}
+		rs[y] = meta[y].rendition
+	}
+	return rs
+}
+
+// restoreRenditions sets the renditions of the rows of meta from rs, as returned by renditions.
+func restoreRenditions(meta []rowMeta, rs []LineRendition) {
+	for y := range meta {
+		meta[y].rendition = LineSingle
+		if y < len(rs) && rs[y] <= LineDoubleHeightBottom {
+			meta[y].rendition = rs[y]
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineRendition(t *testing.T) {
+	term := New(WithSize(10, 5))
+	if s := term.DumpState(); s.PrimaryRendition != nil {
+		t.Fatalf("expected no renditions while every row is single width, got %v", s.PrimaryRendition)
+	}
+
+	writeSeq(t, term, "\033#6wide\r\n\033#3big\r\n\033#4big\r\n\033#6\033#5single")
+	want := []LineRendition{LineDoubleWidth, LineDoubleHeightTop, LineDoubleHeightBottom, LineSingle, LineSingle}
+	if got := term.DumpState().PrimaryRendition; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected renditions %v, got %v", want, got)
+	}
+
+	// Renditions move with their rows, and rows scrolled in are single width.
+	writeSeq(t, term, "\033[5;1H\n")
+	want = []LineRendition{LineDoubleHeightTop, LineDoubleHeightBottom, LineSingle, LineSingle, LineSingle}
+	if got := term.DumpState().PrimaryRendition; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected renditions %v after scrolling, got %v", want, got)
+	}
+
+	// Erasing in a line keeps its rendition, and erasing the display resets it.
+	writeSeq(t, term, "\033[1;1H\033[2K\033[X")
+	if got := term.DumpState().PrimaryRendition[0]; got != LineDoubleHeightTop {
+		t.Fatalf("expected EL and ECH to keep the rendition, got %v", got)
+	}
+	writeSeq(t, term, "\033[2J")
+	if got := term.DumpState().PrimaryRendition; got != nil {
+		t.Fatalf("expected ED to return every row to single width, got %v", got)
+	}
+}
+
+func TestLineRenditionPerScreen(t *testing.T) {
+	term := New(WithSize(10, 3))
+	writeSeq(t, term, "\033#6\033[?47h")
+	s := term.DumpState()
+	if s.PrimaryRendition != nil || len(s.AlternateRendition) != 3 || s.AlternateRendition[0] != LineDoubleWidth {
+		t.Fatalf("expected the rendition to stay with the primary screen, got %v and %v", s.PrimaryRendition, s.AlternateRendition)
+	}
+
+	restored := New(WithState(s))
+	if got := restored.DumpState().AlternateRendition; !reflect.DeepEqual(got, s.AlternateRendition) {
+		t.Fatalf("expected renditions restored, got %v", got)
+	}
+}
+
+func TestAlignmentTestDefaults(t *testing.T) {
+	term := New(WithSize(4, 2))
+	writeSeq(t, term, "\033#6\033[1;4;31m\033(0\033#8")
+
+	if s := term.DumpState(); s.PrimaryRendition != nil {
+		t.Fatalf("expected DECALN to make every line single width, got %v", s.PrimaryRendition)
+	}
+	if g := term.Cell(3, 1); g.Char != 'E' || g.FG != DefaultFG || g.Mode != 0 {
+		t.Fatalf("expected an E of the default rendition, got %+v", g)
+	}
+	if c := term.Cursor(); c.Attr.FG != Red || !IsBold(c.Attr.Mode) {
+		t.Fatalf("expected the cursor attributes kept, got %+v", c.Attr)
+	}
+}
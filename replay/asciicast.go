@@ -0,0 +1,138 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+// Chunk is output a session wrote at Time since it started, or, if Cols and Rows are set, a resize of its terminal.
+type Chunk struct {
+	Time       time.Duration
+	Data       []byte
+	Cols, Rows int
+}
+
+// ChunkReader is a source of a session's chunks, in order.
+type ChunkReader interface {
+	// ReadChunk returns the next chunk, or io.EOF once there are no more.
+	ReadChunk() (Chunk, error)
+}
+
+// AsciicastReader reads the chunks of an asciicast v2 recording, as written by asciinema.
+type AsciicastReader struct {
+	sc         *bufio.Scanner
+	cols, rows int
+	line       int
+}
+
+// maxAsciicastLine is the longest event line an AsciicastReader accepts.
+const maxAsciicastLine = 16 << 20
+
+// NewAsciicastReader returns an AsciicastReader of the recording r, having read its header.
+func NewAsciicastReader(r io.Reader) (*AsciicastReader, error) {
+	a := &AsciicastReader{sc: bufio.NewScanner(r)}
+	a.sc.Buffer(nil, maxAsciicastLine)
+	if !a.sc.Scan() {
+		if err := a.sc.Err(); err != nil {
+			return nil, fmt.Errorf("replay: reading asciicast header: %w", err)
+		}
+		return nil, errors.New("replay: empty asciicast")
+	}
+	a.line = 1
+	var header struct {
+		Version int `json:"version"`
+		Width   int `json:"width"`
+		Height  int `json:"height"`
+	}
+	if err := json.Unmarshal(a.sc.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("replay: parsing asciicast header: %w", err)
+	}
+	if header.Version != 2 {
+		return nil, fmt.Errorf("replay: unsupported asciicast version %d", header.Version)
+	}
+	a.cols, a.rows = header.Width, header.Height
+	return a, nil
+}
+
+// Size returns the size of the recorded terminal, from the header.
+func (a *AsciicastReader) Size() (cols, rows int) {
+	return a.cols, a.rows
+}
+
+// ReadChunk returns the next output or resize event of the recording. Input, marker and other events are skipped.
+func (a *AsciicastReader) ReadChunk() (Chunk, error) {
+	for a.sc.Scan() {
+		a.line++
+		if len(a.sc.Bytes()) == 0 {
+			continue
+		}
+		var event [3]any
+		if err := json.Unmarshal(a.sc.Bytes(), &event); err != nil {
+			return Chunk{}, fmt.Errorf("replay: asciicast line %d: %w", a.line, err)
+		}
+		t, okTime := event[0].(float64)
+		code, okCode := event[1].(string)
+		data, okData := event[2].(string)
+		if !okTime || !okCode || !okData {
+			return Chunk{}, fmt.Errorf("replay: asciicast line %d: malformed event", a.line)
+		}
+		c := Chunk{Time: time.Duration(t * float64(time.Second))}
+		switch code {
+		case "o":
+			c.Data = []byte(data)
+		case "r":
+			if _, err := fmt.Sscanf(data, "%dx%d", &c.Cols, &c.Rows); err != nil || c.Cols <= 0 || c.Rows <= 0 {
+				return Chunk{}, fmt.Errorf("replay: asciicast line %d: bad size %q", a.line, data)
+			}
+		default:
+			continue
+		}
+		return c, nil
+	}
+	if err := a.sc.Err(); err != nil {
+		return Chunk{}, fmt.Errorf("replay: reading asciicast: %w", err)
+	}
+	return Chunk{}, io.EOF
+}
+
+// ChunkSlice is a ChunkReader of chunks held in memory, such as timestamped output captured by a host.
+type ChunkSlice []Chunk
+
+// ReadChunk returns the first chunk and removes it from s.
+func (s *ChunkSlice) ReadChunk() (Chunk, error) {
+	if len(*s) == 0 {
+		return Chunk{}, io.EOF
+	}
+	c := (*s)[0]
+	*s = (*s)[1:]
+	return c, nil
+}
+
+// Feed writes every chunk src reads to term as fast as it can, resizing term for resize chunks, and returns once src
+// is exhausted, such as to bring a terminal to the end state of a recording.
+func Feed(term vt10x.Terminal, src ChunkReader) error {
+	for {
+		c, err := src.ReadChunk()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		apply(term, c)
+	}
+}
+
+// apply writes c to term, or resizes term if c is a resize.
+func apply(term vt10x.Terminal, c Chunk) {
+	if c.Cols > 0 && c.Rows > 0 {
+		term.Resize(c.Cols, c.Rows)
+	}
+	term.Write(c.Data)
+}
@@ -0,0 +1,14 @@
+// Package replay records terminal sessions as they are consumed and indexes them for navigation.
+//
+// A Recorder feeds a session's output to a vt10x.Terminal while counting byte offsets, checkpointing the terminal
+// state at intervals, and keeping the bookmarks a host drops along the way. The resulting Index is persisted next to
+// the raw byte stream, so a player can restore the checkpoint nearest any position or bookmark and replay only the
+// bytes after it. A Follower does the same for a viewer of a live session, skipping ahead whenever it falls too far
+// behind.
+//
+// Timed sessions are read as chunks of output, from asciicast recordings with an AsciicastReader and from ttyrec ones
+// with a TtyrecReader; a TtyrecWriter records them. A Player plays a timed session onto a terminal on a virtual clock,
+// with speed control, pausing, silences cut short, and seeking through checkpoints it takes as it plays. A
+// FrameGenerator plays one and yields the screen at a fixed frame rate, merging frames that show nothing new, for
+// exporting the session as a GIF or video.
+package replay
@@ -0,0 +1,120 @@
+package replay
+
+import (
+	"errors"
+	"io"
+
+	"github.com/hinshun/vt10x"
+)
+
+// DefaultMaxLag is the number of bytes a Follower may fall behind before it skips ahead, unless configured otherwise.
+// It is twice DefaultCheckpointInterval, so there is always a checkpoint to skip to.
+const DefaultMaxLag = 2 * DefaultCheckpointInterval
+
+// followChunk is the number of bytes a Follower reads from the session at a time.
+const followChunk = 32 << 10
+
+// Follower keeps a viewer's terminal up to date with a live session, such as one a viewer joins partway through.
+// Rather than replaying everything it has missed, a Follower that falls more than its maximum lag behind restores the
+// latest checkpoint and replays only the bytes after it, so the viewer's staleness and the work each Poll does stay
+// bounded however fast the session produces output.
+type Follower struct {
+	rec      *Recorder
+	data     io.ReaderAt
+	maxLag   int64
+	termOpts []vt10x.TerminalOption
+	term     vt10x.Terminal
+	offset   int64
+	skips    int
+	buf      []byte
+}
+
+// FollowerOption configures a Follower.
+type FollowerOption func(*Follower)
+
+// WithMaxLag sets the number of bytes the Follower may fall behind before it skips ahead. It should exceed the
+// Recorder's checkpoint interval; otherwise the latest checkpoint may itself be too old to bring the Follower within
+// n bytes.
+func WithMaxLag(n int64) FollowerOption {
+	return func(f *Follower) {
+		f.maxLag = n
+	}
+}
+
+// WithTerminalOptions sets the options the Follower creates its terminals with. The size always comes from the
+// session.
+func WithTerminalOptions(opts ...vt10x.TerminalOption) FollowerOption {
+	return func(f *Follower) {
+		f.termOpts = opts
+	}
+}
+
+// NewFollower returns a Follower of the session rec is recording, starting from its beginning. data must hold the
+// bytes rec's terminal consumed, in order, at least up to rec's offset at each Poll.
+func NewFollower(rec *Recorder, data io.ReaderAt, opts ...FollowerOption) *Follower {
+	f := &Follower{
+		rec:    rec,
+		data:   data,
+		maxLag: DefaultMaxLag,
+		buf:    make([]byte, followChunk),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	cols, rows := rec.Terminal().Size()
+	f.term = vt10x.New(append(f.termOpts, vt10x.WithSize(cols, rows))...)
+	return f
+}
+
+// Terminal returns the Follower's terminal. Skipping ahead replaces it, so viewers should fetch it again after each
+// Poll instead of keeping it.
+func (f *Follower) Terminal() vt10x.Terminal {
+	return f.term
+}
+
+// Offset returns the number of session bytes the Follower's terminal reflects.
+func (f *Follower) Offset() int64 {
+	return f.offset
+}
+
+// Lag returns the number of session bytes the Follower has yet to replay.
+func (f *Follower) Lag() int64 {
+	return f.rec.Offset() - f.offset
+}
+
+// Skips returns the number of times the Follower has skipped ahead.
+func (f *Follower) Skips() int {
+	return f.skips
+}
+
+// Poll brings the Follower's terminal up to the session's current offset, first skipping to the latest checkpoint if
+// the Follower has fallen more than its maximum lag behind. It reports whether it skipped.
+func (f *Follower) Poll() (skipped bool, err error) {
+	head := f.rec.Offset()
+	if f.maxLag > 0 && head-f.offset > f.maxLag {
+		if c, ok := f.rec.Nearest(head); ok && c.Offset > f.offset {
+			f.term = vt10x.New(append(f.termOpts, vt10x.WithState(c.State))...)
+			f.offset = c.Offset
+			f.skips++
+			skipped = true
+		}
+	}
+
+	for f.offset < head {
+		buf := f.buf[:min(int64(len(f.buf)), head-f.offset)]
+		n, err := f.data.ReadAt(buf, f.offset)
+		if n == 0 {
+			if err == nil || errors.Is(err, io.EOF) {
+				// The host has not stored the bytes yet; they will be read on a later Poll.
+				return skipped, nil
+			}
+			return skipped, err
+		}
+		w, werr := f.term.Write(buf[:n])
+		f.offset += int64(w)
+		if werr != nil {
+			return skipped, werr
+		}
+	}
+	return skipped, nil
+}
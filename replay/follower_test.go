@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hinshun/vt10x"
+)
+
+// session is a Recorder whose consumed bytes are kept for Followers to read.
+type session struct {
+	*Recorder
+	log      bytes.Buffer
+	unstored int // trailing bytes of log not yet readable
+}
+
+func newSession(interval int64) *session {
+	return &session{Recorder: NewRecorder(vt10x.New(vt10x.WithSize(40, 5)), WithCheckpointInterval(interval))}
+}
+
+func (s *session) write(t *testing.T, p string) {
+	t.Helper()
+
+	n, err := s.Recorder.Write([]byte(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.log.Write([]byte(p)[:n])
+}
+
+// ReadAt reads from the bytes consumed so far, so it sees the session grow.
+func (s *session) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(s.log.Bytes()[:s.log.Len()-s.unstored]).ReadAt(p, off)
+}
+
+func TestFollowerKeepsUp(t *testing.T) {
+	s := newSession(100)
+	f := NewFollower(s.Recorder, s, WithMaxLag(1000))
+
+	for i := 0; i < 20; i++ {
+		s.write(t, fmt.Sprintf("line %d\r\n", i))
+		if skipped, err := f.Poll(); err != nil || skipped {
+			t.Fatalf("poll %d: expected to replay without skipping, got %v, %v", i, skipped, err)
+		}
+		if f.Lag() != 0 {
+			t.Fatalf("poll %d: expected no lag, got %d", i, f.Lag())
+		}
+	}
+	if !reflect.DeepEqual(f.Terminal().DumpState(), s.Terminal().DumpState()) {
+		t.Fatal("expected the follower to match the session")
+	}
+}
+
+func TestFollowerSkipsAhead(t *testing.T) {
+	s := newSession(100)
+	for i := 0; i < 200; i++ {
+		s.write(t, fmt.Sprintf("\033[3%dmline %d\r\n", i%8, i))
+	}
+
+	f := NewFollower(s.Recorder, s, WithMaxLag(250))
+	skipped, err := f.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !skipped || f.Skips() != 1 {
+		t.Fatalf("expected one skip, got %v and %d", skipped, f.Skips())
+	}
+	if f.Lag() != 0 || f.Offset() != s.Offset() {
+		t.Fatalf("expected the follower to reach offset %d, got %d", s.Offset(), f.Offset())
+	}
+	if got, want := f.Terminal().String(), s.Terminal().String(); got != want {
+		t.Fatalf("expected the follower's screen to match the session:\ngot  %q\nwant %q", got, want)
+	}
+
+	// Once caught up it replays instead of skipping.
+	s.write(t, "tail")
+	if skipped, err := f.Poll(); err != nil || skipped {
+		t.Fatalf("expected to replay the tail, got %v, %v", skipped, err)
+	}
+	if got, want := f.Terminal().String(), s.Terminal().String(); got != want {
+		t.Fatalf("expected the follower's screen to match the session after the tail:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestFollowerWaitsForData(t *testing.T) {
+	s := newSession(0)
+	s.write(t, "héllo")
+
+	// The host has stored only part of the session, ending partway through a rune.
+	s.unstored = s.log.Len() - 2
+	f := NewFollower(s.Recorder, s)
+	if _, err := f.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Offset() != 2 {
+		t.Fatalf("expected to replay the stored bytes, holding the partial rune, got offset %d", f.Offset())
+	}
+
+	s.unstored = 0
+	if _, err := f.Poll(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Lag() != 0 {
+		t.Fatalf("expected to catch up once the data arrived, got lag %d", f.Lag())
+	}
+	if got, want := f.Terminal().String(), s.Terminal().String(); got != want {
+		t.Fatalf("expected the follower's screen to match the session:\ngot  %q\nwant %q", got, want)
+	}
+}
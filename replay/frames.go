@@ -0,0 +1,145 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+// Frame is the screen of a session from Time, for Duration.
+type Frame struct {
+	State    vt10x.TerminalState
+	Time     time.Duration
+	Duration time.Duration
+}
+
+// FrameGenerator turns a timed session into the frames of a video at a fixed frame rate, for an encoder such as
+// image/gif to draw with a renderer such as the raster package. Frames that would show the same screen as the one before
+// are merged into it, so a frame's Duration is a whole number of frame intervals, and an idle session costs nothing.
+type FrameGenerator struct {
+	src      ChunkReader
+	term     vt10x.Terminal
+	interval time.Duration
+
+	// pending is the chunk read but not yet written, being due after the current tick.
+	pending *Chunk
+	eof     bool
+
+	cur     Frame
+	curView frameView
+	started bool
+	done    bool
+}
+
+// frameView is what makes two frames alike.
+type frameView struct {
+	screen        uint64
+	cols, rows    int
+	cursorX       int
+	cursorY       int
+	cursorVisible bool
+}
+
+// NewFrameGenerator returns a FrameGenerator of the session src reads at fps frames per second, on a terminal of cols by
+// rows created with opts. An fps below 1 is taken as 1.
+func NewFrameGenerator(src ChunkReader, cols, rows, fps int, opts ...vt10x.TerminalOption) *FrameGenerator {
+	if fps <= 0 {
+		fps = 1
+	}
+	opts = append(opts[:len(opts):len(opts)], vt10x.WithSize(cols, rows))
+	return &FrameGenerator{
+		src:      src,
+		term:     vt10x.New(opts...),
+		interval: time.Second / time.Duration(fps),
+	}
+}
+
+// Terminal returns the terminal the session is played on.
+func (g *FrameGenerator) Terminal() vt10x.Terminal {
+	return g.term
+}
+
+// Next returns the next frame, or io.EOF after the last. The last frame lasts one interval.
+func (g *FrameGenerator) Next() (Frame, error) {
+	if !g.started {
+		g.started = true
+		if err := g.advance(0); err != nil {
+			return Frame{}, err
+		}
+		g.cur = Frame{State: g.term.DumpState()}
+		g.curView = g.view()
+	}
+	if g.done {
+		return Frame{}, io.EOF
+	}
+	tick := g.cur.Time
+	for {
+		if err := g.read(); err != nil {
+			return Frame{}, err
+		}
+		if g.pending == nil {
+			// The session is over.
+			f := g.cur
+			f.Duration = tick + g.interval - f.Time
+			g.done = true
+			return f, nil
+		}
+		// Nothing changes before the tick the pending chunk is due at.
+		tick = max(tick+g.interval, (g.pending.Time+g.interval-1)/g.interval*g.interval)
+		if err := g.advance(tick); err != nil {
+			return Frame{}, err
+		}
+		if v := g.view(); v != g.curView {
+			f := g.cur
+			f.Duration = tick - f.Time
+			g.cur, g.curView = Frame{State: g.term.DumpState(), Time: tick}, v
+			return f, nil
+		}
+	}
+}
+
+// read reads the next chunk into pending, unless one is there or the session is over.
+func (g *FrameGenerator) read() error {
+	if g.pending != nil || g.eof {
+		return nil
+	}
+	c, err := g.src.ReadChunk()
+	if errors.Is(err, io.EOF) {
+		g.eof = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	g.pending = &c
+	return nil
+}
+
+// advance plays the chunks due by tick.
+func (g *FrameGenerator) advance(tick time.Duration) error {
+	for {
+		if err := g.read(); err != nil {
+			return err
+		}
+		if g.pending == nil || g.pending.Time > tick {
+			return nil
+		}
+		apply(g.term, *g.pending)
+		g.pending = nil
+	}
+}
+
+func (g *FrameGenerator) view() frameView {
+	g.term.Lock()
+	defer g.term.Unlock()
+	cols, rows := g.term.Size()
+	cur := g.term.Cursor()
+	return frameView{
+		screen: g.term.(vt10x.Hasher).ScreenHash(),
+		cols:   cols, rows: rows,
+		cursorX: cur.X, cursorY: cur.Y,
+		cursorVisible: g.term.CursorVisible(),
+	}
+}
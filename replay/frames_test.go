@@ -0,0 +1,133 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+const ms = time.Millisecond
+
+func collectFrames(t *testing.T, g *FrameGenerator) []Frame {
+	t.Helper()
+	var frames []Frame
+	for {
+		f, err := g.Next()
+		if errors.Is(err, io.EOF) {
+			return frames
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f)
+	}
+}
+
+func frameText(f Frame) string {
+	var b strings.Builder
+	for _, row := range f.State.PrimaryBuffer {
+		for _, g := range row {
+			b.WriteRune(g.Char)
+		}
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func TestFrameGenerator(t *testing.T) {
+	chunks := ChunkSlice{
+		{Time: 0, Data: []byte("a")},
+		{Time: 30 * ms, Data: []byte("b")},
+		{Time: 40 * ms, Data: []byte("c")},
+		// Rewriting a cell with the same character changes nothing, so no frame starts here.
+		{Time: 150 * ms, Data: []byte("\bc")},
+		{Time: 5 * time.Second, Data: []byte("d")},
+	}
+	frames := collectFrames(t, NewFrameGenerator(&chunks, 4, 1, 10, vt10x.WithSize(1, 1)))
+
+	want := []struct {
+		text           string
+		start, running time.Duration
+	}{
+		{"a", 0, 100 * ms},
+		{"abc", 100 * ms, 4900 * ms},
+		{"abcd", 5 * time.Second, 100 * ms},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("expected %d frames, got %d: %+v", len(want), len(frames), frames)
+	}
+	for i, w := range want {
+		f := frames[i]
+		if frameText(f) != w.text || f.Time != w.start || f.Duration != w.running {
+			t.Errorf("frame %d: expected %q at %v for %v, got %q at %v for %v", i, w.text, w.start, w.running,
+				frameText(f), f.Time, f.Duration)
+		}
+	}
+	if frames[1].State.Cols != 4 {
+		t.Errorf("expected the generator's size to override the options, got %d columns", frames[1].State.Cols)
+	}
+}
+
+func TestFrameGeneratorCursorAndResize(t *testing.T) {
+	chunks := ChunkSlice{
+		{Time: 10 * ms, Data: []byte("\033[C")},
+		{Time: 20 * ms, Cols: 3, Rows: 2},
+	}
+	frames := collectFrames(t, NewFrameGenerator(&chunks, 2, 1, 100))
+	if len(frames) != 3 {
+		t.Fatalf("expected frames for the cursor move and the resize, got %+v", frames)
+	}
+	if f := frames[1]; f.State.CursorX != 1 || f.Time != 10*ms {
+		t.Errorf("expected the cursor moved at 10ms, got %d at %v", f.State.CursorX, f.Time)
+	}
+	if f := frames[2]; f.State.Cols != 3 || f.State.Rows != 2 || f.Duration != 10*ms {
+		t.Errorf("expected a 3x2 last frame of one interval, got %dx%d for %v", f.State.Cols, f.State.Rows, f.Duration)
+	}
+}
+
+func TestAsciicastReader(t *testing.T) {
+	cast := `{"version": 2, "width": 80, "height": 24, "timestamp": 1504467315}
+[0.248848, "o", "hello\r\n"]
+[1.001376, "i", "ls\r"]
+
+[1.5, "r", "100x30"]
+[2.25, "o", "\u001b[1mbye"]
+`
+	a, err := NewAsciicastReader(strings.NewReader(cast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols, rows := a.Size(); cols != 80 || rows != 24 {
+		t.Fatalf("expected 80x24, got %dx%d", cols, rows)
+	}
+	want := []Chunk{
+		{Time: 248848 * time.Microsecond, Data: []byte("hello\r\n")},
+		{Time: 1500 * ms, Cols: 100, Rows: 30},
+		{Time: 2250 * ms, Data: []byte("\033[1mbye")},
+	}
+	for i, w := range want {
+		c, err := a.ReadChunk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Time.Round(time.Microsecond) != w.Time || string(c.Data) != string(w.Data) || c.Cols != w.Cols || c.Rows != w.Rows {
+			t.Errorf("chunk %d: expected %+v, got %+v", i, w, c)
+		}
+	}
+	if _, err := a.ReadChunk(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	for _, bad := range []string{"", `{"version": 1}`, "{\"version\": 2}\n[1, \"o\"]\n", "{\"version\": 2}\n[1, \"r\", \"x\"]\n"} {
+		a, err := NewAsciicastReader(strings.NewReader(bad))
+		if err == nil {
+			_, err = a.ReadChunk()
+		}
+		if err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("expected an error for %q, got %v", bad, err)
+		}
+	}
+}
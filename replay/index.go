@@ -0,0 +1,168 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+// Checkpoint is the terminal state after the first Offset bytes of a session.
+type Checkpoint struct {
+	Offset int64
+	Time   time.Time
+	State  vt10x.TerminalState
+}
+
+// Bookmark is a named position in a session, with the terminal state there if one was taken.
+type Bookmark struct {
+	Name   string
+	Offset int64
+	Time   time.Time
+	State  *vt10x.TerminalState
+}
+
+// Index holds the checkpoints and bookmarks of a session, each in increasing offset order.
+type Index struct {
+	Checkpoints []Checkpoint
+	Bookmarks   []Bookmark
+}
+
+// Nearest returns the last checkpoint at or before offset, or false if there is none.
+func (ix *Index) Nearest(offset int64) (Checkpoint, bool) {
+	i := sort.Search(len(ix.Checkpoints), func(i int) bool { return ix.Checkpoints[i].Offset > offset })
+	if i == 0 {
+		return Checkpoint{}, false
+	}
+	return ix.Checkpoints[i-1], true
+}
+
+// Bookmark returns the first bookmark with the given name, or false if there is none.
+func (ix *Index) Bookmark(name string) (Bookmark, bool) {
+	for _, b := range ix.Bookmarks {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// indexMagic starts every encoded index, followed by the format version.
+const (
+	indexMagic   = "vt10x-index\n"
+	indexVersion = 1
+)
+
+// ErrIndexFormat is returned by ReadIndex for data that is not an index, or is one written in a format version
+// this library does not know.
+var ErrIndexFormat = errors.New("replay: unrecognized index format")
+
+// indexV1 is the gob-encoded body of a version 1 index. States are stored with vt10x.EncodeSnapshot, so they follow
+// the snapshot format's versioning.
+type indexV1 struct {
+	Checkpoints []entryV1
+	Bookmarks   []entryV1
+}
+
+type entryV1 struct {
+	Name     string
+	Offset   int64
+	Time     time.Time
+	Snapshot []byte
+}
+
+func encodeEntry(name string, offset int64, t time.Time, s *vt10x.TerminalState, c vt10x.Codec) (entryV1, error) {
+	e := entryV1{Name: name, Offset: offset, Time: t}
+	if s != nil {
+		var buf bytes.Buffer
+		if err := vt10x.EncodeSnapshot(&buf, *s, vt10x.WithCompression(c)); err != nil {
+			return e, err
+		}
+		e.Snapshot = buf.Bytes()
+	}
+	return e, nil
+}
+
+// WriteTo writes the index to w in a versioned binary format that ReadIndex reads back.
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	return ix.encode(w, nil)
+}
+
+// Encode writes the index like WriteTo, compressing the snapshots it holds with c, which ReadIndex must find
+// registered with vt10x.RegisterCodec.
+func (ix *Index) Encode(w io.Writer, c vt10x.Codec) error {
+	_, err := ix.encode(w, c)
+	return err
+}
+
+func (ix *Index) encode(w io.Writer, codec vt10x.Codec) (int64, error) {
+	var body indexV1
+	for i := range ix.Checkpoints {
+		c := &ix.Checkpoints[i]
+		e, err := encodeEntry("", c.Offset, c.Time, &c.State, codec)
+		if err != nil {
+			return 0, err
+		}
+		body.Checkpoints = append(body.Checkpoints, e)
+	}
+	for _, b := range ix.Bookmarks {
+		e, err := encodeEntry(b.Name, b.Offset, b.Time, b.State, codec)
+		if err != nil {
+			return 0, err
+		}
+		body.Bookmarks = append(body.Bookmarks, e)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(indexMagic)
+	buf.WriteByte(indexVersion)
+	if err := gob.NewEncoder(&buf).Encode(&body); err != nil {
+		return 0, fmt.Errorf("replay: encoding index: %w", err)
+	}
+	return buf.WriteTo(w)
+}
+
+// ReadIndex reads an index written by Index.WriteTo.
+func ReadIndex(r io.Reader) (*Index, error) {
+	head := make([]byte, len(indexMagic)+1)
+	if _, err := io.ReadFull(r, head); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrIndexFormat
+		}
+		return nil, err
+	}
+	if string(head[:len(indexMagic)]) != indexMagic || head[len(indexMagic)] != indexVersion {
+		return nil, ErrIndexFormat
+	}
+
+	var body indexV1
+	if err := gob.NewDecoder(r).Decode(&body); err != nil {
+		return nil, fmt.Errorf("replay: decoding index: %w", err)
+	}
+
+	ix := &Index{}
+	for _, e := range body.Checkpoints {
+		s, err := vt10x.DecodeSnapshot(bytes.NewReader(e.Snapshot))
+		if err != nil {
+			return nil, fmt.Errorf("replay: checkpoint at offset %d: %w", e.Offset, err)
+		}
+		ix.Checkpoints = append(ix.Checkpoints, Checkpoint{Offset: e.Offset, Time: e.Time, State: s})
+	}
+	for _, e := range body.Bookmarks {
+		b := Bookmark{Name: e.Name, Offset: e.Offset, Time: e.Time}
+		if e.Snapshot != nil {
+			s, err := vt10x.DecodeSnapshot(bytes.NewReader(e.Snapshot))
+			if err != nil {
+				return nil, fmt.Errorf("replay: bookmark %q: %w", e.Name, err)
+			}
+			b.State = &s
+		}
+		ix.Bookmarks = append(ix.Bookmarks, b)
+	}
+	return ix, nil
+}
@@ -0,0 +1,286 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+// retimed is the ChunkReader Retime returns.
+type retimed struct {
+	src      ChunkReader
+	speed    float64
+	maxIdle  time.Duration
+	last, at time.Duration
+}
+
+// Retime returns a ChunkReader of the chunks of src played at speed times their recorded pace, with every silence,
+// including the one before the first chunk, cut to at most maxIdle if it is positive. Its chunk times are those of
+// the compressed session, so a FrameGenerator reading it renders, say, a "2x speed, skip silences" video. A speed
+// that is not positive is taken as 1.
+func Retime(src ChunkReader, speed float64, maxIdle time.Duration) ChunkReader {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &retimed{src: src, speed: speed, maxIdle: maxIdle}
+}
+
+func (r *retimed) ReadChunk() (Chunk, error) {
+	c, err := r.src.ReadChunk()
+	if err != nil {
+		return c, err
+	}
+	gap := max(0, c.Time-r.last)
+	if r.maxIdle > 0 {
+		gap = min(gap, r.maxIdle)
+	}
+	r.last = max(r.last, c.Time)
+	r.at += time.Duration(float64(gap) / r.speed)
+	c.Time = r.at
+	return c, nil
+}
+
+// DefaultCheckpointPeriod is how much of a session a Player plays between the checkpoints it takes for seeking, unless
+// configured otherwise.
+const DefaultCheckpointPeriod = 10 * time.Second
+
+// Player plays a timed session onto a terminal on a virtual clock. It keeps no time of its own: the caller advances
+// it, by the time that has passed for real-time playback or by a frame interval for rendering, and the Player writes
+// the chunks that fell due, at its speed and with silences cut short. It is safe for concurrent use, so a viewer can
+// pause it or change its speed while another goroutine advances it.
+//
+// A Player can also Seek anywhere in the session. It keeps the chunks it has read, and checkpoints the terminal state
+// as it plays, so a seek restores the checkpoint nearest its target and replays only the chunks after it.
+type Player struct {
+	mu       sync.Mutex
+	term     vt10x.Terminal
+	termOpts []vt10x.TerminalOption
+	src      ChunkReader
+	speed    float64
+	maxIdle  time.Duration
+	paused   bool
+
+	// pos is how far into the session, with silences cut short, the Player has played.
+	pos time.Duration
+
+	// chunks are the chunks read so far, of which the first next have been written to term.
+	chunks []Chunk
+	next   int
+	eof    bool
+
+	period      time.Duration
+	checkpoints []playerCheckpoint
+}
+
+// playerCheckpoint is the terminal state after the first next chunks of a session, the last of which is due at time.
+type playerCheckpoint struct {
+	time  time.Duration
+	next  int
+	state vt10x.TerminalState
+}
+
+// PlayerOption configures a Player.
+type PlayerOption func(*Player)
+
+// WithSpeed plays the session at speed times its recorded pace. A speed that is not positive is taken as 1.
+func WithSpeed(speed float64) PlayerOption {
+	return func(p *Player) {
+		p.setSpeed(speed)
+	}
+}
+
+// WithMaxIdle cuts every silence in the session to at most d.
+func WithMaxIdle(d time.Duration) PlayerOption {
+	return func(p *Player) {
+		p.maxIdle = d
+	}
+}
+
+// WithCheckpointPeriod makes the Player checkpoint the terminal state every d of the session it plays. A shorter
+// period makes seeks faster at the cost of memory; a period that is not positive disables checkpoints, so every seek
+// backwards replays the session from its start.
+func WithCheckpointPeriod(d time.Duration) PlayerOption {
+	return func(p *Player) {
+		p.period = d
+	}
+}
+
+// WithSeekTerminalOptions sets the options the Player creates terminals with when a seek restores a checkpoint.
+func WithSeekTerminalOptions(opts ...vt10x.TerminalOption) PlayerOption {
+	return func(p *Player) {
+		p.termOpts = opts
+	}
+}
+
+// NewPlayer returns a Player of the session src reads onto term, at its start and not paused.
+func NewPlayer(term vt10x.Terminal, src ChunkReader, opts ...PlayerOption) *Player {
+	p := &Player{term: term, speed: 1, period: DefaultCheckpointPeriod}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.src = Retime(src, 1, p.maxIdle)
+	p.checkpoints = []playerCheckpoint{{state: term.DumpState()}}
+	return p
+}
+
+// Terminal returns the terminal the Player writes to. A seek that restores a checkpoint replaces it, so viewers should
+// fetch it again after each Seek instead of keeping it.
+func (p *Player) Terminal() vt10x.Terminal {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.term
+}
+
+// Advance moves the virtual clock on by d, and writes the chunks that fall due, at the Player's speed. It does
+// nothing while the Player is paused.
+func (p *Player) Advance(d time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.paused {
+		return nil
+	}
+	p.pos += time.Duration(float64(d) * p.speed)
+	return p.play()
+}
+
+// play writes the chunks due by pos, checkpointing as it goes.
+func (p *Player) play() error {
+	for {
+		if err := p.load(); err != nil {
+			return err
+		}
+		if p.next == len(p.chunks) || p.chunks[p.next].Time > p.pos {
+			return nil
+		}
+		c := p.chunks[p.next]
+		apply(p.term, c)
+		p.next++
+		last := p.checkpoints[len(p.checkpoints)-1]
+		if p.period > 0 && p.next > last.next && c.Time-last.time >= p.period {
+			p.checkpoints = append(p.checkpoints, playerCheckpoint{time: c.Time, next: p.next, state: p.term.DumpState()})
+		}
+	}
+}
+
+// load reads the chunk after those written, unless it has been read or the session is over.
+func (p *Player) load() error {
+	if p.next < len(p.chunks) || p.eof {
+		return nil
+	}
+	c, err := p.src.ReadChunk()
+	if errors.Is(err, io.EOF) {
+		p.eof = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.chunks = append(p.chunks, c)
+	return nil
+}
+
+// Seek moves the Player to position t of the session, with silences cut short, leaving the terminal as it was then.
+// Seeking backwards, or forwards past a checkpoint, restores the last checkpoint before t onto a new terminal and
+// replays the chunks after it. Seeking past the end of the session plays it out.
+func (p *Player) Seek(t time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t = max(0, t)
+	i := sort.Search(len(p.checkpoints), func(i int) bool { return p.checkpoints[i].time > t }) - 1
+	if c := p.checkpoints[i]; t < p.pos || c.next > p.next {
+		p.term = vt10x.New(append(p.termOpts[:len(p.termOpts):len(p.termOpts)], vt10x.WithState(c.state))...)
+		p.next = c.next
+	}
+	p.pos = t
+	return p.play()
+}
+
+// Until returns how long the virtual clock must advance for the next chunk to fall due, at the current speed, so a
+// real-time player can sleep that long. It returns false while the Player is paused or once the session is over, and
+// zero before the first Advance.
+func (p *Player) Until() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.paused || p.done() {
+		return 0, false
+	}
+	if p.next == len(p.chunks) {
+		return 0, true
+	}
+	return time.Duration(float64(p.chunks[p.next].Time-p.pos) / p.speed), true
+}
+
+// Done reports whether every chunk of the session has been written.
+func (p *Player) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.done()
+}
+
+func (p *Player) done() bool {
+	return p.eof && p.next == len(p.chunks)
+}
+
+// Position returns how far into the session the Player has played, with silences cut short.
+func (p *Player) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pos
+}
+
+// Pause stops the virtual clock, so Advance does nothing until Resume.
+func (p *Player) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused = true
+}
+
+// Resume restarts the virtual clock after Pause.
+func (p *Player) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused = false
+}
+
+// Paused reports whether the Player is paused.
+func (p *Player) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.paused
+}
+
+// SetSpeed changes the speed the Player plays at from the next Advance. A speed that is not positive is taken as 1.
+func (p *Player) SetSpeed(speed float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.setSpeed(speed)
+}
+
+func (p *Player) setSpeed(speed float64) {
+	if speed <= 0 {
+		speed = 1
+	}
+	p.speed = speed
+}
+
+// Speed returns the speed the Player plays at.
+func (p *Player) Speed() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.speed
+}
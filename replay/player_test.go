@@ -0,0 +1,148 @@
+package replay
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+func playerText(p *Player) string {
+	return p.Terminal().String()[:4]
+}
+
+func TestPlayerSpeedAndIdle(t *testing.T) {
+	chunks := ChunkSlice{
+		{Time: time.Second, Data: []byte("a")},
+		{Time: 2 * time.Second, Data: []byte("b")},
+		{Time: time.Hour, Data: []byte("c")},
+	}
+	p := NewPlayer(vt10x.New(vt10x.WithSize(4, 1)), &chunks, WithSpeed(2), WithMaxIdle(3*time.Second))
+
+	if wait, ok := p.Until(); !ok || wait != 0 {
+		t.Fatalf("expected to advance at once before starting, got %v, %v", wait, ok)
+	}
+	if err := p.Advance(0); err != nil {
+		t.Fatal(err)
+	}
+	if wait, ok := p.Until(); !ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected the first chunk due in 500ms at 2x, got %v, %v", wait, ok)
+	}
+	p.Advance(time.Second)
+	if got := playerText(p); got != "ab  " {
+		t.Fatalf("expected two chunks after a second at 2x, got %q", got)
+	}
+
+	// The hour of silence is cut to three seconds, a second and a half at 2x.
+	if wait, _ := p.Until(); wait != 1500*time.Millisecond {
+		t.Fatalf("expected the silence cut short, got %v", wait)
+	}
+	p.SetSpeed(0.5)
+	if wait, _ := p.Until(); wait != 6*time.Second {
+		t.Fatalf("expected the wait to follow the speed, got %v", wait)
+	}
+	p.Advance(6 * time.Second)
+	if got := playerText(p); got != "abc " || !p.Done() {
+		t.Fatalf("expected the session played out, got %q, done %v", got, p.Done())
+	}
+	if got := p.Position(); got != 5*time.Second {
+		t.Fatalf("expected the position on the compressed timeline, got %v", got)
+	}
+	if _, ok := p.Until(); ok {
+		t.Fatal("expected nothing left to wait for")
+	}
+}
+
+func TestPlayerPause(t *testing.T) {
+	chunks := ChunkSlice{{Time: time.Second, Data: []byte("a")}}
+	p := NewPlayer(vt10x.New(vt10x.WithSize(4, 1)), &chunks)
+	p.Pause()
+	p.Advance(time.Minute)
+	if _, ok := p.Until(); ok || !p.Paused() || p.Position() != 0 || playerText(p) != "    " {
+		t.Fatalf("expected the paused player to stand still, got %q at %v", playerText(p), p.Position())
+	}
+	p.Resume()
+	p.Advance(time.Second)
+	if got := playerText(p); got != "a   " {
+		t.Fatalf("expected the chunk played after resuming, got %q", got)
+	}
+}
+
+func TestRetime(t *testing.T) {
+	chunks := ChunkSlice{
+		{Time: 10 * time.Second, Data: []byte("a")},
+		{Time: 11 * time.Second, Data: []byte("b")},
+		{Time: 40 * time.Second, Data: []byte("c")},
+	}
+	frames := collectFrames(t, NewFrameGenerator(Retime(&chunks, 2, 2*time.Second), 4, 1, 1))
+	var starts []time.Duration
+	for _, f := range frames {
+		starts = append(starts, f.Time)
+	}
+	want := []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second}
+	if len(starts) != len(want) {
+		t.Fatalf("expected frames starting at %v, got %v", want, starts)
+	}
+	for i := range want {
+		if starts[i] != want[i] {
+			t.Fatalf("expected frames starting at %v, got %v", want, starts)
+		}
+	}
+}
+
+func TestPlayerSeek(t *testing.T) {
+	var chunks ChunkSlice
+	for i := 1; i <= 100; i++ {
+		chunks = append(chunks, Chunk{Time: time.Duration(i) * time.Second, Data: []byte(fmt.Sprintf("\r%4d", i))})
+	}
+	var bells int
+	p := NewPlayer(vt10x.New(vt10x.WithSize(4, 1)), &chunks, WithCheckpointPeriod(10*time.Second),
+		WithSeekTerminalOptions(vt10x.WithBellHandler(func(vt10x.Bell) { bells++ }, 0)))
+	first := p.Terminal()
+
+	if err := p.Seek(95500 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if got := playerText(p); got != "  95" || p.Position() != 95500*time.Millisecond {
+		t.Fatalf("expected 95 after seeking forwards, got %q at %v", got, p.Position())
+	}
+	if p.Terminal() != first {
+		t.Fatal("expected a forward seek from the start to keep playing on the same terminal")
+	}
+	if n := len(p.checkpoints); n != 10 {
+		t.Fatalf("expected a checkpoint every 10s, got %d", n)
+	}
+
+	// Seeking backwards restores the checkpoint at 40s and replays two chunks.
+	if err := p.Seek(42 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := playerText(p); got != "  42" {
+		t.Fatalf("expected 42 after seeking backwards, got %q", got)
+	}
+	if p.Terminal() == first || p.next != 42 {
+		t.Fatalf("expected a new terminal restored from a checkpoint, at chunk %d", p.next)
+	}
+	p.Terminal().Write([]byte("\a"))
+	if bells != 1 {
+		t.Fatal("expected the restored terminal to have the seek options")
+	}
+
+	p.Advance(3 * time.Second)
+	if got := playerText(p); got != "  45" {
+		t.Fatalf("expected playback to go on from the seek, got %q", got)
+	}
+	if err := p.Seek(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got := playerText(p); got != " 100" || !p.Done() {
+		t.Fatalf("expected seeking past the end to play the session out, got %q", got)
+	}
+	if err := p.Seek(0); err != nil {
+		t.Fatal(err)
+	}
+	if got := playerText(p); got != "    " || p.Done() {
+		t.Fatalf("expected an empty screen at the start, got %q", got)
+	}
+}
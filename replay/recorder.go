@@ -0,0 +1,169 @@
+package replay
+
+import (
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hinshun/vt10x"
+)
+
+// DefaultCheckpointInterval is the number of bytes between the checkpoints a Recorder takes unless configured
+// otherwise.
+const DefaultCheckpointInterval = 1 << 20
+
+// Recorder feeds a live session to a terminal and indexes it as it goes. It is safe for concurrent use, so a host
+// can drop bookmarks from one goroutine while another writes output.
+type Recorder struct {
+	mu       sync.Mutex
+	term     vt10x.Terminal
+	now      func() time.Time
+	interval int64
+	offset   int64
+	last     int64 // offset of the last checkpoint
+	index    Index
+
+	// tail holds the last bytes written, enough to tell whether the session ends partway through a rune.
+	tail    [utf8.UTFMax - 1]byte
+	tailLen int
+}
+
+// RecorderOption configures a Recorder.
+type RecorderOption func(*Recorder)
+
+// WithCheckpointInterval makes the Recorder checkpoint the terminal state every n bytes. A non-positive n disables
+// automatic checkpoints; Checkpoint still takes them on demand.
+func WithCheckpointInterval(n int64) RecorderOption {
+	return func(r *Recorder) {
+		r.interval = n
+	}
+}
+
+// WithClock sets the function the Recorder timestamps checkpoints and bookmarks with, in place of time.Now.
+func WithClock(now func() time.Time) RecorderOption {
+	return func(r *Recorder) {
+		r.now = now
+	}
+}
+
+// NewRecorder returns a Recorder writing to term, which should not be written to other than through the Recorder so
+// offsets stay in step with its state.
+func NewRecorder(term vt10x.Terminal, opts ...RecorderOption) *Recorder {
+	r := &Recorder{
+		term:     term,
+		now:      time.Now,
+		interval: DefaultCheckpointInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Terminal returns the terminal the Recorder writes to.
+func (r *Recorder) Terminal() vt10x.Terminal {
+	return r.term
+}
+
+// Write writes p to the terminal, advancing the offset by the bytes it consumed, and checkpoints once the interval
+// has passed.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := r.term.Write(p)
+	r.offset += int64(n)
+	r.remember(p[:n])
+	if r.interval > 0 && r.offset-r.last >= r.interval {
+		r.checkpoint()
+	}
+	return n, err
+}
+
+// Offset returns the number of bytes written so far.
+func (r *Recorder) Offset() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.offset
+}
+
+// remember keeps the end of p, just written, in r.tail.
+func (r *Recorder) remember(p []byte) {
+	if len(p) >= len(r.tail) {
+		r.tailLen = copy(r.tail[:], p[len(p)-len(r.tail):])
+		return
+	}
+	drop := max(0, r.tailLen+len(p)-len(r.tail))
+	r.tailLen = copy(r.tail[:], r.tail[drop:r.tailLen])
+	r.tailLen += copy(r.tail[r.tailLen:], p)
+}
+
+// boundary returns the offset of the last rune boundary. It falls short of the offset when the session ends partway
+// through a rune, whose bytes the terminal holds until the rest arrives, so its state does not reflect them yet.
+func (r *Recorder) boundary() int64 {
+	b := r.tail[:r.tailLen]
+	for i := len(b) - 1; i >= 0; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return r.offset
+			}
+			return r.offset - int64(len(b)-i)
+		}
+	}
+	return r.offset
+}
+
+// Checkpoint records the terminal state at the current offset and returns it. If the session ends partway through
+// a rune the checkpoint is taken at its start, so replaying from the checkpoint's offset picks the rune up whole.
+func (r *Recorder) Checkpoint() Checkpoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.checkpoint()
+}
+
+func (r *Recorder) checkpoint() Checkpoint {
+	c := Checkpoint{Offset: r.boundary(), Time: r.now(), State: r.term.DumpState()}
+	if n := len(r.index.Checkpoints); n > 0 && r.index.Checkpoints[n-1].Offset == c.Offset {
+		r.index.Checkpoints[n-1] = c
+	} else {
+		r.index.Checkpoints = append(r.index.Checkpoints, c)
+	}
+	r.last = r.offset
+	return c
+}
+
+// Bookmark drops a bookmark named name at the current offset, or the start of a rune it splits, and returns it. With
+// snapshot set it also stores the terminal state, so a player can show the bookmark without replaying to it.
+func (r *Recorder) Bookmark(name string, snapshot bool) Bookmark {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := Bookmark{Name: name, Offset: r.boundary(), Time: r.now()}
+	if snapshot {
+		s := r.term.DumpState()
+		b.State = &s
+	}
+	r.index.Bookmarks = append(r.index.Bookmarks, b)
+	return b
+}
+
+// Index returns a copy of the checkpoints and bookmarks recorded so far.
+func (r *Recorder) Index() *Index {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Index{
+		Checkpoints: append([]Checkpoint(nil), r.index.Checkpoints...),
+		Bookmarks:   append([]Bookmark(nil), r.index.Bookmarks...),
+	}
+}
+
+// Nearest returns the last checkpoint at or before offset, or false if there is none.
+func (r *Recorder) Nearest(offset int64) (Checkpoint, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.index.Nearest(offset)
+}
@@ -0,0 +1,226 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+func fixedClock() func() time.Time {
+	t := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+}
+
+// screen returns the primary buffer of s as text, one line per row.
+func screen(s vt10x.TerminalState) string {
+	var b []rune
+	for _, row := range s.PrimaryBuffer {
+		for _, g := range row {
+			b = append(b, g.Char)
+		}
+		b = append(b, '\n')
+	}
+	return string(b)
+}
+
+func write(t *testing.T, r *Recorder, s string) {
+	t.Helper()
+
+	if _, err := r.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecorderCheckpointInterval(t *testing.T) {
+	r := NewRecorder(vt10x.New(vt10x.WithSize(20, 4)), WithCheckpointInterval(10), WithClock(fixedClock()))
+
+	write(t, r, "hello")
+	if n := len(r.Index().Checkpoints); n != 0 {
+		t.Fatalf("expected no checkpoint before the interval, got %d", n)
+	}
+	write(t, r, " world")
+	write(t, r, "!")
+	write(t, r, "0123456789")
+
+	ix := r.Index()
+	if len(ix.Checkpoints) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d", len(ix.Checkpoints))
+	}
+	if ix.Checkpoints[0].Offset != 11 || ix.Checkpoints[1].Offset != 22 {
+		t.Fatalf("unexpected checkpoint offsets %d, %d", ix.Checkpoints[0].Offset, ix.Checkpoints[1].Offset)
+	}
+	if s := screen(ix.Checkpoints[0].State); s[:11] != "hello world" {
+		t.Fatalf("expected the first checkpoint to hold the screen at its offset, got %q", s)
+	}
+	if r.Offset() != 22 {
+		t.Fatalf("expected offset 22, got %d", r.Offset())
+	}
+}
+
+// TestRecorderCheckpointSplitRune ensures a checkpoint taken partway through a rune is placed at its start, so
+// replaying from it picks the rune up whole.
+func TestRecorderCheckpointSplitRune(t *testing.T) {
+	r := NewRecorder(vt10x.New(vt10x.WithSize(20, 4)), WithCheckpointInterval(0))
+	session := "h\u2713i"
+
+	write(t, r, session[:2])
+	write(t, r, session[2:3])
+	c := r.Checkpoint()
+	if c.Offset != 1 {
+		t.Fatalf("expected the checkpoint at the start of the rune, got offset %d", c.Offset)
+	}
+	write(t, r, session[3:])
+
+	term := vt10x.New(vt10x.WithState(c.State))
+	if _, err := term.Write([]byte(session[c.Offset:])); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := term.String(), r.Terminal().String(); got != want {
+		t.Fatalf("replaying from the checkpoint differs:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestRecorderBookmarks(t *testing.T) {
+	r := NewRecorder(vt10x.New(vt10x.WithSize(20, 4)), WithCheckpointInterval(0), WithClock(fixedClock()))
+
+	write(t, r, "intro")
+	plain := r.Bookmark("intro", false)
+	write(t, r, "\r\nbuild")
+	snap := r.Bookmark("build", true)
+
+	if plain.Offset != 5 || plain.State != nil {
+		t.Fatalf("unexpected plain bookmark %+v", plain)
+	}
+	if snap.Offset != 12 || snap.State == nil {
+		t.Fatalf("unexpected snapshot bookmark %+v", snap)
+	}
+	if !snap.Time.After(plain.Time) {
+		t.Fatalf("expected bookmark times to advance, got %v then %v", plain.Time, snap.Time)
+	}
+	if snap.State.CursorY != 1 {
+		t.Fatalf("expected the snapshot to hold the cursor row, got %d", snap.State.CursorY)
+	}
+
+	ix := r.Index()
+	if len(ix.Checkpoints) != 0 {
+		t.Fatalf("expected automatic checkpoints disabled, got %d", len(ix.Checkpoints))
+	}
+	if b, ok := ix.Bookmark("build"); !ok || b.Offset != 12 {
+		t.Fatalf("expected to find the build bookmark, got %+v, %v", b, ok)
+	}
+	if _, ok := ix.Bookmark("missing"); ok {
+		t.Fatal("expected no bookmark named missing")
+	}
+}
+
+func TestIndexNearest(t *testing.T) {
+	r := NewRecorder(vt10x.New(vt10x.WithSize(20, 4)), WithCheckpointInterval(0))
+	write(t, r, "aaaa")
+	r.Checkpoint()
+	write(t, r, "bbbb")
+	r.Checkpoint()
+	r.Checkpoint() // replaces the checkpoint at the same offset
+
+	ix := r.Index()
+	if len(ix.Checkpoints) != 2 {
+		t.Fatalf("expected 2 checkpoints, got %d", len(ix.Checkpoints))
+	}
+	tests := []struct {
+		offset int64
+		want   int64
+		ok     bool
+	}{
+		{0, 0, false},
+		{3, 0, false},
+		{4, 4, true},
+		{7, 4, true},
+		{8, 8, true},
+		{100, 8, true},
+	}
+	for _, tt := range tests {
+		c, ok := ix.Nearest(tt.offset)
+		if ok != tt.ok || (ok && c.Offset != tt.want) {
+			t.Errorf("Nearest(%d): expected %d, %v, got %d, %v", tt.offset, tt.want, tt.ok, c.Offset, ok)
+		}
+	}
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	r := NewRecorder(vt10x.New(vt10x.WithSize(20, 4)), WithCheckpointInterval(0), WithClock(fixedClock()))
+	write(t, r, "\033[1mfirst\033[m")
+	r.Checkpoint()
+	r.Bookmark("plain", false)
+	write(t, r, "\r\nsecond")
+	r.Bookmark("snap", true)
+
+	ix := r.Index()
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Checkpoints) != 1 || len(got.Bookmarks) != 2 {
+		t.Fatalf("expected 1 checkpoint and 2 bookmarks, got %d and %d", len(got.Checkpoints), len(got.Bookmarks))
+	}
+	c := got.Checkpoints[0]
+	if c.Offset != ix.Checkpoints[0].Offset || !c.Time.Equal(ix.Checkpoints[0].Time) {
+		t.Fatalf("checkpoint changed in the round trip: %+v", c)
+	}
+	if screen(c.State) != screen(ix.Checkpoints[0].State) {
+		t.Fatalf("checkpoint screen changed in the round trip: %q", screen(c.State))
+	}
+	if got.Bookmarks[0].Name != "plain" || got.Bookmarks[0].State != nil {
+		t.Fatalf("unexpected plain bookmark %+v", got.Bookmarks[0])
+	}
+	snap := got.Bookmarks[1]
+	if snap.Name != "snap" || snap.State == nil || screen(*snap.State) != screen(*ix.Bookmarks[1].State) {
+		t.Fatalf("unexpected snapshot bookmark %+v", snap)
+	}
+}
+
+func TestReadIndexRejectsOtherData(t *testing.T) {
+	for _, data := range []string{"", "not an index", "vt10x-index\n\x09"} {
+		if _, err := ReadIndex(bytes.NewReader([]byte(data))); !errors.Is(err, ErrIndexFormat) {
+			t.Errorf("ReadIndex(%q): expected ErrIndexFormat, got %v", data, err)
+		}
+	}
+}
+
+func TestIndexCompression(t *testing.T) {
+	r := NewRecorder(vt10x.New(vt10x.WithSize(80, 24)), WithCheckpointInterval(0))
+	write(t, r, "some output")
+	r.Checkpoint()
+	r.Bookmark("here", true)
+
+	var plain, compressed bytes.Buffer
+	if _, err := r.Index().WriteTo(&plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Index().Encode(&compressed, vt10x.GzipCodec); err != nil {
+		t.Fatal(err)
+	}
+	if compressed.Len() >= plain.Len() {
+		t.Fatalf("expected compression to shrink the index, got %d bytes from %d", compressed.Len(), plain.Len())
+	}
+
+	ix, err := ReadIndex(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ix.Checkpoints) != 1 || screen(ix.Checkpoints[0].State) != screen(r.Index().Checkpoints[0].State) {
+		t.Fatal("expected the checkpoint to survive compression")
+	}
+	if b := ix.Bookmarks[0]; b.State == nil || screen(*b.State) != screen(*r.Index().Bookmarks[0].State) {
+		t.Fatal("expected the bookmark snapshot to survive compression")
+	}
+}
@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ttyrecHeaderSize is the size of the header of a ttyrec record: its time in seconds and microseconds since the epoch,
+// and the length of its data, each a little-endian uint32.
+const ttyrecHeaderSize = 12
+
+// maxTtyrecRecord is the longest record a TtyrecReader accepts.
+const maxTtyrecRecord = 16 << 20
+
+// TtyrecReader reads the chunks of a ttyrec recording. Chunk times are relative to the first record.
+type TtyrecReader struct {
+	r       io.Reader
+	start   time.Time
+	started bool
+	header  [ttyrecHeaderSize]byte
+}
+
+// NewTtyrecReader returns a TtyrecReader of the recording r.
+func NewTtyrecReader(r io.Reader) *TtyrecReader {
+	return &TtyrecReader{r: r}
+}
+
+// ReadChunk returns the next record of the recording.
+func (t *TtyrecReader) ReadChunk() (Chunk, error) {
+	c, _, err := t.ReadRecord()
+	return c, err
+}
+
+// ReadRecord returns the next record of the recording, with the time it was recorded at.
+func (t *TtyrecReader) ReadRecord() (Chunk, time.Time, error) {
+	if _, err := io.ReadFull(t.r, t.header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Chunk{}, time.Time{}, fmt.Errorf("replay: truncated ttyrec header: %w", err)
+		}
+		return Chunk{}, time.Time{}, err
+	}
+	sec := binary.LittleEndian.Uint32(t.header[0:])
+	usec := binary.LittleEndian.Uint32(t.header[4:])
+	n := binary.LittleEndian.Uint32(t.header[8:])
+	if n > maxTtyrecRecord {
+		return Chunk{}, time.Time{}, fmt.Errorf("replay: ttyrec record of %d bytes is too long", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(t.r, data); err != nil {
+		return Chunk{}, time.Time{}, fmt.Errorf("replay: truncated ttyrec record: %w", noEOF(err))
+	}
+
+	at := time.Unix(int64(sec), int64(usec)*int64(time.Microsecond))
+	if !t.started {
+		t.start, t.started = at, true
+	}
+	return Chunk{Time: at.Sub(t.start), Data: data}, at, nil
+}
+
+// noEOF turns io.EOF into io.ErrUnexpectedEOF, for data that ends partway through.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// TtyrecWriter records the output of a session in the ttyrec format. It is safe for concurrent use.
+type TtyrecWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+	buf []byte
+}
+
+// NewTtyrecWriter returns a TtyrecWriter recording to w, timestamping writes with the time they are made at.
+func NewTtyrecWriter(w io.Writer) *TtyrecWriter {
+	return &TtyrecWriter{w: w, now: time.Now}
+}
+
+// Write records p as output written now, so a TtyrecWriter can be handed to io.MultiWriter next to the terminal.
+func (t *TtyrecWriter) Write(p []byte) (int, error) {
+	if err := t.WriteRecord(t.now(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteRecord records p as output written at the given time, in a record of its own.
+func (t *TtyrecWriter) WriteRecord(at time.Time, p []byte) error {
+	if len(p) > maxTtyrecRecord {
+		return fmt.Errorf("replay: ttyrec record of %d bytes is too long", len(p))
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = binary.LittleEndian.AppendUint32(t.buf[:0], uint32(at.Unix()))
+	t.buf = binary.LittleEndian.AppendUint32(t.buf, uint32(at.Nanosecond()/int(time.Microsecond)))
+	t.buf = binary.LittleEndian.AppendUint32(t.buf, uint32(len(p)))
+	t.buf = append(t.buf, p...)
+	_, err := t.w.Write(t.buf)
+	return err
+}
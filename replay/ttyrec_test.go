@@ -0,0 +1,81 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hinshun/vt10x"
+)
+
+func TestTtyrecRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewTtyrecWriter(&buf)
+	start := time.Unix(1700000000, 250*int64(time.Microsecond))
+	records := []struct {
+		at   time.Duration
+		data string
+	}{
+		{0, "hello\r\n"},
+		{1500 * time.Millisecond, "\033[1mworld"},
+		{2 * time.Second, ""},
+	}
+	for _, r := range records {
+		if err := w.WriteRecord(start.Add(r.at), []byte(r.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := buf.Len(), 3*ttyrecHeaderSize+len("hello\r\n")+len("\033[1mworld"); got != want {
+		t.Fatalf("expected %d bytes, got %d", want, got)
+	}
+
+	r := NewTtyrecReader(bytes.NewReader(buf.Bytes()))
+	for i, want := range records {
+		c, at, err := r.ReadRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Time != want.at || string(c.Data) != want.data || !at.Equal(start.Add(want.at)) {
+			t.Errorf("record %d: expected %q at %v, got %q at %v (%v)", i, want.data, want.at, c.Data, c.Time, at)
+		}
+	}
+	if _, err := r.ReadChunk(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	term := vt10x.New(vt10x.WithSize(10, 2))
+	if err := Feed(term, NewTtyrecReader(bytes.NewReader(buf.Bytes()))); err != nil {
+		t.Fatal(err)
+	}
+	if got := term.String(); got != "hello     \nworld     \n" {
+		t.Fatalf("expected the recording played, got %q", got)
+	}
+}
+
+func TestTtyrecWriterClock(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewTtyrecWriter(&buf)
+	w.now = func() time.Time { return time.Unix(42, 7000) }
+	if n, err := w.Write([]byte("x")); n != 1 || err != nil {
+		t.Fatalf("expected 1 byte written, got %d, %v", n, err)
+	}
+	want := []byte{42, 0, 0, 0, 7, 0, 0, 0, 1, 0, 0, 0, 'x'}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("expected %v, got %v", want, buf.Bytes())
+	}
+}
+
+func TestTtyrecReaderTruncated(t *testing.T) {
+	for _, data := range [][]byte{
+		{1, 0, 0, 0, 0},
+		{1, 0, 0, 0, 0, 0, 0, 0, 5, 0, 0, 0, 'a'},
+		{1, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff},
+	} {
+		_, err := NewTtyrecReader(bytes.NewReader(data)).ReadChunk()
+		if err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("expected an error for %v, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package vt10x
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSoftReset(t *testing.T) {
+	term := New(WithSize(20, 6))
+	writeSeq(t, term, "\033]0;title\007\033]4;1;rgb:12/34/56\007\033[3g\033[1;5H\033H\033[1;31m")
+	writeSeq(t, term, "hello\033[4h\033[?6h\033[?7l\033[?1h\033=\033[?25l\033[2;4r\033[3;7H\0337")
+	before := term.DumpState()
+
+	writeSeq(t, term, "\033[!p")
+	s := term.DumpState()
+
+	if s.Insert || s.Origin || !s.Wrap || !s.CursorVisible {
+		t.Fatalf("expected DECSTR to reset IRM, DECOM, DECAWM and DECTCEM, got insert=%v origin=%v wrap=%v visible=%v",
+			s.Insert, s.Origin, s.Wrap, s.CursorVisible)
+	}
+	if s.Mode&(ModeAppCursor|ModeAppKeypad) != 0 {
+		t.Fatalf("expected DECSTR to reset the cursor keys and keypad, got mode %b", s.Mode)
+	}
+	if s.ScrollTop != 0 || s.ScrollBottom != 5 {
+		t.Fatalf("expected DECSTR to reset the margins, got %d-%d", s.ScrollTop, s.ScrollBottom)
+	}
+	if s.CursorX != before.CursorX || s.CursorY != before.CursorY {
+		t.Fatalf("expected DECSTR to leave the cursor at (%d,%d), got (%d,%d)", before.CursorX, before.CursorY, s.CursorX, s.CursorY)
+	}
+	if s.SavedCursorX != 0 || s.SavedCursorY != 0 {
+		t.Fatalf("expected DECSTR to home the saved cursor, got (%d,%d)", s.SavedCursorX, s.SavedCursorY)
+	}
+
+	// The screen, tab stops, colors and title survive.
+	if got := extractStr(term, 4, 8, 0); got != "hello" {
+		t.Fatalf("expected DECSTR to keep the screen, got %q", got)
+	}
+	if len(s.TabStops) != 1 || s.TabStops[0] != 4 {
+		t.Fatalf("expected DECSTR to keep the tab stops, got %v", s.TabStops)
+	}
+	if s.Palette[1] != before.Palette[1] || s.Title != "title" {
+		t.Fatal("expected DECSTR to keep the palette and title")
+	}
+
+	// Text written afterwards has default attributes.
+	writeSeq(t, term, "x")
+	if g := term.Cell(before.CursorX, before.CursorY); g.Char != 'x' || g.FG != DefaultFG || g.Mode != 0 {
+		t.Fatalf("expected default attributes after DECSTR, got %+v", g)
+	}
+}
+
+func TestFullReset(t *testing.T) {
+	term := New(WithSize(30, 4), WithScrollbackCapture(10))
+	writeSeq(t, term, "\033]4;1;rgb:12/34/56\007"+strings.Repeat("primary screen text\r\n", 5))
+	writeSeq(t, term, "\033[?1049h"+strings.Repeat("x", 30)+"\033[?2004h")
+
+	writeSeq(t, term, "\033c")
+	s := term.DumpState()
+
+	if s.AltScreen {
+		t.Fatal("expected RIS to return to the primary screen")
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 30; x++ {
+			if s.PrimaryBuffer[y][x] != blankGlyph || s.AlternateBuffer[y][x] != blankGlyph {
+				t.Fatalf("expected RIS to clear both screens, found content at (%d,%d)", x, y)
+			}
+		}
+	}
+	if lines, dropped := term.TakeScrollback(); len(lines) != 0 || dropped != 0 {
+		t.Fatalf("expected RIS to clear the scrollback, got %d lines and %d dropped", len(lines), dropped)
+	}
+	if s.Modes[2004] {
+		t.Fatal("expected RIS to reset the tracked modes")
+	}
+	if s.Palette[1] != DefaultPalette().Indexed[1] {
+		t.Fatalf("expected RIS to reset the palette, got %06x", s.Palette[1])
+	}
+}
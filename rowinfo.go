@@ -0,0 +1,40 @@
+package vt10x
+
+// RowInfo is the metadata of a row of a screen buffer.
+type RowInfo struct {
+	// ID identifies the line the row shows. It stays with the line as the screen scrolls, and a row scrolled or
+	// inserted in gets a new one, so a frontend can move the rows it already drew rather than draw them again.
+	ID uint64
+
+	// Wrapped is set if the row's text continues on the next row, because it reached the right margin and wrapped.
+	Wrapped bool
+
+	// Prompt is set if a shell prompt starts on the row, as marked with OSC 133 ; A.
+	Prompt bool
+}
+
+// rowInfos returns the metadata of the rows of lines, whose bookkeeping is meta.
+func rowInfos(lines []line, meta []rowMeta) []RowInfo {
+	infos := make([]RowInfo, len(meta))
+	for y := range meta {
+		infos[y] = RowInfo{ID: meta[y].id, Prompt: meta[y].prompt}
+		if y < len(lines) && len(lines[y]) > 0 {
+			infos[y].Wrapped = lines[y][len(lines[y])-1].attrs&attrWrap != 0
+		}
+	}
+	return infos
+}
+
+// restoreRowInfos sets the ids and prompt flags of the rows of meta from infos, as returned by rowInfos; the wrap flags
+// are restored with the cells. Rows without one keep the id they have.
+func (t *State) restoreRowInfos(meta []rowMeta, infos []RowInfo) {
+	for y := 0; y < len(meta) && y < len(infos); y++ {
+		meta[y].prompt = infos[y].Prompt
+		if id := infos[y].ID; id != 0 {
+			meta[y].id = id
+			if id > t.lineID {
+				t.lineID = id
+			}
+		}
+	}
+}
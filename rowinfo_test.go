@@ -0,0 +1,55 @@
+package vt10x
+
+import "testing"
+
+func TestRowInfoIDs(t *testing.T) {
+	term := New(WithSize(5, 3))
+	before := term.DumpState().PrimaryRows
+	seen := map[uint64]bool{}
+	for _, r := range before {
+		if r.ID == 0 || seen[r.ID] {
+			t.Fatalf("expected distinct row ids, got %+v", before)
+		}
+		seen[r.ID] = true
+	}
+
+	// The ids move up with their lines, and the row scrolled in gets a new one.
+	writeSeq(t, term, "\033[3;1H\n")
+	after := term.DumpState().PrimaryRows
+	if after[0].ID != before[1].ID || after[1].ID != before[2].ID {
+		t.Fatalf("expected ids to scroll with their lines, got %+v from %+v", after, before)
+	}
+	if seen[after[2].ID] {
+		t.Fatalf("expected a new id for the row scrolled in, got %+v", after)
+	}
+
+	restored := New(WithState(term.DumpState()))
+	if got := restored.DumpState().PrimaryRows; got[2].ID != after[2].ID {
+		t.Fatalf("expected ids restored, got %+v", got)
+	}
+	writeSeq(t, restored, "\n")
+	if got := restored.DumpState().PrimaryRows[2].ID; got <= after[2].ID {
+		t.Fatalf("expected ids after a restore to keep increasing, got %d after %d", got, after[2].ID)
+	}
+}
+
+func TestRowInfoFlags(t *testing.T) {
+	term := New(WithSize(5, 3))
+	writeSeq(t, term, "\033]133;A\007$ abcdef")
+	rows := term.DumpState().PrimaryRows
+	if !rows[0].Prompt || rows[1].Prompt {
+		t.Fatalf("expected only the first row to start a prompt, got %+v", rows)
+	}
+	if !rows[0].Wrapped || rows[1].Wrapped {
+		t.Fatalf("expected only the first row to wrap, got %+v", rows)
+	}
+
+	writeSeq(t, term, "\033[1;1H\033[K")
+	if rows := term.DumpState().PrimaryRows; !rows[0].Prompt {
+		t.Fatalf("expected EL to keep the prompt flag, got %+v", rows)
+	}
+	writeSeq(t, term, "\033[2J")
+	if rows := term.DumpState().PrimaryRows; rows[0].Prompt {
+		t.Fatalf("expected ED to reset the prompt flag, got %+v", rows)
+	}
+}
@@ -9,8 +9,9 @@ import (
 )
 
 // rapidAlphabet deliberately excludes space so written text never has trailing blanks, which keeps
-// TrimRight-based comparisons exact. Multi-byte runes are included since the emulator stores one rune per cell.
-const rapidAlphabet = "abcdefgh01234#_あ⌘"
+// TrimRight-based comparisons exact. Multi-byte runes are included since the emulator stores one rune per cell; they
+// are narrow ones, as the model gives every rune one column.
+const rapidAlphabet = "abcdefgh01234#_é⌘"
 
 // capturedLine is a model scrollback entry: the trimmed text and the screen width at capture time, which the
 // emulator's captured lines keep even if the screen is later resized.
@@ -279,7 +280,7 @@ func TestCaptureScrollbackAccountingProperty(t *testing.T) {
 			pre = rapid.IntRange(0, limit).Draw(rt, "pre")
 		}
 		for i := 0; i < pre; i++ {
-			s.scrollback = append(s.scrollback, []rune{'x'})
+			s.scrollback = append(s.scrollback, ScrollbackLine{Text: []rune{'x'}})
 		}
 		preDropped := rapid.IntRange(0, 3).Draw(rt, "preDropped")
 		s.scrollbackDropped = preDropped
@@ -295,7 +296,7 @@ func TestCaptureScrollbackAccountingProperty(t *testing.T) {
 			grid[y] = string(runes)
 		}
 
-		s.captureScrollback(s.lines, n)
+		s.captureScrollback(s.lines, s.meta, n)
 
 		visible := min(n, rows)
 		captured := 0
@@ -309,7 +310,7 @@ func TestCaptureScrollbackAccountingProperty(t *testing.T) {
 			rt.Fatalf("expected %d retained lines, got %d", pre+captured, len(s.scrollback))
 		}
 		for y := 0; y < captured; y++ {
-			if got := string(s.scrollback[pre+y]); got != grid[y] {
+			if got := string(s.scrollback[pre+y].Text); got != grid[y] {
 				rt.Fatalf("captured line %d: expected %q, got %q", y, grid[y], got)
 			}
 		}
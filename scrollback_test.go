@@ -322,14 +322,14 @@ func TestCaptureScrollbackPastBufferEnd(t *testing.T) {
 
 	// A count past the end of the buffer captures only the rows that exist; rows past the end are not
 	// counted as dropped either.
-	s.captureScrollback(s.lines, 5)
+	s.captureScrollback(s.lines, s.meta, 5)
 
 	if len(s.scrollback) != 3 {
 		t.Fatalf("expected 3 captured lines, got %d", len(s.scrollback))
 	}
 
-	if s.scrollback[0][0] != 'a' {
-		t.Errorf("expected first captured line to start with 'a', got %q", s.scrollback[0][0])
+	if s.scrollback[0].Text[0] != 'a' {
+		t.Errorf("expected first captured line to start with 'a', got %q", s.scrollback[0].Text[0])
 	}
 
 	if s.scrollbackDropped != 0 {
@@ -339,7 +339,7 @@ func TestCaptureScrollbackPastBufferEnd(t *testing.T) {
 	// Same when the limit is hit mid-capture: only the rows that exist count as dropped.
 	s.scrollback, s.scrollbackDropped = nil, 0
 	s.scrollbackLimit = 1
-	s.captureScrollback(s.lines, 5)
+	s.captureScrollback(s.lines, s.meta, 5)
 
 	if len(s.scrollback) != 1 {
 		t.Fatalf("expected 1 captured line, got %d", len(s.scrollback))
@@ -0,0 +1,149 @@
+package vt10x
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// FindOptions configures Find and Search.
+type FindOptions struct {
+	// Regexp makes the pattern a regular expression in RE2 syntax rather than literal text.
+	Regexp bool
+
+	// IgnoreCase matches regardless of case, by Unicode simple case folding.
+	IgnoreCase bool
+
+	// Scrollback also searches the lines captured by WithScrollbackCapture that have not been taken yet. Find only.
+	Scrollback bool
+}
+
+// Match is the span of cells a match covers. Matches do not span rows.
+type Match struct {
+	// Y is the row. Find numbers scrollback rows from -1, the line just above the screen, upwards.
+	Y int
+
+	// X is the first cell of the match and EndX the cell just past its last.
+	X, EndX int
+}
+
+// compileFind compiles pattern as Find and Search use it.
+func compileFind(pattern string, opts FindOptions) (*regexp.Regexp, error) {
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// Search returns the matches of pattern in rows, such as a buffer of TerminalState, top to bottom and left to right,
+// numbering rows from 0. opts.Scrollback is ignored.
+func Search(rows [][]Glyph, pattern string, opts FindOptions) ([]Match, error) {
+	re, err := compileFind(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+	var s searcher
+	for y, row := range rows {
+		s.reset()
+		for _, g := range row {
+			s.addGlyph(g)
+		}
+		s.find(re, y)
+	}
+	return s.matches, nil
+}
+
+// Find returns the matches of pattern on the screen, and with opts.Scrollback in the scrollback not yet taken, top to
+// bottom and left to right.
+func (t *State) Find(pattern string, opts FindOptions) ([]Match, error) {
+	re, err := compileFind(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var s searcher
+	if opts.Scrollback {
+		for i, l := range t.scrollback {
+			s.reset()
+			for _, c := range l.Text {
+				s.add(c)
+			}
+			s.find(re, i-len(t.scrollback))
+		}
+	}
+	for y, row := range t.lines {
+		s.reset()
+		for _, c := range row {
+			s.addCell(c)
+		}
+		s.find(re, y)
+	}
+	return s.matches, nil
+}
+
+// searcher matches a row of cells at a time, mapping byte offsets in the row's text back to cells.
+type searcher struct {
+	text    []byte
+	cells   []int // cells[i] is the cell holding byte i of text
+	ncells  int
+	matches []Match
+}
+
+func (s *searcher) reset() {
+	s.text, s.cells, s.ncells = s.text[:0], s.cells[:0], 0
+}
+
+// add appends the next cell, holding c.
+func (s *searcher) add(c rune) {
+	if c == 0 {
+		c = ' '
+	}
+	n := len(s.text)
+	s.text = utf8.AppendRune(s.text, c)
+	for range len(s.text) - n {
+		s.cells = append(s.cells, s.ncells)
+	}
+	s.ncells++
+}
+
+// addCell appends c. The second half of a wide character adds a cell but no text.
+func (s *searcher) addCell(c cell) {
+	if isWideSpacer(c) {
+		s.ncells++
+		return
+	}
+	s.add(c.char)
+}
+
+// addGlyph is addCell for a Glyph.
+func (s *searcher) addGlyph(g Glyph) {
+	if isSpacerGlyph(g) {
+		s.ncells++
+		return
+	}
+	s.add(g.Char)
+}
+
+// endCell returns the cell just past text that ends at byte i: where the next character starts, which is past the
+// second half of a wide character.
+func (s *searcher) endCell(i int) int {
+	if i < len(s.cells) {
+		return s.cells[i]
+	}
+	return s.ncells
+}
+
+// find records the matches of re in the row, which is row y.
+func (s *searcher) find(re *regexp.Regexp, y int) {
+	for _, loc := range re.FindAllIndex(s.text, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		s.matches = append(s.matches, Match{Y: y, X: s.cells[loc[0]], EndX: s.endCell(loc[1])})
+	}
+}
@@ -0,0 +1,85 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	term := New(WithSize(20, 3))
+	writeSeq(t, term, "héllo wörld\r\nHello again\r\nfoo.bar fooxbar")
+
+	for _, tc := range []struct {
+		pattern string
+		opts    FindOptions
+		want    []Match
+	}{
+		{"wörld", FindOptions{}, []Match{{Y: 0, X: 6, EndX: 11}}},
+		{"hello", FindOptions{}, nil},
+		{"HÉLLO", FindOptions{IgnoreCase: true}, []Match{{Y: 0, X: 0, EndX: 5}}},
+		{"hello", FindOptions{IgnoreCase: true}, []Match{{Y: 1, X: 0, EndX: 5}}},
+		{"foo.bar", FindOptions{}, []Match{{Y: 2, X: 0, EndX: 7}}},
+		{"foo.bar", FindOptions{Regexp: true}, []Match{{Y: 2, X: 0, EndX: 7}, {Y: 2, X: 8, EndX: 15}}},
+		{`l+`, FindOptions{Regexp: true}, []Match{{Y: 0, X: 2, EndX: 4}, {Y: 0, X: 9, EndX: 10}, {Y: 1, X: 2, EndX: 4}}},
+		{`z*`, FindOptions{Regexp: true}, nil},
+	} {
+		got, err := term.(Searcher).Find(tc.pattern, tc.opts)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.pattern, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q %+v: got %v, want %v", tc.pattern, tc.opts, got, tc.want)
+		}
+	}
+
+	if _, err := term.(Searcher).Find("(", FindOptions{Regexp: true}); err == nil {
+		t.Error("expected an invalid regexp to fail")
+	}
+}
+
+// TestFindWide checks that matches after wide characters are reported in cells, not runes.
+func TestFindWide(t *testing.T) {
+	term := New(WithSize(20, 3))
+	writeSeq(t, term, "世界 hello")
+
+	got, err := term.(Searcher).Find("界 h", FindOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Match{{Y: 0, X: 2, EndX: 6}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := term.String(); got[:len("世界 hello")] != "世界 hello" {
+		t.Errorf("expected the text without spacers, got %q", got)
+	}
+}
+
+func TestFindScrollback(t *testing.T) {
+	term := New(WithSize(10, 2), WithScrollbackCapture(10))
+	writeSeq(t, term, "match 1\r\nmatch 2\r\nmatch 3\r\nlast")
+
+	got, err := term.(Searcher).Find("match", FindOptions{Scrollback: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Match{{Y: -2, X: 0, EndX: 5}, {Y: -1, X: 0, EndX: 5}, {Y: 0, X: 0, EndX: 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, _ := term.(Searcher).Find("match", FindOptions{}); len(got) != 1 {
+		t.Fatalf("expected only the screen searched without Scrollback, got %v", got)
+	}
+}
+
+func TestSearchState(t *testing.T) {
+	term := New(WithSize(10, 2))
+	writeSeq(t, term, "\033[2;3Hneedle")
+
+	got, err := Search(term.DumpState().PrimaryBuffer, "needle", FindOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Match{{Y: 1, X: 2, EndX: 8}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
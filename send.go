@@ -0,0 +1,72 @@
+package vt10x
+
+import "strings"
+
+const (
+	pasteStart = "\033[200~"
+	pasteEnd   = "\033[201~"
+)
+
+// EncodeText returns what a terminal in state s, as returned by DumpMeta, sends the application when text is typed
+// or pasted into it. Line breaks, whether "\n", "\r" or "\r\n", are sent as Enter, so newline mode (LNM) and the
+// keyboard protocols apply to them as EncodeKey applies them. If the application enabled bracketed paste (DECSET
+// 2004), text is sent as a paste, with line breaks as "\r" as xterm sends them and any paste end marker in it removed,
+// except for a final line break, which is sent as Enter after the paste so that a shell runs the command line. The
+// other characters are sent as they are, as typed on the main keyboard; use EncodeKey for keypad and function keys.
+func EncodeText(text string, s TerminalState) []byte {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	if text == "" {
+		return nil
+	}
+	if s.Modes[2004] {
+		body, enter := strings.CutSuffix(text, "\n")
+		var b []byte
+		if body != "" {
+			body = strings.ReplaceAll(body, pasteEnd, "")
+			b = append(b, pasteStart...)
+			b = append(b, strings.ReplaceAll(body, "\n", "\r")...)
+			b = append(b, pasteEnd...)
+		}
+		if enter {
+			b = append(b, EncodeKey(KeyEnter, 0, s)...)
+		}
+		return b
+	}
+	var b []byte
+	for {
+		line, rest, found := strings.Cut(text, "\n")
+		b = append(b, line...)
+		if !found {
+			return b
+		}
+		b = append(b, EncodeKey(KeyEnter, 0, s)...)
+		text = rest
+	}
+}
+
+// SendText sends text to the application, through the writer set with WithWriter, as EncodeText encodes it for the
+// terminal's current modes.
+func (t *State) SendText(text string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.send(EncodeText(text, t.dumpMeta()))
+}
+
+// SendKey sends the application what pressing key with mods sends, through the writer set with WithWriter, as
+// EncodeKey encodes it for the terminal's current modes.
+func (t *State) SendKey(key Key, mods KeyMod) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.send(EncodeKey(key, mods, t.dumpMeta()))
+}
+
+func (t *State) send(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	_, err := t.w.Write(b)
+	return err
+}
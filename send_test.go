@@ -0,0 +1,54 @@
+package vt10x
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		mode  ModeFlag
+		paste bool
+		want  string
+	}{
+		{"plain", "ls -l", 0, false, "ls -l"},
+		{"line breaks", "a\nb\r\nc\rd\n", 0, false, "a\rb\rc\rd\r"},
+		{"newline mode", "a\nb\n", ModeCRLF, false, "a\r\nb\r\n"},
+		{"keypad mode leaves text alone", "1+2\n", ModeAppKeypad, false, "1+2\r"},
+		{"empty", "", 0, true, ""},
+		{"paste", "a\nb", 0, true, "\033[200~a\rb\033[201~"},
+		{"paste then enter", "make\n", ModeCRLF, true, "\033[200~make\033[201~\r\n"},
+		{"paste of a line break", "\r\n", 0, true, "\r"},
+		{"paste end marker removed", "x\033[201~rm -rf /\n", 0, true, "\033[200~xrm -rf /\033[201~\r"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := TerminalState{Mode: tc.mode, Modes: map[int]bool{2004: tc.paste}}
+			if got := string(EncodeText(tc.text, s)); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSendText(t *testing.T) {
+	var out bytes.Buffer
+	term := New(WithWriter(&out))
+
+	if err := term.(InputSender).SendText("echo hi\n"); err != nil {
+		t.Fatal(err)
+	}
+	writeSeq(t, term, "\033[20h\033[?2004h\033[?1h")
+	if err := term.(InputSender).SendText("a\nb\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := term.(InputSender).SendKey(KeyUp, 0); err != nil {
+		t.Fatal(err)
+	}
+	want := "echo hi\r" + "\033[200~a\rb\033[201~\r\n" + "\033OA"
+	if got := out.String(); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
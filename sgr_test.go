@@ -0,0 +1,131 @@
+package vt10x
+
+import (
+	"testing"
+)
+
+// sgrGlyph writes a single character with the given SGR parameters and returns the resulting cell.
+func sgrGlyph(t *testing.T, params string) Glyph {
+	t.Helper()
+
+	term := New(WithSize(10, 2))
+	writeSeq(t, term, "\033["+params+"mX")
+	return term.Cell(0, 0)
+}
+
+func TestSGRUnderlineStyles(t *testing.T) {
+	cases := []struct {
+		params string
+		want   UnderlineStyle
+	}{
+		{"4", UnderlineSingle},
+		{"4:0", UnderlineNone},
+		{"4:1", UnderlineSingle},
+		{"4:2", UnderlineDouble},
+		{"4:3", UnderlineCurly},
+		{"4:4", UnderlineDotted},
+		{"4:5", UnderlineDashed},
+		{"4:3;24", UnderlineNone},
+		{"4:3;0", UnderlineNone},
+	}
+	for _, tc := range cases {
+		t.Run(tc.params, func(t *testing.T) {
+			g := sgrGlyph(t, tc.params)
+			if g.Underline != tc.want {
+				t.Fatalf("expected underline style %d, got %d", tc.want, g.Underline)
+			}
+			if IsUnderline(g.Mode) != (tc.want != UnderlineNone) {
+				t.Fatalf("expected IsUnderline=%v for style %d", tc.want != UnderlineNone, tc.want)
+			}
+		})
+	}
+}
+
+func TestSGRUnderlineBadStyleIgnored(t *testing.T) {
+	g := sgrGlyph(t, "4:3;4:9")
+	if g.Underline != UnderlineCurly {
+		t.Fatalf("expected an out-of-range style to be ignored, got %d", g.Underline)
+	}
+}
+
+func TestSGRUnderlineColor(t *testing.T) {
+	cases := []struct {
+		params string
+		want   Color
+	}{
+		{"", DefaultUnderline},
+		{"58;5;196", 196},
+		{"58:5:196", 196},
+		{"58;2;1;2;3", 0x010203},
+		{"58:2::1:2:3", 0x010203},
+		{"58:2:0:1:2:3", 0x010203},
+		{"58:2:1:2:3", 0x010203},
+		{"58;5;196;59", DefaultUnderline},
+		{"58;5;196;0", DefaultUnderline},
+	}
+	for _, tc := range cases {
+		t.Run(tc.params, func(t *testing.T) {
+			if g := sgrGlyph(t, tc.params); g.UnderlineColor != tc.want {
+				t.Fatalf("expected underline color %#x, got %#x", tc.want, g.UnderlineColor)
+			}
+		})
+	}
+}
+
+// TestSGRColonColors ensures the colon form of extended colors no longer truncates the remaining parameters.
+func TestSGRColonColors(t *testing.T) {
+	g := sgrGlyph(t, "38:2::255:128:0;48:5:17;1")
+	if g.FG != 0xff8000 {
+		t.Fatalf("expected fg #ff8000, got %#x", g.FG)
+	}
+	if g.BG != 17 {
+		t.Fatalf("expected bg 17, got %d", g.BG)
+	}
+	if !IsBold(g.Mode) {
+		t.Fatal("expected parameters after the colon colors to apply")
+	}
+}
+
+func TestSGRTextAttributes(t *testing.T) {
+	cases := []struct {
+		name   string
+		set    string
+		reset  string
+		isMode func(int16) bool
+	}{
+		{"bold", "1", "22", IsBold},
+		{"dim", "2", "22", IsDim},
+		{"italic", "3", "23", IsItalic},
+		{"blink", "5", "25", IsBlink},
+		{"reverse", "7", "27", IsReverse},
+		{"invisible", "8", "28", IsInvisible},
+		{"strikethrough", "9", "29", IsStrikethrough},
+		{"overline", "53", "55", IsOverline},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if g := sgrGlyph(t, tc.set); !tc.isMode(g.Mode) {
+				t.Fatalf("expected SGR %s to set %s", tc.set, tc.name)
+			}
+			if g := sgrGlyph(t, tc.set+";"+tc.reset); tc.isMode(g.Mode) {
+				t.Fatalf("expected SGR %s to reset %s", tc.reset, tc.name)
+			}
+			if g := sgrGlyph(t, tc.set+";0"); tc.isMode(g.Mode) {
+				t.Fatalf("expected SGR 0 to reset %s", tc.name)
+			}
+		})
+	}
+}
+
+func TestSGRAttributesInDumpState(t *testing.T) {
+	term := New(WithSize(10, 2))
+	writeSeq(t, term, "\033[2;9;53mX")
+
+	g := term.DumpState().PrimaryBuffer[0][0]
+	if !IsDim(g.Mode) || !IsStrikethrough(g.Mode) || !IsOverline(g.Mode) {
+		t.Fatalf("expected dim, strikethrough and overline in the dumped glyph, got mode %#x", g.Mode)
+	}
+	if IsBold(g.Mode) || IsInvisible(g.Mode) {
+		t.Fatalf("unexpected attributes in the dumped glyph, got mode %#x", g.Mode)
+	}
+}
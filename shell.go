@@ -0,0 +1,115 @@
+package vt10x
+
+import "strconv"
+
+// maxCommands caps the shell commands the terminal remembers; marking more discards the oldest.
+const maxCommands = 1000
+
+// Command is a shell command, as marked by a shell with OSC 133 shell integration: its prompt, the command line typed
+// at it, and the command's output. Rows are screen rows of the primary screen, and are negative once they have
+// scrolled off the top, so row -1 is the last line to scroll off.
+type Command struct {
+	// PromptRow is the row the prompt starts on (OSC 133 ; A).
+	PromptRow int
+
+	// InputRow is the row the command line starts on (OSC 133 ; B), where the prompt ends. It is PromptRow if the
+	// shell did not mark it.
+	InputRow int
+
+	// Executed is set once the command runs (OSC 133 ; C), and OutputRow is then the first row of its output.
+	Executed  bool
+	OutputRow int
+
+	// Finished is set once the command finishes (OSC 133 ; D). EndRow is then the row after the last row of its
+	// output, and ExitCode its exit status, or -1 if the shell did not report one. A command that finishes without
+	// running, such as an empty command line, has OutputRow equal to EndRow.
+	Finished bool
+	EndRow   int
+	ExitCode int
+}
+
+// shellMarks holds the commands marked with OSC 133, with their rows counted from the first row of the primary screen
+// when the terminal was created, so that they need no updating as the screen scrolls.
+type shellMarks struct {
+	commands []Command
+	scrolled int // rows scrolled off the top of the primary screen
+
+	// promptX and inputX are the columns the last command's prompt and command line start at; inputX is -1 until
+	// the shell marks it.
+	promptX, inputX int
+}
+
+// Commands returns the shell commands marked with OSC 133 shell integration sequences, oldest first, so that session
+// players can jump between commands and extract each one's output. It returns nothing for shells without shell
+// integration.
+func (t *State) Commands() []Command {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	commands := make([]Command, len(t.marks.commands))
+	for i, c := range t.marks.commands {
+		c.PromptRow -= t.marks.scrolled
+		c.InputRow -= t.marks.scrolled
+		c.OutputRow -= t.marks.scrolled
+		c.EndRow -= t.marks.scrolled
+		commands[i] = c
+	}
+	return commands
+}
+
+// markRow returns the row a mark at the cursor applies to: the cursor's row, or the next one for a mark at the end
+// of a row of output.
+func (t *State) markRow(next bool) int {
+	y := t.cur.Y
+	if next && t.cur.X > 0 {
+		y++
+	}
+	return t.marks.scrolled + y
+}
+
+// shellMark handles OSC 133 ; A, B, C and D. Marks on the alternate screen, where full-screen programs rather than the
+// shell run, are ignored.
+func (t *State) shellMark(s *strEscape) {
+	if t.mode&ModeAltScreen != 0 {
+		return
+	}
+	var last *Command
+	if n := len(t.marks.commands); n > 0 {
+		last = &t.marks.commands[n-1]
+	}
+	switch s.argString(1, "") {
+	case "A": // prompt start
+		if len(t.marks.commands) == maxCommands {
+			t.marks.commands = append(t.marks.commands[:0], t.marks.commands[1:]...)
+		}
+		row := t.markRow(false)
+		t.marks.commands = append(t.marks.commands, Command{PromptRow: row, InputRow: row, ExitCode: -1})
+		t.marks.promptX, t.marks.inputX = t.cur.X, -1
+		if t.cur.Y >= 0 && t.cur.Y < len(t.meta) {
+			t.meta[t.cur.Y].prompt = true
+		}
+	case "B": // command start
+		if last != nil && !last.Executed {
+			last.InputRow = t.markRow(false)
+			t.marks.inputX = t.cur.X
+		}
+	case "C": // command executed
+		if last != nil && !last.Executed {
+			last.Executed, last.OutputRow = true, t.markRow(false)
+		}
+	case "D": // command finished, with the exit status if there is one
+		if last == nil || last.Finished {
+			break
+		}
+		if !last.Executed {
+			// The command line was empty or cancelled; it produced no output.
+			last.OutputRow = t.markRow(false)
+		}
+		last.Finished, last.EndRow = true, max(t.markRow(true), last.OutputRow)
+		if code, err := strconv.Atoi(s.argString(2, "")); err == nil {
+			last.ExitCode = code
+		}
+	default:
+		t.warnf("unknown OSC 133 mark %q", s)
+	}
+}
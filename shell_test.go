@@ -0,0 +1,65 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+// prompt writes a marked prompt and command line, and the command's marked output and exit status.
+func prompt(t *testing.T, term Terminal, cmd, output string, status string) {
+	t.Helper()
+	writeSeq(t, term, "\033]133;A\007$ \033]133;B\007"+cmd+"\r\n\033]133;C\007"+output+"\033]133;D"+status+"\007")
+}
+
+func TestCommands(t *testing.T) {
+	term := New(WithSize(20, 10))
+	if got := term.(CommandTracker).Commands(); len(got) != 0 {
+		t.Fatalf("expected no commands without shell integration, got %+v", got)
+	}
+
+	prompt(t, term, "ls", "a\r\nb\r\n", ";0")
+	prompt(t, term, "false", "", ";1")
+	prompt(t, term, "", "", "")
+	writeSeq(t, term, "\033]133;A\007$ \033]133;B\007sleep 9\r\n\033]133;C\007zz")
+
+	want := []Command{
+		{PromptRow: 0, InputRow: 0, Executed: true, OutputRow: 1, Finished: true, EndRow: 3, ExitCode: 0},
+		{PromptRow: 3, InputRow: 3, Executed: true, OutputRow: 4, Finished: true, EndRow: 4, ExitCode: 1},
+		{PromptRow: 4, InputRow: 4, Executed: true, OutputRow: 5, Finished: true, EndRow: 5, ExitCode: -1},
+		{PromptRow: 5, InputRow: 5, Executed: true, OutputRow: 6, ExitCode: -1},
+	}
+	if got := term.(CommandTracker).Commands(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected commands\n%+v\ngot\n%+v", want, got)
+	}
+}
+
+func TestCommandsScroll(t *testing.T) {
+	term := New(WithSize(20, 3))
+	prompt(t, term, "seq 3", "1\r\n2\r\n3\r\n", ";0")
+	writeSeq(t, term, "\033]133;A\007$ ")
+
+	// The first command's prompt and output scrolled off the top, so they have negative rows.
+	got := term.(CommandTracker).Commands()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 commands, got %+v", got)
+	}
+	if c := got[0]; c.PromptRow != -2 || c.OutputRow != -1 || c.EndRow != 2 {
+		t.Fatalf("expected the first command at rows -2 to 2, got %+v", c)
+	}
+	if c := got[1]; c.PromptRow != 2 {
+		t.Fatalf("expected the second prompt on the last row, got %+v", c)
+	}
+}
+
+func TestCommandsIgnoredOnAltScreen(t *testing.T) {
+	term := New(WithSize(20, 3))
+	writeSeq(t, term, "\033[?1049h\033]133;A\007\033[?1049l")
+	if got := term.(CommandTracker).Commands(); len(got) != 0 {
+		t.Fatalf("expected marks on the alternate screen ignored, got %+v", got)
+	}
+
+	writeSeq(t, term, "\033]133;A\007\033c")
+	if got := term.(CommandTracker).Commands(); len(got) != 0 {
+		t.Fatalf("expected RIS to forget the commands, got %+v", got)
+	}
+}
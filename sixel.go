@@ -0,0 +1,271 @@
+package vt10x
+
+import (
+	"image"
+	"image/color"
+)
+
+// sixelColors is the number of color registers a sixel image may use.
+const sixelColors = 256
+
+// sixelDefaultColors is the VT340 default palette, in percent, for the first 16 color registers.
+var sixelDefaultColors = [16][3]int{
+	{0, 0, 0}, {20, 20, 80}, {80, 13, 13}, {20, 80, 20},
+	{80, 20, 80}, {20, 80, 80}, {80, 80, 20}, {53, 53, 53},
+	{26, 26, 26}, {33, 33, 60}, {60, 26, 26}, {33, 60, 33},
+	{60, 33, 60}, {33, 60, 60}, {60, 60, 33}, {80, 80, 80},
+}
+
+// sixelDecoder incrementally decodes the body of a DCS sixel sequence (DCS P1;P2;P3 q ... ST). Data is fed a rune at
+// a time as it arrives, so an image of any length is parsed without buffering the sequence.
+type sixelDecoder struct {
+	transparent bool // P2 == 1: pixels never drawn stay transparent instead of taking the background color
+	palette     [sixelColors]color.NRGBA
+	color       int
+
+	x, y          int // current column, and top row of the current six-pixel band
+	width, height int // extent drawn so far, or declared by raster attributes
+	lines         [][]color.NRGBA
+
+	cmd    rune // pending '#', '!' or '"' command collecting numeric arguments
+	args   []int
+	arg    int
+	hasArg bool
+	repeat int
+}
+
+func newSixelDecoder(params []int) *sixelDecoder {
+	d := &sixelDecoder{repeat: 1}
+	if len(params) > 1 && params[1] == 1 {
+		d.transparent = true
+	}
+	for i, c := range sixelDefaultColors {
+		d.palette[i] = sixelRGB(c[0], c[1], c[2])
+	}
+	for i := len(sixelDefaultColors); i < sixelColors; i++ {
+		d.palette[i] = color.NRGBA{A: 0xff}
+	}
+	return d
+}
+
+// isSixelIntro reports whether the DCS parameters collected so far, followed by 'q', start a sixel image rather than
+// another DCS such as DECRQSS ($q).
+func isSixelIntro(buf []rune) bool {
+	for _, c := range buf {
+		if (c < '0' || c > '9') && c != ';' {
+			return false
+		}
+	}
+	return true
+}
+
+// sixelParams parses the numeric DCS parameters preceding the 'q'.
+func sixelParams(buf []rune) []int {
+	var params []int
+	n := 0
+	for _, c := range buf {
+		if c == ';' {
+			params = append(params, n)
+			n = 0
+			continue
+		}
+		n = min(n*10+int(c-'0'), 1<<16)
+	}
+	return append(params, n)
+}
+
+func (d *sixelDecoder) put(c rune) {
+	switch {
+	case c >= '0' && c <= '9':
+		if d.cmd != 0 {
+			d.arg = min(d.arg*10+int(c-'0'), 1<<16)
+			d.hasArg = true
+		}
+		return
+	case c == ';':
+		if d.cmd != 0 {
+			d.pushArg()
+		}
+		return
+	}
+
+	d.finishCommand()
+	switch {
+	case c == '#', c == '!', c == '"':
+		d.cmd = c
+	case c == '$': // graphics carriage return
+		d.x = 0
+	case c == '-': // graphics new line
+		d.x = 0
+		d.y += 6
+	case c >= '?' && c <= '~':
+		d.draw(int(c - '?'))
+	}
+}
+
+func (d *sixelDecoder) pushArg() {
+	if len(d.args) < 8 {
+		d.args = append(d.args, d.arg)
+	}
+	d.arg = 0
+	d.hasArg = false
+}
+
+// finishCommand executes the pending command once a character that cannot be one of its arguments arrives.
+func (d *sixelDecoder) finishCommand() {
+	if d.cmd == 0 {
+		return
+	}
+	if d.hasArg || len(d.args) > 0 {
+		d.pushArg()
+	}
+	args := d.args
+	switch d.cmd {
+	case '!': // repeat introducer: !Pn
+		if len(args) > 0 {
+			d.repeat = max(args[0], 1)
+		}
+	case '#': // color introducer: #Pc selects, #Pc;Pu;Px;Py;Pz defines and selects
+		if len(args) == 0 {
+			break
+		}
+		d.color = args[0] % sixelColors
+		if len(args) >= 5 {
+			switch args[1] {
+			case 1:
+				d.palette[d.color] = sixelHLS(args[2], args[3], args[4])
+			case 2:
+				d.palette[d.color] = sixelRGB(args[2], args[3], args[4])
+			}
+		}
+	case '"': // raster attributes: "Pan;Pad;Ph;Pv
+		if len(args) >= 4 {
+			d.width = max(d.width, min(args[2], maxImageDim))
+			d.height = max(d.height, min(args[3], maxImageDim))
+		}
+	}
+	d.cmd = 0
+	d.args = d.args[:0]
+}
+
+// draw paints the six vertical pixels set in bits, repeated for the pending repeat count, and advances the column.
+func (d *sixelDecoder) draw(bits int) {
+	n := d.repeat
+	d.repeat = 1
+	if bits != 0 {
+		c := d.palette[d.color]
+		for i := 0; i < 6; i++ {
+			y := d.y + i
+			if bits&(1<<i) == 0 || y >= maxImageDim {
+				continue
+			}
+			for len(d.lines) <= y {
+				d.lines = append(d.lines, nil)
+			}
+			end := min(d.x+n, maxImageDim)
+			if len(d.lines[y]) < end {
+				d.lines[y] = append(d.lines[y], make([]color.NRGBA, end-len(d.lines[y]))...)
+			}
+			for x := d.x; x < end; x++ {
+				d.lines[y][x] = c
+			}
+			d.height = max(d.height, y+1)
+		}
+		d.width = max(d.width, min(d.x+n, maxImageDim))
+	}
+	d.x = min(d.x+n, maxImageDim)
+}
+
+// image returns the decoded image, filling undrawn pixels with bg unless the image is transparent, or nil if
+// nothing was drawn.
+func (d *sixelDecoder) image(bg color.NRGBA) *image.NRGBA {
+	d.finishCommand()
+	if d.width == 0 || d.height == 0 {
+		return nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, d.width, d.height))
+	for y := 0; y < d.height; y++ {
+		var line []color.NRGBA
+		if y < len(d.lines) {
+			line = d.lines[y]
+		}
+		for x := 0; x < d.width; x++ {
+			c := bg
+			if x < len(line) && line[x].A != 0 {
+				c = line[x]
+			} else if d.transparent {
+				continue
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func sixelRGB(r, g, b int) color.NRGBA {
+	pct := func(v int) uint8 {
+		return uint8((min(v, 100)*255 + 50) / 100)
+	}
+	return color.NRGBA{R: pct(r), G: pct(g), B: pct(b), A: 0xff}
+}
+
+// sixelHLS converts a DEC HLS color, whose hue 0 is blue rather than red, to RGB.
+func sixelHLS(h, l, s int) color.NRGBA {
+	hue := float64((h+240)%360) / 360
+	light := float64(min(l, 100)) / 100
+	sat := float64(min(s, 100)) / 100
+
+	if sat == 0 {
+		v := int(light * 100)
+		return sixelRGB(v, v, v)
+	}
+	var q float64
+	if light < 0.5 {
+		q = light * (1 + sat)
+	} else {
+		q = light + sat - light*sat
+	}
+	p := 2*light - q
+	channel := func(t float64) int {
+		if t < 0 {
+			t++
+		} else if t > 1 {
+			t--
+		}
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 1.0/2:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return int(v*100 + 0.5)
+	}
+	return sixelRGB(channel(hue+1.0/3), channel(hue), channel(hue-1.0/3))
+}
+
+// drawSixel places the image decoded from the sixel sequence just ended at the cursor, then moves the cursor to the
+// row below the image, scrolling as needed, the way a VT340 with sixel scrolling enabled does.
+func (t *State) drawSixel() {
+	r, g, b := t.ResolveColor(DefaultBG)
+	img := t.sixel.image(color.NRGBA{R: r, G: g, B: b, A: 0xff})
+	t.sixel = nil
+	if img == nil || !t.terminalID.Model.sixel() {
+		return
+	}
+
+	cols, rows := t.imageCells(img)
+	p := t.addImage(ImagePlacement{X: t.cur.X, Y: t.cur.Y, Cols: cols, Rows: rows, Image: img})
+	for i := 0; i < p.Rows; i++ {
+		if t.cur.Y == t.bottom {
+			t.scrollUp(t.top, 1, true)
+		} else {
+			t.moveTo(t.cur.X, t.cur.Y+1)
+		}
+	}
+}
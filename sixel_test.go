@@ -0,0 +1,126 @@
+package vt10x
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSixelImagePlacement(t *testing.T) {
+	term := New(WithSize(20, 10), WithCellSize(4, 6))
+
+	// A 6x12 red block: two bands of six full columns, drawn with a redefined color register.
+	writeSeq(t, term, "\033[2;3H\033Pq#1;2;100;0;0#1!6~-!6~\033\\")
+
+	state := term.DumpState()
+	if len(state.Images) != 1 {
+		t.Fatalf("expected one image, got %d", len(state.Images))
+	}
+	p := state.Images[0]
+	if p.X != 2 || p.Y != 1 || p.Cols != 2 || p.Rows != 2 {
+		t.Fatalf("unexpected placement %+v", p)
+	}
+	if b := p.Image.Bounds(); b.Dx() != 6 || b.Dy() != 12 {
+		t.Fatalf("expected a 6x12 image, got %v", b)
+	}
+	if c := color.NRGBAModel.Convert(p.Image.At(5, 11)).(color.NRGBA); c != (color.NRGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("expected red pixels, got %+v", c)
+	}
+
+	// The cursor moves below the image, in the column it started.
+	if cur := term.Cursor(); cur.X != 2 || cur.Y != 3 {
+		t.Fatalf("expected cursor at (2,3), got (%d,%d)", cur.X, cur.Y)
+	}
+	if s := strings.TrimSpace(term.String()); s != "" {
+		t.Fatalf("expected no sixel data on screen, got %q", s)
+	}
+}
+
+func TestSixelRasterAndTransparency(t *testing.T) {
+	term := New(WithSize(20, 10))
+
+	// Raster attributes declare a 20x20 image of which only the top-left pixel is drawn.
+	writeSeq(t, term, "\033P0;1q\"1;1;20;20#0@\033\\")
+	img := term.DumpState().Images[0].Image
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 20 {
+		t.Fatalf("expected the declared 20x20 size, got %v", b)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a == 0 {
+		t.Fatal("expected the drawn pixel to be opaque")
+	}
+	if _, _, _, a := img.At(10, 10).RGBA(); a != 0 {
+		t.Fatal("expected undrawn pixels to stay transparent with P2=1")
+	}
+
+	// Without P2=1, undrawn pixels take the background color.
+	writeSeq(t, term, "\033Pq\"1;1;4;4#0@\033\\")
+	img = term.DumpState().Images[1].Image
+	if _, _, _, a := img.At(3, 3).RGBA(); a == 0 {
+		t.Fatal("expected undrawn pixels to be filled with the background")
+	}
+}
+
+func TestSixelHLS(t *testing.T) {
+	// DEC hue 120 is red.
+	if c := sixelHLS(120, 50, 100); c != (color.NRGBA{R: 0xff, A: 0xff}) {
+		t.Fatalf("expected red, got %+v", c)
+	}
+	if c := sixelHLS(0, 50, 100); c != (color.NRGBA{B: 0xff, A: 0xff}) {
+		t.Fatalf("expected blue, got %+v", c)
+	}
+}
+
+func TestSixelImagesScrollAndClear(t *testing.T) {
+	term := New(WithSize(10, 5), WithCellSize(1, 6))
+
+	// A one-row image on row 3; the cursor then moves to row 4.
+	writeSeq(t, term, "\033[4;1H\033Pq~\033\\")
+	writeSeq(t, term, "\n\n")
+	if imgs := term.DumpState().Images; len(imgs) != 1 || imgs[0].Y != 1 {
+		t.Fatalf("expected the image to scroll up to row 1, got %+v", imgs)
+	}
+
+	writeSeq(t, term, "\033[?1049h")
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected no images on the alternate screen, got %d", len(imgs))
+	}
+	writeSeq(t, term, "\033[?1049l")
+	if imgs := term.DumpState().Images; len(imgs) != 1 {
+		t.Fatalf("expected the image back with the primary screen, got %d", len(imgs))
+	}
+
+	writeSeq(t, term, "\033[2J")
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected ED 2 to erase the image, got %d", len(imgs))
+	}
+}
+
+func TestSixelBounded(t *testing.T) {
+	term := New(WithSize(80, 24))
+
+	// Repeat counts and raster sizes far past the limit are clamped rather than allocated.
+	writeSeq(t, term, "\033Pq\"1;1;99999;99999!99999~\033\\")
+	b := term.DumpState().Images[0].Image.Bounds()
+	if b.Dx() != maxImageDim || b.Dy() != maxImageDim {
+		t.Fatalf("expected a %dx%[1]d image, got %v", maxImageDim, b)
+	}
+
+	for i := 0; i < maxImages+10; i++ {
+		writeSeq(t, term, "\033[H\033Pq~\033\\")
+	}
+	if n := len(term.DumpState().Images); n != maxImages {
+		t.Fatalf("expected at most %d images, got %d", maxImages, n)
+	}
+}
+
+func TestSixelDoesNotCaptureOtherDCS(t *testing.T) {
+	term := New(WithSize(20, 5))
+
+	writeSeq(t, term, "\033P$qm\033\\ok")
+	if imgs := term.DumpState().Images; len(imgs) != 0 {
+		t.Fatalf("expected DECRQSS not to produce an image, got %d", len(imgs))
+	}
+	if s := extractStr(term, 0, 1, 0); s != "ok" {
+		t.Fatalf("expected text after the DCS, got %q", s)
+	}
+}
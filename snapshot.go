@@ -0,0 +1,216 @@
+package vt10x
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+)
+
+// snapshotMagic starts every encoded snapshot, followed by the format version.
+const snapshotMagic = "vt10x-snapshot\n"
+
+// snapshotVersion is the version of the snapshot format written by EncodeSnapshot. snapshotFormats describes it and
+// every earlier version.
+const snapshotVersion = 2
+
+// ErrSnapshotFormat is returned by DecodeSnapshot for data that is not a snapshot, or is one written by a newer
+// version of this library in a format this one does not know.
+var ErrSnapshotFormat = errors.New("vt10x: unrecognized snapshot format")
+
+// snapshotV1 is the gob-encoded body of a version 1 snapshot. Images are stored as PNG since the gob encoding of
+// image.Image depends on its concrete type.
+type snapshotV1 struct {
+	State  TerminalState
+	Images []snapshotImage
+}
+
+type snapshotImage struct {
+	X, Y, Cols, Rows int
+	ID, PlacementID  uint32
+	Z                int32
+	PNG              []byte
+}
+
+// SnapshotOption configures EncodeSnapshot.
+type SnapshotOption func(*snapshotOptions)
+
+type snapshotOptions struct {
+	codec Codec
+}
+
+// WithCompression compresses the snapshot with c. DecodeSnapshot finds the codec from the name recorded in the
+// snapshot, so readers only need it registered.
+func WithCompression(c Codec) SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.codec = c
+	}
+}
+
+// EncodeSnapshot writes s to w in a compact, versioned binary format that DecodeSnapshot reads back, for persisting
+// terminal state.
+func EncodeSnapshot(w io.Writer, s TerminalState, opts ...SnapshotOption) error {
+	var o snapshotOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	body := snapshotV1{State: s}
+	body.State.Images = nil
+	var err error
+	if body.Images, err = encodeImages(s.Images); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(snapshotVersion); err != nil {
+		return err
+	}
+	cw, err := writeCodecHeader(bw, o.codec)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(cw).Encode(&body); err != nil {
+		return fmt.Errorf("vt10x: encoding snapshot: %w", err)
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// DecodeSnapshot reads a snapshot written by EncodeSnapshot.
+func DecodeSnapshot(r io.Reader) (TerminalState, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, head); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return TerminalState{}, ErrSnapshotFormat
+		}
+		return TerminalState{}, err
+	}
+	if string(head[:len(snapshotMagic)]) != snapshotMagic {
+		return TerminalState{}, ErrSnapshotFormat
+	}
+	version := int(head[len(snapshotMagic)])
+	if version > snapshotVersion {
+		return TerminalState{}, fmt.Errorf("%w: version %d is newer than this library's %d", ErrSnapshotFormat, version,
+			snapshotVersion)
+	}
+	format, ok := snapshotFormats[version]
+	if !ok {
+		return TerminalState{}, ErrSnapshotFormat
+	}
+
+	var body io.Reader = br
+	if format.codecHeader {
+		cr, err := readCodecHeader(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return TerminalState{}, ErrSnapshotFormat
+			}
+			return TerminalState{}, fmt.Errorf("vt10x: decoding snapshot: %w", err)
+		}
+		defer cr.Close()
+		body = cr
+	}
+
+	decoded, err := format.decode(gob.NewDecoder(body))
+	if err != nil {
+		return TerminalState{}, fmt.Errorf("vt10x: decoding snapshot: %w", err)
+	}
+	for v := version; v < snapshotVersion; v++ {
+		if decoded, err = snapshotFormats[v].migrate(decoded); err != nil {
+			return TerminalState{}, fmt.Errorf("vt10x: migrating version %d snapshot: %w", v, err)
+		}
+	}
+	return decoded.(*snapshotV1).state()
+}
+
+// snapshotFormat describes one version of the snapshot format.
+type snapshotFormat struct {
+	// codecHeader is whether the version byte is followed by a codec header.
+	codecHeader bool
+
+	// decode decodes the body of a snapshot of this version.
+	decode func(*gob.Decoder) (any, error)
+
+	// migrate converts a body decoded by decode, or migrated from an earlier version, into the body of the next
+	// version. It is nil for the current version.
+	migrate func(any) (any, error)
+}
+
+// snapshotFormats lists every snapshot format version DecodeSnapshot reads, so that states saved by older versions
+// of the library can be restored by newer ones. To change the format, add a version that decodes the new body, point
+// snapshotVersion at it, and give the previous version a migrate function that converts its body to the new one;
+// snapshots of any earlier version then pass through each migration in turn. Versions must never be removed, and
+// testdata/snapshots holds a snapshot of each one to keep them readable.
+var snapshotFormats = map[int]snapshotFormat{
+	1: {
+		decode: decodeSnapshotV1,
+		// Version 2 only added the codec header, so the body carries over as it is.
+		migrate: func(body any) (any, error) { return body, nil },
+	},
+	2: {
+		codecHeader: true,
+		decode:      decodeSnapshotV1,
+	},
+}
+
+func decodeSnapshotV1(dec *gob.Decoder) (any, error) {
+	var body snapshotV1
+	if err := dec.Decode(&body); err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+// state returns the terminal state held by the body.
+func (body *snapshotV1) state() (TerminalState, error) {
+	s := body.State
+	var err error
+	if s.Images, err = decodeImages(body.Images); err != nil {
+		return TerminalState{}, err
+	}
+	return s, nil
+}
+
+// encodeImages converts placements to their PNG-encoded form.
+func encodeImages(placements []ImagePlacement) ([]snapshotImage, error) {
+	var images []snapshotImage
+	for _, p := range placements {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, p.Image); err != nil {
+			return nil, fmt.Errorf("vt10x: encoding snapshot image: %w", err)
+		}
+		images = append(images, snapshotImage{
+			X: p.X, Y: p.Y, Cols: p.Cols, Rows: p.Rows,
+			ID: p.ID, PlacementID: p.PlacementID, Z: p.Z,
+			PNG: buf.Bytes(),
+		})
+	}
+	return images, nil
+}
+
+// decodeImages converts images encoded by encodeImages back to placements.
+func decodeImages(images []snapshotImage) ([]ImagePlacement, error) {
+	var placements []ImagePlacement
+	for _, si := range images {
+		img, err := png.Decode(bytes.NewReader(si.PNG))
+		if err != nil {
+			return nil, fmt.Errorf("vt10x: decoding snapshot image: %w", err)
+		}
+		placements = append(placements, ImagePlacement{
+			X: si.X, Y: si.Y, Cols: si.Cols, Rows: si.Rows,
+			ID: si.ID, PlacementID: si.PlacementID, Z: si.Z,
+			Image: img,
+		})
+	}
+	return placements, nil
+}
@@ -0,0 +1,95 @@
+package vt10x
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	term := New(WithSize(20, 6), WithCellSize(1, 6))
+	writeSeq(t, term, "\033]0;title\007\033[22t\033[1;38;2;1;2;3mhello\033[m\r\n\033[4:3munder\033Pq#1~~\033\\\033[?2004h\033[3;5r")
+	want := term.DumpState()
+
+	var buf bytes.Buffer
+	if err := EncodeSnapshot(&buf, want); err != nil {
+		t.Fatalf("EncodeSnapshot returned error: %v", err)
+	}
+	got, err := DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("DecodeSnapshot returned error: %v", err)
+	}
+
+	// Images decode to equivalent pixels but not identical values, so compare them separately.
+	if len(got.Images) != 1 || got.Images[0].Image.Bounds() != want.Images[0].Image.Bounds() {
+		t.Fatalf("expected the image to round-trip, got %+v", got.Images)
+	}
+	r1, g1, b1, a1 := got.Images[0].Image.At(1, 5).RGBA()
+	r2, g2, b2, a2 := want.Images[0].Image.At(1, 5).RGBA()
+	if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+		t.Fatal("expected image pixels to round-trip")
+	}
+	got.Images, want.Images = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("state differs after round trip:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestDecodeSnapshotRejectsOtherData(t *testing.T) {
+	for _, data := range []string{"", "hello world", snapshotMagic + "\x63"} {
+		if _, err := DecodeSnapshot(bytes.NewReader([]byte(data))); !errors.Is(err, ErrSnapshotFormat) {
+			t.Fatalf("%q: expected ErrSnapshotFormat, got %v", data, err)
+		}
+	}
+}
+
+func TestWithState(t *testing.T) {
+	term := New(WithSize(20, 6))
+	writeSeq(t, term, "\033]0;title\007\033[22t\033]4;1;rgb:12/34/56\007\033]10;rgb:ff/00/00\007\033[3g\033[1;5H\033H"+
+		"\033[1;31mred\033[m\033[?2004h\033[?25l\033[4 q\033[2;5r\033[3;2Hx\r\n\033[?1049hon the alt screen\033[?6h\033[2;3H")
+	want := term.DumpState()
+
+	restored := New(WithState(want))
+	got := restored.DumpState()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("state differs after restore:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// The restored terminal carries on where the original left off.
+	writeSeq(t, term, "\033[?1049lmore")
+	writeSeq(t, restored, "\033[?1049lmore")
+	if got, want := restored.DumpState(), term.DumpState(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("state differs after further output:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+// TestSnapshotFixtures decodes a snapshot written in each earlier format version, which must stay readable.
+func TestSnapshotFixtures(t *testing.T) {
+	for v := 1; v <= snapshotVersion; v++ {
+		data, err := os.ReadFile(fmt.Sprintf("testdata/snapshots/v%d.snap", v))
+		if err != nil {
+			t.Fatalf("missing fixture for version %d: %v", v, err)
+		}
+		s, err := DecodeSnapshot(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("version %d: DecodeSnapshot returned error: %v", v, err)
+		}
+
+		restored := New(WithState(s))
+		if got := extractStr(restored, 0, 4, 0) + extractStr(restored, 0, 4, 1); got != "helloworld" {
+			t.Fatalf("version %d: unexpected screen %q", v, got)
+		}
+		if g := restored.Cell(0, 0); g.FG != Green+8 || g.Mode&attrBold == 0 {
+			t.Fatalf("version %d: unexpected attributes %+v", v, g)
+		}
+		if s.Title != "fixture" || !s.Modes[2004] || s.CursorY != 2 {
+			t.Fatalf("version %d: unexpected state: title %q, bracketed paste %v, cursor row %d", v, s.Title, s.Modes[2004], s.CursorY)
+		}
+		if len(s.Images) != 1 || s.Images[0].Image.Bounds().Dx() != 2 {
+			t.Fatalf("version %d: expected the sixel image, got %+v", v, s.Images)
+		}
+	}
+}
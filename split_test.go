@@ -0,0 +1,245 @@
+package vt10x
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// splitCorpus covers every parser state an escape sequence can be interrupted in.
+var splitCorpus = []string{
+	"plain text\r\nnext line",
+	"\033[38;2;10;20;30mtruecolor\033[m",
+	"\033[4:3m\033[58:2::1:2:3mcurly\033[59;24m",
+	"\033[5;10Hx\033[2J\033[1;1H",
+	"\033[?1049h\033[?25l\033[?1049l\033[?25h",
+	"\033[2 q\033[22;0t\033[23;0t",
+	"\033]0;window title\007\033]2;st title\033\\",
+	"\033]4;1;rgb:ff/00/00;2;#00ff00\033\\\033]104\007",
+	"\033]10;?\033\\\033]11;rgb:1111/2222/3333\007",
+	"\033P$qm\033\\after dcs",
+	"\033Pq#1;2;100;0;0#1!6~-!6~\033\\",
+	"\033_Gignored apc\033\\\033^pm\033\\",
+	"\033(0lqk\033(B\033#8",
+	"\033[3;5r\033[4;1H\033[2L\033[1M\033[r",
+	"\033[1;1H\033[5@\033[3P\033[2X\033[K",
+	"\0337\033[10;10H\0338\033D\033M\033E",
+	"h\u00e9llo \u2713 \u65e5\u672c\U0001f600",
+	"\033]0;t\u00eftle \u2713\007\033[1;1H\u00e9",
+}
+
+// writeChunks writes each chunk in its own Write, the way a PTY copy loop would, checking that each is consumed whole.
+func writeChunks(t *testing.T, term Terminal, chunks ...string) {
+	t.Helper()
+
+	for _, c := range chunks {
+		n, err := term.Write([]byte(c))
+		if err != nil {
+			t.Fatalf("Write(%q) returned error: %v", c, err)
+		}
+		if n != len(c) {
+			t.Fatalf("Write(%q) consumed %d bytes, want %d", c, n, len(c))
+		}
+	}
+}
+
+// TestSplitWrites checks that splitting the input between two Writes at any byte leaves the terminal in the same
+// state as writing it whole.
+func TestSplitWrites(t *testing.T) {
+	for _, seq := range splitCorpus {
+		want := New(WithSize(20, 8))
+		writeChunks(t, want, seq)
+		wantState := want.DumpState()
+
+		for i := 1; i < len(seq); i++ {
+			got := New(WithSize(20, 8))
+			writeChunks(t, got, seq[:i], seq[i:])
+			if gotState := got.DumpState(); !reflect.DeepEqual(gotState, wantState) {
+				t.Fatalf("%q split at %d: state differs from the unsplit write", seq, i)
+			}
+		}
+	}
+}
+
+// TestSplitWritesThreeWays splits each sequence at every pair of bytes, so that a sequence's introducer, parameters
+// and final byte can each land in a different Write.
+func TestSplitWritesThreeWays(t *testing.T) {
+	for _, seq := range splitCorpus {
+		want := New(WithSize(20, 8))
+		writeChunks(t, want, seq)
+		wantState := want.DumpState()
+
+		for i := 1; i < len(seq); i++ {
+			for j := i + 1; j < len(seq); j++ {
+				got := New(WithSize(20, 8))
+				writeChunks(t, got, seq[:i], seq[i:j], seq[j:])
+				if gotState := got.DumpState(); !reflect.DeepEqual(gotState, wantState) {
+					t.Fatalf("%q split at %d and %d: state differs from the unsplit write", seq, i, j)
+				}
+			}
+		}
+	}
+}
+
+// TestSplitWritesBytewise feeds the whole corpus one byte per Write, the worst case for a chunked PTY read.
+func TestSplitWritesBytewise(t *testing.T) {
+	all := strings.Join(splitCorpus, "")
+
+	want := New(WithSize(20, 8))
+	writeChunks(t, want, all)
+
+	chunks := make([]string, len(all))
+	for i := 0; i < len(all); i++ {
+		chunks[i] = all[i : i+1]
+	}
+	got := New(WithSize(20, 8))
+	writeChunks(t, got, chunks...)
+	if !reflect.DeepEqual(got.DumpState(), want.DumpState()) {
+		t.Fatalf("bytewise writes differ from the unsplit write:\n%s\nvs\n%s", got, want)
+	}
+}
+
+// TestSplitParse feeds the corpus through Parse from a reader returning one byte at a time.
+func TestSplitParse(t *testing.T) {
+	all := strings.Join(splitCorpus, "")
+
+	want := New(WithSize(20, 8))
+	writeChunks(t, want, all)
+
+	got := New(WithSize(20, 8))
+	br := bufio.NewReader(iotest.OneByteReader(strings.NewReader(all)))
+	for {
+		if err := got.Parse(br); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Parse returned error: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(got.DumpState(), want.DumpState()) {
+		t.Fatalf("parsing bytewise differs from the unsplit write:\n%s\nvs\n%s", got, want)
+	}
+}
+
+// TestSplitReadFrom feeds the corpus through ReadFrom from readers that split it at every byte.
+func TestSplitReadFrom(t *testing.T) {
+	all := strings.Join(splitCorpus, "")
+
+	want := New(WithSize(20, 8))
+	writeChunks(t, want, all)
+
+	for name, r := range map[string]io.Reader{
+		"whole":    strings.NewReader(all),
+		"bytewise": iotest.OneByteReader(strings.NewReader(all)),
+		"data+EOF": iotest.DataErrReader(iotest.HalfReader(strings.NewReader(all))),
+	} {
+		got := New(WithSize(20, 8))
+		n, err := io.Copy(got, r)
+		if err != nil {
+			t.Fatalf("%s: io.Copy returned error: %v", name, err)
+		}
+		if n != int64(len(all)) {
+			t.Fatalf("%s: expected %d bytes read, got %d", name, len(all), n)
+		}
+		if !reflect.DeepEqual(got.DumpState(), want.DumpState()) {
+			t.Fatalf("%s: reading differs from the unsplit write:\n%s\nvs\n%s", name, got, want)
+		}
+	}
+}
+
+// TestReadFromError ensures ReadFrom stops at a read error, having parsed what came before it.
+func TestReadFromError(t *testing.T) {
+	errBoom := errors.New("boom")
+	term := New(WithSize(20, 4))
+
+	n, err := term.(io.ReaderFrom).ReadFrom(io.MultiReader(strings.NewReader("hello"), iotest.ErrReader(errBoom)))
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+	if n != 5 || extractStr(term, 0, 4, 0) != "hello" {
+		t.Fatalf("expected the input before the error to be parsed, got %d bytes and %q", n, extractStr(term, 0, 4, 0))
+	}
+}
+
+// TestSplitInvalidUTF8 ensures bytes held as the start of a rune are dropped as invalid, without swallowing what
+// follows, when the next write does not finish the rune.
+func TestSplitInvalidUTF8(t *testing.T) {
+	for _, chunks := range [][]string{
+		{"\xe2\x82", "ab"},
+		{"\xe2", "\x82", "a", "b"},
+		{"\xf0\xe2", "\x9c\x93b"},
+	} {
+		want := New(WithSize(20, 4))
+		writeChunks(t, want, strings.Join(chunks, ""))
+		got := New(WithSize(20, 4))
+		writeChunks(t, got, chunks...)
+		if g, w := got.String(), want.String(); g != w {
+			t.Fatalf("%q: got %q, want %q", chunks, g, w)
+		}
+	}
+}
+
+// TestSplitWriteWithChanges ensures WriteWithChanges reports the row of a rune split across calls once it is written.
+func TestSplitWriteWithChanges(t *testing.T) {
+	term := New(WithSize(20, 4))
+	writeSeq(t, term, "\033[3;1H")
+
+	changed, err := term.WriteWithChanges([]byte("\xe2\x9c"))
+	if err != nil || len(changed) != 0 {
+		t.Fatalf("expected no changes for a partial rune, got %v, %v", changed, err)
+	}
+	changed, err = term.WriteWithChanges([]byte("\x93"))
+	if err != nil || !reflect.DeepEqual(changed, []int{2}) {
+		t.Fatalf("expected row 2 to change, got %v, %v", changed, err)
+	}
+	if c := term.Cell(0, 2).Char; c != '\u2713' {
+		t.Fatalf("expected the check mark, got %q", c)
+	}
+}
+
+// TestSplitWritesWithResponder ensures replies to queries are identical however the query is split.
+func TestSplitWritesWithResponder(t *testing.T) {
+	const seq = "\033]10;?\033\\\033]4;1;?\007\033]11;?\007"
+
+	var want bytes.Buffer
+	writeChunks(t, New(WithWriter(&want)), seq)
+
+	for i := 1; i < len(seq); i++ {
+		var got bytes.Buffer
+		writeChunks(t, New(WithWriter(&got)), seq[:i], seq[i:])
+		if got.String() != want.String() {
+			t.Fatalf("split at %d: expected reply %q, got %q", i, want.String(), got.String())
+		}
+	}
+}
+
+// FuzzChunkedWrite checks that however input is cut into Writes, the terminal ends up in the same state as writing it
+// whole. Each byte of cuts is the length of the next chunk, with the remainder written last.
+func FuzzChunkedWrite(f *testing.F) {
+	for _, seq := range splitCorpus {
+		f.Add([]byte(seq), []byte{1, 2, 3, 5, 8})
+		f.Add([]byte(seq), []byte{byte(len(seq) / 2)})
+	}
+
+	f.Fuzz(func(t *testing.T, data, cuts []byte) {
+		want := New(WithSize(20, 8))
+		want.Write(data)
+
+		got := New(WithSize(20, 8))
+		rest := data
+		for _, c := range cuts {
+			n := min(int(c), len(rest))
+			got.Write(rest[:n])
+			rest = rest[n:]
+		}
+		got.Write(rest)
+
+		if !reflect.DeepEqual(got.DumpState(), want.DumpState()) {
+			t.Fatalf("%q cut into %v: state differs from the unsplit write:\n%s\nvs\n%s", data, cuts, got, want)
+		}
+	})
+}
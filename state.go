@@ -3,7 +3,10 @@ package vt10x
 import (
 	"io"
 	"log"
+	"maps"
 	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -12,6 +15,9 @@ const (
 	// maxResizeDim caps resize dimensions so a pathological session recording can't OOM the host by requesting a
 	// terabyte-sized terminal.
 	maxResizeDim = 2048
+
+	// maxTitleStack caps the XTPUSH title stack, matching xterm; pushing past it discards the oldest entry.
+	maxTitleStack = 10
 )
 
 const (
@@ -22,6 +28,12 @@ const (
 	attrItalic
 	attrBlink
 	attrWrap
+	attrDim
+	attrInvisible
+	attrStrike
+	attrOverline
+	attrProtected
+	attrWide
 )
 
 // IsReverse checks if the attribute contains reverse video mode.
@@ -59,6 +71,37 @@ func IsWrap(attr int16) bool {
 	return attr&attrWrap != 0
 }
 
+// IsDim checks if the attribute contains dim (faint) mode.
+func IsDim(attr int16) bool {
+	return attr&attrDim != 0
+}
+
+// IsInvisible checks if the attribute contains invisible (concealed) mode.
+func IsInvisible(attr int16) bool {
+	return attr&attrInvisible != 0
+}
+
+// IsStrikethrough checks if the attribute contains strikethrough mode.
+func IsStrikethrough(attr int16) bool {
+	return attr&attrStrike != 0
+}
+
+// IsOverline checks if the attribute contains overline mode.
+func IsOverline(attr int16) bool {
+	return attr&attrOverline != 0
+}
+
+// IsProtected checks if the attribute contains DECSCA protection from selective erase.
+func IsProtected(attr int16) bool {
+	return attr&attrProtected != 0
+}
+
+// IsWide checks if the attribute marks a cell of a wide character, which takes two columns: the first cell holds the
+// character and the second, whose Char is 0, only continues it.
+func IsWide(attr int16) bool {
+	return attr&attrWide != 0
+}
+
 const (
 	cursorDefault = 1 << iota
 	cursorWrapNext
@@ -74,7 +117,7 @@ const (
 	ModeInsert
 	ModeAppKeypad
 	ModeAltScreen
-	ModeCRLF
+	ModeCRLF // LNM: line feeds also return the cursor to the first column
 	ModeMouseButton
 	ModeMouseMotion
 	ModeReverse
@@ -89,6 +132,8 @@ const (
 	ModeFocus
 	ModeMouseX10
 	ModeMouseMany
+	ModeSmoothScroll // DECSCLM: the application asked for smooth scrolling, which renderers may animate
+
 	ModeMouseMask = ModeMouseButton | ModeMouseMotion | ModeMouseX10 | ModeMouseMany
 )
 
@@ -99,15 +144,33 @@ type ChangeFlag uint32
 const (
 	ChangedScreen ChangeFlag = 1 << iota
 	ChangedTitle
+	ChangedBell
+)
+
+// UnderlineStyle is the kind of underline drawn under a glyph, as selected by SGR 4:x.
+type UnderlineStyle uint8
+
+// Underline styles.
+const (
+	UnderlineNone UnderlineStyle = iota
+	UnderlineSingle
+	UnderlineDouble
+	UnderlineCurly
+	UnderlineDotted
+	UnderlineDashed
 )
 
 type Glyph struct {
-	Char   rune
-	Mode   int16
-	FG, BG Color
+	Char      rune
+	Mode      int16
+	Underline UnderlineStyle
+	FG, BG    Color
+
+	// UnderlineColor is the color set by SGR 58, or DefaultUnderline to draw the underline in the foreground color.
+	UnderlineColor Color
 }
 
-type line []Glyph
+type line []cell
 
 type Cursor struct {
 	Attr  Glyph
@@ -115,12 +178,33 @@ type Cursor struct {
 	State uint8
 }
 
+// CursorShape is the shape an application requested for the cursor.
+type CursorShape uint8
+
+// Cursor shapes selectable via DECSCUSR.
+const (
+	CursorShapeBlock CursorShape = iota
+	CursorShapeUnderline
+	CursorShapeBar
+)
+
+// CursorStyle describes how the cursor should be drawn, as set by DECSCUSR (CSI Ps SP q) or mode 12.
+type CursorStyle struct {
+	Shape CursorShape
+	Blink bool
+}
+
+// defaultCursorStyle is a blinking block, which is what DECSCUSR 0 selects.
+var defaultCursorStyle = CursorStyle{Shape: CursorShapeBlock, Blink: true}
+
 type parseState func(c rune)
 
 // State represents the terminal emulation state. Use Lock/Unlock
 // methods to synchronize data access with VT.
 type State struct {
+	// Deprecated: DebugLogger receives both debug and warning messages when no Logger is set; use WithLogger.
 	DebugLogger *log.Logger
+	logger      Logger
 
 	w             io.Writer
 	mu            sync.Mutex
@@ -130,22 +214,151 @@ type State struct {
 	altLines      []line
 	dirty         []bool // line dirtiness
 	anydirty      bool
-	cur, curSaved Cursor
+	subs          []*subscription
+	notify        []bool // rows changed since subscribers were last sent an Update
+	sent          sentView
+	cur           Cursor
+	curSaved      savedCursor // saved by DECSC for the active screen
+	altCurSaved   savedCursor // and for the inactive one
+	charsets      charsetState
+	charsetG      int // the set ESC ( ) * + is designating
+	cursorStyle   CursorStyle
 	top, bottom   int // scroll limits
 	mode          ModeFlag
+	altScreenMode int // the mode, 47, 1047 or 1049, that switched to the alternate screen
 	state         parseState
 	str           strEscape
 	csi           csiEscape
+	partial       [utf8.UTFMax]byte // leading bytes of a rune split across Writes
+	partialLen    int
 	numlock       bool
 	tabs          []bool
+	blank         line // shared row standing in for every fully blank row until it is written
+	colors        colorTable
 	title         string
+	answerback    string
+	terminalID    TerminalID
+	limitColors   bool // SGR can select only the first colorLimit colors, as set by the terminal's Profile
+	colorLimit    int
+	backspaceBS   bool         // the terminal starts with DECBKM set, and RIS sets it again
+	noModeReports bool         // DECRQM goes unanswered
+	privModes     map[int]bool // state of registered DEC private modes the terminal does not implement
+	titleStack    []string
+	palette       Palette
 	colorOverride map[Color]Color
 
+	// images and altImages are the graphics placed on the active and inactive screen, and sixel decodes the DCS
+	// sixel sequence in progress, if any. cellWidth and cellHeight map image pixels onto cells.
+	images, altImages     []ImagePlacement
+	sixel                 *sixelDecoder
+	cellWidth, cellHeight int
+
+	// kitty collects the APC G sequence in progress, kittyUpload a chunked transmission awaiting its last chunk, and
+	// kittyImages the image data transmitted for later placement.
+	kitty       *kittyParser
+	kittyUpload *kittyCommand
+	kittyImages []kittyImage
+	kittyID     uint32
+
+	// inline collects the iTerm2 inline image in progress; onOversizedImage is told about ones too large to keep.
+	inline           *inlineImageParser
+	onOversizedImage func(name string, size int)
+
+	// keyboard and altKeyboard are the kitty keyboard protocol flags of the active and inactive screen, and
+	// modifyOtherKeys is xterm's modifyOtherKeys level, set with XTMODKEYS.
+	keyboard, altKeyboard keyboardStack
+	modifyOtherKeys       int
+
+	// limits caps what the terminal keeps of its input, and onTruncate is told when something is cut short.
+	limits     Limits
+	onTruncate func(Limit)
+
+	// onResize is told about each change of size made with Resize, and onWindowOp about each window operation.
+	// windowReports has bit n set for each window report n the terminal answers.
+	onResize      func(cols, rows int)
+	onWindowOp    func(WindowRequest)
+	windowReports uint32
+
+	// onBell is told when the bell rings, at most once per bellInterval, and visualBell marks the bell as visual.
+	bell         bellState
+	onBell       func(Bell)
+	bellInterval time.Duration
+	visualBell   bool
+
+	// onNotify is told of desktop notifications, and marks holds the shell commands marked with OSC 133.
+	onNotify func(Notification)
+	marks    shellMarks
+
+	// directories are the working directories reported with OSC 7, oldest first, and onDirectory is told of each.
+	directories []WorkingDirectory
+	onDirectory func(WorkingDirectory)
+
+	// variables are those set by shell integration with OSC 1337, and onVariable is told of each change.
+	variables  map[string]string
+	onVariable func(name, value string)
+
+	// tmuxPassthrough enables unwrapping tmux passthroughs, and passthrough is the one in progress.
+	tmuxPassthrough bool
+	passthrough     *passthrough
+
+	// c1Controls makes runes 0x80-0x9F, and bytes in that range that are not UTF-8, 8-bit C1 controls.
+	c1Controls bool
+
+	// translateLF makes line feeds return the cursor to the first column whatever the newline mode.
+	translateLF bool
+
+	// columnSwitching makes DECCOLM resize the terminal, and resizePending records that it did, so that the resize
+	// handler is told once the terminal is unlocked.
+	columnSwitching bool
+	resizePending   bool
+
+	// vector is the ReGIS or Tektronix graphics being skipped, and onVector is told of each.
+	vector   *vectorSkip
+	onVector func(VectorGraphics)
+
+	// redaction, if set, masks sensitive output before it is written.
+	redaction *redaction
+
+	// promptDetector finds prompts for AtPrompt when the shell does not mark them.
+	promptDetector PromptDetector
+
+	// watches are the patterns registered with Watch, searched for on the rows marked in watchDirty.
+	watches    []*watchRule
+	watchDirty []bool
+
+	// inputEpochs enables tagging cells with inputEpoch, the epoch advanced by AdvanceInputEpoch.
+	inputEpochs bool
+	inputEpoch  uint64
+
+	// cursorHistory records the cursor's moves, if enabled.
+	cursorHistory cursorHistory
+
+	// idle fires the idle handler once writes pause.
+	idle idleWatch
+
+	// stats counts the activity behind Stats.
+	stats statsState
+
+	// encoding, if set, decodes the input in place of UTF-8.
+	encoding *Encoding
+
 	// scrollbackLimit, when > 0, enables capturing lines as they scroll off the top into scrollback (capped at
 	// scrollbackLimit, with any excess counted in scrollbackDropped). Drained via TakeScrollback.
 	scrollbackLimit   int
-	scrollback        [][]rune
+	scrollback        []ScrollbackLine
 	scrollbackDropped int
+
+	// meta and altMeta hold the bookkeeping for each row of lines and altLines, and move with the rows. lineClock,
+	// when set, timestamps rows in them as they are modified.
+	meta, altMeta []rowMeta
+	lineClock     func() time.Time
+	lineID        uint64 // the id last given to a line
+
+	// scrollLines and scrollMeta are scratch space for rotateRows, and rowGlyphs for unpacking a row.
+	scrollLines []line
+	scrollMeta  []rowMeta
+	rowGlyphs   []Glyph
+	packed      packedAttrs
 }
 
 // TakeScrollback returns the text of lines that have scrolled off the top since the last call and the number of
@@ -157,16 +370,19 @@ func (t *State) TakeScrollback() (lines [][]rune, dropped int) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	lines, dropped = t.scrollback, t.scrollbackDropped
+	for _, l := range t.scrollback {
+		lines = append(lines, l.Text)
+	}
+	dropped = t.scrollbackDropped
 	t.scrollback, t.scrollbackDropped = nil, 0
 
 	return lines, dropped
 }
 
-// captureScrollback records the text of the first n rows of the given screen buffer before they are scrolled off
-// the top. Lines beyond scrollbackLimit are counted as dropped rather than retained, bounding memory under
-// unbounded scroll.
-func (t *State) captureScrollback(lines []line, n int) {
+// captureScrollback records the text of the first n rows of the given screen buffer, whose bookkeeping is meta,
+// before they are scrolled off the top. Lines beyond scrollbackLimit are counted as dropped rather than retained,
+// bounding memory under unbounded scroll.
+func (t *State) captureScrollback(lines []line, meta []rowMeta, n int) {
 	if t.scrollbackLimit <= 0 {
 		return
 	}
@@ -181,11 +397,17 @@ func (t *State) captureScrollback(lines []line, n int) {
 		}
 
 		row := lines[y]
-		runes := make([]rune, len(row))
+		runes := make([]rune, 0, len(row))
 		for x := range row {
-			runes[x] = row[x].Char
+			if !isWideSpacer(row[x]) {
+				runes = append(runes, row[x].char)
+			}
 		}
-		t.scrollback = append(t.scrollback, runes)
+		sl := ScrollbackLine{Text: runes, Wrapped: len(row) > 0 && row[len(row)-1].attrs&attrWrap != 0}
+		if t.lineClock != nil && y < len(meta) {
+			sl.Modified, sl.Scrolled = meta[y].modified, t.lineClock()
+		}
+		t.scrollback = append(t.scrollback, sl)
 	}
 }
 
@@ -203,19 +425,10 @@ func newState(w io.Writer) *State {
 	}
 	return &State{
 		w:             w,
+		palette:       DefaultPalette(),
 		colorOverride: make(map[Color]Color),
-	}
-}
-
-func (t *State) logf(format string, args ...interface{}) {
-	if t.DebugLogger != nil {
-		t.DebugLogger.Printf(format, args...)
-	}
-}
-
-func (t *State) logln(s string) {
-	if t.DebugLogger != nil {
-		t.DebugLogger.Println(s)
+		cellWidth:     defaultCellWidth,
+		cellHeight:    defaultCellHeight,
 	}
 }
 
@@ -224,7 +437,14 @@ func (t *State) lock() {
 }
 
 func (t *State) unlock() {
+	t.runWatches()
+	t.publish()
+	resized, cols, rows := t.resizePending, t.cols, t.rows
+	t.resizePending = false
 	t.mu.Unlock()
+	if resized {
+		t.resized(cols, rows)
+	}
 }
 
 // Lock locks the state object's mutex.
@@ -232,8 +452,11 @@ func (t *State) Lock() {
 	t.mu.Lock()
 }
 
-// Unlock resets change flags and unlocks the state object's mutex.
+// Unlock sends subscribers an Update of the changes made while locked, resets change flags and unlocks the state
+// object's mutex.
 func (t *State) Unlock() {
+	t.runWatches()
+	t.publish()
 	t.resetChanges()
 	t.mu.Unlock()
 }
@@ -245,7 +468,7 @@ func (t *State) Cell(x, y int) Glyph {
 	if y < 0 || y >= len(t.lines) || x < 0 || x >= len(t.lines[y]) {
 		return Glyph{}
 	}
-	cell := t.lines[y][x]
+	cell := t.unpack(t.lines[y][x])
 	fg, ok := t.colorOverride[cell.FG]
 	if ok {
 		cell.FG = fg
@@ -254,6 +477,10 @@ func (t *State) Cell(x, y int) Glyph {
 	if ok {
 		cell.BG = bg
 	}
+	ul, ok := t.colorOverride[cell.UnderlineColor]
+	if ok {
+		cell.UnderlineColor = ul
+	}
 	return cell
 }
 
@@ -298,20 +525,83 @@ func (t *State) resetChanges() {
 	t.changed = 0
 }
 
+// saveCursor performs DECSC, saving the cursor with its attributes, pending wrap and origin mode, and the character
+// sets, in the active screen's slot.
 func (t *State) saveCursor() {
-	t.curSaved = t.cur
+	t.curSaved = savedCursor{Cursor: t.cur, charsets: t.charsets}
 }
 
+// restoreCursor performs DECRC, restoring what saveCursor saved for the active screen, or the home position and
+// default attributes and character sets if nothing was saved.
 func (t *State) restoreCursor() {
-	t.cur = t.curSaved
+	t.cur = t.curSaved.Cursor
+	t.charsets = t.curSaved.charsets
 	t.moveTo(t.cur.X, t.cur.Y)
+	// Like xterm, DECSC saves a pending wrap along with the position, as long as the position is still valid.
+	if t.curSaved.State&cursorWrapNext != 0 && t.cur.X == t.curSaved.X && t.cur.Y == t.curSaved.Y {
+		t.cur.State |= cursorWrapNext
+	}
+}
+
+// setCursorStyle applies a DECSCUSR parameter, returning false if it is not a known style.
+func (t *State) setCursorStyle(ps int) bool {
+	switch ps {
+	case 0, 1:
+		t.cursorStyle = CursorStyle{Shape: CursorShapeBlock, Blink: true}
+	case 2:
+		t.cursorStyle = CursorStyle{Shape: CursorShapeBlock}
+	case 3:
+		t.cursorStyle = CursorStyle{Shape: CursorShapeUnderline, Blink: true}
+	case 4:
+		t.cursorStyle = CursorStyle{Shape: CursorShapeUnderline}
+	case 5:
+		t.cursorStyle = CursorStyle{Shape: CursorShapeBar, Blink: true}
+	case 6:
+		t.cursorStyle = CursorStyle{Shape: CursorShapeBar}
+	default:
+		return false
+	}
+	return true
 }
 
 func (t *State) put(c rune) {
 	if t.state == nil {
 		return
 	}
+	if t.c1Controls && isC1Control(c) {
+		t.putC1(c)
+		return
+	}
+	if t.passthrough != nil {
+		t.putPassthrough(c)
+		return
+	}
 	t.state(c)
+	if t.cursorHistory.limit > 0 {
+		t.recordCursor()
+	}
+}
+
+// setTab sets or clears the tab stop at the cursor column.
+func (t *State) setTab(set bool) {
+	if t.cur.X >= 0 && t.cur.X < len(t.tabs) {
+		t.tabs[t.cur.X] = set
+	}
+}
+
+// clearTabs clears every tab stop.
+func (t *State) clearTabs() {
+	for i := range t.tabs {
+		t.tabs[i] = false
+	}
+}
+
+// defaultTabs replaces the tab stops with one every tabspaces columns.
+func (t *State) defaultTabs() {
+	t.clearTabs()
+	for i := tabspaces; i < len(t.tabs); i += tabspaces {
+		t.tabs[i] = true
+	}
 }
 
 func (t *State) putTab(forward bool) {
@@ -337,6 +627,9 @@ func (t *State) putTab(forward bool) {
 
 func (t *State) newline(firstCol bool) {
 	y := t.cur.Y
+	if t.redaction != nil {
+		t.redactRow(y)
+	}
 	if y == t.bottom {
 		cur := t.cur
 		t.cur = t.defaultCursor()
@@ -374,47 +667,131 @@ func (t *State) setChar(c rune, attr *Glyph, x, y int) {
 		}
 	}
 	t.changed |= ChangedScreen
-	t.dirty[y] = true
-	t.lines[y][x] = *attr
-	t.lines[y][x].Char = c
+	t.markDirty(y)
+	g := *attr
+	g.Char = 0
 	// if t.options.BrightBold && attr.Mode&attrBold != 0 && attr.FG < 8 {
 	if attr.Mode&attrBold != 0 && attr.FG < 8 {
-		t.lines[y][x].FG = attr.FG + 8
+		g.FG = attr.FG + 8
 	}
 	if attr.Mode&attrReverse != 0 {
-		t.lines[y][x].FG = attr.BG
-		t.lines[y][x].BG = attr.FG
+		g.FG = attr.BG
+		g.BG = attr.FG
+	}
+	// Runs of text share their attributes, so pack them once per run rather than per cell.
+	if !t.packed.valid || g != t.packed.attrs {
+		t.packed = packedAttrs{attrs: g, cell: t.pack(g), valid: true}
 	}
+	l := t.writableLine(y)
+	l[x] = t.packed.cell
+	l[x].char = c
+	t.tagEpochs(y, x, x)
+}
+
+// setSpacer makes the cell setChar just wrote at x of row y the first half of a wide character, and the next cell its
+// second half.
+func (t *State) setSpacer(x, y int) {
+	if y < 0 || y >= len(t.lines) || x < 0 || x+1 >= len(t.lines[y]) {
+		return
+	}
+	l := t.writableLine(y)
+	l[x].attrs |= attrWide
+	l[x+1] = l[x]
+	l[x+1].char = 0
+	t.tagEpochs(y, x+1, x+1)
+}
+
+// packedAttrs caches the packed form of the attributes setChar last wrote.
+type packedAttrs struct {
+	attrs Glyph
+	cell  cell
+	valid bool
 }
 
 func (t *State) defaultCursor() Cursor {
 	c := Cursor{}
 	c.Attr.FG = DefaultFG
 	c.Attr.BG = DefaultBG
+	c.Attr.UnderlineColor = DefaultUnderline
 	return c
 }
 
+// reset performs RIS, returning the terminal to its initial state: both screens and any scrollback not yet taken are
+// cleared, the primary screen is made active, and every mode, margin, tab stop and color override is reset.
 func (t *State) reset() {
+	if t.mode&ModeAltScreen != 0 {
+		t.swapScreen()
+	}
 	t.cur = t.defaultCursor()
+	t.charsets = charsetState{}
 	t.saveCursor()
-	for i := range t.tabs {
-		t.tabs[i] = false
-	}
-	for i := tabspaces; i < len(t.tabs); i += tabspaces {
-		t.tabs[i] = true
-	}
+	t.altCurSaved = t.curSaved
+	t.defaultTabs()
 	t.top = 0
 	t.bottom = t.rows - 1
 	t.mode = ModeWrap
+	t.altScreenMode = 0
+	t.cursorStyle = defaultCursorStyle
+	t.titleStack = nil
+	t.privModes = nil
+	if t.backspaceBS {
+		t.trackPrivMode(67, true)
+	}
+	t.keyboard, t.altKeyboard = keyboardStack{}, keyboardStack{}
+	t.modifyOtherKeys = 0
+	t.images, t.altImages = nil, nil
+	t.kittyImages, t.kittyUpload = nil, nil
+	t.scrollback, t.scrollbackDropped = nil, 0
+	t.marks.commands = nil
+	clear(t.colorOverride)
 	// Skip clear on an uninitialized (0x0) terminal: clear would compute a
 	// negative y range (rows-1 == -1) and then try to write to t.dirty[-1].
 	if t.cols > 0 && t.rows > 0 {
-		t.clear(0, 0, t.rows-1, t.cols-1)
+		t.clear(0, 0, t.cols-1, t.rows-1)
+		t.swapScreen()
+		t.clear(0, 0, t.cols-1, t.rows-1)
+		t.swapScreen()
 	}
 	t.moveTo(0, 0)
 }
 
-// TODO: definitely can improve allocs
+// softReset performs DECSTR, which resets the modes, margins and attributes programs commonly change to their
+// defaults, as xterm does, but unlike RIS leaves the screens, the cursor position, tab stops, colors and title
+// alone.
+func (t *State) softReset() {
+	t.mode &^= ModeInsert | ModeKeyboardLock | ModeAppKeypad | ModeAppCursor | ModeHide
+	t.mode |= ModeWrap
+	t.cur.Attr = t.defaultCursor().Attr
+	t.cur.State &^= cursorOrigin
+	t.setScroll(0, t.rows-1)
+	t.charsets = charsetState{}
+	t.curSaved = savedCursor{Cursor: t.defaultCursor()}
+}
+
+// alignmentTest performs DECALN, filling the screen with Es of the default rendition on single-width lines, as DEC
+// STD 070 describes, and like xterm resetting the margins and origin mode and homing the cursor. The cursor's own
+// attributes are left alone.
+func (t *State) alignmentTest() {
+	if t.cols <= 0 || t.rows <= 0 || len(t.lines) == 0 {
+		return
+	}
+	t.clearImages(0, 0, t.cols-1, t.rows-1)
+	c := t.pack(blankGlyph)
+	c.char = 'E'
+	t.changed |= ChangedScreen
+	for y := 0; y < t.rows; y++ {
+		t.markDirty(y)
+		t.meta[y].rendition = LineSingle
+		l := t.writableLine(y)
+		for x := range l {
+			l[x] = c
+		}
+	}
+	t.setScroll(0, t.rows-1)
+	t.cur.State &^= cursorOrigin
+	t.moveTo(0, 0)
+}
+
 func (t *State) resize(cols, rows int) bool {
 	if cols == t.cols && rows == t.rows {
 		return false
@@ -427,37 +804,71 @@ func (t *State) resize(cols, rows int) bool {
 		// Shrinking with the cursor low slides both buffers up, discarding the top `slide` rows the same way a
 		// scroll does; capture the primary screen's rows (even if the alternate screen is active) so history is
 		// not silently lost.
-		t.captureScrollback(t.primaryLines(), slide)
+		t.captureScrollback(t.primaryLines(), t.primaryMeta(), slide)
+		t.marks.scrolled += slide
 		copy(t.lines, t.lines[slide:slide+rows])
 		copy(t.altLines, t.altLines[slide:slide+rows])
-	}
-
-	lines, altLines, tabs := t.lines, t.altLines, t.tabs
-	t.lines = make([]line, rows)
-	t.altLines = make([]line, rows)
+		copy(t.meta, t.meta[slide:slide+rows])
+		copy(t.altMeta, t.altMeta[slide:slide+rows])
+	}
+	t.images = resizeImages(t.images, max(slide, 0), cols, rows)
+	t.altImages = resizeImages(t.altImages, max(slide, 0), cols, rows)
+
+	lines, altLines, tabs, blank := t.lines, t.altLines, t.tabs, t.blank
+	meta, altMeta := t.meta, t.altMeta
+	t.blank = newBlankLine(cols)
+	t.lines = t.newLines(rows)
+	t.altLines = t.newLines(rows)
+	t.meta = make([]rowMeta, rows)
+	t.altMeta = make([]rowMeta, rows)
 	t.dirty = make([]bool, rows)
+	if t.notify != nil {
+		t.notify = make([]bool, rows)
+	}
+	if t.watchDirty != nil {
+		t.watchDirty = make([]bool, rows)
+	}
 	t.tabs = make([]bool, cols)
 
 	minrows := min(rows, t.rows)
 	mincols := min(cols, t.cols)
 	t.changed |= ChangedScreen
 	for i := 0; i < rows; i++ {
-		t.dirty[i] = true
-		t.lines[i] = make(line, cols)
-		t.altLines[i] = make(line, cols)
+		t.markDirty(i)
+	}
+	for i := minrows; i < rows; i++ {
+		t.meta[i].id, t.altMeta[i].id = t.newLineID(), t.newLineID()
 	}
 	for i := 0; i < minrows; i++ {
-		copy(t.lines[i], lines[i])
-		copy(t.altLines[i], altLines[i])
+		// Rows change width, so only their timestamps, line attributes, cell epochs and watched matches carry over.
+		t.meta[i].modified, t.altMeta[i].modified = meta[i].modified, altMeta[i].modified
+		t.meta[i].rendition, t.altMeta[i].rendition = meta[i].rendition, altMeta[i].rendition
+		t.meta[i].id, t.altMeta[i].id = meta[i].id, altMeta[i].id
+		t.meta[i].prompt, t.altMeta[i].prompt = meta[i].prompt, altMeta[i].prompt
+		t.meta[i].epochs, t.altMeta[i].epochs = meta[i].epochs, altMeta[i].epochs
+		t.meta[i].watchID, t.altMeta[i].watchID = meta[i].watchID, altMeta[i].watchID
+		t.meta[i].watchSeen, t.altMeta[i].watchSeen = meta[i].watchSeen, altMeta[i].watchSeen
+		// Blank rows stay shared; only rows holding content are materialized at the new width.
+		if !isSameLine(lines[i], blank) {
+			l := t.materialize(t.lines, i)
+			copy(l, lines[i])
+			trimWide(l)
+		}
+		if !isSameLine(altLines[i], blank) {
+			l := t.materialize(t.altLines, i)
+			copy(l, altLines[i])
+			trimWide(l)
+		}
 	}
 	copy(t.tabs, tabs)
-	if cols > t.cols {
+	if cols > t.cols && t.cols > 0 {
+		// Extend the tab stops past the old width, continuing from the last stop that survived.
 		i := t.cols - 1
 		for i > 0 && !tabs[i] {
 			i--
 		}
-		for i += tabspaces; i < len(tabs); i += tabspaces {
-			tabs[i] = true
+		for i += tabspaces; i < cols; i += tabspaces {
+			t.tabs[i] = true
 		}
 	}
 
@@ -477,7 +888,148 @@ func (t *State) resize(cols, rows int) bool {
 	return slide > 0
 }
 
+// blankGlyph is the content of a cell cleared with default attributes.
+var blankGlyph = Glyph{Char: ' ', FG: DefaultFG, BG: DefaultBG, UnderlineColor: DefaultUnderline}
+
+func newBlankLine(cols int) line {
+	l := make(line, cols)
+	for i := range l {
+		l[i] = blankCell
+	}
+	return l
+}
+
+// newLines returns a screen buffer of rows blank rows. Every row shares t.blank until it is first written, so a mostly
+// empty terminal costs one row of cells rather than rows*cols.
+func (t *State) newLines(rows int) []line {
+	lines := make([]line, rows)
+	for i := range lines {
+		lines[i] = t.blank
+	}
+	return lines
+}
+
+// isSameLine reports whether a and b share backing storage, which is how rows aliasing t.blank are recognized.
+func isSameLine(a, b line) bool {
+	return len(a) > 0 && len(b) > 0 && &a[0] == &b[0]
+}
+
+// materialize gives row y of lines its own storage if it is still sharing t.blank, and returns it. Every write to a
+// cell must go through materialize (or writableLine) so the shared blank row is never modified.
+func (t *State) materialize(lines []line, y int) line {
+	if isSameLine(lines[y], t.blank) {
+		l := make(line, len(t.blank))
+		copy(l, t.blank)
+		lines[y] = l
+	}
+	return lines[y]
+}
+
+// writableLine returns row y of the active screen, materializing it if needed, and marks it modified.
+func (t *State) writableLine(y int) line {
+	t.touch(y)
+	return t.materialize(t.lines, y)
+}
+
+// rowMeta is the bookkeeping for a row of a screen buffer.
+type rowMeta struct {
+	// modified is when the row was last modified, if line timestamps are enabled.
+	modified time.Time
+
+	// hash is the row's content hash, valid while hashed is set.
+	hash   uint64
+	hashed bool
+
+	// rendition is how the row is drawn, as set by DECSWL, DECDWL and DECDHL.
+	rendition LineRendition
+
+	// id identifies the line the row shows, and is new for each row scrolled in; prompt is set if a shell prompt
+	// starts on the row.
+	id     uint64
+	prompt bool
+
+	// links are the URLs on the row, valid while linked is set.
+	links  []Link
+	linked bool
+
+	// watchSeen holds the watched matches last seen on the row, while it showed the line watchID.
+	watchID   uint64
+	watchSeen []watchSeen
+
+	// epochs are the input epochs of the row's cells, if enabled with WithInputEpochs; nil until the row is written.
+	epochs []uint64
+}
+
+// touch records that row y of the active screen was modified.
+func (t *State) touch(y int) {
+	if y < 0 || y >= len(t.meta) {
+		return
+	}
+	m := &t.meta[y]
+	m.hashed, m.linked = false, false
+	if t.lineClock != nil {
+		m.modified = t.lineClock()
+	}
+}
+
+// primaryMeta returns the bookkeeping of the primary-screen buffer; see primaryLines.
+func (t *State) primaryMeta() []rowMeta {
+	if t.mode&ModeAltScreen != 0 {
+		return t.altMeta
+	}
+	return t.meta
+}
+
 func (t *State) clear(x0, y0, x1, y1 int) {
+	t.clearCells(x0, y0, x1, y1, false)
+}
+
+// clearRows clears rows y0 through y1 as they scroll out of the region. Rows about to be scrolled back in at the other
+// end are usually written next, so unlike clear it keeps the storage of rows that have their own, refilled from the
+// blank row, instead of releasing them and allocating again on the next write.
+func (t *State) clearRows(y0, y1 int) {
+	t.clearCells(0, y0, t.cols-1, y1, true)
+	for y := max(y0, 0); y <= y1 && y < len(t.meta); y++ {
+		t.meta[y].id = t.newLineID()
+	}
+}
+
+// newLineID returns the id of a new line.
+func (t *State) newLineID() uint64 {
+	t.lineID++
+	return t.lineID
+}
+
+// rotateRows rotates rows y0 through y1 of the active screen, with their bookkeeping, up by n rows, or down if n is
+// negative: the rows that leave one end of the range re-enter at the other. It moves row headers, never cells.
+func (t *State) rotateRows(y0, y1, n int) {
+	t.scrollLines = rotate(t.lines[y0:y1+1], n, t.scrollLines)
+	t.scrollMeta = rotate(t.meta[y0:y1+1], n, t.scrollMeta)
+	t.changed |= ChangedScreen
+	for y := y0; y <= y1; y++ {
+		t.markDirty(y)
+	}
+}
+
+// rotate rotates s up by n elements, or down if n is negative, with block copies rather than a swap per element. The
+// elements that wrap around are set aside in tmp, which is returned cleared for reuse.
+func rotate[T any](s []T, n int, tmp []T) []T {
+	if n >= 0 {
+		tmp = append(tmp[:0], s[:n]...)
+		copy(s, s[n:])
+		copy(s[len(s)-n:], tmp)
+	} else {
+		tmp = append(tmp[:0], s[len(s)+n:]...)
+		copy(s[-n:], s)
+		copy(s, tmp)
+	}
+	clear(tmp)
+	return tmp[:0]
+}
+
+// clearCells clears the cells from x0, y0 through x1, y1 to the cursor's attributes. Whole rows cleared to defaults
+// share the blank row unless reuse asks to keep their storage.
+func (t *State) clearCells(x0, y0, x1, y1 int, reuse bool) {
 	if t.cols <= 0 || t.rows <= 0 || len(t.lines) == 0 || len(t.dirty) == 0 {
 		return
 	}
@@ -491,20 +1043,123 @@ func (t *State) clear(x0, y0, x1, y1 int) {
 	x1 = clamp(x1, 0, t.cols-1)
 	y0 = clamp(y0, 0, t.rows-1)
 	y1 = clamp(y1, 0, t.rows-1)
+	t.clearImages(x0, y0, x1, y1)
+	g := t.cur.Attr
+	g.Char = ' '
+	g.Mode &^= attrProtected
+	c := t.pack(g)
 	t.changed |= ChangedScreen
 	for y := y0; y <= y1; y++ {
-		t.dirty[y] = true
+		t.markDirty(y)
+		t.tagEpochs(y, x0, x1)
+		t.splitWide(y, x0, x1)
+		if x0 == 0 && x1 == t.cols-1 {
+			// Like a row scrolled in, a row erased entirely is single width and no longer holds a prompt.
+			t.meta[y].rendition, t.meta[y].prompt = LineSingle, false
+		}
+		if g == blankGlyph {
+			if x0 == 0 && x1 == t.cols-1 {
+				// Clearing a whole row to defaults releases it back to the shared blank row.
+				if !isSameLine(t.lines[y], t.blank) {
+					t.touch(y)
+					if reuse {
+						copy(t.lines[y], t.blank)
+						continue
+					}
+				}
+				t.lines[y] = t.blank
+				continue
+			}
+			if isSameLine(t.lines[y], t.blank) {
+				continue
+			}
+		}
+		l := t.writableLine(y)
+		for x := x0; x <= x1; x++ {
+			l[x] = c
+		}
+	}
+}
+
+// selectiveErase performs DECSED and DECSEL over the rectangle from (x0, y0) to (x1, y1): the characters of cells
+// not protected by DECSCA are erased to spaces, and, as on the VT220, their attributes are left alone.
+func (t *State) selectiveErase(x0, y0, x1, y1 int) {
+	if t.cols <= 0 || t.rows <= 0 || len(t.lines) == 0 {
+		return
+	}
+	x0, x1 = clamp(min(x0, x1), 0, t.cols-1), clamp(max(x0, x1), 0, t.cols-1)
+	y0, y1 = clamp(min(y0, y1), 0, t.rows-1), clamp(max(y0, y1), 0, t.rows-1)
+	for y := y0; y <= y1; y++ {
+		if isSameLine(t.lines[y], t.blank) {
+			continue
+		}
+		// A wide character half in the area is erased whole.
+		x0, x1 := x0, x1
+		if x0 > 0 && isWideSpacer(t.lines[y][x0]) {
+			x0--
+		}
+		if x1+1 < t.cols && isWideLead(t.lines[y][x1]) {
+			x1++
+		}
+		var l line
 		for x := x0; x <= x1; x++ {
-			t.lines[y][x] = t.cur.Attr
-			t.lines[y][x].Char = ' '
+			if c := t.lines[y][x]; c.attrs&attrProtected != 0 || c.char == ' ' {
+				continue
+			}
+			if l == nil {
+				l = t.writableLine(y)
+				t.markDirty(y)
+				t.changed |= ChangedScreen
+			}
+			l[x].char = ' '
+			l[x].attrs &^= attrWide
+			t.tagEpochs(y, x, x)
 		}
 	}
 }
 
+// setProtected performs DECSCA, which decides whether characters written from now on are protected from selective
+// erase: 1 protects them, and 0 and 2 do not.
+func (t *State) setProtected(ps int) bool {
+	switch ps {
+	case 0, 2:
+		t.cur.Attr.Mode &^= attrProtected
+	case 1:
+		t.cur.Attr.Mode |= attrProtected
+	default:
+		return false
+	}
+	return true
+}
+
+// clearInLine erases cells x0 through x1 of the cursor row, for EL and ECH, which unlike ED leave the row's
+// rendition and prompt flag alone even when they erase all of it.
+func (t *State) clearInLine(x0, x1 int) {
+	y := t.cur.Y
+	if y < 0 || y >= len(t.meta) {
+		return
+	}
+	m := t.meta[y]
+	t.clear(x0, y, x1, y)
+	t.meta[y].rendition, t.meta[y].prompt = m.rendition, m.prompt
+}
+
 func (t *State) clearAll() {
 	t.clear(0, 0, t.cols-1, t.rows-1)
 }
 
+// moveRows moves the cursor to column x, n rows down, or up if n is negative. Like xterm, a cursor that starts inside
+// the scroll region stops at its margin, and one outside it at the edge of the screen.
+func (t *State) moveRows(x, n int) {
+	y := t.cur.Y + n
+	if n < 0 && t.cur.Y >= t.top {
+		y = max(y, t.top)
+	} else if n > 0 && t.cur.Y <= t.bottom {
+		y = min(y, t.bottom)
+	}
+	t.moveTo(x, y)
+}
+
 func (t *State) moveAbsTo(x, y int) {
 	if t.cur.State&cursorOrigin != 0 {
 		y += t.top
@@ -536,19 +1191,64 @@ func (t *State) moveTo(x, y int) {
 	t.cur.Y = y
 }
 
+// setAltScreen switches to or from the alternate screen for DECSET or DECRST of mode a. The three modes differ in
+// what they clear and save, as in xterm: 47 only switches, 1047 clears the alternate screen before leaving it, and
+// 1049 saves the cursor before switching and clears the alternate screen after, and restores the cursor on leaving.
+func (t *State) setAltScreen(a int, set bool) {
+	alt := t.mode&ModeAltScreen != 0
+	if set {
+		if a == 1049 {
+			t.saveCursor()
+		}
+		if !alt {
+			t.swapScreen()
+			t.altScreenMode = a
+		}
+		if a == 1049 && t.cols > 0 && t.rows > 0 {
+			t.clear(0, 0, t.cols-1, t.rows-1)
+		}
+		return
+	}
+	if alt {
+		if a == 1047 && t.cols > 0 && t.rows > 0 {
+			t.clear(0, 0, t.cols-1, t.rows-1)
+		}
+		t.swapScreen()
+		t.altScreenMode = 0
+	}
+	if a == 1049 {
+		t.restoreCursor()
+	}
+}
+
 func (t *State) swapScreen() {
 	t.lines, t.altLines = t.altLines, t.lines
+	t.meta, t.altMeta = t.altMeta, t.meta
+	t.images, t.altImages = t.altImages, t.images
+	t.keyboard, t.altKeyboard = t.altKeyboard, t.keyboard
+	t.curSaved, t.altCurSaved = t.altCurSaved, t.curSaved
 	t.mode ^= ModeAltScreen
 	t.dirtyAll()
 }
 
+// markDirty marks row y changed, for Changed and dirty-row callers as well as for subscribers.
+func (t *State) markDirty(y int) {
+	t.dirty[y] = true
+	if t.notify != nil {
+		t.notify[y] = true
+	}
+	if t.watchDirty != nil {
+		t.watchDirty[y] = true
+	}
+}
+
 func (t *State) dirtyAll() {
 	t.changed |= ChangedScreen
 	if len(t.dirty) == 0 {
 		return
 	}
 	for y := 0; y < t.rows; y++ {
-		t.dirty[y] = true
+		t.markDirty(y)
 	}
 }
 
@@ -605,13 +1305,10 @@ func (t *State) scrollDown(orig, n int) {
 	if n == 0 {
 		return
 	}
-	t.clear(0, t.bottom-n+1, t.cols-1, t.bottom)
-	t.changed |= ChangedScreen
-	for i := t.bottom; i >= orig+n; i-- {
-		t.lines[i], t.lines[i-n] = t.lines[i-n], t.lines[i]
-		t.dirty[i] = true
-		t.dirty[i-n] = true
-	}
+	t.stats.LinesScrolled += int64(n)
+	t.clearRows(t.bottom-n+1, t.bottom)
+	t.scrollImages(orig, -n)
+	t.rotateRows(orig, t.bottom, -n)
 
 	// TODO: selection scroll
 }
@@ -624,18 +1321,16 @@ func (t *State) scrollUp(orig, n int, capture bool) {
 	if n == 0 {
 		return
 	}
+	t.stats.LinesScrolled += int64(n)
 	// Scrollback only records primary-screen lines that scroll off the top row of the screen; interior region
 	// scrolls (orig > 0) and alternate-screen scrolls discard content that is not primary-screen history.
 	if capture && orig == 0 && t.mode&ModeAltScreen == 0 {
-		t.captureScrollback(t.lines, n)
-	}
-	t.clear(0, orig, t.cols-1, orig+n-1)
-	t.changed |= ChangedScreen
-	for i := orig; i <= t.bottom-n; i++ {
-		t.lines[i], t.lines[i+n] = t.lines[i+n], t.lines[i]
-		t.dirty[i] = true
-		t.dirty[i+n] = true
+		t.captureScrollback(t.lines, t.meta, n)
+		t.marks.scrolled += n
 	}
+	t.clearRows(orig, orig+n-1)
+	t.scrollImages(orig, n)
+	t.rotateRows(orig, t.bottom, n)
 
 	// TODO: selection scroll
 }
@@ -658,7 +1353,8 @@ func (t *State) setMode(priv bool, set bool, args []int) {
 				mode := t.mode
 				t.modMode(set, ModeReverse)
 				if mode != t.mode {
-					// TODO: redraw
+					// No cell changes, but every one looks different.
+					t.changed |= ChangedScreen
 				}
 			case 6: // DECOM - origin
 				if set {
@@ -667,19 +1363,29 @@ func (t *State) setMode(priv bool, set bool, args []int) {
 					t.cur.State &^= cursorOrigin
 				}
 				t.moveAbsTo(0, 0)
+			case 3: // DECCOLM - column
+				t.setColumnMode(set)
+			case 4: // DECSCLM - scroll
+				t.modMode(set, ModeSmoothScroll)
 			case 7: // DECAWM - auto wrap
 				t.modMode(set, ModeWrap)
+			case 38: // DECTEK - Tektronix mode
+				if set {
+					t.startVector(VectorTektronix)
+					t.state = t.parseTek
+				}
+			case 66: // DECNKM - numeric keypad
+				t.modMode(set, ModeAppKeypad)
 			// IGNORED:
 			case 0, // error
 				2,  // DECANM - ANSI/VT52
-				3,  // DECCOLM - column
-				4,  // DECSCLM - scroll
 				8,  // DECARM - auto repeat
 				18, // DECPFF - printer feed
 				19, // DECPEX - printer extent
-				42, // DECNRCM - national characters
-				12: // att610 - start blinking cursor
+				42: // DECNRCM - national characters
 				break
+			case 12: // att610 - start blinking cursor
+				t.cursorStyle.Blink = set
 			case 25: // DECTCEM - text cursor enable mode
 				t.modMode(!set, ModeHide)
 			case 9: // X10 mouse compatibility mode
@@ -700,19 +1406,12 @@ func (t *State) setMode(priv bool, set bool, args []int) {
 				t.modMode(set, ModeMouseSgr)
 			case 1034:
 				t.modMode(set, Mode8bit)
-			case 1049, // = 1047 and 1048
-				47, 1047:
-				alt := t.mode&ModeAltScreen != 0
-				if alt {
-					t.clear(0, 0, t.cols-1, t.rows-1)
-				}
-				if set != alt {
-					t.swapScreen()
-				}
-				if a != 1049 {
-					break
+			case 2048: // in-band resize notifications, starting with the current size
+				if set {
+					t.reportInBandSize()
 				}
-				fallthrough
+			case 47, 1047, 1049:
+				t.setAltScreen(a, set)
 			case 1048:
 				if set {
 					t.saveCursor()
@@ -729,8 +1428,11 @@ func (t *State) setMode(priv bool, set bool, args []int) {
 				// urxvt mangled mouse mode; incompatiblt and can be mistaken
 				// for other control codes
 			default:
-				t.logf("unknown private set/reset mode %d\n", a)
+				if _, known := decModes[a]; !known {
+					t.warnf("unknown private set/reset mode %d", a)
+				}
 			}
+			t.trackPrivMode(a, set)
 		}
 	} else {
 		for _, a := range args {
@@ -740,182 +1442,358 @@ func (t *State) setMode(priv bool, set bool, args []int) {
 				t.modMode(set, ModeKeyboardLock)
 			case 4: // IRM - insertion-replacement
 				t.modMode(set, ModeInsert)
-				t.logln("insert mode not implemented")
 			case 12: // SRM - send/receive
 				t.modMode(set, ModeEcho)
 			case 20: // LNM - linefeed/newline
 				t.modMode(set, ModeCRLF)
 			case 34:
-				t.logln("right-to-left mode not implemented")
+				t.warnf("right-to-left mode not implemented")
 			case 96:
-				t.logln("right-to-left copy mode not implemented")
+				t.warnf("right-to-left copy mode not implemented")
 			default:
-				t.logf("unknown set/reset mode %d\n", a)
+				t.warnf("unknown set/reset mode %d", a)
 			}
 		}
 	}
 }
 
-func (t *State) setAttr(attr []int) {
+func (t *State) setAttr(attr []int, subs [][]int) {
 	if len(attr) == 0 {
 		attr = []int{0}
 	}
 	for i := 0; i < len(attr); i++ {
 		a := attr[i]
+		var sub []int
+		if i < len(subs) {
+			sub = subs[i]
+		}
 		switch a {
 		case 0:
-			t.cur.Attr.Mode &^= attrReverse | attrUnderline | attrBold | attrItalic | attrBlink
+			t.cur.Attr.Mode &^= attrReverse | attrUnderline | attrBold | attrItalic | attrBlink |
+				attrDim | attrInvisible | attrStrike | attrOverline
 			t.cur.Attr.FG = DefaultFG
 			t.cur.Attr.BG = DefaultBG
+			t.cur.Attr.Underline = UnderlineNone
+			t.cur.Attr.UnderlineColor = DefaultUnderline
 		case 1:
 			t.cur.Attr.Mode |= attrBold
+		case 2:
+			t.cur.Attr.Mode |= attrDim
 		case 3:
 			t.cur.Attr.Mode |= attrItalic
 		case 4:
-			t.cur.Attr.Mode |= attrUnderline
+			style := UnderlineSingle
+			if len(sub) > 0 {
+				// SGR 4:x selects an underline style; 4:0 turns underline off.
+				if !between(sub[0], int(UnderlineNone), int(UnderlineDashed)) {
+					t.warnf("bad underline style %d", sub[0])
+					break
+				}
+				style = UnderlineStyle(sub[0])
+			}
+			t.setUnderline(style)
 		case 5, 6: // slow, rapid blink
 			t.cur.Attr.Mode |= attrBlink
 		case 7:
 			t.cur.Attr.Mode |= attrReverse
-		case 21, 22:
+		case 8:
+			t.cur.Attr.Mode |= attrInvisible
+		case 9:
+			t.cur.Attr.Mode |= attrStrike
+		case 21:
 			t.cur.Attr.Mode &^= attrBold
+		case 22: // normal intensity: neither bold nor dim
+			t.cur.Attr.Mode &^= attrBold | attrDim
 		case 23:
 			t.cur.Attr.Mode &^= attrItalic
 		case 24:
-			t.cur.Attr.Mode &^= attrUnderline
+			t.setUnderline(UnderlineNone)
 		case 25, 26:
 			t.cur.Attr.Mode &^= attrBlink
 		case 27:
 			t.cur.Attr.Mode &^= attrReverse
-		case 38:
-			if i+2 < len(attr) && attr[i+1] == 5 {
-				i += 2
-				if between(attr[i], 0, 255) {
-					t.cur.Attr.FG = Color(attr[i])
-				} else {
-					t.logf("bad fgcolor %d\n", attr[i])
-				}
-			} else if i+4 < len(attr) && attr[i+1] == 2 {
-				i += 4
-				r, g, b := attr[i-2], attr[i-1], attr[i]
-				if !between(r, 0, 255) || !between(g, 0, 255) || !between(b, 0, 255) {
-					t.logf("bad fg rgb color (%d,%d,%d)\n", r, g, b)
-				} else {
-					t.cur.Attr.FG = Color(r<<16 | g<<8 | b)
-				}
+		case 28:
+			t.cur.Attr.Mode &^= attrInvisible
+		case 29:
+			t.cur.Attr.Mode &^= attrStrike
+		case 53:
+			t.cur.Attr.Mode |= attrOverline
+		case 55:
+			t.cur.Attr.Mode &^= attrOverline
+		case 38, 48, 58:
+			var c Color
+			var ok bool
+			if len(sub) > 0 {
+				c, ok = t.sgrColorSub(a, sub)
 			} else {
-				t.logf("gfx attr %d unknown\n", a)
+				c, i, ok = t.sgrColor(a, attr, i)
+			}
+			if !ok || !t.colorAllowed(c) {
+				break
+			}
+			switch a {
+			case 38:
+				t.cur.Attr.FG = c
+			case 48:
+				t.cur.Attr.BG = c
+			case 58:
+				t.cur.Attr.UnderlineColor = c
 			}
 		case 39:
 			t.cur.Attr.FG = DefaultFG
-		case 48:
-			if i+2 < len(attr) && attr[i+1] == 5 {
-				i += 2
-				if between(attr[i], 0, 255) {
-					t.cur.Attr.BG = Color(attr[i])
-				} else {
-					t.logf("bad bgcolor %d\n", attr[i])
-				}
-			} else if i+4 < len(attr) && attr[i+1] == 2 {
-				i += 4
-				r, g, b := attr[i-2], attr[i-1], attr[i]
-				if !between(r, 0, 255) || !between(g, 0, 255) || !between(b, 0, 255) {
-					t.logf("bad bg rgb color (%d,%d,%d)\n", r, g, b)
-				} else {
-					t.cur.Attr.BG = Color(r<<16 | g<<8 | b)
-				}
-			} else {
-				t.logf("gfx attr %d unknown\n", a)
-			}
 		case 49:
 			t.cur.Attr.BG = DefaultBG
+		case 59:
+			t.cur.Attr.UnderlineColor = DefaultUnderline
 		default:
+			var fg, bg bool
+			var c Color
 			if between(a, 30, 37) {
-				t.cur.Attr.FG = Color(a - 30)
+				fg, c = true, Color(a-30)
 			} else if between(a, 40, 47) {
-				t.cur.Attr.BG = Color(a - 40)
+				bg, c = true, Color(a-40)
 			} else if between(a, 90, 97) {
-				t.cur.Attr.FG = Color(a - 90 + 8)
+				fg, c = true, Color(a-90+8)
 			} else if between(a, 100, 107) {
-				t.cur.Attr.BG = Color(a - 100 + 8)
+				bg, c = true, Color(a-100+8)
 			} else {
-				t.logf("gfx attr %d unknown\n", a)
+				t.warnf("gfx attr %d unknown", a)
 			}
+			switch {
+			case !t.colorAllowed(c):
+			case fg:
+				t.cur.Attr.FG = c
+			case bg:
+				t.cur.Attr.BG = c
+			}
+		}
+	}
+}
+
+func (t *State) setUnderline(style UnderlineStyle) {
+	t.cur.Attr.Underline = style
+	if style == UnderlineNone {
+		t.cur.Attr.Mode &^= attrUnderline
+	} else {
+		t.cur.Attr.Mode |= attrUnderline
+	}
+}
+
+// sgrColor parses the semicolon form of an extended color (38;5;n or 38;2;r;g;b, likewise for 48 and 58) starting at
+// attr[i], returning the color and the index of the last argument consumed.
+func (t *State) sgrColor(a int, attr []int, i int) (Color, int, bool) {
+	if i+2 < len(attr) && attr[i+1] == 5 {
+		i += 2
+		if !between(attr[i], 0, 255) {
+			t.warnf("bad %d color %d", a, attr[i])
+			return 0, i, false
+		}
+		return Color(attr[i]), i, true
+	} else if i+4 < len(attr) && attr[i+1] == 2 {
+		i += 4
+		r, g, b := attr[i-2], attr[i-1], attr[i]
+		if !between(r, 0, 255) || !between(g, 0, 255) || !between(b, 0, 255) {
+			t.warnf("bad %d rgb color (%d,%d,%d)", a, r, g, b)
+			return 0, i, false
+		}
+		return Color(r<<16 | g<<8 | b), i, true
+	}
+	t.warnf("gfx attr %d unknown", a)
+	return 0, i, false
+}
+
+// sgrColorSub parses the colon form of an extended color: 38:5:n, or 38:2:cs:r:g:b where the color space id cs may be
+// empty or, as many applications emit it, left out entirely (38:2:r:g:b).
+func (t *State) sgrColorSub(a int, sub []int) (Color, bool) {
+	switch {
+	case sub[0] == 5 && len(sub) >= 2:
+		if !between(sub[1], 0, 255) {
+			t.warnf("bad %d color %d", a, sub[1])
+			return 0, false
 		}
+		return Color(sub[1]), true
+	case sub[0] == 2 && len(sub) >= 4:
+		rgb := sub[1:4]
+		if len(sub) >= 5 {
+			rgb = sub[2:5]
+		}
+		r, g, b := rgb[0], rgb[1], rgb[2]
+		if !between(r, 0, 255) || !between(g, 0, 255) || !between(b, 0, 255) {
+			t.warnf("bad %d rgb color (%d,%d,%d)", a, r, g, b)
+			return 0, false
+		}
+		return Color(r<<16 | g<<8 | b), true
 	}
+	t.warnf("gfx attr %d unknown", a)
+	return 0, false
 }
 
+// horizontalMargins returns the columns that ICH, DCH, IL and DL act between. Left and right margins (DECSLRM) are
+// not supported, so this is always the full width; the editing functions go through it so margins only need adding
+// here.
+func (t *State) horizontalMargins() (left, right int) {
+	return 0, t.cols - 1
+}
+
+// insertBlanks implements ICH: it shifts the cursor's row right by n from the cursor to the right margin, discarding
+// what passes the margin, and blanks the gap. Nothing happens with the cursor outside the left and right margins.
 func (t *State) insertBlanks(n int) {
 	if t.cols <= 0 || t.rows <= 0 || t.cur.Y < 0 || t.cur.Y >= len(t.lines) || t.cur.Y >= len(t.dirty) {
 		return
 	}
+	left, right := t.horizontalMargins()
+	if t.cur.X < left || t.cur.X > right {
+		return
+	}
+	t.cur.State &^= cursorWrapNext
 	// Clamp: CSI args are untrusted; prevent negative or overflowing slice bounds below.
-	n = clamp(n, 0, t.cols-t.cur.X)
+	n = clamp(n, 1, right-t.cur.X+1)
 	src := t.cur.X
 	dst := src + n
-	size := t.cols - dst
+	size := right + 1 - dst
 	t.changed |= ChangedScreen
-	t.dirty[t.cur.Y] = true
+	t.markDirty(t.cur.Y)
+	wrapped := t.lines[t.cur.Y][right].attrs & attrWrap
 
-	if dst >= t.cols {
-		t.clear(t.cur.X, t.cur.Y, t.cols-1, t.cur.Y)
+	if dst > right {
+		t.clear(t.cur.X, t.cur.Y, right, t.cur.Y)
 	} else {
-		copy(t.lines[t.cur.Y][dst:dst+size], t.lines[t.cur.Y][src:src+size])
+		// Wide characters split by the cursor, or by the right margin once shifted, are blanked.
+		t.splitWide(t.cur.Y, src, src+size-1)
+		l := t.writableLine(t.cur.Y)
+		copy(l[dst:dst+size], l[src:src+size])
+		t.shiftEpochs(t.cur.Y, dst, src, size)
 		t.clear(src, t.cur.Y, dst-1, t.cur.Y)
 	}
+	t.keepWrap(t.cur.Y, right, wrapped)
 }
 
+// insertBlankLines implements IL: it scrolls the region from the cursor's row to the bottom margin down by n and
+// moves the cursor to the left margin. Nothing happens with the cursor outside the scroll region.
 func (t *State) insertBlankLines(n int) {
-	if t.cur.Y < t.top || t.cur.Y > t.bottom {
+	if !t.cursorInRegion() {
 		return
 	}
-	t.scrollDown(t.cur.Y, n)
+	t.scrollDown(t.cur.Y, max(n, 1))
+	left, _ := t.horizontalMargins()
+	t.moveTo(left, t.cur.Y)
 }
 
+// deleteLines implements DL: it scrolls the region from the cursor's row to the bottom margin up by n and moves the
+// cursor to the left margin. Nothing happens with the cursor outside the scroll region.
 func (t *State) deleteLines(n int) {
-	if t.cur.Y < t.top || t.cur.Y > t.bottom {
+	if !t.cursorInRegion() {
 		return
 	}
-	t.scrollUp(t.cur.Y, n, false)
+	t.scrollUp(t.cur.Y, max(n, 1), false)
+	left, _ := t.horizontalMargins()
+	t.moveTo(left, t.cur.Y)
 }
 
+// cursorInRegion reports whether the cursor is inside the scroll region and the left and right margins.
+func (t *State) cursorInRegion() bool {
+	left, right := t.horizontalMargins()
+	return t.cur.Y >= t.top && t.cur.Y <= t.bottom && t.cur.X >= left && t.cur.X <= right
+}
+
+// deleteChars implements DCH: it shifts the cursor's row left by n from the cursor to the right margin and blanks
+// the columns freed at the margin. Nothing happens with the cursor outside the left and right margins.
 func (t *State) deleteChars(n int) {
 	if t.cols <= 0 || t.rows <= 0 || t.cur.Y < 0 || t.cur.Y >= len(t.lines) || t.cur.Y >= len(t.dirty) {
 		return
 	}
+	left, right := t.horizontalMargins()
+	if t.cur.X < left || t.cur.X > right {
+		return
+	}
+	t.cur.State &^= cursorWrapNext
 	// Clamp: CSI args are untrusted; prevent negative or overflowing slice bounds below.
-	n = clamp(n, 0, t.cols-t.cur.X)
+	n = clamp(n, 1, right-t.cur.X+1)
 	src := t.cur.X + n
 	dst := t.cur.X
-	size := t.cols - src
+	size := right + 1 - src
 	t.changed |= ChangedScreen
-	t.dirty[t.cur.Y] = true
+	t.markDirty(t.cur.Y)
+	wrapped := t.lines[t.cur.Y][right].attrs & attrWrap
 
-	if src >= t.cols {
-		t.clear(t.cur.X, t.cur.Y, t.cols-1, t.cur.Y)
+	if src > right {
+		t.clear(t.cur.X, t.cur.Y, right, t.cur.Y)
 	} else {
-		copy(t.lines[t.cur.Y][dst:dst+size], t.lines[t.cur.Y][src:src+size])
-		t.clear(t.cols-n, t.cur.Y, t.cols-1, t.cur.Y)
+		// Wide characters with only one half deleted are blanked.
+		t.splitWide(t.cur.Y, dst, src-1)
+		l := t.writableLine(t.cur.Y)
+		copy(l[dst:dst+size], l[src:src+size])
+		t.shiftEpochs(t.cur.Y, dst, src, size)
+		t.clear(right+1-n, t.cur.Y, right, t.cur.Y)
+	}
+	t.keepWrap(t.cur.Y, right, wrapped)
+}
+
+// keepWrap puts the autowrap flag, as it was on the cell at the right margin before ICH or DCH shifted the cells of
+// row y, back on that cell. The flag marks that the row continues on the next one rather than belonging to a
+// character, so it must neither move with the characters nor be lost when the last cell is shifted out or erased.
+// Autowrap only ever flags the right margin, so a row without the flag there has none to fix.
+func (t *State) keepWrap(y, right int, wrapped uint16) {
+	if wrapped == 0 {
+		return
+	}
+	l := t.writableLine(y)
+	for x := range l[:right+1] {
+		l[x].attrs &^= attrWrap
 	}
+	l[right].attrs |= wrapped
 }
 
 func (t *State) setTitle(title string) {
+	if n := t.limits.TitleLength; n > 0 && utf8.RuneCountInString(title) > n {
+		title = string([]rune(title)[:n])
+		t.truncated(LimitTitle)
+	}
 	t.changed |= ChangedTitle
 	t.title = title
 }
 
+// pushTitle saves the current title on the title stack (XTPUSHTITLE / CSI 22 t).
+func (t *State) pushTitle() {
+	if len(t.titleStack) >= maxTitleStack {
+		t.titleStack = append(t.titleStack[:0], t.titleStack[1:]...)
+	}
+	t.titleStack = append(t.titleStack, t.title)
+}
+
+// popTitle restores the most recently pushed title (XTPOPTITLE / CSI 23 t). Popping an empty stack leaves the title
+// unchanged.
+func (t *State) popTitle() {
+	if len(t.titleStack) == 0 {
+		return
+	}
+	title := t.titleStack[len(t.titleStack)-1]
+	t.titleStack = t.titleStack[:len(t.titleStack)-1]
+	t.setTitle(title)
+}
+
 func (t *State) Size() (cols, rows int) {
 	return t.cols, t.rows
 }
 
+// Resize changes the size of the virtual terminal. Sizes outside [1, 2048] are ignored.
+func (t *State) Resize(cols, rows int) {
+	if t.setSize(cols, rows) {
+		t.resized(cols, rows)
+	}
+}
+
 func (t *State) String() string {
 	t.Lock()
 	defer t.Unlock()
 
-	var view []rune
+	view := make([]rune, 0, t.rows*(t.cols+1))
 	for y := 0; y < t.rows; y++ {
 		for x := 0; x < t.cols; x++ {
 			attr := t.Cell(x, y)
+			if isSpacerGlyph(attr) {
+				continue
+			}
 			view = append(view, attr.Char)
 		}
 		view = append(view, '\n')
@@ -932,20 +1810,93 @@ type TerminalState struct {
 	CursorX         int
 	CursorY         int
 	CursorVisible   bool
+	CursorStyle     CursorStyle
 	PrimaryBuffer   [][]Glyph
 	AlternateBuffer [][]Glyph
 	AltScreen       bool
+	AltScreenMode   int // the mode, 47, 1047 or 1049, that switched to the alternate screen, or 0 on the primary screen
 	ScrollTop       int
 	ScrollBottom    int
 	TabStops        []int
 	Wrap            bool
+	WrapPending     bool // the cursor is in the last column and the next character wraps first
 	Insert          bool
 	Origin          bool
-	AutoWrap        bool
-	ReverseVideo    bool
-	Title           string
-	SavedCursorX    int
-	SavedCursorY    int
+	// Deprecated: AutoWrap always equals Wrap; use Wrap.
+	AutoWrap     bool
+	ReverseVideo bool
+	AppCursor    bool // cursor keys send application sequences (DECCKM)
+	AppKeypad    bool // the keypad sends application sequences (DECKPAM) rather than characters (DECKPNM)
+	Mode         ModeFlag
+	Title        string
+
+	// KeyboardFlags are the kitty keyboard protocol flags of the active screen.
+	KeyboardFlags KeyboardFlags
+
+	// ModifyOtherKeys is xterm's modifyOtherKeys level, 0 to 2, which decides how keys with modifiers are encoded when
+	// the kitty keyboard protocol is not in use.
+	ModifyOtherKeys int
+
+	// BellCount is the number of times the bell has rung, and LastBell when it last rang, or the zero time if it has
+	// not. VisualBell is set if the terminal was configured with WithVisualBell.
+	BellCount  int
+	LastBell   time.Time
+	VisualBell bool
+
+	// WorkingDirectory is the path of the working directory the shell last reported with OSC 7, and
+	// DirectoryHistory every directory it reported, oldest first, up to the last 100.
+	WorkingDirectory string
+	DirectoryHistory []WorkingDirectory
+
+	// Variables are the variables set by shell integration with iTerm2's OSC 1337 commands: user.<name> for each
+	// SetUserVar, username and hostname for RemoteHost, path for CurrentDir, and shell for ShellIntegrationVersion.
+	Variables map[string]string
+
+	TitleStack   []string
+	SavedCursorX int
+	SavedCursorY int
+
+	// AlternateSavedCursorX and AlternateSavedCursorY are where DECSC last saved the cursor on the inactive screen,
+	// which is the position 1049 returns to while the alternate screen is active. Like the buffers, SavedCursorX and
+	// SavedCursorY are for the active screen.
+	AlternateSavedCursorX int
+	AlternateSavedCursorY int
+
+	// Palette holds the RGB value in effect for each of the 256 indexed colors, and ForegroundColor,
+	// BackgroundColor and CursorColor those of the default colors, including any OSC 4/10/11/12 overrides.
+	Palette         []Color
+	ForegroundColor Color
+	BackgroundColor Color
+	CursorColor     Color
+
+	// Modes maps the number of every DEC private mode the terminal recognizes to whether it is set, including modes
+	// it tracks but does not implement. DECModeName names them.
+	Modes map[int]bool
+
+	// Images are the graphics placed on the active screen, oldest first, as drawn by sixel sequences, the kitty
+	// graphics protocol, and iTerm2 inline images.
+	Images []ImagePlacement
+
+	// PrimaryModified and AlternateModified hold when each row of PrimaryBuffer and AlternateBuffer was last
+	// modified, or the zero time for a row not written since the terminal was created. They are nil unless line
+	// timestamps are enabled with WithLineTimestamps.
+	PrimaryModified   []time.Time
+	AlternateModified []time.Time
+
+	// PrimaryRendition and AlternateRendition hold how each row of PrimaryBuffer and AlternateBuffer is drawn, as set
+	// by DECDWL and DECDHL. They are nil when every row is single width.
+	PrimaryRendition   []LineRendition
+	AlternateRendition []LineRendition
+
+	// PrimaryRows and AlternateRows hold the metadata of each row of PrimaryBuffer and AlternateBuffer. Like the
+	// buffers, DumpMeta leaves them out.
+	PrimaryRows   []RowInfo
+	AlternateRows []RowInfo
+
+	// PrimaryEpochs and AlternateEpochs hold the input epoch each cell of PrimaryBuffer and AlternateBuffer was last
+	// written in, if enabled with WithInputEpochs, and are nil otherwise. DumpMeta leaves them out.
+	PrimaryEpochs   [][]uint64
+	AlternateEpochs [][]uint64
 }
 
 // DumpState returns the terminal state
@@ -953,24 +1904,115 @@ func (t *State) DumpState() TerminalState {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	return t.dumpState()
+}
+
+// dumpState is DumpState with the terminal locked.
+func (t *State) dumpState() TerminalState {
+	state := t.dumpMeta()
+
+	copyBuffer := func(src []line) [][]Glyph {
+		buf := make([][]Glyph, t.rows)
+		slab := make([]Glyph, t.rows*t.cols)
+		for y := 0; y < t.rows; y++ {
+			buf[y] = slab[y*t.cols : (y+1)*t.cols : (y+1)*t.cols]
+			if y >= len(src) {
+				continue
+			}
+			if isSameLine(src[y], t.blank) {
+				for x := range buf[y] {
+					buf[y][x] = blankGlyph
+				}
+				continue
+			}
+			t.unpackLine(buf[y], src[y])
+		}
+		return buf
+	}
+
+	state.PrimaryBuffer = copyBuffer(t.lines)
+	state.AlternateBuffer = copyBuffer(t.altLines)
+	state.PrimaryRendition = renditions(t.meta)
+	state.AlternateRendition = renditions(t.altMeta)
+	state.PrimaryRows = rowInfos(t.lines, t.meta)
+	state.AlternateRows = rowInfos(t.altLines, t.altMeta)
+	if t.inputEpochs {
+		state.PrimaryEpochs = epochRows(t.meta, t.cols)
+		state.AlternateEpochs = epochRows(t.altMeta, t.cols)
+	}
+	if t.lineClock != nil {
+		state.PrimaryModified = modifiedTimes(t.meta)
+		state.AlternateModified = modifiedTimes(t.altMeta)
+	}
+	state.Images = copyImages(t.images)
+
+	return state
+}
+
+// DumpMeta returns the terminal state without the screen buffers: modes, cursor, title, scroll region, and tab stops.
+// Its cost does not depend on the screen size, so it suits consumers that poll metadata often and only fetch the
+// grid via DumpState when the screen has changed.
+func (t *State) DumpMeta() TerminalState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.dumpMeta()
+}
+
+func (t *State) dumpMeta() TerminalState {
 	state := TerminalState{
 		Cols:          t.cols,
 		Rows:          t.rows,
 		CursorX:       t.cur.X,
 		CursorY:       t.cur.Y,
 		CursorVisible: t.mode&ModeHide == 0,
+		WrapPending:   t.cur.State&cursorWrapNext != 0,
+		CursorStyle:   t.cursorStyle,
 		AltScreen:     t.mode&ModeAltScreen != 0,
+		AltScreenMode: t.altScreenMode,
 		ScrollTop:     t.top,
 		ScrollBottom:  t.bottom,
 		Title:         t.title,
 		SavedCursorX:  t.curSaved.X,
 		SavedCursorY:  t.curSaved.Y,
-		Wrap:          t.mode&ModeWrap != 0,
-		Insert:        t.mode&ModeInsert != 0,
-		Origin:        t.cur.State&cursorOrigin != 0,
-		AutoWrap:      t.mode&ModeWrap != 0, // Same as Wrap
-		ReverseVideo:  t.mode&ModeReverse != 0,
+
+		AlternateSavedCursorX: t.altCurSaved.X,
+		AlternateSavedCursorY: t.altCurSaved.Y,
+		Wrap:                  t.mode&ModeWrap != 0,
+		Insert:                t.mode&ModeInsert != 0,
+		Origin:                t.cur.State&cursorOrigin != 0,
+		AutoWrap:              t.mode&ModeWrap != 0, // Same as Wrap
+		ReverseVideo:          t.mode&ModeReverse != 0,
+		AppCursor:             t.mode&ModeAppCursor != 0,
+		AppKeypad:             t.mode&ModeAppKeypad != 0,
+		Mode:                  t.mode,
+		Modes:                 t.dumpModes(),
+		KeyboardFlags:         t.keyboard.flags,
+		ModifyOtherKeys:       t.modifyOtherKeys,
+		BellCount:             t.bell.count,
+		LastBell:              t.bell.last,
+		VisualBell:            t.visualBell,
+	}
+
+	if len(t.variables) > 0 {
+		state.Variables = maps.Clone(t.variables)
+	}
+	if n := len(t.directories); n > 0 {
+		state.WorkingDirectory = t.directories[n-1].Path
+		state.DirectoryHistory = append([]WorkingDirectory(nil), t.directories...)
+	}
+
+	if len(t.titleStack) > 0 {
+		state.TitleStack = append([]string(nil), t.titleStack...)
+	}
+
+	state.Palette = make([]Color, 256)
+	for i := range state.Palette {
+		state.Palette[i] = t.effectiveColor(Color(i))
 	}
+	state.ForegroundColor = t.effectiveColor(DefaultFG)
+	state.BackgroundColor = t.effectiveColor(DefaultBG)
+	state.CursorColor = t.effectiveColor(DefaultCursor)
 
 	for i, isTab := range t.tabs {
 		if isTab {
@@ -978,21 +2020,111 @@ func (t *State) DumpState() TerminalState {
 		}
 	}
 
-	copyBuffer := func(src []line) [][]Glyph {
-		buf := make([][]Glyph, t.rows)
-		for y := 0; y < t.rows; y++ {
-			buf[y] = make([]Glyph, t.cols)
-			for x := 0; x < t.cols; x++ {
-				if y < len(src) && x < len(src[y]) {
-					buf[y][x] = src[y][x]
-				}
+	return state
+}
+
+// restore replaces the terminal state with s, as returned by DumpState. Parts of the state that TerminalState does
+// not carry, such as the cursor attributes and any sequence in progress, keep their reset values.
+func (t *State) restore(s TerminalState) {
+	t.resize(s.Cols, s.Rows)
+	if s.Cols != t.cols || s.Rows != t.rows {
+		return
+	}
+
+	restoreLines := func(dst []line, meta []rowMeta, src [][]Glyph) {
+		for y := 0; y < t.rows && y < len(src); y++ {
+			meta[y].hashed, meta[y].linked = false, false
+			if isBlankRow(src[y]) {
+				dst[y] = t.blank
+				continue
+			}
+			l := t.materialize(dst, y)
+			for x := 0; x < len(l) && x < len(src[y]); x++ {
+				l[x] = t.pack(src[y][x])
 			}
 		}
-		return buf
+	}
+	restoreLines(t.lines, t.meta, s.PrimaryBuffer)
+	restoreLines(t.altLines, t.altMeta, s.AlternateBuffer)
+	restoreRenditions(t.meta, s.PrimaryRendition)
+	restoreRenditions(t.altMeta, s.AlternateRendition)
+	t.restoreRowInfos(t.meta, s.PrimaryRows)
+	t.restoreRowInfos(t.altMeta, s.AlternateRows)
+	if t.lineClock != nil {
+		restoreModified(t.meta, s.PrimaryModified)
+		restoreModified(t.altMeta, s.AlternateModified)
+	}
+	if t.inputEpochs {
+		t.restoreEpochs(t.meta, s.PrimaryEpochs)
+		t.restoreEpochs(t.altMeta, s.AlternateEpochs)
+	}
+	t.images = copyImages(s.Images)
+
+	t.mode = s.Mode
+	if s.CursorVisible {
+		t.mode &^= ModeHide
+	} else {
+		t.mode |= ModeHide
+	}
+	t.altScreenMode = 0
+	if t.mode&ModeAltScreen != 0 {
+		t.altScreenMode = s.AltScreenMode
+	}
+	t.cursorStyle = s.CursorStyle
+	t.setScroll(s.ScrollTop, s.ScrollBottom)
+	t.clearTabs()
+	for _, x := range s.TabStops {
+		if x >= 0 && x < len(t.tabs) {
+			t.tabs[x] = true
+		}
+	}
+	t.title = s.Title
+	t.titleStack = append([]string(nil), s.TitleStack...)
+	t.keyboard.flags = s.KeyboardFlags & keyboardFlagsMask
+	t.modifyOtherKeys = clamp(s.ModifyOtherKeys, 0, maxModifyOtherKeys)
+	t.bell.count, t.bell.last = max(s.BellCount, 0), s.LastBell
+	t.directories = append([]WorkingDirectory(nil), s.DirectoryHistory[max(len(s.DirectoryHistory)-maxDirectoryHistory, 0):]...)
+	if len(t.directories) == 0 && s.WorkingDirectory != "" {
+		t.directories = []WorkingDirectory{{Path: s.WorkingDirectory}}
+	}
+	t.variables = nil
+	if len(s.Variables) > 0 {
+		t.variables = maps.Clone(s.Variables)
+	}
+	for a, set := range s.Modes {
+		t.trackPrivMode(a, set)
 	}
 
-	state.PrimaryBuffer = copyBuffer(t.lines)
-	state.AlternateBuffer = copyBuffer(t.altLines)
+	for i, c := range s.Palette {
+		if i < 256 && c != t.palette.resolve(Color(i)) {
+			t.colorOverride[Color(i)] = c
+		}
+	}
+	for c, v := range map[Color]Color{DefaultFG: s.ForegroundColor, DefaultBG: s.BackgroundColor, DefaultCursor: s.CursorColor} {
+		if v != t.palette.resolve(c) {
+			t.colorOverride[c] = v
+		}
+	}
 
-	return state
+	t.curSaved.X, t.curSaved.Y = s.SavedCursorX, s.SavedCursorY
+	t.altCurSaved.X, t.altCurSaved.Y = s.AlternateSavedCursorX, s.AlternateSavedCursorY
+	if s.Origin {
+		t.cur.State |= cursorOrigin
+	}
+	t.moveTo(s.CursorX, s.CursorY)
+	if s.WrapPending {
+		t.cur.State |= cursorWrapNext
+	}
+	t.dirtyAll()
+	t.changed |= ChangedTitle
+}
+
+// isBlankRow reports whether every cell of row holds blankGlyph.
+func isBlankRow(row []Glyph) bool {
+	for _, g := range row {
+		if g != blankGlyph {
+			return false
+		}
+	}
+	return true
 }
@@ -1,6 +1,7 @@
 package vt10x
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -301,3 +302,36 @@ func TestDumpStateEmptyTerminal(t *testing.T) {
 		t.Errorf("expected empty alternate buffer, got %d rows", len(state.AlternateBuffer))
 	}
 }
+
+func TestDumpMetaMatchesDumpState(t *testing.T) {
+	term := New(WithSize(40, 10))
+	writeSeq(t, term, "\033]0;meta\007\033[22t\033[3;8r\033[?6h\033[4;5H\033[?25l\033[4 q\033[3g\033[5GH\033H")
+
+	full := term.DumpState()
+	meta := term.(MetaDumper).DumpMeta()
+
+	if meta.PrimaryBuffer != nil || meta.AlternateBuffer != nil {
+		t.Fatal("expected DumpMeta to omit the screen buffers")
+	}
+
+	full.PrimaryBuffer, full.AlternateBuffer = nil, nil
+	full.PrimaryRows, full.AlternateRows = nil, nil
+	if !reflect.DeepEqual(full, meta) {
+		t.Fatalf("DumpMeta mismatch:\nDumpState: %+v\nDumpMeta:  %+v", full, meta)
+	}
+	if meta.Mode&ModeHide == 0 || !meta.Origin || meta.Title != "meta" || len(meta.TabStops) != 1 {
+		t.Fatalf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestDumpMetaCostIndependentOfSize(t *testing.T) {
+	term := New(WithSize(maxResizeDim, 512))
+
+	allocs := testing.AllocsPerRun(10, func() {
+		_ = term.(MetaDumper).DumpMeta()
+	})
+	// The tab stop list grows by appending; nothing should scale with the number of cells.
+	if allocs > 20 {
+		t.Fatalf("expected DumpMeta to allocate independently of the screen size, got %v allocs", allocs)
+	}
+}
@@ -0,0 +1,51 @@
+package vt10x
+
+import "time"
+
+// Stats counts the activity of a terminal since it was created, for showing how busy a session is without
+// counting the bytes of escape sequences as output.
+type Stats struct {
+	// Bytes is the number of bytes of input parsed.
+	Bytes int64
+	// Cells is the number of printable characters written to the screen.
+	Cells int64
+	// LinesScrolled is the number of lines scrolled, up or down, on either screen.
+	LinesScrolled int64
+	// CSI and OSC are the number of control sequences and operating system commands handled.
+	CSI, OSC int64
+	// PeakBytesPerSecond is the most bytes parsed in any one second, counted in one second windows from the first
+	// write after a pause.
+	PeakBytesPerSecond int64
+}
+
+// statsState holds the counters behind Stats, and the output rate window.
+type statsState struct {
+	Stats
+	windowStart time.Time
+	windowBytes int64
+}
+
+// Stats returns the terminal's activity since it was created.
+func (t *State) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stats.Stats
+}
+
+// countBytes adds n bytes of input to the stats.
+func (t *State) countBytes(n int) {
+	if n <= 0 {
+		return
+	}
+	s := &t.stats
+	s.Bytes += int64(n)
+	now := t.now()
+	if s.windowStart.IsZero() || now.Sub(s.windowStart) >= time.Second {
+		s.windowStart, s.windowBytes = now, 0
+	}
+	s.windowBytes += int64(n)
+	if s.windowBytes > s.PeakBytesPerSecond {
+		s.PeakBytesPerSecond = s.windowBytes
+	}
+}
@@ -0,0 +1,68 @@
+package vt10x
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	term := New(WithSize(10, 3))
+	in := "\033[1mab\033[0m\r\nc\033]2;title\a\n\n\033M"
+	writeSeq(t, term, in)
+	// Only the last LF, at the bottom row, scrolls; RI there just moves the cursor up.
+	got := term.(StatsReporter).Stats()
+	want := Stats{
+		Bytes:              int64(len(in)),
+		Cells:              3,
+		LinesScrolled:      1,
+		CSI:                2,
+		OSC:                1,
+		PeakBytesPerSecond: int64(len(in)),
+	}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestStatsCountsWideAndScrollDown(t *testing.T) {
+	term := New(WithSize(10, 3))
+	writeSeq(t, term, "世界\033[2T\033[5S")
+	got := term.(StatsReporter).Stats()
+	if got.Cells != 2 || got.LinesScrolled != 5 {
+		t.Errorf("expected 2 cells and 5 lines scrolled, got %+v", got)
+	}
+	if got.Bytes != int64(len("世界\033[2T\033[5S")) {
+		t.Errorf("expected bytes counted, not runes, got %d", got.Bytes)
+	}
+}
+
+func TestStatsParse(t *testing.T) {
+	term := New()
+	if err := term.Parse(bufio.NewReader(strings.NewReader("é\033[H"))); err != nil {
+		t.Fatal(err)
+	}
+	if got := term.(StatsReporter).Stats(); got.Bytes != 5 || got.Cells != 1 || got.CSI != 1 {
+		t.Errorf("unexpected stats %+v", got)
+	}
+}
+
+func TestStatsPeakRate(t *testing.T) {
+	now := time.Unix(0, 0)
+	term := New(WithLineTimestamps(func() time.Time { return now }))
+	writeSeq(t, term, strings.Repeat("a", 100))
+	now = now.Add(500 * time.Millisecond)
+	writeSeq(t, term, strings.Repeat("b", 50))
+	now = now.Add(time.Second)
+	writeSeq(t, term, strings.Repeat("c", 120))
+	now = now.Add(200 * time.Millisecond)
+	writeSeq(t, term, strings.Repeat("d", 10))
+	got := term.(StatsReporter).Stats()
+	if got.PeakBytesPerSecond != 150 {
+		t.Errorf("expected a peak of 150 bytes per second, got %d", got.PeakBytesPerSecond)
+	}
+	if got.Bytes != 280 {
+		t.Errorf("expected 280 bytes, got %d", got.Bytes)
+	}
+}
@@ -12,26 +12,36 @@ import (
 // as far as I can tell, don't really have a name; STR is the name I took from
 // suckless which I imagine comes from rxvt or xterm).
 type strEscape struct {
-	typ  rune
-	buf  []rune
-	args []string
+	typ       rune
+	buf       []rune
+	args      []string
+	truncated bool // runes past the length limit were discarded
 }
 
 func (s *strEscape) reset() {
 	s.typ = 0
 	s.buf = s.buf[:0]
 	s.args = nil
+	s.truncated = false
 }
 
-func (s *strEscape) put(c rune) {
+// put appends c, unless the string already holds limit runes. It reports whether c was kept.
+func (s *strEscape) put(c rune, limit int) bool {
 	// TODO: improve allocs with an array backed slice; bench first
-	if len(s.buf) < 256 {
+	if len(s.buf) < limit {
 		s.buf = append(s.buf, c)
+		return true
 	}
 	// Going by st, it is better to remain silent when the STR sequence is not
 	// ended so that it is apparent to users something is wrong. The length sanity
 	// check ensures we don't absorb the entire stream into memory.
 	// TODO: see what rxvt or xterm does
+	return false
+}
+
+// String returns the sequence as received, up to the length limit, for logging.
+func (s *strEscape) String() string {
+	return "\033" + string(s.typ) + string(s.buf)
 }
 
 func (s *strEscape) parse() {
@@ -56,84 +66,78 @@ func (s *strEscape) argString(i int, def string) string {
 	return s.args[i]
 }
 
+// endStr drops the decoders of a sequence too large for strEscape, once the sequence ends or is aborted.
+func (t *State) endStr() {
+	t.sixel, t.kitty, t.inline = nil, nil, nil
+	t.endVector()
+}
+
 func (t *State) handleSTR() {
 	s := &t.str
 	s.parse()
 
 	switch s.typ {
 	case ']': // OSC - operating system command
-		var p *string
+		t.stats.OSC++
 		switch d := s.arg(0, 0); d {
 		case 0, 1, 2:
 			title := s.argString(1, "")
 			if title != "" {
 				t.setTitle(title)
 			}
-		case 10:
-			if len(s.args) < 2 {
-				break
+		case 4: // color set/query: 4;index;spec[;index;spec...]
+			for i := 1; i+1 < len(s.args); i += 2 {
+				j := s.arg(i, -1)
+				c := s.argString(i+1, "")
+				if c == "?" {
+					t.osc4ColorResponse(j)
+				} else if err := t.setColorName(j, &c); err != nil {
+					t.warnf("invalid color j=%d, p=%s", j, c)
+				}
 			}
-
-			c := s.argString(1, "")
-
-			if c == "?" {
-				t.oscColorResponse(int(DefaultFG), 10)
-			} else if err := t.setColorName(int(DefaultFG), &c); err != nil {
-				t.logf("invalid foreground color: %s\n", maybe(&c))
-			} else {
-				// TODO: redraw
+		case 10, 11, 12: // dynamic colors: fg, bg, cursor
+			// Each further argument addresses the next dynamic color, so "10;?;?" queries both fg and bg.
+			for i := 1; i < len(s.args) && d+i-1 <= 12; i++ {
+				num := d + i - 1
+				j := dynamicColor(num)
+				c := s.argString(i, "")
+				if c == "?" {
+					t.oscColorResponse(int(j), num)
+				} else if err := t.setColorName(int(j), &c); err != nil {
+					t.warnf("invalid dynamic color %d: %s", num, c)
+				}
 			}
-		case 11:
-			if len(s.args) < 2 {
+		case 7: // current working directory: 7;file://host/path
+			t.setWorkingDirectory(s)
+		case 9, 777: // desktop notification: 9;message (iTerm2) or 777;notify;title;body (urxvt)
+			t.showNotification(s)
+		case 133: // shell integration: 133;A prompt, 133;B command line, 133;C output, 133;D[;exit status] finished
+			t.shellMark(s)
+		case 104: // color reset: 104[;index...], all colors when no index is given
+			if len(s.args) <= 1 || (len(s.args) == 2 && s.args[1] == "") {
+				for j := 0; j < 256; j++ {
+					_ = t.setColorName(j, nil)
+				}
 				break
 			}
-
-			c := s.argString(1, "")
-			if c == "?" {
-				t.oscColorResponse(int(DefaultBG), 11)
-			} else if err := t.setColorName(int(DefaultBG), &c); err != nil {
-				t.logf("invalid cursor color: %s\n", maybe(&c))
-			} else {
-				// TODO: redraw
+			for i := 1; i < len(s.args); i++ {
+				j := s.arg(i, -1)
+				if err := t.setColorName(j, nil); err != nil {
+					t.warnf("invalid color reset j=%d", j)
+				}
 			}
-		// case 12:
-		// if len(s.args) < 2 {
-		// 	break
-		// }
-
-		// c := s.argString(1, "")
-		// p := &c
-		// if p != nil && *p == "?" {
-		// 	t.oscColorResponse(int(DefaultCursor), 12)
-		// } else if err := t.setColorName(int(DefaultCursor), p); err != nil {
-		// 	t.logf("invalid background color: %s\n", p)
-		// } else {
-		// 	// TODO: redraw
-		// }
-		case 4: // color set
-			if len(s.args) < 3 {
+		case 110, 111, 112: // dynamic color reset
+			_ = t.setColorName(int(dynamicColor(d-100)), nil)
+		case 1337: // iTerm2 proprietary
+			if t.inline != nil {
+				t.drawInlineImage()
 				break
 			}
-
-			c := s.argString(2, "")
-			p = &c
-			fallthrough
-		case 104: // color reset
-			j := -1
-			if len(s.args) > 1 {
-				j = s.arg(1, 0)
-			}
-			if p != nil && *p == "?" { // report
-				t.osc4ColorResponse(j)
-			} else if err := t.setColorName(j, p); err != nil {
-				if !(d == 104 && len(s.args) <= 1) {
-					t.logf("invalid color j=%d, p=%s\n", j, maybe(p))
-				}
-			} else {
-				// TODO: redraw
+			if !t.iterm2Command(s) {
+				t.warnf("unknown OSC 1337 command %q", s)
 			}
 		default:
-			t.logf("unknown OSC command %d\n", d)
+			t.warnf("unknown OSC command %q", s)
 			// TODO: s.dump()
 		}
 	case 'k': // old title set compatibility
@@ -141,19 +145,42 @@ func (t *State) handleSTR() {
 		if title != "" {
 			t.setTitle(title)
 		}
+	case 'P': // DCS - device control string
+		if t.sixel != nil {
+			t.drawSixel()
+			break
+		}
+		t.warnf("unhandled DCS sequence %q", s)
+	case '_': // APC - application program command
+		if t.kitty != nil {
+			t.handleKitty()
+			break
+		}
+		t.warnf("unhandled APC sequence %q", s)
 	default:
 		// TODO: Ignore these codes instead of complain?
-		// 'P': // DSC - device control string
-		// '_': // APC - application program command
 		// '^': // PM - privacy message
 
-		t.logf("unhandled STR sequence '%c'\n", s.typ)
-		// t.str.dump()
+		t.warnf("unhandled STR sequence %q", s)
+	}
+}
+
+// dynamicColor maps OSC 10, 11 and 12 to the default color they control.
+func dynamicColor(num int) Color {
+	switch num {
+	case 10:
+		return DefaultFG
+	case 11:
+		return DefaultBG
+	default:
+		return DefaultCursor
 	}
 }
 
+// setColorName sets the palette entry or default color j to the color spec p, or restores its default when p is
+// nil. Changing a color repaints every cell that uses it, so the whole screen is marked dirty.
 func (t *State) setColorName(j int, p *string) error {
-	if !between(j, 0, 1<<24) {
+	if !between(j, 0, 255) && !between(j, int(DefaultFG), int(DefaultCursor)) {
 		return fmt.Errorf("invalid color value %d", j)
 	}
 	if t.colorOverride == nil {
@@ -162,6 +189,9 @@ func (t *State) setColorName(j int, p *string) error {
 
 	if p == nil {
 		// restore color
+		if _, ok := t.colorOverride[Color(j)]; !ok {
+			return nil
+		}
 		delete(t.colorOverride, Color(j))
 	} else {
 		// set color
@@ -171,25 +201,40 @@ func (t *State) setColorName(j int, p *string) error {
 		}
 		t.colorOverride[Color(j)] = Color(r<<16 | g<<8 | b)
 	}
+	t.dirtyAll()
 
 	return nil
 }
 
+// effectiveColor resolves a palette index or default color to the 24-bit RGB value currently in effect, honoring OSC
+// overrides. Colors that are already RGB are returned as is.
+func (t *State) effectiveColor(c Color) Color {
+	if c == DefaultUnderline {
+		c = DefaultFG
+	}
+	if k, ok := t.colorOverride[c]; ok {
+		return k
+	}
+	return t.palette.resolve(c)
+}
+
+// ResolveColor returns the RGB components of c, which may be an indexed color, a default color, or a 24-bit color,
+// using the terminal's palette and any OSC overrides currently in effect. Renderers should use it rather than
+// keeping their own palette table.
+func (t *State) ResolveColor(c Color) (r, g, b uint8) {
+	return splitRGB(t.effectiveColor(c))
+}
+
 func (t *State) oscColorResponse(j, num int) {
 	if t.w == nil {
 		return
 	}
 	if j < 0 {
-		t.logf("failed to fetch osc color %d\n", j)
+		t.warnf("failed to fetch osc color %d", j)
 		return
 	}
 
-	k, ok := t.colorOverride[Color(j)]
-	if ok {
-		j = int(k)
-	}
-
-	r, g, b := rgb(j)
+	r, g, b := rgb(int(t.effectiveColor(Color(j))))
 	t.w.Write([]byte(fmt.Sprintf("\033]%d;rgb:%02x%02x/%02x%02x/%02x%02x\007", num, r, r, g, g, b, b)))
 }
 
@@ -197,17 +242,12 @@ func (t *State) osc4ColorResponse(j int) {
 	if t.w == nil {
 		return
 	}
-	if j < 0 {
-		t.logf("failed to fetch osc4 color %d\n", j)
+	if !between(j, 0, 255) {
+		t.warnf("failed to fetch osc4 color %d", j)
 		return
 	}
 
-	k, ok := t.colorOverride[Color(j)]
-	if ok {
-		j = int(k)
-	}
-
-	r, g, b := rgb(j)
+	r, g, b := rgb(int(t.effectiveColor(Color(j))))
 	t.w.Write([]byte(fmt.Sprintf("\033]4;%d;rgb:%02x%02x/%02x%02x/%02x%02x\007", j, r, r, g, g, b, b)))
 }
 
@@ -215,9 +255,28 @@ func rgb(j int) (r, g, b int) {
 	return (j >> 16) & 0xff, (j >> 8) & 0xff, j & 0xff
 }
 
+const (
+	rgbExpr  = `^([\da-f]{1})\/([\da-f]{1})\/([\da-f]{1})$|^([\da-f]{2})\/([\da-f]{2})\/([\da-f]{2})$|^([\da-f]{3})\/([\da-f]{3})\/([\da-f]{3})$|^([\da-f]{4})\/([\da-f]{4})\/([\da-f]{4})$`
+	hashExpr = `[\da-f]`
+)
+
+var (
+	rgbPattern  = regexp.MustCompile(rgbExpr)
+	hashPattern = regexp.MustCompile(hashExpr)
+)
+
 var (
-	RGBPattern  = regexp.MustCompile(`^([\da-f]{1})\/([\da-f]{1})\/([\da-f]{1})$|^([\da-f]{2})\/([\da-f]{2})\/([\da-f]{2})$|^([\da-f]{3})\/([\da-f]{3})\/([\da-f]{3})$|^([\da-f]{4})\/([\da-f]{4})\/([\da-f]{4})$`)
-	HashPattern = regexp.MustCompile(`[\da-f]`)
+	// RGBPattern matches the body of an rgb: color spec.
+	//
+	// Deprecated: this is an implementation detail of OSC color parsing; it will be removed in the next major
+	// version. Changing it has no effect on the terminal.
+	RGBPattern = regexp.MustCompile(rgbExpr)
+
+	// HashPattern matches the hex digits of a # color spec.
+	//
+	// Deprecated: this is an implementation detail of OSC color parsing; it will be removed in the next major
+	// version. Changing it has no effect on the terminal.
+	HashPattern = regexp.MustCompile(hashExpr)
 )
 
 func parseColor(p string) (r, g, b int, err error) {
@@ -229,7 +288,7 @@ func parseColor(p string) (r, g, b int, err error) {
 	low := strings.ToLower(p)
 	if strings.HasPrefix(low, "rgb:") {
 		low = low[4:]
-		sm := RGBPattern.FindAllStringSubmatch(low, -1)
+		sm := rgbPattern.FindAllStringSubmatch(low, -1)
 		if len(sm) != 1 || len(sm[0]) == 0 {
 			err = fmt.Errorf("invalid rgb color spec: %s", p)
 			return
@@ -268,7 +327,7 @@ func parseColor(p string) (r, g, b int, err error) {
 		return r, g, b, nil
 	} else if strings.HasPrefix(low, "#") {
 		low = low[1:]
-		m := HashPattern.FindAllString(low, -1)
+		m := hashPattern.FindAllString(low, -1)
 		if !oneOf(len(m), []int{3, 6, 9, 12}) {
 			err = fmt.Errorf("invalid hash color spec: %s", p)
 			return
@@ -309,13 +368,6 @@ func parseColor(p string) (r, g, b int, err error) {
 	}
 }
 
-func maybe(p *string) string {
-	if p == nil {
-		return "<nil>"
-	}
-	return *p
-}
-
 func firstNonEmpty(strs ...string) string {
 	if len(strs) == 0 {
 		return ""
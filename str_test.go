@@ -1,6 +1,7 @@
 package vt10x
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -170,3 +171,90 @@ func TestParseColor(t *testing.T) {
 		})
 	}
 }
+
+func TestOSCPaletteQuery(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithWriter(&buf))
+
+	writeSeq(t, term, "\033]4;1;?\007")
+	if got, want := buf.String(), "\033]4;1;rgb:cdcd/0000/0000\007"; got != want {
+		t.Fatalf("expected default palette reply %q, got %q", want, got)
+	}
+
+	buf.Reset()
+	writeSeq(t, term, "\033]4;1;#102030;200;rgb:ff/80/00\007\033]4;1;?;200;?\007")
+	if got, want := buf.String(), "\033]4;1;rgb:1010/2020/3030\007\033]4;200;rgb:ffff/8080/0000\007"; got != want {
+		t.Fatalf("expected overridden palette replies %q, got %q", want, got)
+	}
+
+	state := term.DumpState()
+	if state.Palette[1] != 0x102030 || state.Palette[200] != 0xff8000 || state.Palette[2] != 0x00cd00 {
+		t.Fatalf("unexpected effective palette entries: 1=%#x 200=%#x 2=%#x",
+			state.Palette[1], state.Palette[200], state.Palette[2])
+	}
+}
+
+func TestOSCPaletteReset(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033]4;1;#ffffff;2;#ffffff;3;#ffffff\007\033]104;1\007")
+	state := term.DumpState()
+	if state.Palette[1] != xtermPalette[1] || state.Palette[2] != 0xffffff {
+		t.Fatalf("expected OSC 104;1 to reset only color 1, got 1=%#x 2=%#x", state.Palette[1], state.Palette[2])
+	}
+
+	writeSeq(t, term, "\033]104\007")
+	state = term.DumpState()
+	if state.Palette[2] != xtermPalette[2] || state.Palette[3] != xtermPalette[3] {
+		t.Fatalf("expected OSC 104 to reset every color, got 2=%#x 3=%#x", state.Palette[2], state.Palette[3])
+	}
+}
+
+func TestOSCDynamicColors(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithWriter(&buf))
+
+	writeSeq(t, term, "\033]10;?\007\033]11;?\007\033]12;?\007")
+	want := "\033]10;rgb:e5e5/e5e5/e5e5\007\033]11;rgb:0000/0000/0000\007\033]12;rgb:e5e5/e5e5/e5e5\007"
+	if got := buf.String(); got != want {
+		t.Fatalf("expected default dynamic color replies %q, got %q", want, got)
+	}
+
+	writeSeq(t, term, "\033]10;#112233;#445566;#778899\007")
+	state := term.DumpState()
+	if state.ForegroundColor != 0x112233 || state.BackgroundColor != 0x445566 || state.CursorColor != 0x778899 {
+		t.Fatalf("unexpected dynamic colors fg=%#x bg=%#x cursor=%#x",
+			state.ForegroundColor, state.BackgroundColor, state.CursorColor)
+	}
+
+	buf.Reset()
+	writeSeq(t, term, "\033]11;?;?\007")
+	if got, want := buf.String(), "\033]11;rgb:4444/5555/6666\007\033]12;rgb:7777/8888/9999\007"; got != want {
+		t.Fatalf("expected chained query replies %q, got %q", want, got)
+	}
+
+	writeSeq(t, term, "\033]110\007\033]111\007\033]112\007")
+	state = term.DumpState()
+	if state.ForegroundColor != defaultForeground || state.BackgroundColor != defaultBackground ||
+		state.CursorColor != defaultForeground {
+		t.Fatalf("expected OSC 110-112 to restore defaults, got fg=%#x bg=%#x cursor=%#x",
+			state.ForegroundColor, state.BackgroundColor, state.CursorColor)
+	}
+}
+
+func TestOSCColorChangeRepaints(t *testing.T) {
+	term := New(WithSize(10, 3))
+	st := term.(*terminal)
+
+	st.Lock()
+	st.Unlock() // resets change flags
+	writeSeq(t, term, "\033]4;5;#123456\007")
+	if !st.Changed(ChangedScreen) {
+		t.Fatal("expected a palette change to mark the screen changed")
+	}
+	for y, dirty := range st.dirty {
+		if !dirty {
+			t.Fatalf("expected row %d to be dirty after a palette change", y)
+		}
+	}
+}
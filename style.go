@@ -0,0 +1,71 @@
+package vt10x
+
+// styleMask holds the attribute bits that change how a glyph is drawn. The line wrap and DECSCA protection flags are
+// kept in a glyph's Mode too, but are left out of its Style.
+const styleMask = ^int16(attrWrap | attrProtected)
+
+// Style is how a glyph is drawn: its attributes and colors, without its character. Bold brightening is already
+// applied to FG, and reverse video to FG and BG, so two glyphs with equal styles look the same.
+type Style struct {
+	Mode           int16
+	Underline      UnderlineStyle
+	FG, BG         Color
+	UnderlineColor Color
+}
+
+// DefaultStyle is the style of a blank cell of a terminal that has not been written to.
+var DefaultStyle = blankGlyph.Style()
+
+// Style returns the style g is drawn in.
+func (g Glyph) Style() Style {
+	return Style{
+		Mode:           g.Mode & styleMask,
+		Underline:      g.Underline,
+		FG:             g.FG,
+		BG:             g.BG,
+		UnderlineColor: g.UnderlineColor,
+	}
+}
+
+// SameStyle reports whether g and o are drawn alike but for their characters, such as for a renderer or encoder to
+// tell whether a cell continues the run of the one before it.
+func (g Glyph) SameStyle(o Glyph) bool {
+	return g.Style().Equal(o.Style())
+}
+
+// Equal reports whether s and o are the same style.
+func (s Style) Equal(o Style) bool {
+	return s == o
+}
+
+// IsDefault reports whether s is DefaultStyle: default colors and no attributes.
+func (s Style) IsDefault() bool {
+	return s == DefaultStyle
+}
+
+// Bold reports whether the style is bold.
+func (s Style) Bold() bool { return IsBold(s.Mode) }
+
+// Dim reports whether the style is dim (faint).
+func (s Style) Dim() bool { return IsDim(s.Mode) }
+
+// Italic reports whether the style is italic.
+func (s Style) Italic() bool { return IsItalic(s.Mode) }
+
+// Underlined reports whether the style is underlined, in the kind Underline names.
+func (s Style) Underlined() bool { return IsUnderline(s.Mode) }
+
+// Blink reports whether the style blinks.
+func (s Style) Blink() bool { return IsBlink(s.Mode) }
+
+// Reverse reports whether the style was written in reverse video. FG and BG are already swapped.
+func (s Style) Reverse() bool { return IsReverse(s.Mode) }
+
+// Invisible reports whether the style is invisible (concealed).
+func (s Style) Invisible() bool { return IsInvisible(s.Mode) }
+
+// Strikethrough reports whether the style is struck through.
+func (s Style) Strikethrough() bool { return IsStrikethrough(s.Mode) }
+
+// Overline reports whether the style is overlined.
+func (s Style) Overline() bool { return IsOverline(s.Mode) }
@@ -0,0 +1,37 @@
+package vt10x
+
+import "testing"
+
+func TestGlyphStyle(t *testing.T) {
+	term := New(WithSize(4, 3))
+	writeSeq(t, term, "\033[1;31mA\033[1;91mB\033[22mC\033[mxyzw\033[7mr\033[0;4:3;58;5;2mu")
+
+	a, b, c := term.Cell(0, 0), term.Cell(1, 0), term.Cell(2, 0)
+	if !a.SameStyle(b) {
+		t.Fatalf("expected bold red and bold bright red to look alike, got %+v and %+v", a.Style(), b.Style())
+	}
+	if b.SameStyle(c) || b.FG != c.FG {
+		t.Fatalf("expected bold and plain bright red to differ only in weight, got %+v and %+v", b.Style(), c.Style())
+	}
+	if s := a.Style(); !s.Bold() || s.Italic() || s.IsDefault() {
+		t.Fatalf("expected a bold style, got %+v", s)
+	}
+
+	// The wrap flag on the last cell of a row does not change its style.
+	w, y := term.Cell(3, 0), term.Cell(0, 1)
+	if w.Mode == y.Mode || !w.SameStyle(y) || !w.Style().IsDefault() {
+		t.Fatalf("expected the wrapped cell drawn like the others, got %+v and %+v", w, y)
+	}
+	if !term.Cell(3, 2).Style().Equal(DefaultStyle) {
+		t.Fatalf("expected a blank cell in the default style, got %+v", term.Cell(3, 2).Style())
+	}
+
+	r := term.Cell(3, 1).Style()
+	if !r.Reverse() || r.FG != DefaultBG || r.BG != DefaultFG {
+		t.Fatalf("expected reverse video with swapped colors, got %+v", r)
+	}
+	u := term.Cell(0, 2).Style()
+	if !u.Underlined() || u.Underline != UnderlineCurly || u.UnderlineColor != Green || u.Reverse() {
+		t.Fatalf("expected a green curly underline, got %+v", u)
+	}
+}
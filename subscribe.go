@@ -0,0 +1,147 @@
+package vt10x
+
+// Update describes what changed on the terminal since the previous Update a subscriber received.
+type Update struct {
+	// Rows are the rows of the screen that changed, in increasing order. The slice may be shared with other
+	// subscribers and must not be modified.
+	Rows []int
+
+	// Cursor is set if the cursor moved, or was shown or hidden.
+	Cursor bool
+
+	// Title is set if the title changed.
+	Title bool
+
+	// Bell is set if the bell rang.
+	Bell bool
+
+	// Reverse is set if reverse video (DECSCNM) was switched on or off. That changes how every cell looks without
+	// changing any, so no rows are reported for it, and a renderer honoring it must redraw the whole screen.
+	Reverse bool
+}
+
+// merge returns u with the changes of a later update v added, dropping rows at or past rows, which a resize in between
+// removed.
+func (u Update) merge(v Update, rows int) Update {
+	merged := make([]int, 0, len(u.Rows)+len(v.Rows))
+	i, j := 0, 0
+	for i < len(u.Rows) || j < len(v.Rows) {
+		switch {
+		case j == len(v.Rows) || i < len(u.Rows) && u.Rows[i] < v.Rows[j]:
+			if u.Rows[i] < rows {
+				merged = append(merged, u.Rows[i])
+			}
+			i++
+		case i == len(u.Rows) || v.Rows[j] < u.Rows[i]:
+			merged = append(merged, v.Rows[j])
+			j++
+		default:
+			merged = append(merged, v.Rows[j])
+			i, j = i+1, j+1
+		}
+	}
+	return Update{Rows: merged, Cursor: u.Cursor || v.Cursor, Title: u.Title || v.Title, Bell: u.Bell || v.Bell,
+		Reverse: u.Reverse || v.Reverse}
+}
+
+// sentView is the part of the terminal an Update reports on besides its rows, as it was when subscribers were last
+// sent one.
+type sentView struct {
+	x, y    int
+	hidden  bool
+	title   string
+	bells   int
+	reverse bool
+}
+
+// subscription is a channel returned by Subscribe.
+type subscription struct {
+	ch chan Update
+}
+
+// Subscribe returns a channel that receives an Update after each write, resize or other change to the terminal, so that
+// a renderer can wait for changes rather than poll for them. Updates coalesce: a subscriber that has not received the
+// previous update when the next is sent gets one update covering both, so a slow renderer redraws once for a burst of
+// writes and never holds up the writer.
+//
+// Updates are sent as the terminal is unlocked, after Write, WriteWithChanges, ReadFrom, Parse and Resize, and after
+// Unlock for changes made while the caller held the lock. Call cancel to stop them; it closes the channel.
+func (t *State) Subscribe() (updates <-chan Update, cancel func()) {
+	t.lock()
+	defer t.unlock()
+
+	// Changes made before subscribing go to the existing subscribers only.
+	t.publish()
+	if t.notify == nil {
+		t.notify = make([]bool, t.rows)
+	}
+	t.sent = t.view()
+	s := &subscription{ch: make(chan Update, 1)}
+	t.subs = append(t.subs, s)
+	return s.ch, func() { t.unsubscribe(s) }
+}
+
+func (t *State) unsubscribe(s *subscription) {
+	t.lock()
+	defer t.unlock()
+
+	for i, sub := range t.subs {
+		if sub == s {
+			t.subs = append(t.subs[:i], t.subs[i+1:]...)
+			close(s.ch)
+			break
+		}
+	}
+	if len(t.subs) == 0 {
+		t.notify = nil
+	}
+}
+
+func (t *State) view() sentView {
+	return sentView{x: t.cur.X, y: t.cur.Y, hidden: t.mode&ModeHide != 0, title: t.title, bells: t.bell.count,
+		reverse: t.mode&ModeReverse != 0}
+}
+
+// publish sends subscribers an Update of the changes since the last one. The terminal must be locked, which makes the
+// writer the only sender on their channels.
+func (t *State) publish() {
+	if len(t.subs) == 0 {
+		return
+	}
+	var u Update
+	for y, changed := range t.notify {
+		if changed {
+			u.Rows = append(u.Rows, y)
+			t.notify[y] = false
+		}
+	}
+	v := t.view()
+	u.Cursor = v.x != t.sent.x || v.y != t.sent.y || v.hidden != t.sent.hidden
+	u.Title = v.title != t.sent.title
+	u.Bell = v.bells != t.sent.bells
+	u.Reverse = v.reverse != t.sent.reverse
+	if len(u.Rows) == 0 && !u.Cursor && !u.Title && !u.Bell && !u.Reverse {
+		return
+	}
+	t.sent = v
+	for _, s := range t.subs {
+		s.send(u, t.rows)
+	}
+}
+
+// send delivers u, merged into the update still waiting in the channel if there is one.
+func (s *subscription) send(u Update, rows int) {
+	select {
+	case s.ch <- u:
+		return
+	default:
+	}
+	select {
+	case prev := <-s.ch:
+		u = prev.merge(u, rows)
+	default:
+		// The subscriber took the previous update in the meantime.
+	}
+	// With no other senders, the channel has room now.
+	s.ch <- u
+}
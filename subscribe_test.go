@@ -0,0 +1,126 @@
+package vt10x
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	term := New(WithSize(10, 5))
+	updates, cancel := term.(Subscriber).Subscribe()
+	defer cancel()
+
+	writeSeq(t, term, "\033[3;1Hhello")
+	u := <-updates
+	if want := (Update{Rows: []int{2}, Cursor: true}); !reflect.DeepEqual(u, want) {
+		t.Fatalf("expected %+v, got %+v", want, u)
+	}
+
+	// Updates not yet received coalesce into one.
+	writeSeq(t, term, "\033[5;1Hx")
+	writeSeq(t, term, "\033[1;1Hy\033]0;title\007")
+	u = <-updates
+	if want := (Update{Rows: []int{0, 4}, Cursor: true, Title: true}); !reflect.DeepEqual(u, want) {
+		t.Fatalf("expected %+v, got %+v", want, u)
+	}
+	select {
+	case u := <-updates:
+		t.Fatalf("expected the writes to coalesce, got a second update %+v", u)
+	default:
+	}
+
+	// Scrolling changes rows away from the cursor.
+	writeSeq(t, term, "\033[5;1H\n")
+	if u = <-updates; !reflect.DeepEqual(u.Rows, []int{0, 1, 2, 3, 4}) {
+		t.Fatalf("expected a scroll to change every row, got %v", u.Rows)
+	}
+
+	// A write that changes nothing sends nothing.
+	writeSeq(t, term, "\033[m")
+	select {
+	case u := <-updates:
+		t.Fatalf("expected no update, got %+v", u)
+	default:
+	}
+
+	// So does a resize.
+	term.Resize(10, 2)
+	if u = <-updates; !reflect.DeepEqual(u.Rows, []int{0, 1}) {
+		t.Fatalf("expected a resize to change every row, got %v", u.Rows)
+	}
+}
+
+func TestSubscribeReverseVideo(t *testing.T) {
+	term := New(WithSize(10, 5))
+	updates, cancel := term.(Subscriber).Subscribe()
+	defer cancel()
+
+	writeSeq(t, term, "\033[?5h")
+	if u := <-updates; !reflect.DeepEqual(u, Update{Reverse: true}) {
+		t.Fatalf("expected only reverse video reported, got %+v", u)
+	}
+	writeSeq(t, term, "\033[?5h")
+	select {
+	case u := <-updates:
+		t.Fatalf("expected no update when reverse video stays on, got %+v", u)
+	default:
+	}
+	writeSeq(t, term, "\033[?5l")
+	if u := <-updates; !u.Reverse {
+		t.Fatalf("expected switching reverse video off reported, got %+v", u)
+	}
+}
+
+func TestSubscribeCoalescesAcrossResize(t *testing.T) {
+	term := New(WithSize(10, 5))
+	updates, cancel := term.(Subscriber).Subscribe()
+	defer cancel()
+
+	writeSeq(t, term, "\033[5;1Hx")
+	term.Resize(10, 2)
+	if u := <-updates; !reflect.DeepEqual(u.Rows, []int{0, 1}) {
+		t.Fatalf("expected rows removed by the resize to be dropped, got %v", u.Rows)
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	term := New(WithSize(10, 2))
+	first, cancelFirst := term.(Subscriber).Subscribe()
+	second, cancelSecond := term.(Subscriber).Subscribe()
+	defer cancelSecond()
+
+	writeSeq(t, term, "a")
+	cancelFirst()
+	cancelFirst()
+	if u, ok := <-first; !ok || !reflect.DeepEqual(u.Rows, []int{0}) {
+		t.Fatalf("expected the pending update before the channel closes, got %+v, %v", u, ok)
+	}
+	if _, ok := <-first; ok {
+		t.Fatal("expected cancel to close the channel")
+	}
+	writeSeq(t, term, "b")
+	if u := <-second; !reflect.DeepEqual(u.Rows, []int{0}) {
+		t.Fatalf("expected the other subscriber to keep receiving, got %+v", u)
+	}
+}
+
+// TestSubscribeConcurrent lets a subscriber read while another goroutine writes; run with -race.
+func TestSubscribeConcurrent(t *testing.T) {
+	term := New(WithSize(10, 5))
+	updates, cancel := term.(Subscriber).Subscribe()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range updates {
+			_ = term.String()
+		}
+	}()
+	for range 1000 {
+		writeSeq(t, term, "x")
+	}
+	cancel()
+	wg.Wait()
+}
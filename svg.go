@@ -0,0 +1,214 @@
+package vt10x
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// SVGTheme configures how ExportSVG draws a screen.
+type SVGTheme struct {
+	// FontFamily is the CSS font-family of the text. It should list monospace fonts, as the text is laid out in a
+	// fixed grid whatever font the viewer picks.
+	FontFamily string
+
+	// FontSize is the font size in pixels.
+	FontSize float64
+
+	// CellWidth and CellHeight are the size of a cell in pixels. Zero picks the usual proportions of a monospace
+	// font: 0.6 and 1.2 times FontSize.
+	CellWidth, CellHeight float64
+
+	// Palette, if set, replaces the palette and default colors in effect when the state was dumped.
+	Palette *Palette
+
+	// HideCursor leaves out the cursor, which is otherwise drawn as a block if the state shows it.
+	HideCursor bool
+}
+
+// DefaultSVGTheme returns a theme drawing 14 pixel text in the first of several common monospace fonts installed.
+func DefaultSVGTheme() SVGTheme {
+	return SVGTheme{
+		FontFamily: "Menlo, Consolas, 'DejaVu Sans Mono', 'Liberation Mono', monospace",
+		FontSize:   14,
+	}
+}
+
+// svgRun is a run of cells of a row drawn alike.
+type svgRun struct {
+	x, n  int
+	style Style
+	text  []rune
+}
+
+// ExportSVG returns a self-contained SVG image of the active screen of s: its text in the theme's font, with the
+// colors, attributes and line renditions of each cell, and the cursor. Each character is placed in its own cell, so
+// the grid stays aligned whatever the font's metrics. A screen in reverse video is drawn with the default colors
+// swapped.
+func ExportSVG(s TerminalState, theme SVGTheme) []byte {
+	if theme.FontSize <= 0 {
+		theme.FontSize = DefaultSVGTheme().FontSize
+	}
+	if theme.FontFamily == "" {
+		theme.FontFamily = DefaultSVGTheme().FontFamily
+	}
+	cw, ch := theme.CellWidth, theme.CellHeight
+	if cw <= 0 {
+		cw = theme.FontSize * 0.6
+	}
+	if ch <= 0 {
+		ch = theme.FontSize * 1.2
+	}
+	color := func(c Color) string {
+		if s.ReverseVideo {
+			c = ReverseVideoColor(c)
+		}
+		var r, g, b uint8
+		if theme.Palette != nil {
+			r, g, b = splitRGB(theme.Palette.resolve(c))
+		} else {
+			r, g, b = s.ResolveColor(c)
+		}
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	num := func(f float64) string {
+		return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+	}
+
+	var b bytes.Buffer
+	w, h := num(float64(s.Cols)*cw), num(float64(s.Rows)*ch)
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s" font-family="`,
+		w, h, w, h)
+	xml.EscapeText(&b, []byte(theme.FontFamily))
+	fmt.Fprintf(&b, `" font-size="%s">`+"\n", num(theme.FontSize))
+	fmt.Fprintf(&b, `<rect width="%s" height="%s" fill="%s"/>`+"\n", w, h, color(DefaultBG))
+
+	for y, row := range s.PrimaryBuffer {
+		top := float64(y) * ch
+		rendition := LineSingle
+		if y < len(s.PrimaryRendition) {
+			rendition = s.PrimaryRendition[y]
+		}
+		// Double width and double height rows are drawn at single width and scaled up, clipped to the row.
+		switch rendition {
+		case LineDoubleWidth:
+			fmt.Fprintf(&b, `<g transform="scale(2 1)">`+"\n")
+		case LineDoubleHeightTop, LineDoubleHeightBottom:
+			origin := top
+			if rendition == LineDoubleHeightBottom {
+				origin += ch
+			}
+			fmt.Fprintf(&b, `<svg y="%s" width="%s" height="%s" overflow="hidden"><g transform="translate(0 %s) scale(2) translate(0 -%s)">`+"\n",
+				num(top), w, num(ch), num(origin-top), num(origin))
+			top = 0
+		}
+
+		cursor := -1
+		if y == s.CursorY && s.CursorVisible && !theme.HideCursor {
+			cursor = s.CursorX
+		}
+		for _, r := range svgRuns(row, cursor) {
+			x := float64(r.x) * cw
+			if r.style.BG != DefaultBG {
+				fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+					num(x), num(top), num(float64(r.n)*cw), num(ch), color(r.style.BG))
+			}
+			if r.style.Invisible() {
+				continue
+			}
+			fg := color(r.style.FG)
+			opacity := ""
+			if r.style.Dim() {
+				opacity = ` opacity="0.5"`
+			}
+			var xs []string
+			var text []rune
+			for i, c := range r.text {
+				if c != ' ' && c != 0 {
+					xs = append(xs, num(x+float64(i)*cw))
+					text = append(text, c)
+				}
+			}
+			if len(text) > 0 {
+				fmt.Fprintf(&b, `<text x="`)
+				for i, x := range xs {
+					if i > 0 {
+						b.WriteByte(' ')
+					}
+					b.WriteString(x)
+				}
+				fmt.Fprintf(&b, `" y="%s" dominant-baseline="central" fill="%s"%s`, num(top+ch/2), fg, opacity)
+				if r.style.Bold() {
+					b.WriteString(` font-weight="bold"`)
+				}
+				if r.style.Italic() {
+					b.WriteString(` font-style="italic"`)
+				}
+				b.WriteByte('>')
+				xml.EscapeText(&b, []byte(string(text)))
+				b.WriteString("</text>\n")
+			}
+
+			// Decorations are drawn as lines rather than with text-decoration, which cannot take the underline color
+			// or style and which renderers place inconsistently.
+			thick := theme.FontSize / 14
+			line := func(ly float64, stroke, extra string) {
+				fmt.Fprintf(&b, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-width="%s"%s%s/>`+"\n",
+					num(x), num(ly), num(x+float64(r.n)*cw), num(ly), stroke, num(thick), opacity, extra)
+			}
+			if r.style.Underlined() {
+				stroke := fg
+				if r.style.UnderlineColor != DefaultUnderline {
+					stroke = color(r.style.UnderlineColor)
+				}
+				ly := top + ch - 2*thick
+				switch r.style.Underline {
+				case UnderlineDouble:
+					line(ly-2*thick, stroke, "")
+					line(ly, stroke, "")
+				case UnderlineCurly, UnderlineDashed:
+					line(ly, stroke, fmt.Sprintf(` stroke-dasharray="%s"`, num(3*thick)))
+				case UnderlineDotted:
+					line(ly, stroke, fmt.Sprintf(` stroke-dasharray="%s"`, num(thick)))
+				default:
+					line(ly, stroke, "")
+				}
+			}
+			if r.style.Strikethrough() {
+				line(top+ch/2, fg, "")
+			}
+			if r.style.Overline() {
+				line(top+thick, fg, "")
+			}
+		}
+
+		switch rendition {
+		case LineDoubleWidth:
+			b.WriteString("</g>\n")
+		case LineDoubleHeightTop, LineDoubleHeightBottom:
+			b.WriteString("</g></svg>\n")
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+// svgRuns splits row into runs of cells drawn alike. The cell in column cursor, if any, is drawn as the cursor.
+func svgRuns(row []Glyph, cursor int) []svgRun {
+	var runs []svgRun
+	for x, g := range row {
+		style := g.Style()
+		if x == cursor {
+			style.FG, style.BG = DefaultBG, DefaultCursor
+		}
+		if n := len(runs); n > 0 && runs[n-1].style.Equal(style) {
+			runs[n-1].n++
+			runs[n-1].text = append(runs[n-1].text, g.Char)
+			continue
+		}
+		runs = append(runs, svgRun{x: x, n: 1, style: style, text: []rune{g.Char}})
+	}
+	return runs
+}
@@ -0,0 +1,78 @@
+package vt10x
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestExportSVG(t *testing.T) {
+	term := New(WithSize(6, 3))
+	writeSeq(t, term, "\033[1;4:2;31;42ma<b\033[m \033[9;58;5;4mz\r\n\033#6wi\033[?25l")
+
+	out := string(ExportSVG(term.DumpState(), DefaultSVGTheme()))
+	if err := xml.Unmarshal([]byte(out), new(struct{})); err != nil {
+		t.Fatalf("expected well-formed XML, got %v:\n%s", err, out)
+	}
+	for _, want := range []string{
+		`width="50.4" height="50.4"`,
+		`<rect width="50.4" height="50.4" fill="#000000"/>`,
+		// The run's background, then its text with each character placed in its cell.
+		`<rect x="0" y="0" width="25.2" height="16.8" fill="#00cd00"/>`,
+		`<text x="0 8.4 16.8" y="8.4" dominant-baseline="central" fill="#ff0000" font-weight="bold">a&lt;b</text>`,
+		// A double underline in the text color, and a strikethrough with an underline color set.
+		`<line x1="0" y1="12.8" x2="25.2" y2="12.8" stroke="#ff0000" stroke-width="1"/>`,
+		`<line x1="0" y1="14.8" x2="25.2" y2="14.8" stroke="#ff0000" stroke-width="1"/>`,
+		`<line x1="33.6" y1="8.4" x2="42" y2="8.4" stroke="#e5e5e5" stroke-width="1"/>`,
+		`<g transform="scale(2 1)">`,
+		`<text x="0 8.4" y="25.2" dominant-baseline="central" fill="#e5e5e5">wi</text>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "#e5e5e5\"/>") {
+		t.Errorf("expected no cursor block while the cursor is hidden:\n%s", out)
+	}
+}
+
+func TestExportSVGReverseVideo(t *testing.T) {
+	term := New(WithSize(2, 1))
+	writeSeq(t, term, "\033[?5h\033[?25la\033[31mb")
+
+	out := string(ExportSVG(term.DumpState(), DefaultSVGTheme()))
+	for _, want := range []string{
+		`<rect width="16.8" height="16.8" fill="#e5e5e5"/>`,
+		`<text x="0" y="8.4" dominant-baseline="central" fill="#000000">a</text>`,
+		`<text x="8.4" y="8.4" dominant-baseline="central" fill="#cd0000">b</text>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestExportSVGCursorAndTheme(t *testing.T) {
+	term := New(WithSize(2, 1))
+	writeSeq(t, term, "x")
+	p := DefaultPalette()
+	p.Background = 0x102030
+	p.Cursor = 0xffffff
+
+	out := string(ExportSVG(term.DumpState(), SVGTheme{FontFamily: `"Fira Code"`, FontSize: 10, CellWidth: 7, Palette: &p}))
+	for _, want := range []string{
+		`font-family="&#34;Fira Code&#34;" font-size="10"`,
+		`width="14" height="12"`,
+		`fill="#102030"`,
+		`<rect x="7" y="0" width="7" height="12" fill="#ffffff"/>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+
+	out = string(ExportSVG(term.DumpState(), SVGTheme{HideCursor: true}))
+	if strings.Contains(out, `x="8.4" y="0"`) {
+		t.Errorf("expected no cursor with HideCursor:\n%s", out)
+	}
+}
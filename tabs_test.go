@@ -0,0 +1,81 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+func assertTabStops(t *testing.T, term Terminal, want ...int) {
+	t.Helper()
+
+	if got := term.DumpState().TabStops; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected tab stops %v, got %v", want, got)
+	}
+}
+
+func TestTabStops(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		want []int
+	}{
+		{"default", "", []int{8, 16, 24, 32}},
+		{"HTS", "\033[4G\033H", []int{3, 8, 16, 24, 32}},
+		{"TBC 0", "\033[17G\033[g", []int{8, 24, 32}},
+		{"TBC 3", "\033[3g", nil},
+		{"TBC 5", "\033[5g", nil},
+		{"CTC 0", "\033[6G\033[W", []int{5, 8, 16, 24, 32}},
+		{"CTC 2", "\033[9G\033[2W", []int{16, 24, 32}},
+		{"CTC 5", "\033[5W", nil},
+		{"DECST8C", "\033[3g\033[3G\033H\033[?5W", []int{8, 16, 24, 32}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := New(WithSize(40, 4))
+			writeSeq(t, term, tt.seq)
+			assertTabStops(t, term, tt.want...)
+		})
+	}
+}
+
+func TestTabMovement(t *testing.T) {
+	term := New(WithSize(40, 4))
+	writeSeq(t, term, "\033[3g\033[5G\033H\033[12G\033H\033[30G\033H\033[1G")
+
+	steps := []struct {
+		seq  string
+		want int
+	}{
+		{"\t", 4},
+		{"\033[I", 11},
+		{"\033[0I", 29}, // a count of 0 moves one stop
+		{"\033[I", 39},  // past the last stop, to the right margin
+		{"\033[2Z", 11},
+		{"\033[0Z", 4},
+		{"\033[Z", 0}, // before the first stop, to the left margin
+		{"\033[3I", 29},
+	}
+	for _, s := range steps {
+		writeSeq(t, term, s.seq)
+		if x := term.Cursor().X; x != s.want {
+			t.Fatalf("%q: expected column %d, got %d", s.seq, s.want, x)
+		}
+	}
+}
+
+// TestTabAlignment checks that text aligned with tabs after a prompt lands in the columns the stops set.
+func TestTabAlignment(t *testing.T) {
+	term := New(WithSize(40, 4))
+	writeSeq(t, term, "\033[3g\033[11G\033H\033[21G\033H\r$ a\tb\tc\r\nlonger name\tx\ty")
+
+	if got := extractStr(term, 0, 20, 0); got != "$ a       b         c" {
+		t.Fatalf("unexpected first row %q", got)
+	}
+	// A field past a stop moves on to the next one, and past the last stop to the right margin.
+	if got := extractStr(term, 0, 20, 1); got != "longer name         x" {
+		t.Fatalf("unexpected second row %q", got)
+	}
+	if g := term.Cell(39, 1); g.Char != 'y' {
+		t.Fatalf("expected y at the right margin, got %q", g.Char)
+	}
+}
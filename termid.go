@@ -0,0 +1,102 @@
+package vt10x
+
+import "fmt"
+
+// TerminalModel is a terminal the emulator can identify as in its device attribute reports.
+type TerminalModel uint8
+
+// Terminal models.
+const (
+	// ModelXterm answers like xterm, which reports itself as a VT420 with sixel graphics and xterm's patch level as
+	// its firmware version.
+	ModelXterm TerminalModel = iota
+	ModelVT100
+	ModelVT220
+	ModelVT420
+
+	// ModelVT102 answers like the Linux console, which reports itself as a VT102.
+	ModelVT102
+
+	// ModelScreen answers like GNU screen, which reports itself as a VT100 to DA1 and with its own type, 83, to DA2.
+	ModelScreen
+)
+
+// TerminalID is which terminal the emulator claims to be when asked with DA1 (CSI c), DA2 (CSI > c) and DECRPTUI
+// (CSI = c). Applications recorded against a given terminal negotiate the same features with one claiming to be it.
+type TerminalID struct {
+	Model TerminalModel
+
+	// Version is the firmware version DA2 reports, which for xterm is its patch number.
+	Version int
+
+	// UnitID is the unit id DECRPTUI reports, as eight hex digits.
+	UnitID uint32
+}
+
+// DefaultTerminalID is the TerminalID of a terminal not configured with WithTerminalID.
+var DefaultTerminalID = TerminalID{Model: ModelXterm, Version: 390}
+
+// WithTerminalID sets which terminal the emulator claims to be in its device attribute reports. Models without sixel
+// graphics also ignore sixel images, as the terminal they claim to be would.
+func WithTerminalID(id TerminalID) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.terminalID = &id
+	}
+}
+
+// sixel reports whether the model draws sixel graphics.
+func (m TerminalModel) sixel() bool {
+	return m == ModelXterm
+}
+
+// primaryAttributes returns the DA1 parameters of the model: its conformance level, then the features it has of those
+// the emulator implements: sixel graphics (4), selective erase (6) and ANSI color (22). A VT100 reports itself with
+// the advanced video option instead.
+func (m TerminalModel) primaryAttributes() string {
+	switch m {
+	case ModelVT100, ModelScreen:
+		return "1;2"
+	case ModelVT102:
+		return "6"
+	case ModelVT220:
+		return "62;6;22"
+	case ModelVT420:
+		return "64;6;22"
+	}
+	return "64;4;6;22"
+}
+
+// secondaryType returns the terminal type DA2 reports, or false for a VT100 or VT102, which do not answer DA2.
+func (m TerminalModel) secondaryType() (int, bool) {
+	switch m {
+	case ModelVT100, ModelVT102:
+		return 0, false
+	case ModelVT220:
+		return 1, true
+	case ModelScreen:
+		return 83, true
+	}
+	return 41, true
+}
+
+// deviceAttributes answers DA1, DA2 or DA3 (DECRPTUI), according to the private marker of the request. Only a
+// parameter of 0 is a request.
+func (t *State) deviceAttributes(mark byte, arg int) {
+	if arg != 0 {
+		return
+	}
+	m := t.terminalID.Model
+	switch mark {
+	case 0:
+		fmt.Fprintf(t.w, "\033[?%sc", m.primaryAttributes())
+	case '>':
+		if pp, ok := m.secondaryType(); ok {
+			fmt.Fprintf(t.w, "\033[>%d;%d;0c", pp, t.terminalID.Version)
+		}
+	case '=':
+		// DECRPTUI came with the VT400 series.
+		if m == ModelVT420 || m == ModelXterm {
+			fmt.Fprintf(t.w, "\033P!|%08X\033\\", t.terminalID.UnitID)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package vt10x
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeviceAttributes(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		id            *TerminalID
+		da1, da2, da3 string
+	}{
+		{"default", nil, "\033[?64;4;6;22c", "\033[>41;390;0c", "\033P!|00000000\033\\"},
+		{"vt100", &TerminalID{Model: ModelVT100}, "\033[?1;2c", "", ""},
+		{"vt220", &TerminalID{Model: ModelVT220, Version: 25}, "\033[?62;6;22c", "\033[>1;25;0c", ""},
+		{"vt420", &TerminalID{Model: ModelVT420, Version: 10, UnitID: 0xbeef}, "\033[?64;6;22c", "\033[>41;10;0c",
+			"\033P!|0000BEEF\033\\"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var replies strings.Builder
+			opts := []TerminalOption{WithWriter(&replies)}
+			if tc.id != nil {
+				opts = append(opts, WithTerminalID(*tc.id))
+			}
+			term := New(opts...)
+			for _, q := range []struct{ seq, want string }{
+				{"\033[c", tc.da1}, {"\033[0c", tc.da1}, {"\033[>c", tc.da2}, {"\033[=0c", tc.da3},
+				// Requests with other parameters, and the private form, which is not a request, go unanswered.
+				{"\033[1c", ""}, {"\033[?6c", ""},
+			} {
+				replies.Reset()
+				writeSeq(t, term, q.seq)
+				if got := replies.String(); got != q.want {
+					t.Errorf("%q: expected %q, got %q", q.seq, q.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTerminalIDGatesSixel(t *testing.T) {
+	const sixel = "\033Pq#1;2;100;0;0#1!6~-!6~\033\\"
+	term := New(WithSize(20, 10), WithTerminalID(TerminalID{Model: ModelVT220}))
+	writeSeq(t, term, sixel+"x")
+	if n := len(term.DumpState().Images); n != 0 {
+		t.Fatalf("expected a VT220 to ignore sixel images, got %d", n)
+	}
+	if s := strings.TrimSpace(term.String()); s != "x" {
+		t.Fatalf("expected the sixel data consumed, got %q", s)
+	}
+
+	term = New(WithSize(20, 10))
+	writeSeq(t, term, sixel)
+	if n := len(term.DumpState().Images); n != 1 {
+		t.Fatalf("expected xterm to draw sixel images, got %d", n)
+	}
+}
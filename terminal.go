@@ -0,0 +1,283 @@
+package vt10x
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+type terminal struct {
+	*State
+}
+
+var _ Terminal = (*terminal)(nil)
+
+func newTerminal(info TerminalInfo) *terminal {
+	t := &terminal{newState(info.w)}
+	t.scrollbackLimit = info.scrollbackLimit
+	if info.palette != nil {
+		t.palette = *info.palette
+	}
+	t.onOversizedImage = info.onOversizedImage
+	t.limits, t.onTruncate = info.limits, info.onTruncate
+	t.onResize, t.onWindowOp = info.onResize, info.onWindowOp
+	t.windowReports = defaultWindowReports
+	if info.windowReports != nil {
+		t.windowReports = *info.windowReports
+	}
+	t.onBell, t.bellInterval, t.visualBell = info.onBell, info.bellInterval, info.visualBell
+	t.onNotify, t.onDirectory, t.onVariable = info.onNotify, info.onDirectory, info.onVariable
+	t.logger = info.logger
+	t.lineClock = info.lineClock
+	t.tmuxPassthrough = info.tmuxPassthrough
+	t.c1Controls = info.c1Controls
+	t.translateLF = info.translateLF
+	t.columnSwitching = info.columnSwitching
+	t.onVector = info.onVector
+	t.idle.after, t.idle.fn = info.idleAfter, info.onIdle
+	t.cursorHistory.limit = info.cursorHistory
+	t.inputEpochs = info.inputEpochs
+	t.promptDetector = info.promptDetector
+	if info.redaction != nil {
+		t.redaction = newRedaction(*info.redaction)
+	}
+	t.encoding = info.encoding
+	t.answerback = info.answerback
+	t.terminalID = DefaultTerminalID
+	if info.terminalID != nil {
+		t.terminalID = *info.terminalID
+	}
+	if info.profile != nil {
+		t.applyProfile(*info.profile)
+	}
+	if info.cellW > 0 {
+		t.cellWidth, t.cellHeight = info.cellW, info.cellH
+	}
+	t.init(info.cols, info.rows)
+	if info.state != nil {
+		t.restore(*info.state)
+	}
+	return t
+}
+
+func (t *terminal) init(cols, rows int) {
+	t.numlock = true
+	t.state = t.parse
+	t.cur.Attr.FG = DefaultFG
+	t.cur.Attr.BG = DefaultBG
+	t.cur.Attr.UnderlineColor = DefaultUnderline
+	t.setSize(cols, rows)
+	t.reset()
+}
+
+// Write parses input and writes terminal changes to state.
+func (t *terminal) Write(p []byte) (int, error) {
+	t.lock()
+	defer t.unlock()
+
+	t.write(p, nil)
+	return len(p), nil
+}
+
+// write parses the runes in p. A rune split across writes, as PTY reads often leave one, is held until the rest of
+// it arrives, so p may end anywhere. If dirty is non-nil the rows each rune touched are added to it. The terminal must
+// be locked.
+func (t *terminal) write(p []byte, dirty map[int]bool) {
+	t.countBytes(len(p))
+	t.wrote()
+	t.parseBytes(p, dirty)
+	if t.redaction != nil {
+		t.checkPrompts()
+	}
+}
+
+// parseBytes decodes p and parses its runes for write.
+func (t *terminal) parseBytes(p []byte, dirty map[int]bool) {
+	if t.encoding != nil {
+		for _, b := range p {
+			t.putRune(t.encoding.Decode(b), dirty)
+		}
+		return
+	}
+	if t.partialLen > 0 {
+		p = t.completeRune(p, dirty)
+	}
+	for i := 0; i < len(p); {
+		c, sz := rune(p[i]), 1
+		if c >= utf8.RuneSelf {
+			c, sz = utf8.DecodeRune(p[i:])
+			if c == utf8.RuneError && sz == 1 {
+				if !utf8.FullRune(p[i:]) {
+					t.partialLen = copy(t.partial[:], p[i:])
+					return
+				}
+				if t.c1Controls && isC1Control(rune(p[i])) {
+					// A raw C1 byte can never start a UTF-8 sequence, so it is read as the control.
+					t.putRune(rune(p[i]), dirty)
+					i++
+					continue
+				}
+				t.warnf("invalid utf8 sequence %q", p[i:i+1])
+				i++
+				continue
+			}
+		}
+		i += sz
+		t.putRune(c, dirty)
+	}
+}
+
+// completeRune finishes the rune held from an earlier write with the leading bytes of p and returns the rest of p.
+func (t *terminal) completeRune(p []byte, dirty map[int]bool) []byte {
+	for t.partialLen > 0 {
+		n := copy(t.partial[t.partialLen:], p)
+		b := t.partial[:t.partialLen+n]
+		if !utf8.FullRune(b) {
+			// p is too short to finish it; hold on for the next write.
+			t.partialLen = len(b)
+			return nil
+		}
+		c, sz := utf8.DecodeRune(b)
+		if c == utf8.RuneError && sz == 1 {
+			// The held bytes were not the start of a rune after all. Drop the first and retry with the rest, which
+			// may themselves begin one.
+			t.warnf("invalid utf8 sequence %q", b[:1])
+			t.partialLen = copy(t.partial[:], t.partial[1:t.partialLen])
+			continue
+		}
+		p = p[sz-t.partialLen:]
+		t.partialLen = 0
+		t.putRune(c, dirty)
+	}
+	return p
+}
+
+// putRune parses c, adding the rows it touched to dirty if that is non-nil.
+func (t *terminal) putRune(c rune, dirty map[int]bool) {
+	if dirty == nil {
+		t.put(c)
+		return
+	}
+	dirty[t.cur.Y] = true
+	t.put(c)
+	dirty[t.cur.Y] = true
+}
+
+// readFromBufSize is the size of the buffer ReadFrom reads into.
+const readFromBufSize = 32 << 10
+
+// ReadFrom reads from r until EOF or an error, parsing the input as it arrives, and returns the number of bytes read.
+// It lets io.Copy(term, pty) feed the terminal through one reused buffer. The terminal is locked while each read is
+// parsed but not while waiting for the next.
+func (t *terminal) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, readFromBufSize)
+	var n int64
+	for {
+		m, err := r.Read(buf)
+		n += int64(m)
+		if m > 0 {
+			t.lock()
+			t.write(buf[:m], nil)
+			t.unlock()
+		}
+		if err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+	}
+}
+
+// WriteWithChanges writes to the terminal state and returns the line numbers that changed.
+func (t *terminal) WriteWithChanges(p []byte) ([]int, error) {
+	dirtyLines := make(map[int]bool)
+	t.lock()
+	defer t.unlock()
+
+	t.write(p, dirtyLines)
+	return uniqueSorted(dirtyLines), nil
+}
+
+func uniqueSorted(m map[int]bool) []int {
+	lines := make([]int, 0, len(m))
+	for line := range m {
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return lines
+	}
+
+	slices.Sort(lines)
+	return lines
+}
+
+// TODO: add tests for expected blocking behavior
+func (t *terminal) Parse(br *bufio.Reader) error {
+	var locked bool
+	defer func() {
+		if locked {
+			t.unlock()
+		}
+	}()
+	for {
+		c, sz, invalid, err := t.readRune(br)
+		if err != nil {
+			return err
+		}
+		if invalid {
+			if !t.c1Controls {
+				t.warnf("invalid utf8 sequence")
+				break
+			}
+			br.UnreadRune()
+			b, _ := br.ReadByte()
+			if c = rune(b); !isC1Control(c) {
+				t.warnf("invalid utf8 sequence")
+				break
+			}
+		}
+		if !locked {
+			t.lock()
+			locked = true
+		}
+		t.countBytes(sz)
+		t.wrote()
+
+		// put rune for parsing and update state
+		t.put(c)
+
+		// break if our buffer is empty, or if buffer contains an
+		// incomplete rune.
+		n := br.Buffered()
+		if n == 0 || (t.encoding == nil && n < 4 && !fullRuneBuffered(br)) {
+			break
+		}
+	}
+	if locked && t.redaction != nil {
+		t.checkPrompts()
+	}
+	return nil
+}
+
+// readRune reads the next rune from br, decoded with the terminal's encoding, and returns its size in bytes and
+// whether it was invalid UTF-8.
+func (t *terminal) readRune(br *bufio.Reader) (c rune, sz int, invalid bool, err error) {
+	if t.encoding != nil {
+		b, err := br.ReadByte()
+		return t.encoding.Decode(b), 1, false, err
+	}
+	c, sz, err = br.ReadRune()
+	return c, sz, c == unicode.ReplacementChar && sz == 1, err
+}
+
+func fullRuneBuffered(br *bufio.Reader) bool {
+	n := br.Buffered()
+	buf, err := br.Peek(n)
+	if err != nil {
+		return false
+	}
+	return utf8.FullRune(buf)
+}
@@ -0,0 +1,50 @@
+package vt10x
+
+import "time"
+
+// WithLineTimestamps records when each row was last modified, exposed in TerminalState, and when captured lines
+// scrolled off the screen, exposed by TakeScrollbackLines. now supplies the time, and is typically time.Now; it is
+// called on every cell write while the terminal is locked.
+func WithLineTimestamps(now func() time.Time) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.lineClock = now
+	}
+}
+
+// ScrollbackLine is a line captured as it scrolled off the top of the screen.
+type ScrollbackLine struct {
+	Text []rune
+
+	// Modified is when the line was last modified, Scrolled when it scrolled off the screen. Both are zero unless
+	// line timestamps were enabled with WithLineTimestamps, and Modified is also zero for a line never written.
+	Modified, Scrolled time.Time
+
+	// Wrapped is set if the text continues on the next line, because it reached the right margin and wrapped.
+	Wrapped bool
+}
+
+// TakeScrollbackLines is TakeScrollback with the timestamps of each line.
+func (t *State) TakeScrollbackLines() (lines []ScrollbackLine, dropped int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines, dropped = t.scrollback, t.scrollbackDropped
+	t.scrollback, t.scrollbackDropped = nil, 0
+	return lines, dropped
+}
+
+// modifiedTimes returns the modification time of each row of meta.
+func modifiedTimes(meta []rowMeta) []time.Time {
+	times := make([]time.Time, len(meta))
+	for y := range meta {
+		times[y] = meta[y].modified
+	}
+	return times
+}
+
+// restoreModified sets the modification times of the rows of meta from times, as returned by modifiedTimes.
+func restoreModified(meta []rowMeta, times []time.Time) {
+	for y := 0; y < len(meta) && y < len(times); y++ {
+		meta[y].modified = times[y]
+	}
+}
@@ -0,0 +1,72 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stepClock returns a clock that starts at the Unix epoch and advances only when step is called.
+func stepClock() (now func() time.Time, step func()) {
+	t := time.Unix(0, 0)
+	return func() time.Time { return t }, func() { t = t.Add(time.Second) }
+}
+
+func TestLineTimestamps(t *testing.T) {
+	now, step := stepClock()
+	term := New(WithSize(10, 3), WithLineTimestamps(now), WithScrollbackCapture(10))
+	t0 := now()
+
+	writeSeq(t, term, "one")
+	step()
+	writeSeq(t, term, "\r\ntwo")
+	step()
+	s := term.DumpState()
+	if got, want := s.PrimaryModified, []time.Time{t0, t0.Add(time.Second), {}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected row timestamps %v, want %v", got, want)
+	}
+
+	// Scrolling carries the timestamps with the rows, and the line that scrolls off keeps its own.
+	writeSeq(t, term, "\r\nthree\r\nfour")
+	s = term.DumpState()
+	if got, want := s.PrimaryModified, []time.Time{t0.Add(time.Second), now(), now()}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected row timestamps after scrolling %v, want %v", got, want)
+	}
+	lines, _ := term.(ScrollbackLineTaker).TakeScrollbackLines()
+	if len(lines) != 1 || string(lines[0].Text[:3]) != "one" || !lines[0].Modified.Equal(t0) ||
+		!lines[0].Scrolled.Equal(now()) {
+		t.Fatalf("unexpected scrollback %+v", lines)
+	}
+
+	// Timestamps survive a restore.
+	restored := New(WithState(s), WithLineTimestamps(now))
+	if got := restored.DumpState().PrimaryModified; !reflect.DeepEqual(got, s.PrimaryModified) {
+		t.Fatalf("restored timestamps %v, want %v", got, s.PrimaryModified)
+	}
+}
+
+func TestLineTimestampsAltScreen(t *testing.T) {
+	now, step := stepClock()
+	term := New(WithSize(10, 2), WithLineTimestamps(now))
+
+	writeSeq(t, term, "main")
+	step()
+	writeSeq(t, term, "\033[?1049h\033[2;1Halt")
+	// As with the buffers, PrimaryModified follows the active screen.
+	s := term.DumpState()
+	if !s.PrimaryModified[1].Equal(now()) || !s.AlternateModified[0].Equal(now().Add(-time.Second)) {
+		t.Fatalf("unexpected timestamps on the alternate screen: %v and %v", s.PrimaryModified, s.AlternateModified)
+	}
+}
+
+func TestLineTimestampsDisabled(t *testing.T) {
+	term := New(WithSize(10, 2), WithScrollbackCapture(10))
+	writeSeq(t, term, "a\r\nb\r\nc")
+	if s := term.DumpState(); s.PrimaryModified != nil || s.AlternateModified != nil {
+		t.Fatal("expected no timestamps unless enabled")
+	}
+	lines, _ := term.(ScrollbackLineTaker).TakeScrollbackLines()
+	if len(lines) != 1 || !lines[0].Modified.IsZero() || !lines[0].Scrolled.IsZero() {
+		t.Fatalf("expected scrollback without timestamps, got %+v", lines)
+	}
+}
@@ -0,0 +1,56 @@
+package vt10x
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestTitleStackPushPop(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033]0;shell\007")
+	writeSeq(t, term, "\033[22;0t")
+	writeSeq(t, term, "\033]2;vim\007")
+	if got := term.Title(); got != "vim" {
+		t.Fatalf("expected title vim, got %q", got)
+	}
+
+	state := term.DumpState()
+	if !slices.Equal(state.TitleStack, []string{"shell"}) {
+		t.Fatalf("expected title stack [shell], got %q", state.TitleStack)
+	}
+
+	writeSeq(t, term, "\033[23;0t")
+	if got := term.Title(); got != "shell" {
+		t.Fatalf("expected title restored to shell, got %q", got)
+	}
+	if state := term.DumpState(); len(state.TitleStack) != 0 {
+		t.Fatalf("expected empty title stack, got %q", state.TitleStack)
+	}
+}
+
+func TestTitleStackPopEmpty(t *testing.T) {
+	term := New()
+
+	writeSeq(t, term, "\033]0;shell\007\033[23t")
+	if got := term.Title(); got != "shell" {
+		t.Fatalf("expected title unchanged, got %q", got)
+	}
+}
+
+func TestTitleStackBounded(t *testing.T) {
+	term := New()
+
+	for i := 0; i < maxTitleStack+5; i++ {
+		writeSeq(t, term, fmt.Sprintf("\033]0;t%d\007\033[22t", i))
+	}
+
+	state := term.DumpState()
+	if len(state.TitleStack) != maxTitleStack {
+		t.Fatalf("expected %d stacked titles, got %d", maxTitleStack, len(state.TitleStack))
+	}
+	if got, want := state.TitleStack[0], "t5"; got != want {
+		t.Fatalf("expected oldest retained title %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,81 @@
+package vt10x
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// maxVariables caps the variables the terminal keeps; setting a new one past it is ignored.
+const maxVariables = 256
+
+// WithVariableHandler sets a function called when a variable set by shell integration changes, with its name and new
+// value; see TerminalState.Variables. It is called while the terminal is locked, so it must not call back into the
+// terminal.
+func WithVariableHandler(fn func(name, value string)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onVariable = fn
+	}
+}
+
+// iterm2Command handles the OSC 1337 commands other than inline images, which shell integrations send:
+//
+//	SetUserVar=name=base64 value
+//	RemoteHost=user@host
+//	CurrentDir=path
+//	ShellIntegrationVersion=version;shell=name
+//
+// They set variables named as iTerm2 names them: user.name for SetUserVar, username and hostname for RemoteHost,
+// path for CurrentDir, and shell for ShellIntegrationVersion. CurrentDir is also recorded as a working directory.
+func (t *State) iterm2Command(s *strEscape) bool {
+	cmd, arg, _ := strings.Cut(s.argString(1, ""), "=")
+	switch cmd {
+	case "SetUserVar":
+		name, enc, ok := strings.Cut(arg, "=")
+		value, err := base64.StdEncoding.DecodeString(enc)
+		if !ok || name == "" || err != nil {
+			t.warnf("invalid OSC 1337 user variable %q", s)
+			break
+		}
+		t.setVariable("user."+name, string(value))
+	case "RemoteHost":
+		user, host, ok := strings.Cut(arg, "@")
+		if !ok {
+			user, host = "", arg
+		}
+		t.setVariable("username", user)
+		t.setVariable("hostname", host)
+	case "CurrentDir":
+		t.setVariable("path", arg)
+		if arg != "" {
+			t.addWorkingDirectory(t.variables["hostname"], arg)
+		}
+	case "ShellIntegrationVersion":
+		for _, a := range s.args[2:] {
+			if k, v, _ := strings.Cut(a, "="); k == "shell" {
+				t.setVariable("shell", v)
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// setVariable sets the variable name to value, telling the variable handler if it changed.
+func (t *State) setVariable(name, value string) {
+	old, ok := t.variables[name]
+	if ok && old == value {
+		return
+	}
+	if !ok && len(t.variables) == maxVariables {
+		t.warnf("too many variables, ignoring %q", name)
+		return
+	}
+	if t.variables == nil {
+		t.variables = make(map[string]string)
+	}
+	t.variables[name] = value
+	if t.onVariable != nil {
+		t.onVariable(name, value)
+	}
+}
@@ -0,0 +1,51 @@
+package vt10x
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	type change struct{ name, value string }
+	var changes []change
+	term := New(WithVariableHandler(func(name, value string) { changes = append(changes, change{name, value}) }))
+
+	writeSeq(t, term, "\033]1337;SetUserVar=job=YnVpbGQ=\007\033]1337;SetUserVar=job=YnVpbGQ=\007")
+	writeSeq(t, term, "\033]1337;RemoteHost=me@box\033\\\033]1337;CurrentDir=/home/me\007")
+	writeSeq(t, term, "\033]1337;ShellIntegrationVersion=14;shell=zsh\007")
+	writeSeq(t, term, "\033]1337;SetUserVar=bad=!!\007\033]1337;SetUserVar=empty=\007")
+
+	want := map[string]string{
+		"user.job":   "build",
+		"username":   "me",
+		"hostname":   "box",
+		"path":       "/home/me",
+		"shell":      "zsh",
+		"user.empty": "",
+	}
+	s := term.(MetaDumper).DumpMeta()
+	if !reflect.DeepEqual(s.Variables, want) {
+		t.Fatalf("expected variables %v, got %v", want, s.Variables)
+	}
+	if len(changes) != len(want) || changes[0] != (change{"user.job", "build"}) {
+		t.Fatalf("expected one change per variable, got %v", changes)
+	}
+	if s.WorkingDirectory != "/home/me" || s.DirectoryHistory[0].Host != "box" {
+		t.Fatalf("expected CurrentDir recorded as the working directory, got %+v", s.DirectoryHistory)
+	}
+
+	restored := New(WithState(term.DumpState()))
+	if got := restored.(MetaDumper).DumpMeta().Variables; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected variables restored, got %v", got)
+	}
+}
+
+func TestVariablesLimit(t *testing.T) {
+	term := New()
+	for i := 0; i <= maxVariables; i++ {
+		writeSeq(t, term, "\033]1337;SetUserVar=v"+string(rune('a'+i/26%26))+string(rune('a'+i%26))+"=eA==\007")
+	}
+	if n := len(term.(MetaDumper).DumpMeta().Variables); n != maxVariables {
+		t.Fatalf("expected %d variables kept, got %d", maxVariables, n)
+	}
+}
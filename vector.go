@@ -0,0 +1,66 @@
+package vt10x
+
+// VectorGraphicsKind is a vector graphics protocol the terminal skips.
+type VectorGraphicsKind uint8
+
+const (
+	// VectorReGIS is DEC's Remote Graphic Instruction Set, sent as DCS Pn p ... ST.
+	VectorReGIS VectorGraphicsKind = iota + 1
+	// VectorTektronix is Tektronix 4014 mode, entered with DECSET 38 and left with ESC ETX.
+	VectorTektronix
+)
+
+func (k VectorGraphicsKind) String() string {
+	switch k {
+	case VectorReGIS:
+		return "ReGIS"
+	case VectorTektronix:
+		return "Tektronix"
+	}
+	return "unknown"
+}
+
+// VectorGraphics reports vector graphics the terminal skipped. The terminal draws no vector graphics, so it discards
+// their commands rather than printing them as text.
+type VectorGraphics struct {
+	Kind VectorGraphicsKind
+	// Size is the number of runes skipped.
+	Size int
+}
+
+// vectorSkip is the vector graphics being skipped.
+type vectorSkip struct {
+	kind VectorGraphicsKind
+	size int
+	esc  bool // the last rune was ESC
+}
+
+// startVector starts skipping vector graphics of kind k.
+func (t *State) startVector(k VectorGraphicsKind) {
+	t.vector = &vectorSkip{kind: k}
+}
+
+// endVector stops skipping vector graphics and reports them.
+func (t *State) endVector() {
+	v := t.vector
+	if v == nil {
+		return
+	}
+	t.vector = nil
+	if t.onVector != nil {
+		t.onVector(VectorGraphics{Kind: v.kind, Size: v.size})
+	}
+}
+
+// parseTek skips Tektronix mode until ESC ETX switches back, as in xterm. CAN and SUB, which abort sequences, are
+// part of the Tektronix command set, so they do not end it.
+func (t *State) parseTek(c rune) {
+	t.vector.size++
+	if t.vector.esc && c == 003 {
+		t.vector.size -= 2
+		t.endVector()
+		t.state = t.parse
+		return
+	}
+	t.vector.esc = c == 033
+}
@@ -0,0 +1,46 @@
+package vt10x
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReGISSkipped(t *testing.T) {
+	var got []VectorGraphics
+	term := New(WithVectorGraphicsHandler(func(v VectorGraphics) { got = append(got, v) }))
+	writeSeq(t, term, "a\033P1pS(E)P[100,100]V[200,200]T'hi'\033\\b")
+	if s := strings.TrimRight(strings.SplitN(term.String(), "\n", 2)[0], " "); s != "ab" {
+		t.Errorf("expected the ReGIS commands skipped, got %q", s)
+	}
+	want := []VectorGraphics{{Kind: VectorReGIS, Size: len("S(E)P[100,100]V[200,200]T'hi'")}}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestTektronixSkipped(t *testing.T) {
+	var got []VectorGraphics
+	var replies strings.Builder
+	term := New(WithWriter(&replies), WithVectorGraphicsHandler(func(v VectorGraphics) { got = append(got, v) }))
+	writeSeq(t, term, "a\033[?38h\x1d#0x,2@\x1f\x18text\033\x0cmore")
+	if len(got) != 0 {
+		t.Fatalf("expected no event before Tektronix mode ends, got %+v", got)
+	}
+	writeSeq(t, term, "\033\x03b\033[?38$p")
+	if s := strings.TrimRight(strings.SplitN(term.String(), "\n", 2)[0], " "); s != "ab" {
+		t.Errorf("expected the Tektronix commands skipped, got %q", s)
+	}
+	want := VectorGraphics{Kind: VectorTektronix, Size: len("\x1d#0x,2@\x1f\x18text\033\x0cmore")}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if r := replies.String(); r != "\033[?38;2$y" {
+		t.Errorf("expected DECTEK reported reset, got %q", r)
+	}
+}
+
+func TestVectorGraphicsKindString(t *testing.T) {
+	if VectorReGIS.String() != "ReGIS" || VectorTektronix.String() != "Tektronix" {
+		t.Errorf("unexpected names %s, %s", VectorReGIS, VectorTektronix)
+	}
+}
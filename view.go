@@ -0,0 +1,71 @@
+package vt10x
+
+// ScreenView is a read-only view of a terminal screen, so that code that draws or inspects one can be written once for
+// both the live terminal and snapshots of it. Every Terminal is one while it is locked, and TerminalState.View returns
+// one of a snapshot.
+type ScreenView interface {
+	// Size returns the size of the screen.
+	Size() (cols, rows int)
+
+	// Cell returns the glyph at column x of row y, both zero-based, or a zero Glyph outside the screen.
+	Cell(x, y int) Glyph
+
+	// Cursor returns the cursor. Only its position and state are set for a snapshot, which does not hold the
+	// attributes the cursor writes with.
+	Cursor() Cursor
+
+	// CursorVisible reports whether the cursor is shown.
+	CursorVisible() bool
+
+	// Mode returns the terminal modes.
+	Mode() ModeFlag
+
+	// Title returns the window title.
+	Title() string
+}
+
+var _ ScreenView = (*State)(nil)
+
+// stateView is the ScreenView of a TerminalState.
+type stateView struct {
+	s TerminalState
+}
+
+// View returns a read-only view of the active screen of s, as PrimaryBuffer holds it.
+func (s TerminalState) View() ScreenView {
+	return stateView{s}
+}
+
+func (v stateView) Size() (cols, rows int) {
+	return v.s.Cols, v.s.Rows
+}
+
+func (v stateView) Cell(x, y int) Glyph {
+	if y < 0 || y >= len(v.s.PrimaryBuffer) || x < 0 || x >= len(v.s.PrimaryBuffer[y]) {
+		return Glyph{}
+	}
+	return v.s.PrimaryBuffer[y][x]
+}
+
+func (v stateView) Cursor() Cursor {
+	c := Cursor{X: v.s.CursorX, Y: v.s.CursorY}
+	if v.s.WrapPending {
+		c.State |= cursorWrapNext
+	}
+	if v.s.Origin {
+		c.State |= cursorOrigin
+	}
+	return c
+}
+
+func (v stateView) CursorVisible() bool {
+	return v.s.CursorVisible
+}
+
+func (v stateView) Mode() ModeFlag {
+	return v.s.Mode
+}
+
+func (v stateView) Title() string {
+	return v.s.Title
+}
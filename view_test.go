@@ -0,0 +1,36 @@
+package vt10x
+
+import "testing"
+
+// viewSummary returns the size, cursor, modes and title of v, and every cell of it and just outside it.
+func viewSummary(v ScreenView) []any {
+	cols, rows := v.Size()
+	out := []any{cols, rows, v.Cursor().X, v.Cursor().Y, v.Cursor().State, v.CursorVisible(), v.Mode(), v.Title()}
+	for y := -1; y <= rows; y++ {
+		for x := -1; x <= cols; x++ {
+			out = append(out, v.Cell(x, y))
+		}
+	}
+	return out
+}
+
+func TestStateView(t *testing.T) {
+	term := New(WithSize(5, 3))
+	writeSeq(t, term, "\033]2;title\007\033[?6h\033[1;31mab\r\n\033[4mcdefg\033[?25l\033[?1h")
+
+	term.Lock()
+	live := viewSummary(term)
+	term.Unlock()
+	snap := viewSummary(term.DumpState().View())
+	if len(live) != len(snap) {
+		t.Fatalf("expected views of the same size, got %d and %d", len(live), len(snap))
+	}
+	for i := range live {
+		if live[i] != snap[i] {
+			t.Fatalf("expected the snapshot to match the terminal at %d: %v, got %v", i, live[i], snap[i])
+		}
+	}
+	if g := term.DumpState().View().Cell(5, 0); g != (Glyph{}) {
+		t.Fatalf("expected a zero glyph outside the screen, got %+v", g)
+	}
+}
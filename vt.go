@@ -4,7 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"time"
 )
 
 // Terminal represents the virtual terminal emulator.
@@ -12,7 +12,8 @@ type Terminal interface {
 	// View displays the virtual terminal.
 	View
 
-	// Write parses input and writes terminal changes to state.
+	// Write parses input and writes terminal changes to state. Input may be split anywhere, even partway through a
+	// UTF-8 sequence, whose leading bytes are held until the rest arrives, so Write always consumes all of p.
 	io.Writer
 
 	// Parse blocks on read on pty or io.Reader, then parses sequences until
@@ -68,14 +69,170 @@ type View interface {
 	DumpState() TerminalState
 }
 
+// MetaDumper is implemented by views that can dump their state without the screen buffers, such as the terminal New
+// returns and State. It is not part of View, so that implementations of View keep compiling; type-assert to it.
+type MetaDumper interface {
+	// DumpMeta returns the current state of the terminal without the screen buffers.
+	DumpMeta() TerminalState
+}
+
+// Hasher is implemented by views that keep content hashes of their rows, such as the terminal New returns and State,
+// for detecting changes without copying the screen.
+type Hasher interface {
+	// LineHash returns a content hash of row y, recomputed only once the row changes.
+	LineHash(y int) uint64
+
+	// ScreenHash returns a content hash of the whole screen.
+	ScreenHash() uint64
+}
+
+// The interfaces below are implemented by the terminal New returns, and by vt10xtest.Fake, for features added after
+// Terminal and View were first published. They are kept out of Terminal so that its implementations keep compiling;
+// callers type-assert a Terminal to the ones they use. The terminal also implements io.ReaderFrom.
+
+// ScrollbackLineTaker takes timestamped scrollback.
+type ScrollbackLineTaker interface {
+	// TakeScrollbackLines is TakeScrollback with the time each line was last modified and scrolled off, if line
+	// timestamps were enabled with WithLineTimestamps.
+	TakeScrollbackLines() (lines []ScrollbackLine, dropped int)
+}
+
+// CursorRecorder hands over the cursor moves recorded with WithCursorHistory.
+type CursorRecorder interface {
+	// TakeCursorHistory returns the positions the cursor moved to since the last call, if recording was enabled with
+	// WithCursorHistory, along with the number of moves dropped because the limit was reached, then resets both.
+	TakeCursorHistory() (moves []CursorMove, dropped int)
+}
+
+// PromptReporter tells whether a shell is waiting at its prompt.
+type PromptReporter interface {
+	// AtPrompt reports whether the cursor is at a shell prompt, going by OSC 133 marks or, for shells without them, a
+	// PromptDetector, and returns the prompt.
+	AtPrompt() (Prompt, bool)
+}
+
+// LinkFinder indexes the URLs on the screen.
+type LinkFinder interface {
+	// Links returns the URLs in the text on the screen, searching only the rows that changed since the last call.
+	Links() []Link
+}
+
+// Watcher reports text as it appears on the screen.
+type Watcher interface {
+	// Watch calls fn with the matches of pattern that appear on the screen, searching only the rows that change, until
+	// cancel is called.
+	Watch(pattern string, opts FindOptions, fn func(WatchEvent)) (cancel func(), err error)
+}
+
+// InputSender sends input to the application, encoded for the terminal's modes.
+type InputSender interface {
+	// SendText sends text to the application through the writer set with WithWriter, encoded by EncodeText for the
+	// terminal's bracketed paste, newline and keyboard modes.
+	SendText(text string) error
+
+	// SendKey sends the application what pressing key with mods sends, encoded by EncodeKey for the terminal's modes.
+	SendKey(key Key, mods KeyMod) error
+}
+
+// EpochAdvancer starts the input epochs cells are tagged with.
+type EpochAdvancer interface {
+	// AdvanceInputEpoch starts a new input epoch, with which cells written from now on are tagged if enabled with
+	// WithInputEpochs, and returns it.
+	AdvanceInputEpoch() uint64
+}
+
+// Searcher finds text on the screen.
+type Searcher interface {
+	// Find returns the cells matching pattern on the screen, and optionally in the scrollback not yet taken.
+	Find(pattern string, opts FindOptions) ([]Match, error)
+}
+
+// LineJoiner joins wrapped rows back into lines.
+type LineJoiner interface {
+	// LogicalLines returns the lines on the screen, and optionally in the scrollback not yet taken, with the rows
+	// that wrapped joined back together.
+	LogicalLines(scrollback bool) []LogicalLine
+}
+
+// CommandTracker follows shell commands through shell integration marks.
+type CommandTracker interface {
+	// Commands returns the shell commands marked with OSC 133 shell integration sequences, with the rows of their
+	// prompts and output and their exit statuses.
+	Commands() []Command
+}
+
+// Subscriber notifies of changes, for renderers that wait for them rather than poll.
+type Subscriber interface {
+	// Subscribe returns a channel of updates naming the rows that changed after each write, coalescing those not yet
+	// received, and a function that ends the subscription.
+	Subscribe() (updates <-chan Update, cancel func())
+}
+
+// StatsReporter counts the terminal's activity.
+type StatsReporter interface {
+	// Stats returns counts of the terminal's activity since it was created: bytes parsed, cells written, lines
+	// scrolled, sequences handled and the peak output rate.
+	Stats() Stats
+}
+
+// MemoryManager accounts for the terminal's memory and caps it.
+type MemoryManager interface {
+	// MemoryUsage returns an estimate of the memory the terminal holds in its screens, scrollback and images.
+	MemoryUsage() MemoryUsage
+
+	// Trim drops the oldest scrollback lines and images until MemoryUsage is at most maxBytes, and returns it.
+	Trim(maxBytes int64) MemoryUsage
+}
+
+// TerminalOption configures a terminal created with New or NewWithCommand. Every setting is an option, so new ones can
+// be added without changing the constructors.
 type TerminalOption func(*TerminalInfo)
 
+// Option is TerminalOption, under the shorter name.
+type Option = TerminalOption
+
+// TerminalInfo holds the settings options build up. Its fields are unexported; use the With functions.
 type TerminalInfo struct {
 	w               io.Writer
 	cols, rows      int
 	scrollbackLimit int
+	palette         *Palette
+	cellW, cellH    int
+
+	onOversizedImage func(name string, size int)
+	limits           Limits
+	onTruncate       func(Limit)
+	onResize         func(cols, rows int)
+	onWindowOp       func(WindowRequest)
+	windowReports    *uint32
+	onBell           func(Bell)
+	bellInterval     time.Duration
+	visualBell       bool
+	onNotify         func(Notification)
+	onDirectory      func(WorkingDirectory)
+	onVariable       func(name, value string)
+	logger           Logger
+	lineClock        func() time.Time
+	tmuxPassthrough  bool
+	c1Controls       bool
+	translateLF      bool
+	columnSwitching  bool
+	onVector         func(VectorGraphics)
+	idleAfter        time.Duration
+	onIdle           func(TerminalState)
+	cursorHistory    int
+	inputEpochs      bool
+	redaction        *Redaction
+	promptDetector   PromptDetector
+	encoding         *Encoding
+	answerback       string
+	terminalID       *TerminalID
+	profile          *Profile
+	state            *TerminalState
 }
 
+// WithWriter sets the writer the terminal sends its replies to, such as cursor position and device attribute
+// reports, which are meant for the program running in it. The default discards them.
 func WithWriter(w io.Writer) TerminalOption {
 	return func(info *TerminalInfo) {
 		if w == nil {
@@ -85,6 +242,12 @@ func WithWriter(w io.Writer) TerminalOption {
 	}
 }
 
+// WithResponder is WithWriter, under a name that says what the writer is for.
+func WithResponder(w io.Writer) TerminalOption {
+	return WithWriter(w)
+}
+
+// WithSize sets the size of the terminal in cells. The default is 80x24.
 func WithSize(cols, rows int) TerminalOption {
 	return func(info *TerminalInfo) {
 		info.cols = cols
@@ -104,10 +267,124 @@ func WithScrollbackCapture(limit int) TerminalOption {
 	}
 }
 
+// WithScrollback is WithScrollbackCapture, under a shorter name.
+func WithScrollback(limit int) TerminalOption {
+	return WithScrollbackCapture(limit)
+}
+
+// WithPalette sets the palette the terminal starts with and that OSC color resets restore, in place of
+// DefaultPalette.
+func WithPalette(p Palette) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.palette = &p
+	}
+}
+
+// WithCellSize sets the size in pixels of a cell, used to work out how many cells an image covers. It should match
+// the size the terminal reports to applications; the default is 10x20. Non-positive sizes are ignored.
+func WithCellSize(width, height int) TerminalOption {
+	return func(info *TerminalInfo) {
+		if width <= 0 || height <= 0 {
+			return
+		}
+		info.cellW, info.cellH = width, height
+	}
+}
+
+// WithOversizedImageHandler sets a function called when an iTerm2 inline image (OSC 1337 File=) is dropped because its
+// payload is too large to keep, with the file name and size the sequence declared, or the size received if it
+// declared none. Recorders can use it to note the image instead of silently losing it. It is called while the
+// terminal is locked, so it must not call back into the terminal.
+func WithOversizedImageHandler(fn func(name string, size int)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onOversizedImage = fn
+	}
+}
+
+// WithLimits caps the memory the terminal spends on what programs send it; see Limits.
+func WithLimits(l Limits) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.limits = l
+	}
+}
+
+// WithTruncationHandler sets a function called when input is cut short or discarded to stay within the terminal's
+// Limits, so services can tell a misbehaving program from a quiet one. A string is reported once, when it first
+// reaches the limit. It is called while the terminal is locked, so it must not call back into the terminal.
+func WithTruncationHandler(fn func(Limit)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onTruncate = fn
+	}
+}
+
+// WithAnswerback sets the answerback message the terminal sends through the writer set with WithWriter when it
+// receives ENQ (0x05). The default is empty, which sends nothing, as in xterm.
+func WithAnswerback(s string) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.answerback = s
+	}
+}
+
+// WithTmuxPassthrough makes the terminal unwrap tmux passthrough sequences (DCS tmux; ... ST, with every ESC inside
+// doubled) and parse the sequence inside, as the terminal outside tmux would. Without it a passthrough is parsed as
+// an unknown DCS that the doubled ESCs cut short, so sessions recorded inside tmux lose the wrapped sequences.
+func WithTmuxPassthrough() TerminalOption {
+	return func(info *TerminalInfo) {
+		info.tmuxPassthrough = true
+	}
+}
+
+// WithVectorGraphicsHandler sets a function called when the terminal has skipped ReGIS or Tektronix vector graphics,
+// which it does not draw, once they end. It is called while the terminal is locked, so it must not call back into the
+// terminal.
+func WithVectorGraphicsHandler(fn func(VectorGraphics)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onVector = fn
+	}
+}
+
+// WithC1Controls makes the terminal recognize the 8-bit C1 controls 0x80-0x9F, such as IND (0x84), NEL (0x85), DCS
+// (0x90), CSI (0x9B), ST (0x9C) and OSC (0x9D), as the 7-bit ESC sequences they stand for. Legacy streams send them
+// either as raw bytes or as UTF-8 encoded runes; both are recognized, though a raw byte that completes a UTF-8 sequence
+// is read as UTF-8. Without it, C1 runes are printed and raw C1 bytes are dropped as invalid UTF-8.
+func WithC1Controls() TerminalOption {
+	return func(info *TerminalInfo) {
+		info.c1Controls = true
+	}
+}
+
+// WithLineFeedTranslation makes line feeds, and the VT and FF controls treated like them, also return the cursor to
+// the first column, as a tty's onlcr flag does for programs' output. It is for output captured without a tty, such as
+// logs, which otherwise renders stair-stepped. Without it, only newline mode (LNM, set with CSI 20 h) does that.
+func WithLineFeedTranslation() TerminalOption {
+	return func(info *TerminalInfo) {
+		info.translateLF = true
+	}
+}
+
+// WithInputEncoding makes the terminal decode its input with e instead of as UTF-8, for streams from programs that
+// predate it, such as Latin-1 text or CP437 recordings of DOS-era BBSes. Every byte is then one rune, so none is
+// dropped as invalid. A nil e restores UTF-8.
+func WithInputEncoding(e *Encoding) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.encoding = e
+	}
+}
+
+// WithState makes the terminal start from s, as returned by DumpState or DecodeSnapshot, instead of a blank screen, so a
+// viewer can resume a session from a checkpoint. It sets the size to that of s. The cursor attributes and any
+// sequence in progress are not part of s, so they start reset.
+func WithState(s TerminalState) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.cols, info.rows = s.Cols, s.Rows
+		info.state = &s
+	}
+}
+
 // New returns a new virtual terminal emulator.
 func New(opts ...TerminalOption) Terminal {
 	info := TerminalInfo{
-		w:    ioutil.Discard,
+		w:    io.Discard,
 		cols: 80,
 		rows: 24,
 	}
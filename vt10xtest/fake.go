@@ -0,0 +1,613 @@
+// Package vt10xtest provides a fake vt10x.Terminal for testing code that consumes terminal output, such as renderers
+// and recorders, without driving real escape sequences through the emulator.
+package vt10xtest
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hinshun/vt10x"
+)
+
+// Fake is a scriptable vt10x.Terminal. It plays back a fixed sequence of states, advancing to the next one on every
+// successful Write, and records everything written to it. Failures are injected with FailWrites and FailParse.
+//
+// Like the real emulator, Lock and Unlock only guard the view for the caller; Fake's own bookkeeping is synchronized
+// separately, so every method is safe for concurrent use.
+type Fake struct {
+	viewMu sync.Mutex
+
+	mu         sync.Mutex
+	states     []vt10x.TerminalState
+	cur        int
+	writes     [][]byte
+	resizes    [][2]int
+	scrollback [][]rune
+	dropped    int
+	commands   []vt10x.Command
+	stats      vt10x.Stats
+	memory     vt10x.MemoryUsage
+	epoch      uint64
+	sent       [][]byte
+	subs       []chan vt10x.Update
+
+	writeErr   error
+	writeAfter int
+	shortWrite bool
+	parseErr   error
+}
+
+var (
+	_ vt10x.Terminal   = (*Fake)(nil)
+	_ vt10x.MetaDumper = (*Fake)(nil)
+	_ vt10x.Hasher     = (*Fake)(nil)
+
+	_ interface {
+		io.ReaderFrom
+		vt10x.ScrollbackLineTaker
+		vt10x.CursorRecorder
+		vt10x.PromptReporter
+		vt10x.LinkFinder
+		vt10x.Watcher
+		vt10x.InputSender
+		vt10x.EpochAdvancer
+		vt10x.Searcher
+		vt10x.LineJoiner
+		vt10x.CommandTracker
+		vt10x.Subscriber
+		vt10x.StatsReporter
+		vt10x.MemoryManager
+	} = (*Fake)(nil)
+)
+
+// New returns a Fake that starts in the first of states and advances through them on each successful Write, staying
+// on the last one. With no states it presents an empty 80x24 screen.
+func New(states ...vt10x.TerminalState) *Fake {
+	if len(states) == 0 {
+		states = []vt10x.TerminalState{StateFromText(80, 24)}
+	}
+	return &Fake{states: states}
+}
+
+// StateFromText builds a cols x rows state whose primary screen shows lines, one per row, in default colors. Lines
+// longer than cols are truncated and rows without a line are blank.
+func StateFromText(cols, rows int, lines ...string) vt10x.TerminalState {
+	blank := vt10x.Glyph{Char: ' ', FG: vt10x.DefaultFG, BG: vt10x.DefaultBG, UnderlineColor: vt10x.DefaultUnderline}
+
+	newBuffer := func(text []string) [][]vt10x.Glyph {
+		buf := make([][]vt10x.Glyph, rows)
+		for y := range buf {
+			buf[y] = make([]vt10x.Glyph, cols)
+			for x := range buf[y] {
+				buf[y][x] = blank
+			}
+			if y >= len(text) {
+				continue
+			}
+			x := 0
+			for _, r := range text[y] {
+				if x >= cols {
+					break
+				}
+				buf[y][x].Char = r
+				x++
+			}
+		}
+		return buf
+	}
+
+	return vt10x.TerminalState{
+		Cols:            cols,
+		Rows:            rows,
+		CursorVisible:   true,
+		ScrollBottom:    rows - 1,
+		Wrap:            true,
+		AutoWrap:        true,
+		Mode:            vt10x.ModeWrap,
+		PrimaryBuffer:   newBuffer(lines),
+		AlternateBuffer: newBuffer(nil),
+	}
+}
+
+// FailWrites makes Write and WriteWithChanges return err once after successful writes have been accepted; every
+// later call fails too. If short is set, failing writes report consuming half of their input rather than none, to
+// exercise callers' handling of partial writes. A nil err clears the injection.
+func (f *Fake) FailWrites(err error, after int, short bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeErr = err
+	f.writeAfter = after
+	f.shortWrite = short
+}
+
+// FailParse makes Parse return err without reading. A nil err clears the injection.
+func (f *Fake) FailParse(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.parseErr = err
+}
+
+// SetScrollback sets what the next TakeScrollback returns.
+func (f *Fake) SetScrollback(lines []string, dropped int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.scrollback = nil
+	for _, l := range lines {
+		f.scrollback = append(f.scrollback, []rune(l))
+	}
+	f.dropped = dropped
+}
+
+// SetCommands sets the shell commands Commands returns.
+func (f *Fake) SetCommands(commands []vt10x.Command) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.commands = append([]vt10x.Command(nil), commands...)
+}
+
+// SetStats sets the counts Stats returns.
+func (f *Fake) SetStats(s vt10x.Stats) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stats = s
+}
+
+// SetMemoryUsage sets the estimate MemoryUsage and Trim return.
+func (f *Fake) SetMemoryUsage(m vt10x.MemoryUsage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.memory = m
+}
+
+// Writes returns a copy of every chunk accepted by Write, WriteWithChanges, Parse, and ReadFrom, in order.
+func (f *Fake) Writes() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	writes := make([][]byte, len(f.writes))
+	for i, w := range f.writes {
+		writes[i] = append([]byte(nil), w...)
+	}
+	return writes
+}
+
+// Sent returns a copy of everything sent with SendText and SendKey, encoded for the state current when it was sent,
+// in order.
+func (f *Fake) Sent() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sent := make([][]byte, len(f.sent))
+	for i, b := range f.sent {
+		sent[i] = append([]byte(nil), b...)
+	}
+	return sent
+}
+
+// Resizes returns every size passed to Resize, in order, as {cols, rows} pairs.
+func (f *Fake) Resizes() [][2]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([][2]int(nil), f.resizes...)
+}
+
+func (f *Fake) state() *vt10x.TerminalState {
+	return &f.states[f.cur]
+}
+
+// write records p and advances the script, or fails as configured by FailWrites.
+func (f *Fake) write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.writeErr != nil {
+		if f.writeAfter <= 0 {
+			n := 0
+			if f.shortWrite {
+				n = len(p) / 2
+			}
+			return n, f.writeErr
+		}
+		f.writeAfter--
+	}
+
+	f.writes = append(f.writes, append([]byte(nil), p...))
+	if f.cur < len(f.states)-1 {
+		f.cur++
+	}
+	f.publish()
+	return len(p), nil
+}
+
+// publish sends subscribers an update reporting every row of the current state as changed, replacing any update they
+// have not received yet, which it covers. f.mu must be held.
+func (f *Fake) publish() {
+	u := vt10x.Update{Rows: make([]int, f.state().Rows), Cursor: true, Title: true}
+	for i := range u.Rows {
+		u.Rows[i] = i
+	}
+	for _, ch := range f.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- u
+	}
+}
+
+// Write records p and advances to the next scripted state.
+func (f *Fake) Write(p []byte) (int, error) {
+	return f.write(p)
+}
+
+// WriteWithChanges records p, advances to the next scripted state, and reports every row of it as changed.
+func (f *Fake) WriteWithChanges(p []byte) ([]int, error) {
+	if _, err := f.write(p); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	changed := make([]int, f.state().Rows)
+	for i := range changed {
+		changed[i] = i
+	}
+	return changed, nil
+}
+
+// Parse reads one buffered chunk from br and writes it, like the real emulator does.
+func (f *Fake) Parse(br *bufio.Reader) error {
+	f.mu.Lock()
+	err := f.parseErr
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, err := br.Peek(1); err != nil {
+		return err
+	}
+	p := make([]byte, br.Buffered())
+	if _, err := br.Read(p); err != nil {
+		return err
+	}
+	_, err = f.write(p)
+	return err
+}
+
+// ReadFrom reads r until EOF and writes each chunk it reads, like the real emulator does.
+func (f *Fake) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, 32<<10)
+	var n int64
+	for {
+		m, err := r.Read(buf)
+		if m > 0 {
+			w, werr := f.write(buf[:m])
+			n += int64(w)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// Subscribe returns a channel that receives an update after each successful write, reporting every row as changed
+// like WriteWithChanges.
+func (f *Fake) Subscribe() (updates <-chan vt10x.Update, cancel func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan vt10x.Update, 1)
+	f.subs = append(f.subs, ch)
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		for i, sub := range f.subs {
+			if sub == ch {
+				f.subs = append(f.subs[:i], f.subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}
+}
+
+// TakeScrollback returns and clears what was set with SetScrollback.
+func (f *Fake) TakeScrollback() (lines [][]rune, dropped int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lines, dropped = f.scrollback, f.dropped
+	f.scrollback, f.dropped = nil, 0
+	return lines, dropped
+}
+
+// TakeScrollbackLines is TakeScrollback for lines without timestamps.
+func (f *Fake) TakeScrollbackLines() (lines []vt10x.ScrollbackLine, dropped int) {
+	text, dropped := f.TakeScrollback()
+	for _, l := range text {
+		lines = append(lines, vt10x.ScrollbackLine{Text: l})
+	}
+	return lines, dropped
+}
+
+// LogicalLines returns the lines of the current primary buffer, with the rows that wrapped joined. The scrollback is
+// not included, whatever scrollback is.
+func (f *Fake) LogicalLines(scrollback bool) []vt10x.LogicalLine {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return vt10x.LogicalLines(f.state().PrimaryBuffer)
+}
+
+// Commands returns a copy of what was set with SetCommands.
+func (f *Fake) Commands() []vt10x.Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]vt10x.Command(nil), f.commands...)
+}
+
+// Stats returns what was set with SetStats.
+func (f *Fake) Stats() vt10x.Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.stats
+}
+
+// MemoryUsage returns what was set with SetMemoryUsage.
+func (f *Fake) MemoryUsage() vt10x.MemoryUsage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.memory
+}
+
+// Trim returns what was set with SetMemoryUsage; the fake has nothing to drop.
+func (f *Fake) Trim(maxBytes int64) vt10x.MemoryUsage {
+	return f.MemoryUsage()
+}
+
+// TakeCursorHistory returns nothing; the fake does not track the cursor's moves.
+func (f *Fake) TakeCursorHistory() (moves []vt10x.CursorMove, dropped int) {
+	return nil, 0
+}
+
+// AdvanceInputEpoch counts the epochs started; the fake tags no cells with them.
+func (f *Fake) AdvanceInputEpoch() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.epoch++
+	return f.epoch
+}
+
+// AtPrompt applies DefaultPromptDetector to the current state.
+func (f *Fake) AtPrompt() (vt10x.Prompt, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return vt10x.DefaultPromptDetector(f.state().View())
+}
+
+// Links returns the URLs in the current primary buffer.
+func (f *Fake) Links() []vt10x.Link {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return vt10x.FindLinks(f.state().PrimaryBuffer)
+}
+
+// Watch checks pattern and returns; the fake reports no matches.
+func (f *Fake) Watch(pattern string, opts vt10x.FindOptions, fn func(vt10x.WatchEvent)) (cancel func(), err error) {
+	if _, err := vt10x.Search(nil, pattern, opts); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+// SendText records text as EncodeText encodes it for the current state; see Sent.
+func (f *Fake) SendText(text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, vt10x.EncodeText(text, *f.state()))
+	return nil
+}
+
+// SendKey records key as EncodeKey encodes it for the current state; see Sent.
+func (f *Fake) SendKey(key vt10x.Key, mods vt10x.KeyMod) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sent = append(f.sent, vt10x.EncodeKey(key, mods, *f.state()))
+	return nil
+}
+
+// Find searches the current primary buffer, and with opts.Scrollback what was set with SetScrollback and not yet
+// taken.
+func (f *Fake) Find(pattern string, opts vt10x.FindOptions) ([]vt10x.Match, error) {
+	f.mu.Lock()
+	rows := f.state().PrimaryBuffer
+	var scrollback [][]vt10x.Glyph
+	if opts.Scrollback {
+		for _, l := range f.scrollback {
+			row := make([]vt10x.Glyph, len(l))
+			for x, c := range l {
+				row[x].Char = c
+			}
+			scrollback = append(scrollback, row)
+		}
+	}
+	f.mu.Unlock()
+
+	matches, err := vt10x.Search(append(scrollback, rows...), pattern, opts)
+	for i := range matches {
+		matches[i].Y -= len(scrollback)
+	}
+	return matches, err
+}
+
+// LineHash returns the content hash of row y of the current primary buffer.
+func (f *Fake) LineHash(y int) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rows := f.state().PrimaryBuffer
+	if y < 0 || y >= len(rows) {
+		return 0
+	}
+	return vt10x.HashLine(rows[y])
+}
+
+// ScreenHash returns the content hash of the current primary buffer.
+func (f *Fake) ScreenHash() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return vt10x.ScreenHash(f.state().PrimaryBuffer)
+}
+
+// String returns the text of the current primary buffer, one line per row.
+func (f *Fake) String() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var sb strings.Builder
+	for _, row := range f.state().PrimaryBuffer {
+		for _, g := range row {
+			sb.WriteRune(g.Char)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Size returns the size of the current state.
+func (f *Fake) Size() (cols, rows int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.state().Cols, f.state().Rows
+}
+
+// Resize records the request and applies the size to the current state; the buffers are left as scripted.
+func (f *Fake) Resize(cols, rows int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.resizes = append(f.resizes, [2]int{cols, rows})
+	f.state().Cols, f.state().Rows = cols, rows
+}
+
+// Mode returns the mode of the current state.
+func (f *Fake) Mode() vt10x.ModeFlag {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.state().Mode
+}
+
+// Title returns the title of the current state.
+func (f *Fake) Title() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.state().Title
+}
+
+// Cell returns the glyph at (x, y) of the current primary buffer, or a zero Glyph when out of range.
+func (f *Fake) Cell(x, y int) vt10x.Glyph {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	buf := f.state().PrimaryBuffer
+	if y < 0 || y >= len(buf) || x < 0 || x >= len(buf[y]) {
+		return vt10x.Glyph{}
+	}
+	return buf[y][x]
+}
+
+// Cursor returns the cursor position of the current state.
+func (f *Fake) Cursor() vt10x.Cursor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return vt10x.Cursor{X: f.state().CursorX, Y: f.state().CursorY}
+}
+
+// CursorVisible returns the cursor visibility of the current state.
+func (f *Fake) CursorVisible() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.state().CursorVisible
+}
+
+// Lock locks the view for the caller.
+func (f *Fake) Lock() {
+	f.viewMu.Lock()
+}
+
+// Unlock unlocks the view.
+func (f *Fake) Unlock() {
+	f.viewMu.Unlock()
+}
+
+// DumpState returns a copy of the current state.
+func (f *Fake) DumpState() vt10x.TerminalState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state := *f.state()
+	state.PrimaryBuffer = copyBuffer(state.PrimaryBuffer)
+	state.AlternateBuffer = copyBuffer(state.AlternateBuffer)
+	state.TabStops = append([]int(nil), state.TabStops...)
+	state.TitleStack = append([]string(nil), state.TitleStack...)
+	state.Palette = append([]vt10x.Color(nil), state.Palette...)
+	state.Images = append([]vt10x.ImagePlacement(nil), state.Images...)
+	if state.Modes != nil {
+		modes := make(map[int]bool, len(state.Modes))
+		for a, set := range state.Modes {
+			modes[a] = set
+		}
+		state.Modes = modes
+	}
+	return state
+}
+
+// DumpMeta returns a copy of the current state without its buffers.
+func (f *Fake) DumpMeta() vt10x.TerminalState {
+	state := f.DumpState()
+	state.PrimaryBuffer, state.AlternateBuffer = nil, nil
+	return state
+}
+
+func copyBuffer(src [][]vt10x.Glyph) [][]vt10x.Glyph {
+	if src == nil {
+		return nil
+	}
+	buf := make([][]vt10x.Glyph, len(src))
+	for y := range src {
+		buf[y] = append([]vt10x.Glyph(nil), src[y]...)
+	}
+	return buf
+}
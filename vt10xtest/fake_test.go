@@ -0,0 +1,162 @@
+package vt10xtest
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hinshun/vt10x"
+)
+
+func TestFakePlaysBackStates(t *testing.T) {
+	f := New(
+		StateFromText(10, 2, "first"),
+		StateFromText(10, 2, "second", "line"),
+	)
+
+	if got := f.Cell(0, 0).Char; got != 'f' {
+		t.Fatalf("expected the first state, got %q", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.Write([]byte("x")); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+
+	if got, want := f.String(), "second    \nline      \n"; got != want {
+		t.Fatalf("expected to stay on the last state %q, got %q", want, got)
+	}
+	if n := len(f.Writes()); n != 3 {
+		t.Fatalf("expected 3 recorded writes, got %d", n)
+	}
+}
+
+func TestFakeFailWrites(t *testing.T) {
+	errBoom := errors.New("boom")
+	f := New()
+	f.FailWrites(errBoom, 1, true)
+
+	if _, err := f.Write([]byte("ok")); err != nil {
+		t.Fatalf("expected the first write to succeed, got %v", err)
+	}
+
+	n, err := f.Write([]byte("abcd"))
+	if !errors.Is(err, errBoom) || n != 2 {
+		t.Fatalf("expected a short write of 2 bytes with the injected error, got %d, %v", n, err)
+	}
+	if _, err := f.WriteWithChanges([]byte("x")); !errors.Is(err, errBoom) {
+		t.Fatalf("expected later writes to keep failing, got %v", err)
+	}
+
+	f.FailWrites(nil, 0, false)
+	changed, err := f.WriteWithChanges([]byte("x"))
+	if err != nil || len(changed) != 24 {
+		t.Fatalf("expected a cleared injection to accept writes reporting 24 rows, got %v, %v", changed, err)
+	}
+	if n := len(f.Writes()); n != 2 {
+		t.Fatalf("expected only accepted writes to be recorded, got %d", n)
+	}
+}
+
+func TestFakeParse(t *testing.T) {
+	f := New()
+	br := bufio.NewReader(strings.NewReader("hello"))
+
+	if err := f.Parse(br); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if w := f.Writes(); len(w) != 1 || string(w[0]) != "hello" {
+		t.Fatalf("expected Parse to write the buffered chunk, got %q", w)
+	}
+
+	errBoom := errors.New("boom")
+	f.FailParse(errBoom)
+	if err := f.Parse(br); !errors.Is(err, errBoom) {
+		t.Fatalf("expected the injected parse error, got %v", err)
+	}
+}
+
+func TestFakeReadFrom(t *testing.T) {
+	f := New()
+
+	n, err := f.ReadFrom(iotest.OneByteReader(strings.NewReader("hello")))
+	if err != nil || n != 5 {
+		t.Fatalf("expected 5 bytes read without error, got %d, %v", n, err)
+	}
+	if w := f.Writes(); len(w) != 5 || string(w[0]) != "h" || string(w[4]) != "o" {
+		t.Fatalf("expected ReadFrom to write each chunk read, got %q", w)
+	}
+}
+
+func TestFakeDumpStateIsolated(t *testing.T) {
+	f := New(StateFromText(4, 1, "abcd"))
+
+	state := f.DumpState()
+	state.PrimaryBuffer[0][0].Char = 'z'
+	if got := f.Cell(0, 0).Char; got != 'a' {
+		t.Fatalf("expected DumpState to return a copy, but the fake now shows %q", got)
+	}
+
+	if meta := f.DumpMeta(); meta.PrimaryBuffer != nil || meta.Cols != 4 {
+		t.Fatalf("unexpected DumpMeta: %+v", meta)
+	}
+}
+
+func TestFakeResizeAndScrollback(t *testing.T) {
+	f := New()
+	f.Resize(100, 30)
+	if cols, rows := f.Size(); cols != 100 || rows != 30 {
+		t.Fatalf("expected 100x30 after Resize, got %dx%d", cols, rows)
+	}
+	if r := f.Resizes(); len(r) != 1 || r[0] != [2]int{100, 30} {
+		t.Fatalf("expected the resize to be recorded, got %v", r)
+	}
+
+	f.SetScrollback([]string{"gone"}, 3)
+	lines, dropped := f.TakeScrollback()
+	if len(lines) != 1 || string(lines[0]) != "gone" || dropped != 3 {
+		t.Fatalf("unexpected scrollback %q, %d", lines, dropped)
+	}
+	if lines, dropped := f.TakeScrollback(); lines != nil || dropped != 0 {
+		t.Fatalf("expected TakeScrollback to drain, got %q, %d", lines, dropped)
+	}
+}
+
+func TestFakeFind(t *testing.T) {
+	f := New(StateFromText(10, 2, "one", "two one"))
+	f.SetScrollback([]string{"one lost"}, 0)
+
+	got, err := f.Find("one", vt10x.FindOptions{Scrollback: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []vt10x.Match{{Y: -1, X: 0, EndX: 3}, {Y: 0, X: 0, EndX: 3}, {Y: 1, X: 4, EndX: 7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestFakeSubscribe(t *testing.T) {
+	f := New(StateFromText(10, 2, "one"), StateFromText(10, 2, "two"))
+	updates, cancel := f.Subscribe()
+
+	f.Write([]byte("x"))
+	f.Write([]byte("y"))
+	if u := <-updates; !reflect.DeepEqual(u.Rows, []int{0, 1}) {
+		t.Fatalf("expected every row reported, got %v", u.Rows)
+	}
+	select {
+	case u := <-updates:
+		t.Fatalf("expected the two writes to coalesce, got a second update %+v", u)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-updates; ok {
+		t.Fatal("expected cancel to close the channel")
+	}
+}
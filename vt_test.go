@@ -15,6 +15,15 @@ func extractStr(term Terminal, x0, x1, row int) string {
 	return string(s)
 }
 
+// writeSeq writes seq to term, failing the test on error.
+func writeSeq(t *testing.T, term Terminal, seq string) {
+	t.Helper()
+
+	if _, err := term.Write([]byte(seq)); err != nil {
+		t.Fatalf("Write(%q) returned error: %v", seq, err)
+	}
+}
+
 func TestPlainChars(t *testing.T) {
 	term := New()
 	expected := "Hello world!"
@@ -167,7 +176,7 @@ func FuzzTerminal(f *testing.F) {
 		"\033[9999d",
 
 		// write-back paths: OSC color queries and DSR/CPR
-		// FuzzTerminal uses New() with ioutil.Discard so no nil-writer panic here;
+		// FuzzTerminal uses New() with io.Discard so no nil-writer panic here;
 		// seeds exercise the OSC/DSR/CPR code paths for general coverage
 		"\033]10;?\007",          // OSC fg color query
 		"\033]11;?\007",          // OSC bg color query
@@ -0,0 +1,138 @@
+package vt10x
+
+import (
+	"regexp"
+	"slices"
+)
+
+// WatchEvent reports a match of a watched pattern that has appeared on the screen.
+type WatchEvent struct {
+	// Match is where on the screen the text is.
+	Match
+
+	// Text is the text matched.
+	Text string
+}
+
+// watchRule is a pattern registered with Watch.
+type watchRule struct {
+	re *regexp.Regexp
+	fn func(WatchEvent)
+}
+
+// watchSeen is a match of a rule last seen on a row, kept so that it is reported only when it first appears.
+type watchSeen struct {
+	rule    *watchRule
+	x, endX int
+	text    string
+}
+
+// Watch calls fn with each match of pattern, as Find takes it, that appears on the screen from now on, so live
+// sessions can raise alerts on output such as "Permission denied" or "panic:". Only the rows that changed are
+// searched, after each write or Unlock. A match is reported once when it appears; it is reported again only if its
+// row is rewritten without it first, or if it scrolls onto a new line. Text already on the screen when Watch is called
+// is not reported. fn is called while the terminal is locked, so it must not call back into the terminal. Call cancel
+// to stop watching.
+func (t *State) Watch(pattern string, opts FindOptions, fn func(WatchEvent)) (cancel func(), err error) {
+	re, err := compileFind(pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := &watchRule{re: re, fn: fn}
+	t.watches = append(t.watches, r)
+	if t.watchDirty == nil {
+		t.watchDirty = make([]bool, t.rows)
+	}
+	// Record what is already on the screen as seen, for both screens, with changes not yet searched searched first.
+	t.runWatches()
+	for _, screen := range []struct {
+		lines []line
+		meta  []rowMeta
+	}{{t.lines, t.meta}, {t.altLines, t.altMeta}} {
+		for y := range screen.meta {
+			if y < len(screen.lines) {
+				t.watchRow(screen.lines[y], &screen.meta[y], y, []*watchRule{r}, false, nil)
+			}
+		}
+	}
+	return func() { t.unwatch(r) }, nil
+}
+
+func (t *State) unwatch(r *watchRule) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, w := range t.watches {
+		if w == r {
+			t.watches = append(t.watches[:i], t.watches[i+1:]...)
+			break
+		}
+	}
+	if len(t.watches) == 0 {
+		t.watchDirty = nil
+	}
+}
+
+// runWatches searches the rows changed since it last ran for the watched patterns and reports new matches. The
+// terminal must be locked.
+func (t *State) runWatches() {
+	if len(t.watches) == 0 {
+		return
+	}
+	var events []func()
+	for y, dirty := range t.watchDirty {
+		if !dirty || y >= len(t.lines) || y >= len(t.meta) {
+			continue
+		}
+		t.watchDirty[y] = false
+		events = t.watchRow(t.lines[y], &t.meta[y], y, t.watches, true, events)
+	}
+	for _, report := range events {
+		report()
+	}
+}
+
+// watchRow searches row y, whose bookkeeping is m, for the patterns of rules, replacing what m records as seen for
+// them, and returns events with the reports of new matches added if report is set.
+func (t *State) watchRow(l line, m *rowMeta, y int, rules []*watchRule, report bool, events []func()) []func() {
+	var old []watchSeen
+	if m.watchID == m.id {
+		old = m.watchSeen
+	}
+	// Keep what was seen for rules not searched now, dropping any that were cancelled.
+	seen := make([]watchSeen, 0, len(old))
+	for _, s := range old {
+		if !slices.Contains(rules, s.rule) && slices.Contains(t.watches, s.rule) {
+			seen = append(seen, s)
+		}
+	}
+
+	var s searcher
+	for _, c := range l {
+		s.addCell(c)
+	}
+	for _, r := range rules {
+		s.matches = s.matches[:0]
+		s.find(r.re, y)
+		for _, match := range s.matches {
+			text := make([]rune, 0, match.EndX-match.X)
+			for _, c := range l[match.X:match.EndX] {
+				if !isWideSpacer(c) {
+					text = append(text, c.char)
+				}
+			}
+			hit := watchSeen{rule: r, x: match.X, endX: match.EndX, text: string(text)}
+			seen = append(seen, hit)
+			if report && !slices.Contains(old, hit) {
+				fn, e := r.fn, WatchEvent{Match: match, Text: hit.text}
+				events = append(events, func() { fn(e) })
+			}
+		}
+	}
+	m.watchID, m.watchSeen = m.id, seen
+	return events
+}
@@ -0,0 +1,80 @@
+package vt10x
+
+import (
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	term := New(WithSize(30, 3))
+	var events []WatchEvent
+	cancel, err := term.(Watcher).Watch("panic:", FindOptions{}, func(e WatchEvent) { events = append(events, e) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeSeq(t, term, "ok\r\n")
+	if len(events) != 0 {
+		t.Fatalf("expected no events, got %+v", events)
+	}
+	writeSeq(t, term, "x panic: boom")
+	want := WatchEvent{Match: Match{Y: 1, X: 2, EndX: 8}, Text: "panic:"}
+	if len(events) != 1 || events[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, events)
+	}
+
+	// Changes elsewhere on the row, and scrolling, do not report it again.
+	writeSeq(t, term, "!\r\n\r\n")
+	if len(events) != 1 {
+		t.Fatalf("expected the match reported once, got %+v", events)
+	}
+
+	// The same text on a new line is a new match.
+	writeSeq(t, term, "panic: again")
+	if len(events) != 2 || events[1].Y != 2 || events[1].X != 0 {
+		t.Fatalf("expected a second event on row 2, got %+v", events)
+	}
+
+	cancel()
+	writeSeq(t, term, "\r\npanic:")
+	if len(events) != 2 {
+		t.Errorf("expected no events after cancel, got %+v", events)
+	}
+}
+
+func TestWatchRegexpAndExistingText(t *testing.T) {
+	term := New(WithSize(40, 3))
+	writeSeq(t, term, "Permission denied\r\n")
+	var events []WatchEvent
+	_, err := term.(Watcher).Watch(`permission denied|error \d+`, FindOptions{Regexp: true, IgnoreCase: true}, func(e WatchEvent) {
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Text already on the screen is not reported, even when its row changes.
+	writeSeq(t, term, "\033[1;30Hx\033[2;1H")
+	if len(events) != 0 {
+		t.Fatalf("expected no events for existing text, got %+v", events)
+	}
+	writeSeq(t, term, "ERROR 42 and permission DENIED")
+	if len(events) != 2 || events[0].Text != "ERROR 42" || events[1].Text != "permission DENIED" {
+		t.Fatalf("expected both matches, got %+v", events)
+	}
+
+	if _, err := term.(Watcher).Watch("(", FindOptions{Regexp: true}, func(WatchEvent) {}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestWatchResize(t *testing.T) {
+	term := New()
+	var events []WatchEvent
+	term.(Watcher).Watch("hi", FindOptions{}, func(e WatchEvent) { events = append(events, e) })
+	writeSeq(t, term, "hi")
+	term.Resize(40, 10)
+	writeSeq(t, term, " there")
+	if len(events) != 1 {
+		t.Errorf("expected a resize not to report the match again, got %+v", events)
+	}
+}
@@ -0,0 +1,66 @@
+package vt10x
+
+import "golang.org/x/text/width"
+
+// runeWidth returns the number of columns c takes: 2 for the wide and fullwidth characters of East Asian scripts and
+// emoji, and 1 for everything else, including combining marks, which the emulator does not combine.
+func runeWidth(c rune) int {
+	if c < 0x1100 {
+		return 1
+	}
+	switch width.LookupRune(c).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// isWideLead reports whether c holds a wide character, whose second half is the next cell.
+func isWideLead(c cell) bool {
+	return c.attrs&attrWide != 0 && c.char != 0
+}
+
+// isWideSpacer reports whether c is the second half of a wide character.
+func isWideSpacer(c cell) bool {
+	return c.attrs&attrWide != 0 && c.char == 0
+}
+
+// isSpacerGlyph reports whether g is the second half of a wide character.
+func isSpacerGlyph(g Glyph) bool {
+	return g.Mode&attrWide != 0 && g.Char == 0
+}
+
+// splitWide blanks the wide characters of row y that straddle either end of the cells x0 through x1, which are about
+// to be overwritten, erased or shifted apart, so that no character is left with only one of its two cells. Like
+// xterm, it blanks both halves, keeping their attributes.
+func (t *State) splitWide(y, x0, x1 int) {
+	if y < 0 || y >= len(t.lines) {
+		return
+	}
+	l := t.lines[y]
+	if x0 > 0 && x0 < len(l) && isWideSpacer(l[x0]) {
+		t.blankWide(y, x0-1)
+	}
+	if x1 >= 0 && x1+1 < len(l) && isWideLead(l[x1]) {
+		t.blankWide(y, x1)
+	}
+}
+
+// blankWide blanks the wide character whose first cell is x of row y.
+func (t *State) blankWide(y, x int) {
+	l := t.writableLine(y)
+	for i := x; i < x+2 && i < len(l); i++ {
+		l[i].char = ' '
+		l[i].attrs &^= attrWide
+	}
+	t.markDirty(y)
+	t.tagEpochs(y, x, min(x+1, len(l)-1))
+}
+
+// trimWide blanks a wide character cut in half by the right edge of l, as narrowing the terminal leaves one.
+func trimWide(l line) {
+	if n := len(l); n > 0 && isWideLead(l[n-1]) {
+		l[n-1].char = ' '
+		l[n-1].attrs &^= attrWide
+	}
+}
@@ -0,0 +1,178 @@
+package vt10x
+
+import "fmt"
+
+// WithResizeHandler sets a function called with the new size each time Resize changes the size of the terminal, so
+// embedders can pass the resize on to the application, as a pty does with SIGWINCH. It is called after the terminal
+// is unlocked, from the goroutine that called Resize, or that wrote DECCOLM with WithColumnSwitching.
+func WithResizeHandler(fn func(cols, rows int)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onResize = fn
+	}
+}
+
+// setSize resizes the terminal, locking it, and reports whether the size changed. Sizes outside [1, 2048] are
+// ignored. Applications that enabled in-band resize notifications (DECSET 2048) are sent the new size.
+func (t *State) setSize(cols, rows int) bool {
+	t.lock()
+	defer t.unlock()
+
+	if cols == t.cols && rows == t.rows {
+		return false
+	}
+	t.resize(cols, rows)
+	if cols != t.cols || rows != t.rows {
+		return false
+	}
+	if on, _ := t.privMode(2048); on {
+		t.reportInBandSize()
+	}
+	return true
+}
+
+// resized tells the resize handler, if there is one, that the terminal is now cols x rows. The terminal must not be
+// locked.
+func (t *State) resized(cols, rows int) {
+	if t.onResize != nil {
+		t.onResize(cols, rows)
+	}
+}
+
+// reportInBandSize sends the in-band resize notification: CSI 48 ; rows ; cols ; height ; width t, with the text
+// area's height and width in pixels.
+func (t *State) reportInBandSize() {
+	t.w.Write([]byte(fmt.Sprintf("\033[48;%d;%d;%d;%dt", t.rows, t.cols, t.rows*t.cellHeight, t.cols*t.cellWidth)))
+}
+
+// WindowOp is an XTWINOPS window operation, the first parameter of CSI Ps ; ... t.
+type WindowOp int
+
+// Window operations. The terminal has no window of its own, so it leaves the ones that act on a window to the window
+// handler, and answers the reports itself.
+const (
+	WindowDeiconify    WindowOp = 1  // de-iconify the window
+	WindowIconify      WindowOp = 2  // iconify the window
+	WindowMove         WindowOp = 3  // move the window to Args x, y in pixels
+	WindowResizePixels WindowOp = 4  // resize the text area to Args height, width in pixels
+	WindowRaise        WindowOp = 5  // raise the window to the front
+	WindowLower        WindowOp = 6  // lower the window to the bottom
+	WindowRefresh      WindowOp = 7  // redraw the window
+	WindowResizeChars  WindowOp = 8  // resize the text area to Args rows, cols
+	WindowMaximize     WindowOp = 9  // maximize or restore the window, as Args[0] is 1 or 0
+	WindowFullscreen   WindowOp = 10 // enter, leave or toggle full screen, as Args[0] is 1, 0 or 2
+
+	WindowReportState        WindowOp = 11 // report whether the window is iconified
+	WindowReportPosition     WindowOp = 13 // report the position of the window in pixels
+	WindowReportSizePixels   WindowOp = 14 // report the size of the text area in pixels
+	WindowReportScreenPixels WindowOp = 15 // report the size of the screen in pixels
+	WindowReportCellSize     WindowOp = 16 // report the size of a cell in pixels
+	WindowReportSizeChars    WindowOp = 18 // report the size of the text area in characters
+	WindowReportScreenChars  WindowOp = 19 // report the size of the screen in characters
+	WindowReportIconLabel    WindowOp = 20 // report the icon label
+	WindowReportTitle        WindowOp = 21 // report the window title
+
+	WindowPushTitle WindowOp = 22 // save the title on the title stack
+	WindowPopTitle  WindowOp = 23 // restore the title from the title stack
+
+	// WindowResizeLines resizes the window to Args[0] lines (DECSLPP). The sequence carries the number of lines as the
+	// operation itself, any value of 24 or more.
+	WindowResizeLines WindowOp = 24
+)
+
+// WindowRequest is a window operation an application asked for.
+type WindowRequest struct {
+	Op WindowOp
+
+	// Args are the parameters after the operation, as sent, with an omitted parameter as 0: CSI 8 ; ; 100 t, which
+	// keeps the height, has Args 0, 100.
+	Args []int
+}
+
+// defaultWindowReports are the reports the terminal answers unless configured otherwise. The title reports are left
+// out, as in xterm, since they let whatever set the title type into the application.
+const defaultWindowReports = 1<<WindowReportState | 1<<WindowReportPosition | 1<<WindowReportSizePixels |
+	1<<WindowReportScreenPixels | 1<<WindowReportCellSize | 1<<WindowReportSizeChars | 1<<WindowReportScreenChars
+
+// WithWindowHandler sets a function called with each window operation (XTWINOPS) the terminal receives, so embedders
+// can iconify, move or resize the window showing it. The terminal changes nothing about itself for the operations
+// that act on the window; in particular it does not resize, so honoring a resize request is up to the handler, by
+// calling Resize once the terminal is unlocked. It is called while the terminal is locked, so it must not call back
+// into the terminal.
+func WithWindowHandler(fn func(WindowRequest)) TerminalOption {
+	return func(info *TerminalInfo) {
+		info.onWindowOp = fn
+	}
+}
+
+// WithWindowReports sets which window reports the terminal answers through the writer set with WithWriter, in place
+// of the default of all but WindowReportIconLabel and WindowReportTitle. Reports left out, and any other operations,
+// are ignored, so a window handler can answer them instead.
+func WithWindowReports(ops ...WindowOp) TerminalOption {
+	return func(info *TerminalInfo) {
+		var reports uint32
+		for _, op := range ops {
+			if op >= WindowReportState && op <= WindowReportTitle {
+				reports |= 1 << op
+			}
+		}
+		info.windowReports = &reports
+	}
+}
+
+// windowOp handles CSI Ps ; ... t.
+func (t *State) windowOp(c *csiEscape) {
+	op := WindowOp(c.arg(0, 0))
+	var args []int
+	if len(c.args) > 1 {
+		args = append(args, c.args[1:]...)
+	}
+	if op >= WindowResizeLines {
+		op, args = WindowResizeLines, []int{int(op)}
+	}
+	if op < WindowDeiconify || op == 12 {
+		t.warnf("unknown window operation %q", c)
+		return
+	}
+
+	switch op {
+	case WindowPushTitle: // XTPUSHTITLE - push title (icon and window titles are not tracked separately)
+		t.pushTitle()
+	case WindowPopTitle: // XTPOPTITLE - pop title
+		t.popTitle()
+	}
+	if op <= WindowReportTitle && t.windowReports&(1<<op) != 0 {
+		t.reportWindow(op)
+	}
+	if t.onWindowOp != nil {
+		t.onWindowOp(WindowRequest{Op: op, Args: args})
+	}
+}
+
+// reportWindow answers a window report. The terminal stands for a window the size of its text area, at the top left
+// of a screen of the same size, that is never iconified.
+func (t *State) reportWindow(op WindowOp) {
+	var reply string
+	switch op {
+	case WindowReportState:
+		reply = "\033[1t"
+	case WindowReportPosition:
+		reply = "\033[3;0;0t"
+	case WindowReportSizePixels:
+		reply = fmt.Sprintf("\033[4;%d;%dt", t.rows*t.cellHeight, t.cols*t.cellWidth)
+	case WindowReportScreenPixels:
+		reply = fmt.Sprintf("\033[5;%d;%dt", t.rows*t.cellHeight, t.cols*t.cellWidth)
+	case WindowReportCellSize:
+		reply = fmt.Sprintf("\033[6;%d;%dt", t.cellHeight, t.cellWidth)
+	case WindowReportSizeChars:
+		reply = fmt.Sprintf("\033[8;%d;%dt", t.rows, t.cols)
+	case WindowReportScreenChars:
+		reply = fmt.Sprintf("\033[9;%d;%dt", t.rows, t.cols)
+	case WindowReportIconLabel:
+		reply = "\033]L" + t.title + "\033\\"
+	case WindowReportTitle:
+		reply = "\033]l" + t.title + "\033\\"
+	default:
+		return
+	}
+	t.w.Write([]byte(reply))
+}
@@ -0,0 +1,154 @@
+package vt10x
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestResizeHandler(t *testing.T) {
+	var sizes [][2]int
+	term := New(WithSize(80, 24), WithResizeHandler(func(cols, rows int) {
+		sizes = append(sizes, [2]int{cols, rows})
+	}))
+
+	term.Resize(100, 30)
+	term.Resize(100, 30) // unchanged
+	term.Resize(0, 30)   // ignored
+	term.Resize(40, 10)
+	want := [][2]int{{100, 30}, {40, 10}}
+	if len(sizes) != len(want) {
+		t.Fatalf("expected the handler called with %v, got %v", want, sizes)
+	}
+	for i := range want {
+		if sizes[i] != want[i] {
+			t.Fatalf("expected the handler called with %v, got %v", want, sizes)
+		}
+	}
+}
+
+func TestResizeHandlerUnlocked(t *testing.T) {
+	var term Terminal
+	var text string
+	term = New(WithResizeHandler(func(cols, rows int) {
+		// The handler may use the terminal.
+		text = term.String()
+	}))
+
+	term.Resize(4, 1)
+	if text != "    \n" {
+		t.Fatalf("expected the handler to see the resized screen, got %q", text)
+	}
+}
+
+func TestInBandResize(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithSize(80, 24), WithWriter(&buf))
+
+	term.Resize(100, 30)
+	if buf.Len() != 0 {
+		t.Fatalf("expected no report before DECSET 2048, got %q", buf.String())
+	}
+
+	writeSeq(t, term, "\033[?2048h")
+	if want := "\033[48;30;100;600;1000t"; buf.String() != want {
+		t.Fatalf("expected the current size reported on enabling, %q, got %q", want, buf.String())
+	}
+	buf.Reset()
+
+	term.Resize(40, 10)
+	term.Resize(40, 10)
+	if want := "\033[48;10;40;200;400t"; buf.String() != want {
+		t.Fatalf("expected one report of the new size, %q, got %q", want, buf.String())
+	}
+	buf.Reset()
+
+	writeSeq(t, term, "\033[?2048$p\033[?2048l")
+	term.Resize(80, 24)
+	if want := "\033[?2048;1$y"; buf.String() != want {
+		t.Fatalf("expected no report after DECRST 2048, got %q", buf.String())
+	}
+}
+
+func TestWindowSizeQueries(t *testing.T) {
+	var buf bytes.Buffer
+	term := New(WithSize(80, 24), WithCellSize(8, 16), WithWriter(&buf))
+
+	writeSeq(t, term, "\033[14t\033[18t")
+	if want := "\033[4;384;640t\033[8;24;80t"; buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestWindowReports(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []TerminalOption
+		seq  string
+		want string
+	}{
+		{"state", nil, "\033[11t", "\033[1t"},
+		{"position", nil, "\033[13t", "\033[3;0;0t"},
+		{"screen pixels", nil, "\033[15t", "\033[5;480;800t"},
+		{"cell size", nil, "\033[16t", "\033[6;20;10t"},
+		{"screen chars", nil, "\033[19t", "\033[9;24;80t"},
+		{"title not reported by default", nil, "\033]2;secret\007\033[20t\033[21t", ""},
+		{"title reported when enabled", []TerminalOption{WithWindowReports(WindowReportTitle, WindowReportIconLabel)},
+			"\033]2;hi\007\033[20t\033[21t", "\033]Lhi\033\\\033]lhi\033\\"},
+		{"reports disabled", []TerminalOption{WithWindowReports()}, "\033[14t\033[18t", ""},
+		{"only some reports", []TerminalOption{WithWindowReports(WindowReportSizeChars)}, "\033[14t\033[18t",
+			"\033[8;24;80t"},
+		{"operations get no reply", nil, "\033[1t\033[3;10;20t\033[8;40;100t", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			term := New(append(tc.opts, WithWriter(&buf))...)
+			writeSeq(t, term, tc.seq)
+			if got := buf.String(); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWindowHandler(t *testing.T) {
+	var reqs []WindowRequest
+	term := New(WithSize(80, 24), WithWindowHandler(func(r WindowRequest) {
+		reqs = append(reqs, r)
+	}))
+
+	writeSeq(t, term, "\033[2t\033[3;10;20t\033[8;40;100t\033[8;;100t\033[18t\033[48t\033[12t\033[t")
+	want := []WindowRequest{
+		{Op: WindowIconify},
+		{Op: WindowMove, Args: []int{10, 20}},
+		{Op: WindowResizeChars, Args: []int{40, 100}},
+		{Op: WindowResizeChars, Args: []int{0, 100}},
+		{Op: WindowReportSizeChars},
+		{Op: WindowResizeLines, Args: []int{48}},
+	}
+	if len(reqs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, reqs)
+	}
+	for i := range want {
+		if reqs[i].Op != want[i].Op || !slices.Equal(reqs[i].Args, want[i].Args) {
+			t.Fatalf("request %d: expected %v, got %v", i, want[i], reqs[i])
+		}
+	}
+	if cols, rows := term.Size(); cols != 80 || rows != 24 {
+		t.Fatalf("expected resize requests to leave the terminal %dx%d, got %dx%d", 80, 24, cols, rows)
+	}
+}
+
+func TestWindowTitleStack(t *testing.T) {
+	var ops []WindowOp
+	term := New(WithWindowHandler(func(r WindowRequest) { ops = append(ops, r.Op) }))
+
+	writeSeq(t, term, "\033]2;one\007\033[22t\033]2;two\007\033[23t")
+	if title := term.Title(); title != "one" {
+		t.Fatalf("expected the title popped, got %q", title)
+	}
+	if !slices.Equal(ops, []WindowOp{WindowPushTitle, WindowPopTitle}) {
+		t.Fatalf("expected the title stack operations passed on, got %v", ops)
+	}
+}